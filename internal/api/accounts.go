@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"fmt"
 	"net/http"
+	"strings"
 	"text/template"
 
 	"github.com/alexdglover/sage/internal/models"
@@ -56,6 +57,9 @@ type AccountFormDTO struct {
 	AccountTypeName string
 	AccountTypes    []models.AccountType // the DTO probably shouldn't be using the models
 	DefaultParser   string
+	NetWorthGroup   string
+	// Groups already in use, suggested when choosing a net worth group
+	NetWorthGroups []string
 }
 
 func (ac *AccountController) generateAccountsView(w http.ResponseWriter, req *http.Request) {
@@ -156,6 +160,7 @@ func (ac *AccountController) generateAccountForm(w http.ResponseWriter, req *htt
 			AccountID:       fmt.Sprint(account.ID),
 			AccountName:     account.Name,
 			AccountTypeName: account.AccountType.Name,
+			NetWorthGroup:   account.NetWorthGroup,
 		}
 	}
 
@@ -166,6 +171,12 @@ func (ac *AccountController) generateAccountForm(w http.ResponseWriter, req *htt
 	}
 	dto.AccountTypes = accountTypes
 
+	dto.NetWorthGroups, err = ac.AccountRepository.GetNetWorthGroups()
+	if err != nil {
+		http.Error(w, "Unable to get net worth groups", http.StatusInternalServerError)
+		return
+	}
+
 	tmpl := template.Must(template.New("accountsForm").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(accountFormTmpl))
 
@@ -202,6 +213,7 @@ func (ac *AccountController) upsertAccount(w http.ResponseWriter, req *http.Requ
 	}
 
 	account.Name = accountName
+	account.NetWorthGroup = strings.TrimSpace(req.FormValue("netWorthGroup"))
 	accountTypeID, err := utils.StringToUint(accountTypeIDFormValue)
 	if err != nil {
 		http.Error(w, "Unable to find parse an account type ID", http.StatusBadRequest)
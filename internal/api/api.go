@@ -6,6 +6,7 @@ import (
 	_ "embed"
 	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/alexdglover/sage/internal/utils/logger"
 )
@@ -22,6 +23,17 @@ type ApiServer struct {
 	TransactionController *TransactionController
 	SettingsController    *SettingsController
 	CashFlowController    *CashFlowReportHandler
+
+	CategorizerEvaluationController *CategorizerEvaluationController
+	RecategorizationController      *RecategorizationController
+	MerchantPatternController       *MerchantPatternController
+	TransferController              *TransferController
+	SubscriptionController          *SubscriptionController
+	NotificationController          *NotificationController
+	GoalController                  *GoalController
+	ReconciliationController        *ReconciliationController
+	InvestmentController            *InvestmentController
+	LoanController                  *LoanController
 }
 
 //go:embed assets
@@ -45,6 +57,12 @@ func (as *ApiServer) StartApiServer(ctx context.Context) {
 	http.HandleFunc("POST /import-submission", as.ImportController.importSubmissionHandler)
 
 	http.HandleFunc("GET /spending-by-category", as.SpendingController.spendingByCategoryHandler)
+	http.HandleFunc("GET /spending-by-tag", as.SpendingController.spendingByTagHandler)
+	http.HandleFunc("GET /subscriptions", as.SubscriptionController.generateSubscriptionsView)
+	http.HandleFunc("GET /reconciliation", as.ReconciliationController.generateReconciliationView)
+	http.HandleFunc("GET /reconciliation/period", as.ReconciliationController.generateReconciliationPeriodView)
+	http.HandleFunc("GET /investments", as.InvestmentController.generateInvestmentsView)
+	http.HandleFunc("POST /investments/classify", as.InvestmentController.classifyInvestmentTransactions)
 
 	http.HandleFunc("GET /accounts", as.AccountController.generateAccountsView)
 	http.HandleFunc("POST /accounts", as.AccountController.upsertAccount)
@@ -53,6 +71,12 @@ func (as *ApiServer) StartApiServer(ctx context.Context) {
 
 	http.HandleFunc("GET /balances", as.BalanceController.generateBalancesView)
 	http.HandleFunc("POST /balances", as.BalanceController.upsertBalance)
+	http.HandleFunc("GET /balances/derive", as.BalanceController.generateDeriveBalancesForm)
+	http.HandleFunc("POST /balances/derive", as.BalanceController.deriveBalances)
+	http.HandleFunc("DELETE /balances/derived", as.BalanceController.deleteDerivedBalances)
+	http.HandleFunc("GET /balances/valuationRule", as.BalanceController.generateValuationRuleForm)
+	http.HandleFunc("POST /balances/valuationRule", as.BalanceController.upsertValuationRule)
+	http.HandleFunc("DELETE /balances/valuationRule", as.BalanceController.deleteValuationRule)
 	http.HandleFunc("GET /balanceForm", as.BalanceController.generateBalanceForm)
 
 	http.HandleFunc("GET /budgets", as.BudgetController.generateBudgetsView)
@@ -60,23 +84,93 @@ func (as *ApiServer) StartApiServer(ctx context.Context) {
 	http.HandleFunc("DELETE /budgets", as.BudgetController.deleteBudget)
 	http.HandleFunc("GET /budgetForm", as.BudgetController.generateBudgetForm)
 	http.HandleFunc("GET /budgetDetails", as.BudgetController.generateBudgetDetailsView)
+	http.HandleFunc("GET /budgets/suggest", as.BudgetController.generateBudgetSuggestionsView)
+	http.HandleFunc("POST /budgets/suggest", as.BudgetController.acceptBudgetSuggestions)
+	http.HandleFunc("GET /goals", as.GoalController.generateGoalsView)
+	http.HandleFunc("POST /goals", as.GoalController.upsertGoal)
+	http.HandleFunc("DELETE /goals", as.GoalController.deleteGoal)
+	http.HandleFunc("GET /goalForm", as.GoalController.generateGoalForm)
+	http.HandleFunc("GET /goalDetails", as.GoalController.generateGoalDetailsView)
+	http.HandleFunc("GET /loans", as.LoanController.generateLoansView)
+	http.HandleFunc("POST /loans", as.LoanController.upsertLoan)
+	http.HandleFunc("DELETE /loans", as.LoanController.deleteLoan)
+	http.HandleFunc("GET /loanForm", as.LoanController.generateLoanForm)
+	http.HandleFunc("GET /loanDetails", as.LoanController.generateLoanDetailsView)
 
 	http.HandleFunc("GET /categories", as.CategoryController.generateCategoriesView)
 	http.HandleFunc("POST /categories", as.CategoryController.upsertCategory)
 	http.HandleFunc("DELETE /categories", as.CategoryController.deleteCategory)
 	http.HandleFunc("GET /categoryForm", as.CategoryController.generateCategoryForm)
+	http.HandleFunc("GET /categories/merge", as.CategoryController.generateCategoryMergeForm)
+	http.HandleFunc("POST /categories/merge", as.CategoryController.mergeCategories)
 
 	http.HandleFunc("GET /transactions", as.TransactionController.generateTransactionsView)
 	http.HandleFunc("POST /transactions", as.TransactionController.upsertTransaction)
 	http.HandleFunc("DELETE /transactions", as.TransactionController.deleteTransaction)
 	http.HandleFunc("GET /transactionForm", as.TransactionController.generateTransactionForm)
+	http.HandleFunc("GET /transactions/split", as.TransactionController.generateTransactionSplitForm)
+	http.HandleFunc("POST /transactions/split", as.TransactionController.saveTransactionSplits)
+	http.HandleFunc("DELETE /transactions/split", as.TransactionController.removeTransactionSplits)
+	http.HandleFunc("GET /transactions/attachments", as.TransactionController.downloadAttachment)
+	http.HandleFunc("POST /transactions/attachments", as.TransactionController.uploadAttachment)
+	http.HandleFunc("DELETE /transactions/attachments", as.TransactionController.deleteAttachment)
+	http.HandleFunc("GET /transfers", as.TransferController.generateTransfersView)
+	http.HandleFunc("POST /transfers/match", as.TransferController.matchTransfers)
+	http.HandleFunc("POST /transfers/confirm", as.TransferController.confirmTransferPair)
+	http.HandleFunc("POST /transfers/unlink", as.TransferController.unlinkTransferPair)
+	http.HandleFunc("GET /recategorize", as.RecategorizationController.generateRecategorizeView)
+	http.HandleFunc("POST /recategorize", as.RecategorizationController.applyRecategorization)
 
 	http.HandleFunc("GET /settings", as.SettingsController.generateSettingsView)
 	http.HandleFunc("POST /settings", as.SettingsController.upsertSettings)
+	http.HandleFunc("POST /settings/rebuild-model", as.SettingsController.rebuildModel)
+	http.HandleFunc("GET /categorizer-evaluation", as.CategorizerEvaluationController.generateEvaluationView)
+	http.HandleFunc("POST /categorizer-evaluation", as.CategorizerEvaluationController.evaluateBackends)
+
+	http.HandleFunc("GET /merchant-patterns", as.MerchantPatternController.generateMerchantPatternsView)
+	http.HandleFunc("POST /merchant-patterns", as.MerchantPatternController.upsertMerchantPattern)
+	http.HandleFunc("DELETE /merchant-patterns", as.MerchantPatternController.deleteMerchantPattern)
+	http.HandleFunc("GET /merchant-patterns/preview", as.MerchantPatternController.previewMerchantName)
 	http.HandleFunc("/cash-flow", as.CashFlowController.ServeHTTP)
 
+	http.HandleFunc("GET /notifications", as.NotificationController.generateNotificationsView)
+	http.HandleFunc("DELETE /notifications", as.NotificationController.deleteNotification)
+	http.HandleFunc("GET /notifications/badge", as.NotificationController.generateNotificationBadge)
+	http.HandleFunc("POST /notifications/read", as.NotificationController.markAllNotificationsRead)
+	http.HandleFunc("POST /notifications/check", as.NotificationController.checkAlerts)
+	http.HandleFunc("POST /alert-rules", as.NotificationController.upsertAlertRule)
+	http.HandleFunc("DELETE /alert-rules", as.NotificationController.deleteAlertRule)
+
 	logger := logger.Get()
 	logger.Info("Starting Server on http://localhost:8080")
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// Only listen on this machine, since anything that can reach the server can change the settings
+	log.Fatal(http.ListenAndServe("127.0.0.1:8080", sameOriginOnly(http.DefaultServeMux)))
+}
+
+// sameOriginOnly rejects requests that change something when they come from another website, like a form on
+// a web page that posts to localhost:8080 without the user knowing. Browsers say where a request came from in
+// the Sec-Fetch-Site header, or the Origin header if they're older. Requests without either, like ones from
+// curl, aren't from a website so they're allowed.
+func sameOriginOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, req)
+			return
+		}
+		if site := req.Header.Get("Sec-Fetch-Site"); site != "" {
+			if site != "same-origin" && site != "none" {
+				http.Error(w, "Cross-site requests aren't allowed", http.StatusForbidden)
+				return
+			}
+		} else if origin := req.Header.Get("Origin"); origin != "" {
+			originURL, err := url.Parse(origin)
+			if err != nil || originURL.Host != req.Host {
+				http.Error(w, "Cross-site requests aren't allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
 }
@@ -8,14 +8,19 @@ import (
 	"net/url"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
 type BalanceController struct {
-	AccountRepository *models.AccountRepository
-	BalanceRepository *models.BalanceRepository
+	AccountRepository       *models.AccountRepository
+	BalanceRepository       *models.BalanceRepository
+	BalanceHistoryService   *services.BalanceHistoryService
+	ValuationRuleRepository *models.ValuationRuleRepository
+	ValuationService        *services.ValuationService
 }
 
 //go:embed balances.html
@@ -24,6 +29,9 @@ var balancesPageTmpl string
 //go:embed balanceForm.html
 var balanceFormTmpl string
 
+//go:embed deriveBalancesForm.html
+var deriveBalancesFormTmpl string
+
 type BalanceDTO struct {
 	ID            uint
 	UpdatedAt     string
@@ -32,11 +40,14 @@ type BalanceDTO struct {
 	Amount        string
 	AccountID     uint
 	AccountName   string
+	Derived       bool
+	Estimated     bool
 }
 
 type BalancesPageDTO struct {
 	ActivePage          string // This is used to highlight the active page in the navigation
 	AccountID           uint
+	HasValuationRule    bool
 	Balances            []BalanceDTO
 	BalanceSaved        bool
 	BalanceSavedMessage string
@@ -55,6 +66,17 @@ type BalanceFormDTO struct {
 	ErrorMessage string
 }
 
+type DeriveBalancesFormDTO struct {
+	ActivePage  string
+	AccountID   uint
+	AccountName string
+	// Only reported balances can be rolled forwards and backwards from
+	AnchorBalances  []BalanceDTO
+	AnchorBalanceID uint
+	Granularity     string
+	ErrorMessage    string
+}
+
 func (bc *BalanceController) generateBalancesView(w http.ResponseWriter, req *http.Request) {
 	// Get all balances for a given account
 	accountIDQueryParameter := req.URL.Query().Get("accountID")
@@ -75,12 +97,16 @@ func (bc *BalanceController) generateBalancesView(w http.ResponseWriter, req *ht
 			Amount:        utils.CentsToDollarStringHumanized(balance.Amount),
 			AccountID:     balance.AccountID,
 			AccountName:   balance.Account.Name,
+			Derived:       balance.Derived,
+			Estimated:     balance.Estimated,
 		}
 	}
+	_, err = bc.ValuationRuleRepository.GetValuationRuleForAccount(accountID)
 	balancesPageDTO := BalancesPageDTO{
-		ActivePage: "balances",
-		AccountID:  accountID,
-		Balances:   balancesDTO,
+		ActivePage:       "balances",
+		AccountID:        accountID,
+		HasValuationRule: err == nil,
+		Balances:         balancesDTO,
 	}
 	if req.URL.Query().Get("balanceSaved") != "" {
 		balancesPageDTO.BalanceSaved = true
@@ -204,6 +230,11 @@ func (bc *BalanceController) upsertBalance(w http.ResponseWriter, req *http.Requ
 		http.Error(w, "Unable to save balance", http.StatusBadRequest)
 		return
 	}
+	// Re-estimate the account's other months around the new balance, if it has a valuation rule
+	err = bc.ValuationService.ApplyValuationRuleForAccount(accountID, time.Now())
+	if err != nil {
+		fmt.Println("Error applying valuation rule: ", err)
+	}
 
 	// Redirect to the balances page with the balanceSaved query parameter set to true
 	var balanceSavedMessage string
@@ -230,3 +261,93 @@ func (bc *BalanceController) upsertBalance(w http.ResponseWriter, req *http.Requ
 
 	bc.generateBalancesView(w, &balanceViewReq)
 }
+
+func (bc *BalanceController) generateDeriveBalancesForm(w http.ResponseWriter, req *http.Request) {
+	accountID, err := utils.StringToUint(req.URL.Query().Get("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	bc.renderDeriveBalancesForm(w, DeriveBalancesFormDTO{AccountID: accountID, Granularity: services.BalanceHistoryMonthEnd})
+}
+
+func (bc *BalanceController) renderDeriveBalancesForm(w http.ResponseWriter, dto DeriveBalancesFormDTO) {
+	dto.ActivePage = "balances"
+	account, err := bc.AccountRepository.GetAccountByID(dto.AccountID)
+	if err != nil {
+		http.Error(w, "Unable to get account", http.StatusInternalServerError)
+		return
+	}
+	dto.AccountName = account.Name
+	for _, balance := range bc.BalanceRepository.GetBalancesForAccount(context.TODO(), dto.AccountID) {
+		if balance.Derived || balance.Estimated {
+			continue
+		}
+		dto.AnchorBalances = append(dto.AnchorBalances, BalanceDTO{
+			ID:            balance.ID,
+			EffectiveDate: balance.EffectiveDate,
+			Amount:        utils.CentsToDollarStringHumanized(balance.Amount),
+		})
+	}
+
+	tmpl := template.Must(template.New("deriveBalancesForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(deriveBalancesFormTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// deriveBalances replaces the account's derived balances with ones rolled forwards and backwards from the
+// chosen reported balance
+func (bc *BalanceController) deriveBalances(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+	accountID, err := utils.StringToUint(req.FormValue("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	dto := DeriveBalancesFormDTO{AccountID: accountID, Granularity: req.FormValue("granularity")}
+	dto.AnchorBalanceID, err = utils.StringToUint(req.FormValue("anchorBalanceID"))
+	if err != nil || dto.AnchorBalanceID == 0 {
+		dto.ErrorMessage = "Choose a reported balance to start from"
+		bc.renderDeriveBalancesForm(w, dto)
+		return
+	}
+
+	derived, err := bc.BalanceHistoryService.DeriveBalances(accountID, dto.AnchorBalanceID, dto.Granularity, time.Now())
+	if err != nil {
+		fmt.Println("Error deriving balances: ", err)
+		dto.ErrorMessage = "Unable to derive balances: " + err.Error()
+		bc.renderDeriveBalancesForm(w, dto)
+		return
+	}
+	// Derived balances take the place of estimates in the same months
+	err = bc.ValuationService.ApplyValuationRuleForAccount(accountID, time.Now())
+	if err != nil {
+		fmt.Println("Error applying valuation rule: ", err)
+	}
+	bc.sendBalancesViewAfterChange(w, accountID, fmt.Sprintf("Derived %d balances from transactions", derived))
+}
+
+// deleteDerivedBalances removes every derived balance of an account, leaving the reported balances
+func (bc *BalanceController) deleteDerivedBalances(w http.ResponseWriter, req *http.Request) {
+	accountID, err := utils.StringToUint(req.FormValue("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	err = bc.BalanceRepository.ReplaceDerivedBalances(accountID, nil)
+	if err != nil {
+		http.Error(w, "Unable to delete derived balances", http.StatusInternalServerError)
+		return
+	}
+	bc.sendBalancesViewAfterChange(w, accountID, "Derived balances removed")
+}
+
+func (bc *BalanceController) sendBalancesViewAfterChange(w http.ResponseWriter, accountID uint, message string) {
+	queryValues := url.Values{}
+	queryValues.Add("balanceSaved", message)
+	queryValues.Add("accountID", fmt.Sprint(accountID))
+	bc.generateBalancesView(w, &http.Request{Method: "GET", URL: &url.URL{RawQuery: queryValues.Encode()}})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"text/template"
+	"time"
 
 	"github.com/alexdglover/sage/internal/models"
 	"github.com/alexdglover/sage/internal/services"
@@ -30,12 +31,66 @@ var budgetDetailTmpl string
 const ColorGreen string = "#198754"
 const ColorRed string = "#dc3545"
 
+// budgetPeriodLabel is how a budget period is described on the budget pages
+type budgetPeriodLabel struct {
+	Adjective string // e.g. "Monthly"
+	Noun      string // e.g. "month"
+	Plural    string // e.g. "months"
+}
+
+var budgetPeriodLabels = map[string]budgetPeriodLabel{
+	models.BudgetPeriodWeekly:    {Adjective: "Weekly", Noun: "week", Plural: "weeks"},
+	models.BudgetPeriodMonthly:   {Adjective: "Monthly", Noun: "month", Plural: "months"},
+	models.BudgetPeriodQuarterly: {Adjective: "Quarterly", Noun: "quarter", Plural: "quarters"},
+	models.BudgetPeriodAnnual:    {Adjective: "Annual", Noun: "year", Plural: "years"},
+}
+
+// budgetPeriodName names the period that starts on the date, e.g. "Aug" for a month or "Q3 2026" for a quarter
+func budgetPeriodName(period string, start time.Time) string {
+	switch period {
+	case models.BudgetPeriodWeekly:
+		return start.Format("Jan 2")
+	case models.BudgetPeriodQuarterly:
+		return fmt.Sprintf("Q%d %d", (int(start.Month())+2)/3, start.Year())
+	case models.BudgetPeriodAnnual:
+		return fmt.Sprint(start.Year())
+	default:
+		return utils.ConvertTimeToMonString(start)
+	}
+}
+
+// budgetPeriodLongName names the period that starts on the date unambiguously, e.g. "August 2026"
+func budgetPeriodLongName(period string, start time.Time) string {
+	switch period {
+	case models.BudgetPeriodWeekly:
+		return "Week of " + start.Format("Jan 2, 2006")
+	case models.BudgetPeriodQuarterly, models.BudgetPeriodAnnual:
+		return budgetPeriodName(period, start)
+	default:
+		return start.Format("January 2006")
+	}
+}
+
+// humanizedDate formats a YYYY-MM-DD date like "Jun 1, 2026"
+func humanizedDate(date string) string {
+	return utils.ISO8601DateStringToTime(date).Format("Jan 2, 2006")
+}
+
 type BudgetDTO struct {
 	ID           uint
 	CategoryName string
+	PeriodLabel  budgetPeriodLabel
 	Amount       string
 	Spend        string
 	PercentUsed  int
+	Rollover     bool
+	CarriedIn    string
+	Available    string
+	Overdrawn    bool
+	// Forecast of spending by the end of the period
+	ProjectedSpend    string
+	ProjectedToExceed bool
+	UpcomingCharges   []string // e.g. "Netflix, $15.49 on Oct 15"
 }
 
 type BudgetsPageDTO struct {
@@ -51,15 +106,50 @@ type BudgetFormDTO struct {
 	Updating     bool
 	BudgetID     string
 	CategoryName string
+	Period       string
 	Amount       string
-	Categories   []models.Category
+	Rollover     bool
+	// EffectiveDate is the first day a new or changed budget applies, in YYYY-MM-DD format
+	EffectiveDate string
+	// Categories in tree order, since budgets can be set on parent categories as well as subcategories
+	Categories   []*services.CategoryNode
+	Periods      []BudgetPeriodOptionDTO
+	ErrorMessage string
+}
+
+type BudgetPeriodOptionDTO struct {
+	Value string
+	Label string
+}
+
+type BudgetDataByPeriodDTO struct {
+	HasBudget bool
+	Amount    string
+	Color     string
+	Period    string
+	Spend     string
+	// Humanized amounts for the table under the chart
+	AmountHumanized string
+	SpendHumanized  string
 }
 
-type BudgetDataByMonthDTO struct {
-	Amount string
-	Color  string
-	Month  string
-	Spend  string
+// RolloverPeriodDTO is one period of a rollover budget's carryover history
+type RolloverPeriodDTO struct {
+	Period    string
+	Allocated string
+	CarriedIn string
+	Spent     string
+	Available string
+	Overdrawn bool
+}
+
+// BudgetHistoryDTO describes one of the amounts a category's budget has had
+type BudgetHistoryDTO struct {
+	Amount      string
+	PeriodLabel budgetPeriodLabel
+	Rollover    bool
+	StartDate   string
+	EndDate     string
 }
 
 type BudgetDetailDTO struct {
@@ -68,21 +158,23 @@ type BudgetDetailDTO struct {
 	CategoryName        string
 	NumOfMonthsExceeded string
 	ExceededColor       string
+	PeriodLabel         budgetPeriodLabel
+	NumOfPeriods        int
 	Average             string
 	StdDev              string
 	Volatility          string
-	BudgetData          []BudgetDataByMonthDTO
+	BudgetData          []BudgetDataByPeriodDTO
+	History             []BudgetHistoryDTO
+	// Only set for rollover budgets. RolloverHistory is newest first.
+	Rollover        bool
+	Available       string
+	Overdrawn       bool
+	RolloverHistory []RolloverPeriodDTO
 }
 
 func (bc *BudgetController) generateBudgetForm(w http.ResponseWriter, req *http.Request) {
 	var dto BudgetFormDTO
-	dto.ActivePage = "budgets"
-
-	categories, err := bc.CategoryRepository.GetAllCategories()
-	if err != nil {
-		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
-	}
-	dto.Categories = categories
+	dto.Period = models.BudgetPeriodMonthly
 
 	budgetIDQueryParameter := req.URL.Query().Get("budgetID")
 	if budgetIDQueryParameter != "" {
@@ -100,7 +192,13 @@ func (bc *BudgetController) generateBudgetForm(w http.ResponseWriter, req *http.
 		dto.Updating = true
 		dto.BudgetID = fmt.Sprint(budget.ID)
 		dto.CategoryName = budget.Category.Name
+		if budget.Period != "" {
+			dto.Period = budget.Period
+		}
 		dto.Amount = utils.CentsToDollarStringHumanized(budget.Amount)
+		dto.Rollover = budget.Rollover
+		// Changes apply from the start of the current period by default, so earlier periods keep their budget
+		dto.EffectiveDate = utils.TimeToISO8601DateString(models.BudgetPeriodStart(dto.Period, time.Now()))
 	} else {
 		dto.Updating = false
 	}
@@ -112,6 +210,22 @@ func (bc *BudgetController) generateBudgetForm(w http.ResponseWriter, req *http.
 		dto.CategoryName = categoryNameQueryParameter
 	}
 
+	bc.renderBudgetForm(w, dto)
+}
+
+func (bc *BudgetController) renderBudgetForm(w http.ResponseWriter, dto BudgetFormDTO) {
+	dto.ActivePage = "budgets"
+
+	categories, err := bc.CategoryRepository.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
+	}
+	dto.Categories = services.FlattenCategoryTree(services.BuildCategoryTree(categories))
+	for _, period := range models.BudgetPeriods {
+		dto.Periods = append(dto.Periods, BudgetPeriodOptionDTO{Value: period, Label: budgetPeriodLabels[period].Adjective})
+	}
+
 	tmpl := template.Must(template.New("budgetForm").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(budgetsFormTmpl))
 
@@ -121,12 +235,18 @@ func (bc *BudgetController) generateBudgetForm(w http.ResponseWriter, req *http.
 	}
 }
 
+// upsertBudget creates or updates a budget. If an existing budget's amount or period changes from a date
+// after it started, the existing budget ends the day before and a new one starts on that date, so earlier
+// periods keep being compared against the old budget.
 func (bc *BudgetController) upsertBudget(w http.ResponseWriter, req *http.Request) {
 	req.ParseForm()
 
 	budgetID := req.FormValue("budgetID")
 	budgetCategory := req.FormValue("budgetCategory")
+	period := req.FormValue("period")
 	amount := req.FormValue("amount")
+	effectiveDate := req.FormValue("effectiveDate")
+	rollover := req.FormValue("rollover") == "on"
 
 	var budget models.Budget
 
@@ -150,17 +270,53 @@ func (bc *BudgetController) upsertBudget(w http.ResponseWriter, req *http.Reques
 		http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
 		return
 	}
-	budget.CategoryID = budgetCategoryID
-	category, err := bc.CategoryRepository.GetCategoryByID(budget.CategoryID)
+	category, err := bc.CategoryRepository.GetCategoryByID(budgetCategoryID)
 	if err != nil {
 		panic("failed to get category from CategoryID")
 	}
-	budget.Category = category
 
-	budget.Amount = utils.DollarStringToCents(amount)
+	if _, ok := models.BudgetPeriodsPerYear[period]; !ok {
+		http.Error(w, "Unable to parse budget period", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", effectiveDate); effectiveDate != "" && err != nil {
+		bc.renderBudgetForm(w, BudgetFormDTO{
+			Updating:      budgetID != "",
+			BudgetID:      budgetID,
+			CategoryName:  category.Name,
+			Period:        period,
+			Amount:        amount,
+			Rollover:      rollover,
+			EffectiveDate: effectiveDate,
+			ErrorMessage:  "The effective date must be a real date in YYYY-MM-DD format",
+		})
+		return
+	}
 
-	_, err = bc.BudgetRepository.Save(budget)
+	amountInCents := utils.DollarStringToCents(amount)
+	changed := amountInCents != budget.Amount || period != budget.Period || rollover != budget.Rollover
+	if budget.ID != 0 && changed && effectiveDate != "" && effectiveDate > budget.StartDate {
+		_, err = bc.BudgetRepository.Supersede(budget, models.Budget{
+			Amount:     amountInCents,
+			Period:     period,
+			Rollover:   rollover,
+			StartDate:  effectiveDate,
+			CategoryID: category.ID,
+		})
+	} else {
+		budget.CategoryID = category.ID
+		budget.Category = category
+		budget.Period = period
+		budget.Rollover = rollover
+		budget.Amount = amountInCents
+		// Otherwise this corrects the budget for every period it has applied to, so it keeps its start date
+		if budget.ID == 0 {
+			budget.StartDate = effectiveDate
+		}
+		_, err = bc.BudgetRepository.Save(budget)
+	}
 	if err != nil {
+		fmt.Println("Error saving budget: ", err)
 		http.Error(w, "Unable to save budget", http.StatusBadRequest)
 		return
 	}
@@ -204,9 +360,21 @@ func (bc *BudgetController) sendViewResponse(w http.ResponseWriter, update bool)
 		budgetsDTO[i] = BudgetDTO{
 			ID:           budget.ID,
 			CategoryName: budget.CategoryName,
+			PeriodLabel:  budgetPeriodLabels[budget.Period],
 			Amount:       utils.CentsToDollarStringHumanized(budget.Amount),
 			Spend:        utils.CentsToDollarStringHumanized(budget.Spend),
 			PercentUsed:  budget.PercentUsed,
+			Rollover:     budget.Rollover,
+			CarriedIn:    utils.CentsToDollarStringHumanized(budget.CarriedIn),
+			Available:    utils.CentsToDollarStringHumanized(budget.Available),
+			Overdrawn:    budget.Available < 0,
+
+			ProjectedSpend:    utils.CentsToDollarStringHumanized(budget.Forecast.ProjectedSpend),
+			ProjectedToExceed: budget.Forecast.ProjectedToExceed,
+		}
+		for _, charge := range budget.Forecast.UpcomingCharges {
+			budgetsDTO[i].UpcomingCharges = append(budgetsDTO[i].UpcomingCharges, fmt.Sprintf("%v, $%v on %v",
+				charge.Merchant, utils.CentsToDollarStringHumanized(charge.LatestAmount), charge.NextExpectedDate.Format("Jan 2")))
 		}
 	}
 	budgetsPageDTO := BudgetsPageDTO{
@@ -228,7 +396,7 @@ func (bc *BudgetController) sendViewResponse(w http.ResponseWriter, update bool)
 	}
 }
 
-// Generate the budget details view, pulling detailed statistics about the budget over the last 6 months
+// Generate the budget details view, comparing spending against the budget in force over the last 6 periods
 func (bc *BudgetController) generateBudgetDetailsView(w http.ResponseWriter, req *http.Request) {
 	budgetIDQueryParameter := req.URL.Query().Get("budgetID")
 	budgetID, err := utils.StringToUint(budgetIDQueryParameter)
@@ -245,48 +413,102 @@ func (bc *BudgetController) generateBudgetDetailsView(w http.ResponseWriter, req
 		return
 	}
 
-	// Get comparison of budget vs spend for the last 6 months
-	spendAndBudgetByMonth, err := bc.BudgetService.GetBudgetAndMonthlySpend(budgetID, 6)
+	// Get comparison of budget vs spend for the last 6 periods
+	numOfPeriods := 6
+	spendAndBudgetByPeriod, err := bc.BudgetService.GetBudgetAndSpendByPeriod(budgetID, numOfPeriods)
 	if err != nil {
 		message := fmt.Sprintf("Error fetching spend and budget info for budget: %v Reason: %v", budgetID, err)
 		http.Error(w, message, http.StatusInternalServerError)
 		return
 	}
 	timesExceeded := 0
-	budgetData := []BudgetDataByMonthDTO{}
-	for _, spendAndBudget := range spendAndBudgetByMonth {
-		if spendAndBudget.Spend > spendAndBudget.Amount {
+	budgetData := []BudgetDataByPeriodDTO{}
+	for _, spendAndBudget := range spendAndBudgetByPeriod {
+		exceeded := spendAndBudget.HasBudget && spendAndBudget.Spend > spendAndBudget.Amount
+		if exceeded {
 			timesExceeded++
 		}
 		var color string
-		if spendAndBudget.Spend > spendAndBudget.Amount {
+		if exceeded {
 			color = ColorRed
 		} else {
 			color = ColorGreen
 		}
-		budgetData = append(budgetData, BudgetDataByMonthDTO{
-			Amount: utils.CentsToDollarStringMachineSafe(spendAndBudget.Amount),
-			Color:  color,
-			Month:  utils.ConvertTimeToMonString(spendAndBudget.Month),
-			Spend:  utils.CentsToDollarStringMachineSafe(spendAndBudget.Spend),
+		budgetData = append(budgetData, BudgetDataByPeriodDTO{
+			HasBudget:       spendAndBudget.HasBudget,
+			Amount:          utils.CentsToDollarStringMachineSafe(spendAndBudget.Amount),
+			Color:           color,
+			Period:          budgetPeriodName(spendAndBudget.Period, spendAndBudget.PeriodStart),
+			Spend:           utils.CentsToDollarStringMachineSafe(spendAndBudget.Spend),
+			AmountHumanized: utils.CentsToDollarStringHumanized(spendAndBudget.Amount),
+			SpendHumanized:  utils.CentsToDollarStringHumanized(spendAndBudget.Spend),
 		})
 	}
 
-	averageSpend, spendStdDeviation, err := bc.BudgetService.GetMeanAndStandardDeviation(budgetID, 6)
+	averageSpend, spendStdDeviation, err := bc.BudgetService.GetMeanAndStandardDeviation(budgetID, numOfPeriods)
 	if err != nil {
 		message := fmt.Sprintf("Error fetching average spend and standard deviation for budget: %v Reason: %v", budgetID, err)
 		http.Error(w, message, http.StatusInternalServerError)
 		return
 	}
 
+	categoryBudgets, err := bc.BudgetRepository.GetBudgetsForCategory(budget.CategoryID)
+	if err != nil {
+		http.Error(w, "Unable to get budget history", http.StatusInternalServerError)
+		return
+	}
+	history := []BudgetHistoryDTO{}
+	for _, categoryBudget := range categoryBudgets {
+		historyDTO := BudgetHistoryDTO{
+			Amount:      utils.CentsToDollarStringHumanized(categoryBudget.Amount),
+			PeriodLabel: budgetPeriodLabels[categoryBudget.Period],
+			Rollover:    categoryBudget.Rollover,
+		}
+		if categoryBudget.StartDate != "" {
+			historyDTO.StartDate = humanizedDate(categoryBudget.StartDate)
+		}
+		if categoryBudget.EndDate != "" {
+			historyDTO.EndDate = humanizedDate(categoryBudget.EndDate)
+		}
+		history = append(history, historyDTO)
+	}
+
 	budgetDetailDTO := BudgetDetailDTO{
 		ActivePage:          "budgets",
 		ID:                  budgetID,
 		CategoryName:        budget.Category.Name,
 		NumOfMonthsExceeded: fmt.Sprint(timesExceeded),
+		PeriodLabel:         budgetPeriodLabels[budget.Period],
+		NumOfPeriods:        numOfPeriods,
 		Average:             utils.CentsToDollarStringHumanized(averageSpend),
 		StdDev:              utils.CentsToDollarStringHumanized(spendStdDeviation),
 		BudgetData:          budgetData,
+		History:             history,
+	}
+
+	if budget.Rollover {
+		rolloverHistory, err := bc.BudgetService.GetRolloverHistory(budget)
+		if err != nil {
+			http.Error(w, "Unable to get rollover history", http.StatusInternalServerError)
+			return
+		}
+		for i := len(rolloverHistory) - 1; i >= 0; i-- {
+			rolloverPeriod := rolloverHistory[i]
+			budgetDetailDTO.RolloverHistory = append(budgetDetailDTO.RolloverHistory, RolloverPeriodDTO{
+				Period:    budgetPeriodLongName(budget.Period, rolloverPeriod.PeriodStart),
+				Allocated: utils.CentsToDollarStringHumanized(rolloverPeriod.Allocated),
+				CarriedIn: utils.CentsToDollarStringHumanized(rolloverPeriod.CarriedIn),
+				Spent:     utils.CentsToDollarStringHumanized(rolloverPeriod.Spent),
+				Available: utils.CentsToDollarStringHumanized(rolloverPeriod.Available),
+				Overdrawn: rolloverPeriod.Available < 0,
+			})
+		}
+		if len(rolloverHistory) > 0 {
+			current := rolloverHistory[len(rolloverHistory)-1]
+			budgetDetailDTO.Rollover = true
+			budgetDetailDTO.Available = utils.CentsToDollarStringHumanized(current.Available)
+			budgetDetailDTO.Overdrawn = current.Available < 0
+		}
 	}
 
 	if timesExceeded >= 3 {
@@ -0,0 +1,147 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+//go:embed budgetSuggestions.html
+var budgetSuggestionsTmpl string
+
+// Defaults for the suggest budgets wizard
+const (
+	defaultSuggestionMonths     = 6
+	defaultSuggestionPercentile = 75
+)
+
+type BudgetSuggestionsPageDTO struct {
+	ActivePage   string
+	Months       int
+	MonthOptions []int
+	Method       string
+	Percentile   int
+	Suggestions  []BudgetSuggestionDTO
+	ErrorMessage string
+}
+
+type BudgetSuggestionDTO struct {
+	CategoryID   uint
+	CategoryPath string
+	Mean         string
+	Median       string
+	Max          string
+	// How many of the months looked at had any spending, e.g. a bill paid every other month is 3 of 6
+	MonthsWithSpending int
+	Amount             string
+}
+
+// generateBudgetSuggestionsView shows a suggested monthly budget for each expense category without a budget,
+// based on its spending over the last few months
+func (bc *BudgetController) generateBudgetSuggestionsView(w http.ResponseWriter, req *http.Request) {
+	dto := BudgetSuggestionsPageDTO{
+		ActivePage:   "budgets",
+		Months:       defaultSuggestionMonths,
+		MonthOptions: []int{3, 6, 12, 24},
+		Method:       services.SuggestionMethodMedian,
+		Percentile:   defaultSuggestionPercentile,
+	}
+	query := req.URL.Query()
+	if query.Get("months") != "" {
+		months, err := strconv.Atoi(query.Get("months"))
+		if err != nil {
+			http.Error(w, "Unable to parse months", http.StatusBadRequest)
+			return
+		}
+		dto.Months = months
+	}
+	if query.Get("method") != "" {
+		dto.Method = query.Get("method")
+	}
+	if query.Get("percentile") != "" {
+		percentile, err := strconv.Atoi(query.Get("percentile"))
+		if err != nil {
+			http.Error(w, "Unable to parse percentile", http.StatusBadRequest)
+			return
+		}
+		dto.Percentile = percentile
+	}
+
+	suggestions, err := bc.BudgetService.SuggestBudgets(dto.Months, dto.Method, dto.Percentile)
+	if err != nil {
+		// The only errors from bad input are the number of months and percentile, which can be corrected
+		dto.ErrorMessage = fmt.Sprintf("Unable to suggest budgets: %v", err)
+	}
+	for _, suggestion := range suggestions {
+		monthsWithSpending := 0
+		for _, amount := range suggestion.MonthlySpend {
+			if amount > 0 {
+				monthsWithSpending++
+			}
+		}
+		dto.Suggestions = append(dto.Suggestions, BudgetSuggestionDTO{
+			CategoryID:         suggestion.CategoryID,
+			CategoryPath:       suggestion.CategoryPath,
+			Mean:               utils.CentsToDollarStringHumanized(suggestion.Mean),
+			Median:             utils.CentsToDollarStringHumanized(suggestion.Median),
+			Max:                utils.CentsToDollarStringHumanized(suggestion.Max),
+			MonthsWithSpending: monthsWithSpending,
+			Amount:             utils.CentsToDollarStringMachineSafe(suggestion.SuggestedAmount),
+		})
+	}
+	bc.renderBudgetSuggestions(w, dto)
+}
+
+func (bc *BudgetController) renderBudgetSuggestions(w http.ResponseWriter, dto BudgetSuggestionsPageDTO) {
+	tmpl := template.Must(template.New("budgetSuggestions").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(budgetSuggestionsTmpl))
+	err := utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// acceptBudgetSuggestions creates a monthly budget for every accepted suggestion, using the amount as it was
+// edited on the page. Like new budgets from the budget form, they apply to every period.
+func (bc *BudgetController) acceptBudgetSuggestions(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+
+	var budgets []models.Budget
+	for _, categoryIDInput := range req.Form["categoryID"] {
+		if req.FormValue("accept-"+categoryIDInput) != "on" {
+			continue
+		}
+		categoryID, err := utils.StringToUint(categoryIDInput)
+		if err != nil {
+			http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
+			return
+		}
+		amount := req.FormValue("amount-" + categoryIDInput)
+		if _, err := strconv.ParseFloat(amount, 64); err != nil || !utils.AmountValid(amount) {
+			http.Error(w, fmt.Sprintf("Invalid amount %q", amount), http.StatusBadRequest)
+			return
+		}
+		budgets = append(budgets, models.Budget{
+			Amount:     utils.DollarStringToCents(amount),
+			Period:     models.BudgetPeriodMonthly,
+			CategoryID: categoryID,
+		})
+	}
+
+	err := bc.BudgetRepository.CreateBudgets(budgets)
+	if err != nil {
+		fmt.Println("Error creating budgets: ", err)
+		http.Error(w, "Unable to create budgets", http.StatusInternalServerError)
+		return
+	}
+	bc.sendViewResponse(w, true)
+}
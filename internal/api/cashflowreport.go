@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"net/http"
 	"text/template"
-	"time"
 
 	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
@@ -14,13 +13,16 @@ import (
 var cashFlowTmpl string
 
 type CashFlowReportHandler struct {
-	cashFlowService *services.CashFlowService
+	cashFlowService  *services.CashFlowService
+	dateRangeService *services.DateRangeService
 }
 
 type ExpenseData struct {
 	Name                string
 	Amount              string
 	AmountHumanReadable string
+	// Label of the parent category's node for subcategories, empty for top level categories
+	ParentLabel string
 }
 
 type CashFlowDto struct {
@@ -32,41 +34,28 @@ type CashFlowDto struct {
 	TotalIncomeHumanReadable   string
 	TotalExpenses              string
 	TotalExpensesHumanReadable string
+	TotalSavings               string
+	TotalSavingsHumanReadable  string
 	Expenses                   []ExpenseData
-	StartDate                  string // YYYY-MM-DD for date input
-	EndDate                    string // YYYY-MM-DD for date input
+	DateRangeDTO
 }
 
-func NewCashFlowReportHandler(cfs *services.CashFlowService) *CashFlowReportHandler {
+func NewCashFlowReportHandler(cfs *services.CashFlowService, drs *services.DateRangeService) *CashFlowReportHandler {
 	return &CashFlowReportHandler{
-		cashFlowService: cfs,
+		cashFlowService:  cfs,
+		dateRangeService: drs,
 	}
 }
 
 func (h *CashFlowReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Parse startDate and endDate from query params
-	query := r.URL.Query()
-	startDateStr := query.Get("startDate")
-	endDateStr := query.Get("endDate")
-
-	var startDate, endDate time.Time
-	var err error
-
-	if endDateStr != "" {
-		endDate = utils.ISO8601DateStringToTime(endDateStr)
-	} else {
-		endDate = time.Now()
-		endDateStr = utils.TimeToISO8601DateString(endDate)
-	}
-
-	if startDateStr != "" {
-		startDate = utils.ISO8601DateStringToTime(startDateStr)
-	} else {
-		startDate = endDate.AddDate(0, -3, 0)
-		startDateStr = utils.TimeToISO8601DateString(startDate)
+	var dateRangeDTO DateRangeDTO
+	dateRange, err := parseDateRange(r, h.dateRangeService, "/cash-flow", services.DateRangeLast3Months, &dateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	cashFlowData, err := h.cashFlowService.GetCashFlowData(r.Context(), startDate, endDate)
+	cashFlowData, err := h.cashFlowService.GetCashFlowData(r.Context(), dateRange.StartDate, dateRange.EndDate)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -89,15 +78,20 @@ func (h *CashFlowReportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		TotalIncomeHumanReadable:   utils.CentsToDollarStringHumanized(cashFlowData.TotalIncome),
 		TotalExpenses:              utils.CentsToDollarStringMachineSafe(cashFlowData.TotalExpenses),
 		TotalExpensesHumanReadable: utils.CentsToDollarStringHumanized(cashFlowData.TotalExpenses),
-		StartDate:                  startDateStr,
-		EndDate:                    endDateStr,
+		TotalSavings:               utils.CentsToDollarStringMachineSafe(cashFlowData.TotalSavings),
+		TotalSavingsHumanReadable:  utils.CentsToDollarStringHumanized(cashFlowData.TotalSavings),
+		DateRangeDTO:               dateRangeDTO,
 	}
 	for _, expense := range cashFlowData.Expenses {
-		dto.Expenses = append(dto.Expenses, ExpenseData{
+		expenseData := ExpenseData{
 			Name:                expense.Name,
 			Amount:              utils.CentsToDollarStringMachineSafe(expense.Amount),
 			AmountHumanReadable: utils.CentsToDollarStringHumanized(expense.Amount),
-		})
+		}
+		if expense.ParentName != "" {
+			expenseData.ParentLabel = expense.ParentName + " - $" + utils.CentsToDollarStringHumanized(expense.ParentAmount)
+		}
+		dto.Expenses = append(dto.Expenses, expenseData)
 	}
 
 	tmpl := template.Must(template.New("cashFlow").Parse(pageComponents))
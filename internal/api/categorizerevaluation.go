@@ -0,0 +1,193 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type CategorizerEvaluationController struct {
+	Categorizer *services.MLCategorizer
+
+	// Cross-validating every backend is slow, so the last evaluation is kept until it's run again
+	mutex          sync.Mutex
+	lastEvaluation *categorizerEvaluationRun
+}
+
+// categorizerEvaluationRun is the result of cross-validating every backend at once
+type categorizerEvaluationRun struct {
+	Folds       int
+	EvaluatedAt time.Time
+	Evaluations map[string]services.CategorizerEvaluation
+	Errors      map[string]error
+}
+
+//go:embed categorizerEvaluation.html
+var categorizerEvaluationTmpl string
+
+type backendEvaluationSummary struct {
+	Name           string
+	Label          string
+	Selected       bool
+	Current        bool
+	Accuracy       string
+	MacroPrecision string
+	MacroRecall    string
+}
+
+type categoryEvaluationRow struct {
+	CategoryName string
+	Precision    string
+	Recall       string
+	F1           string
+	Support      int
+}
+
+type confusionMatrixCell struct {
+	Count   int
+	Correct bool
+}
+
+type confusionMatrixRow struct {
+	CategoryName string
+	Cells        []confusionMatrixCell
+}
+
+type CategorizerEvaluationPageDTO struct {
+	ActivePage      string
+	Backends        []backendEvaluationSummary
+	SelectedBackend string
+	SelectedLabel   string
+	Folds           int
+	SampleCount     int
+	ErrorMessage    string
+	// EvaluatedAt is empty until the backends have been evaluated
+	EvaluatedAt string
+
+	Categories      []categoryEvaluationRow
+	MatrixLabels    []string
+	ConfusionMatrix []confusionMatrixRow
+}
+
+// generateEvaluationView shows the last evaluation of the categorizer backends, with the full breakdown for the
+// selected backend. Backends are only evaluated on request, since cross-validating all of them is slow.
+func (cec *CategorizerEvaluationController) generateEvaluationView(w http.ResponseWriter, req *http.Request) {
+	cec.mutex.Lock()
+	run := cec.lastEvaluation
+	cec.mutex.Unlock()
+	cec.renderEvaluation(w, req, run)
+}
+
+// evaluateBackends cross-validates every categorizer backend against the training set and shows the results
+func (cec *CategorizerEvaluationController) evaluateBackends(w http.ResponseWriter, req *http.Request) {
+	folds := services.DefaultEvaluationFolds
+	if req.FormValue("folds") != "" {
+		var err error
+		folds, err = strconv.Atoi(req.FormValue("folds"))
+		if err != nil || folds < 2 || folds > 20 {
+			http.Error(w, "Invalid value for folds, must be between 2 and 20", http.StatusBadRequest)
+			return
+		}
+	}
+
+	run := &categorizerEvaluationRun{
+		Folds:       folds,
+		EvaluatedAt: time.Now(),
+		Evaluations: map[string]services.CategorizerEvaluation{},
+		Errors:      map[string]error{},
+	}
+	for _, backend := range services.GetCategorizerBackendOptions() {
+		evaluation, err := cec.Categorizer.EvaluateBackend(backend.Name, folds)
+		if err != nil {
+			fmt.Println("Error evaluating categorizer backend ", backend.Name, ": ", err)
+			run.Errors[backend.Name] = err
+			continue
+		}
+		run.Evaluations[backend.Name] = evaluation
+	}
+
+	cec.mutex.Lock()
+	cec.lastEvaluation = run
+	cec.mutex.Unlock()
+	cec.renderEvaluation(w, req, run)
+}
+
+func (cec *CategorizerEvaluationController) renderEvaluation(w http.ResponseWriter, req *http.Request, run *categorizerEvaluationRun) {
+	currentBackend := cec.Categorizer.GetModelStatus().Backend
+	dto := CategorizerEvaluationPageDTO{
+		ActivePage:      "settings",
+		SelectedBackend: currentBackend,
+		Folds:           services.DefaultEvaluationFolds,
+	}
+	if req.FormValue("backend") != "" {
+		dto.SelectedBackend = req.FormValue("backend")
+	}
+	if run != nil {
+		dto.Folds = run.Folds
+		dto.EvaluatedAt = run.EvaluatedAt.Format("Jan 2, 2006 3:04 PM")
+	}
+
+	var selectedEvaluation services.CategorizerEvaluation
+	for _, backend := range services.GetCategorizerBackendOptions() {
+		summary := backendEvaluationSummary{
+			Name:     backend.Name,
+			Label:    backend.Label,
+			Selected: backend.Name == dto.SelectedBackend,
+			Current:  backend.Name == currentBackend,
+		}
+		if run != nil {
+			if err, ok := run.Errors[backend.Name]; ok {
+				dto.ErrorMessage = err.Error()
+			} else {
+				evaluation := run.Evaluations[backend.Name]
+				summary.Accuracy = formatRatio(evaluation.Accuracy)
+				summary.MacroPrecision = formatRatio(evaluation.MacroPrecision)
+				summary.MacroRecall = formatRatio(evaluation.MacroRecall)
+				if summary.Selected {
+					selectedEvaluation = evaluation
+				}
+			}
+		}
+		if summary.Selected {
+			dto.SelectedLabel = backend.Label
+		}
+		dto.Backends = append(dto.Backends, summary)
+	}
+	if dto.SelectedLabel == "" {
+		http.Error(w, "Unknown categorizer backend", http.StatusBadRequest)
+		return
+	}
+
+	dto.SampleCount = selectedEvaluation.SampleCount
+	for i, category := range selectedEvaluation.Categories {
+		dto.Categories = append(dto.Categories, categoryEvaluationRow{
+			CategoryName: category.CategoryName,
+			Precision:    formatRatio(category.Precision),
+			Recall:       formatRatio(category.Recall),
+			F1:           formatRatio(category.F1),
+			Support:      category.Support,
+		})
+		dto.MatrixLabels = append(dto.MatrixLabels, category.CategoryName)
+
+		row := confusionMatrixRow{CategoryName: category.CategoryName}
+		for j, count := range selectedEvaluation.ConfusionMatrix[i] {
+			row.Cells = append(row.Cells, confusionMatrixCell{Count: count, Correct: i == j})
+		}
+		dto.ConfusionMatrix = append(dto.ConfusionMatrix, row)
+	}
+
+	tmpl := template.Must(template.New("categorizerEvaluation").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(categorizerEvaluationTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func formatRatio(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
@@ -2,12 +2,14 @@ package api
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"text/template"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
@@ -15,6 +17,7 @@ type CategoryController struct {
 	CategoryRepository *models.CategoryRepository
 	BalanceRepository  *models.BalanceRepository
 	BudgetRepository   *models.BudgetRepository
+	Categorizer        *services.MLCategorizer
 }
 
 //go:embed categories.html
@@ -23,10 +26,28 @@ var categoriesPageTmpl string
 //go:embed categoryForm.html
 var categoryFormTmpl string
 
+//go:embed categoryMergeForm.html
+var categoryMergeFormTmpl string
+
 type CategoryDTO struct {
 	ID        uint
 	Name      string
+	Kind      string
+	KindLabel string
 	HasBudget bool
+	// Subcategories, shown nested under the category
+	Children []CategoryDTO
+}
+
+type ParentCategoryOptionDTO struct {
+	ID    uint
+	Label string
+	Kind  string
+}
+
+type CategoryKindOptionDTO struct {
+	Kind  string
+	Label string
 }
 
 type CategoriesPageDTO struct {
@@ -34,18 +55,46 @@ type CategoriesPageDTO struct {
 	Categories          []CategoryDTO
 	CategorySaved       bool
 	CreatedCategoryName string
+	CategoriesMerged    bool
+	MergedMessage       string
 }
 
 type CategoryFormDTO struct {
 	ActivePage string // This is used to highlight the active page in the navigation
 	// If we're updating an existing category in the form, Updating will be true
 	// If we're creating a new category, Updating will be false
-	Updating     bool
-	CategoryID   string
+	Updating         bool
+	CategoryID       string
+	CategoryName     string
+	ParentCategoryID uint
+	Kind             string
+	CategoryKinds    []CategoryKindOptionDTO
+	// Categories that can be the parent, excluding the category itself and its subcategories
+	ParentCategories []ParentCategoryOptionDTO
+	ErrorMessage     string
+}
+
+type CategoryMergeFormDTO struct {
+	ActivePage   string
+	CategoryID   uint
 	CategoryName string
+	// Rows that will be moved into the target category
+	Counts models.CategoryMergeCounts
+	// Categories the category can be merged into, which is every category except itself
+	TargetCategories []ParentCategoryOptionDTO
+	ErrorMessage     string
 }
 
 func (ac *CategoryController) generateCategoriesView(w http.ResponseWriter, req *http.Request) {
+	categoriesPageDTO := CategoriesPageDTO{}
+	if req.URL.Query().Get("categorySaved") != "" {
+		categoriesPageDTO.CategorySaved = true
+		categoriesPageDTO.CreatedCategoryName = req.URL.Query().Get("categorySaved")
+	}
+	ac.generateCategoriesViewContent(w, categoriesPageDTO)
+}
+
+func (ac *CategoryController) generateCategoriesViewContent(w http.ResponseWriter, categoriesPageDTO CategoriesPageDTO) {
 	// Get all categories
 	categories, err := ac.CategoryRepository.GetAllCategoriesAndBudgetStatus()
 	if err != nil {
@@ -53,27 +102,19 @@ func (ac *CategoryController) generateCategoriesView(w http.ResponseWriter, req
 		return
 	}
 
-	// Build categories DTO
-	categoriesDTO := []CategoryDTO{}
+	// Build categories DTO as a tree
+	hasBudget := map[uint]bool{}
+	treeCategories := []models.Category{}
 	for _, category := range categories {
 		// Skip the "Unknown" category because we don't want the user to edit/delete it
 		if category.Name == "Unknown" {
 			continue
 		}
-		categoriesDTO = append(categoriesDTO, CategoryDTO{
-			ID:        category.ID,
-			Name:      category.Name,
-			HasBudget: category.HasBudget,
-		})
-	}
-	categoriesPageDTO := CategoriesPageDTO{
-		ActivePage: "categories",
-		Categories: categoriesDTO,
-	}
-	if req.URL.Query().Get("categorySaved") != "" {
-		categoriesPageDTO.CategorySaved = true
-		categoriesPageDTO.CreatedCategoryName = req.URL.Query().Get("categorySaved")
+		hasBudget[category.ID] = category.HasBudget
+		treeCategories = append(treeCategories, category.Category)
 	}
+	categoriesPageDTO.ActivePage = "categories"
+	categoriesPageDTO.Categories = buildCategoryDTOs(services.BuildCategoryTree(treeCategories), hasBudget)
 
 	tmpl := template.Must(template.New("categoriesPage").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Funcs(template.FuncMap{
@@ -86,8 +127,35 @@ func (ac *CategoryController) generateCategoriesView(w http.ResponseWriter, req
 	}
 }
 
+func buildCategoryDTOs(nodes []*services.CategoryNode, hasBudget map[uint]bool) []CategoryDTO {
+	categoryDTOs := []CategoryDTO{}
+	for _, node := range nodes {
+		categoryDTOs = append(categoryDTOs, CategoryDTO{
+			ID:        node.Category.ID,
+			Name:      node.Category.Name,
+			Kind:      node.Category.Kind,
+			KindLabel: categoryKindLabel(node.Category.Kind),
+			HasBudget: hasBudget[node.Category.ID],
+			Children:  buildCategoryDTOs(node.Children, hasBudget),
+		})
+	}
+	return categoryDTOs
+}
+
+func categoryKindLabel(kind string) string {
+	for _, categoryKind := range models.CategoryKinds {
+		if categoryKind.Kind == kind {
+			return categoryKind.Label
+		}
+	}
+	return "Expense"
+}
+
 func (ac *CategoryController) generateCategoryForm(w http.ResponseWriter, req *http.Request) {
-	var dto CategoryFormDTO
+	dto := CategoryFormDTO{
+		ActivePage: "categories",
+		Kind:       models.CategoryKindExpense,
+	}
 
 	categoryIDQueryParameter := req.URL.Query().Get("categoryID")
 	if categoryIDQueryParameter != "" {
@@ -102,18 +170,58 @@ func (ac *CategoryController) generateCategoryForm(w http.ResponseWriter, req *h
 			return
 		}
 
-		dto = CategoryFormDTO{
-			ActivePage:   "categories",
-			Updating:     true,
-			CategoryID:   fmt.Sprint(category.ID),
-			CategoryName: category.Name,
+		dto.Updating = true
+		dto.CategoryID = fmt.Sprint(category.ID)
+		dto.CategoryName = category.Name
+		if category.Kind != "" {
+			dto.Kind = category.Kind
+		}
+		if category.ParentID != nil {
+			dto.ParentCategoryID = *category.ParentID
+		}
+	}
+
+	ac.renderCategoryForm(w, dto)
+}
+
+func (ac *CategoryController) renderCategoryForm(w http.ResponseWriter, dto CategoryFormDTO) {
+	categories, err := ac.CategoryRepository.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
+	}
+	categoryTree := services.BuildCategoryTree(categories)
+	// A category can't be moved underneath itself
+	var excludedIDs map[uint]bool
+	if dto.CategoryID != "" {
+		categoryID, err := utils.StringToUint(dto.CategoryID)
+		if err != nil {
+			http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
+			return
+		}
+		excludedIDs = services.CategoryAndDescendantIDs(categoryTree, categoryID)
+	}
+	for _, node := range services.FlattenCategoryTree(categoryTree) {
+		if excludedIDs[node.Category.ID] || node.Category.Name == "Unknown" {
+			continue
 		}
+		dto.ParentCategories = append(dto.ParentCategories, ParentCategoryOptionDTO{
+			ID:    node.Category.ID,
+			Label: node.Path,
+			Kind:  node.Category.Kind,
+		})
+	}
+	for _, categoryKind := range models.CategoryKinds {
+		dto.CategoryKinds = append(dto.CategoryKinds, CategoryKindOptionDTO{
+			Kind:  categoryKind.Kind,
+			Label: categoryKind.Label,
+		})
 	}
 
 	tmpl := template.Must(template.New("categoryForm").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(categoryFormTmpl))
 
-	err := utils.RenderTemplateAsHTML(w, tmpl, dto)
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
 	if err != nil {
 		panic(err)
 	}
@@ -124,6 +232,14 @@ func (ac *CategoryController) upsertCategory(w http.ResponseWriter, req *http.Re
 
 	categoryID := req.FormValue("categoryID")
 	categoryName := req.FormValue("categoryName")
+	kind := req.FormValue("kind")
+	if kind == "" {
+		kind = models.CategoryKindExpense
+	}
+	if !models.IsValidCategoryKind(kind) {
+		http.Error(w, "Unable to parse category kind", http.StatusBadRequest)
+		return
+	}
 
 	var category models.Category
 
@@ -143,6 +259,36 @@ func (ac *CategoryController) upsertCategory(w http.ResponseWriter, req *http.Re
 	}
 
 	category.Name = categoryName
+	category.Kind = kind
+	category.ParentID = nil
+	if parentCategoryID := req.FormValue("parentCategoryID"); parentCategoryID != "" {
+		parentID, err := utils.StringToUint(parentCategoryID)
+		if err != nil {
+			http.Error(w, "Unable to parse parent category ID", http.StatusBadRequest)
+			return
+		}
+		// Moving a category underneath one of its own subcategories would create a cycle
+		if category.ID != 0 {
+			categories, err := ac.CategoryRepository.GetAllCategories()
+			if err != nil {
+				http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+				return
+			}
+			if services.CategoryAndDescendantIDs(services.BuildCategoryTree(categories), category.ID)[parentID] {
+				ac.renderCategoryForm(w, CategoryFormDTO{
+					ActivePage:       "categories",
+					Updating:         true,
+					CategoryID:       categoryID,
+					CategoryName:     categoryName,
+					ParentCategoryID: parentID,
+					Kind:             kind,
+					ErrorMessage:     "A category can't be moved underneath itself or one of its subcategories",
+				})
+				return
+			}
+		}
+		category.ParentID = &parentID
+	}
 
 	_, err := ac.CategoryRepository.Save(category)
 	if err != nil {
@@ -182,3 +328,114 @@ func (ac *CategoryController) deleteCategory(w http.ResponseWriter, req *http.Re
 
 	ac.generateCategoriesView(w, req)
 }
+
+func (ac *CategoryController) generateCategoryMergeForm(w http.ResponseWriter, req *http.Request) {
+	categoryID, err := utils.StringToUint(req.URL.Query().Get("categoryID"))
+	if err != nil {
+		http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
+		return
+	}
+	ac.renderCategoryMergeForm(w, categoryID, "")
+}
+
+func (ac *CategoryController) renderCategoryMergeForm(w http.ResponseWriter, categoryID uint, errorMessage string) {
+	category, err := ac.CategoryRepository.GetCategoryByID(categoryID)
+	if err != nil {
+		http.Error(w, "Unable to get category", http.StatusInternalServerError)
+		return
+	}
+	counts, err := ac.CategoryRepository.GetCategoryMergeCounts(categoryID)
+	if err != nil {
+		fmt.Println("Error counting rows to merge: ", err)
+		http.Error(w, "Unable to count the category's transactions", http.StatusInternalServerError)
+		return
+	}
+	categories, err := ac.CategoryRepository.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
+	}
+
+	dto := CategoryMergeFormDTO{
+		ActivePage:   "categories",
+		CategoryID:   category.ID,
+		CategoryName: category.Name,
+		Counts:       counts,
+		ErrorMessage: errorMessage,
+	}
+	categoryTree := services.BuildCategoryTree(categories)
+	excludedIDs := services.CategoryAndDescendantIDs(categoryTree, category.ID)
+	for _, node := range services.FlattenCategoryTree(categoryTree) {
+		if excludedIDs[node.Category.ID] || node.Category.Name == "Unknown" {
+			continue
+		}
+		dto.TargetCategories = append(dto.TargetCategories, ParentCategoryOptionDTO{
+			ID:    node.Category.ID,
+			Label: node.Path,
+			Kind:  node.Category.Kind,
+		})
+	}
+
+	tmpl := template.Must(template.New("categoryMergeForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(categoryMergeFormTmpl))
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// mergeCategories moves everything in one category into another, deletes the first category and retrains
+// the categorizer, since its training transactions now belong to a different category
+func (ac *CategoryController) mergeCategories(w http.ResponseWriter, req *http.Request) {
+	categoryID, err := utils.StringToUint(req.FormValue("categoryID"))
+	if err != nil {
+		http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
+		return
+	}
+	if req.FormValue("targetCategoryID") == "" {
+		ac.renderCategoryMergeForm(w, categoryID, "Choose a category to merge into")
+		return
+	}
+	targetCategoryID, err := utils.StringToUint(req.FormValue("targetCategoryID"))
+	if err != nil {
+		http.Error(w, "Unable to parse target category ID", http.StatusBadRequest)
+		return
+	}
+	category, err := ac.CategoryRepository.GetCategoryByID(categoryID)
+	if err != nil {
+		http.Error(w, "Unable to get category", http.StatusBadRequest)
+		return
+	}
+	if category.Name == "Unknown" {
+		http.Error(w, "The Unknown category can't be merged", http.StatusBadRequest)
+		return
+	}
+	targetCategory, err := ac.CategoryRepository.GetCategoryByID(targetCategoryID)
+	if err != nil {
+		http.Error(w, "Unable to get target category", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := ac.CategoryRepository.MergeCategories(categoryID, targetCategoryID)
+	if errors.Is(err, models.ErrMergeIntoSubtree) {
+		ac.renderCategoryMergeForm(w, categoryID, "A category can't be merged into itself or one of its subcategories")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error merging categories: ", err)
+		http.Error(w, "Unable to merge categories", http.StatusInternalServerError)
+		return
+	}
+	err = ac.Categorizer.BuildModel()
+	if err != nil {
+		fmt.Println("Error rebuilding categorization model: ", err)
+		http.Error(w, "Categories merged, but unable to retrain the categorizer", http.StatusInternalServerError)
+		return
+	}
+
+	ac.generateCategoriesViewContent(w, CategoriesPageDTO{
+		CategoriesMerged: true,
+		MergedMessage: fmt.Sprintf("Merged %v into %v: moved %v transactions (%v categorized by hand), %v splits, %v budgets and %v subcategories",
+			category.Name, targetCategory.Name, counts.Transactions, counts.TrainingLabels, counts.Splits, counts.Budgets, counts.Subcategories),
+	})
+}
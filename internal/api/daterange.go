@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// DateRangeDTO is the time frame picked with the date range controls shared by the reports
+type DateRangeDTO struct {
+	DateRangePath string // Page the controls link to
+	// Other query parameters of the page, kept when the time frame changes
+	DateRangeParams  map[string]string
+	DateRangeWindow  string
+	DateRangeWindows []services.DateRangeWindow
+	StartDate        string // YYYY-MM-DD
+	EndDate          string // YYYY-MM-DD
+	// DateRangeQuery is the query string of the time frame, for links that keep it
+	DateRangeQuery string
+}
+
+// parseDateRange reads the relativeWindow, startDate and endDate form values, fills in the date range controls
+// and returns the time frame they cover
+func parseDateRange(req *http.Request, dateRangeService *services.DateRangeService, path string, defaultWindow string, dto *DateRangeDTO) (services.DateRange, error) {
+	dateRange, err := dateRangeService.GetDateRange(req.FormValue("relativeWindow"), req.FormValue("startDate"), req.FormValue("endDate"), defaultWindow, time.Now())
+	if err != nil {
+		return services.DateRange{}, err
+	}
+
+	dto.DateRangePath = path
+	dto.DateRangeWindow = dateRange.Window
+	dto.DateRangeWindows = services.DateRangeWindows
+	dto.StartDate = utils.TimeToISO8601DateString(dateRange.StartDate)
+	dto.EndDate = utils.TimeToISO8601DateString(dateRange.EndDate)
+	query := url.Values{"relativeWindow": {dateRange.Window}}
+	if dateRange.Window == services.DateRangeCustom {
+		query.Set("startDate", dto.StartDate)
+		query.Set("endDate", dto.EndDate)
+	}
+	dto.DateRangeQuery = query.Encode()
+	return dateRange, nil
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type GoalController struct {
+	AccountRepository *models.AccountRepository
+	GoalRepository    *models.GoalRepository
+	GoalService       *services.GoalService
+}
+
+//go:embed goals.html
+var goalsPageTmpl string
+
+//go:embed goalForm.html
+var goalFormTmpl string
+
+//go:embed goalDetail.html
+var goalDetailTmpl string
+
+// goalStatusLabel is how a goal's status is shown, with the Bootstrap color used for it
+type goalStatusLabel struct {
+	Label string
+	Color string
+}
+
+var goalStatusLabels = map[string]goalStatusLabel{
+	services.GoalStatusAchieved:  {Label: "Achieved", Color: "success"},
+	services.GoalStatusOnTrack:   {Label: "On track", Color: "success"},
+	services.GoalStatusBehind:    {Label: "Behind", Color: "warning"},
+	services.GoalStatusOverdue:   {Label: "Target date passed", Color: "danger"},
+	services.GoalStatusNoHistory: {Label: "Not enough history", Color: "secondary"},
+}
+
+type GoalsPageDTO struct {
+	ActivePage string
+	Goals      []GoalDTO
+	GoalSaved  bool
+}
+
+type GoalDTO struct {
+	ID                          uint
+	Name                        string
+	AccountNames                string
+	TargetAmount                string
+	TargetDate                  string
+	CurrentAmount               string
+	PercentComplete             int
+	Remaining                   string
+	MonthsLeft                  int
+	RequiredMonthlyContribution string
+	MonthlyGrowth               string
+	ProjectedAmount             string
+	ProjectedCompletionDate     string
+	Status                      string
+	StatusLabel                 goalStatusLabel
+}
+
+type GoalFormDTO struct {
+	ActivePage   string
+	Updating     bool
+	GoalID       string
+	Name         string
+	TargetAmount string
+	TargetDate   string
+	Accounts     []GoalAccountOptionDTO
+	ErrorMessage string
+}
+
+type GoalAccountOptionDTO struct {
+	ID       uint
+	Name     string
+	Selected bool
+}
+
+type GoalDetailDTO struct {
+	ActivePage string
+	Goal       GoalDTO
+	// The chart's labels and datasets line up, with null where a dataset has no value for a date
+	ChartLabels []string
+	Actual      []string
+	Projected   []string
+	Target      string
+}
+
+func goalToDTO(progress services.GoalProgress) GoalDTO {
+	accountNames := []string{}
+	for _, account := range progress.Goal.Accounts {
+		accountNames = append(accountNames, account.Name)
+	}
+	dto := GoalDTO{
+		ID:                          progress.Goal.ID,
+		Name:                        progress.Goal.Name,
+		AccountNames:                strings.Join(accountNames, ", "),
+		TargetAmount:                utils.CentsToDollarStringHumanized(progress.Goal.TargetAmount),
+		TargetDate:                  humanizedDate(progress.Goal.TargetDate),
+		CurrentAmount:               utils.CentsToDollarStringHumanized(progress.CurrentAmount),
+		PercentComplete:             progress.PercentComplete,
+		Remaining:                   utils.CentsToDollarStringHumanized(progress.Remaining),
+		MonthsLeft:                  progress.MonthsLeft,
+		RequiredMonthlyContribution: utils.CentsToDollarStringHumanized(progress.RequiredMonthlyContribution),
+		MonthlyGrowth:               utils.CentsToDollarStringHumanized(progress.MonthlyGrowth),
+		ProjectedAmount:             utils.CentsToDollarStringHumanized(progress.ProjectedAmount),
+		Status:                      progress.Status,
+		StatusLabel:                 goalStatusLabels[progress.Status],
+	}
+	if progress.ProjectedCompletionDate != nil {
+		dto.ProjectedCompletionDate = progress.ProjectedCompletionDate.Format("Jan 2, 2006")
+	}
+	return dto
+}
+
+func (gc *GoalController) generateGoalsView(w http.ResponseWriter, req *http.Request) {
+	gc.sendGoalsViewResponse(w, false)
+}
+
+func (gc *GoalController) sendGoalsViewResponse(w http.ResponseWriter, saved bool) {
+	goals, err := gc.GoalRepository.GetAllGoals()
+	if err != nil {
+		http.Error(w, "Unable to get goals", http.StatusInternalServerError)
+		return
+	}
+	dto := GoalsPageDTO{ActivePage: "goals", GoalSaved: saved}
+	now := time.Now()
+	for _, goal := range goals {
+		dto.Goals = append(dto.Goals, goalToDTO(gc.GoalService.GetGoalProgress(goal, now)))
+	}
+
+	tmpl := template.Must(template.New("goalsPage").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Funcs(template.FuncMap{
+		"mod": func(i, j int) int { return i % j },
+	}).Parse(goalsPageTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// generateGoalDetailsView charts a goal's balance over the last year and projects it to the target date
+func (gc *GoalController) generateGoalDetailsView(w http.ResponseWriter, req *http.Request) {
+	goalID, err := utils.StringToUint(req.URL.Query().Get("goalID"))
+	if err != nil {
+		http.Error(w, "Unable to parse goal ID", http.StatusBadRequest)
+		return
+	}
+	goal, err := gc.GoalRepository.GetGoalByID(goalID)
+	if err != nil {
+		http.Error(w, "Unable to get goal", http.StatusNotFound)
+		return
+	}
+	progress := gc.GoalService.GetGoalProgress(goal, time.Now())
+
+	dto := GoalDetailDTO{
+		ActivePage: "goals",
+		Goal:       goalToDTO(progress),
+		Target:     utils.CentsToDollarStringMachineSafe(goal.TargetAmount),
+	}
+	for _, point := range progress.History {
+		dto.ChartLabels = append(dto.ChartLabels, point.Date.Format("Jan 2, 2006"))
+		dto.Actual = append(dto.Actual, utils.CentsToDollarStringMachineSafe(point.Amount))
+		dto.Projected = append(dto.Projected, "null")
+	}
+	// The projection starts from today, which is already the last point of the history
+	for i, point := range progress.Projection {
+		if i == 0 && len(dto.Projected) > 0 {
+			dto.Projected[len(dto.Projected)-1] = utils.CentsToDollarStringMachineSafe(point.Amount)
+			continue
+		}
+		dto.ChartLabels = append(dto.ChartLabels, point.Date.Format("Jan 2, 2006"))
+		dto.Actual = append(dto.Actual, "null")
+		dto.Projected = append(dto.Projected, utils.CentsToDollarStringMachineSafe(point.Amount))
+	}
+
+	tmpl := template.Must(template.New("goalDetail").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(goalDetailTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func (gc *GoalController) generateGoalForm(w http.ResponseWriter, req *http.Request) {
+	dto := GoalFormDTO{}
+	selectedAccounts := map[uint]bool{}
+	goalIDQueryParameter := req.URL.Query().Get("goalID")
+	if goalIDQueryParameter != "" {
+		goalID, err := utils.StringToUint(goalIDQueryParameter)
+		if err != nil {
+			http.Error(w, "Unable to parse goal ID", http.StatusBadRequest)
+			return
+		}
+		goal, err := gc.GoalRepository.GetGoalByID(goalID)
+		if err != nil {
+			http.Error(w, "Unable to get goal", http.StatusNotFound)
+			return
+		}
+		dto.Updating = true
+		dto.GoalID = fmt.Sprint(goal.ID)
+		dto.Name = goal.Name
+		dto.TargetAmount = utils.CentsToDollarStringMachineSafe(goal.TargetAmount)
+		dto.TargetDate = goal.TargetDate
+		for _, account := range goal.Accounts {
+			selectedAccounts[account.ID] = true
+		}
+	}
+	gc.renderGoalForm(w, dto, selectedAccounts)
+}
+
+func (gc *GoalController) renderGoalForm(w http.ResponseWriter, dto GoalFormDTO, selectedAccounts map[uint]bool) {
+	dto.ActivePage = "goals"
+	accounts, err := gc.AccountRepository.GetAllAccounts()
+	if err != nil {
+		http.Error(w, "Unable to get accounts", http.StatusInternalServerError)
+		return
+	}
+	for _, account := range accounts {
+		dto.Accounts = append(dto.Accounts, GoalAccountOptionDTO{
+			ID:       account.ID,
+			Name:     account.Name,
+			Selected: selectedAccounts[account.ID],
+		})
+	}
+
+	tmpl := template.Must(template.New("goalForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(goalFormTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func (gc *GoalController) upsertGoal(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+
+	goalID := req.FormValue("goalID")
+	dto := GoalFormDTO{
+		Updating:     goalID != "",
+		GoalID:       goalID,
+		Name:         strings.TrimSpace(req.FormValue("name")),
+		TargetAmount: strings.TrimSpace(req.FormValue("targetAmount")),
+		TargetDate:   req.FormValue("targetDate"),
+	}
+	selectedAccounts := map[uint]bool{}
+	var accounts []models.Account
+	for _, accountIDInput := range req.Form["accountIDs"] {
+		accountID, err := utils.StringToUint(accountIDInput)
+		if err != nil {
+			http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+			return
+		}
+		selectedAccounts[accountID] = true
+		account := models.Account{}
+		account.ID = accountID
+		accounts = append(accounts, account)
+	}
+
+	// Show validation problems on the form so the goal can be fixed without retyping it
+	targetAmount, err := strconv.ParseFloat(dto.TargetAmount, 64)
+	switch {
+	case dto.Name == "":
+		dto.ErrorMessage = "The goal needs a name"
+	case err != nil || targetAmount <= 0:
+		dto.ErrorMessage = "The target amount must be a dollar amount greater than 0"
+	case !utils.DateValid(dto.TargetDate):
+		dto.ErrorMessage = "The target date must be in YYYY-MM-DD format"
+	case len(accounts) == 0:
+		dto.ErrorMessage = "Choose at least one account that's saving towards the goal"
+	}
+	if dto.ErrorMessage != "" {
+		gc.renderGoalForm(w, dto, selectedAccounts)
+		return
+	}
+
+	goal := models.Goal{}
+	if goalID != "" {
+		id, err := utils.StringToUint(goalID)
+		if err != nil {
+			http.Error(w, "Unable to parse goal ID", http.StatusBadRequest)
+			return
+		}
+		goal, err = gc.GoalRepository.GetGoalByID(id)
+		if err != nil {
+			http.Error(w, "Unable to get goal", http.StatusNotFound)
+			return
+		}
+	}
+	goal.Name = dto.Name
+	goal.TargetAmount = utils.DollarStringToCents(dto.TargetAmount)
+	goal.TargetDate = dto.TargetDate
+	goal.Accounts = accounts
+
+	_, err = gc.GoalRepository.Save(goal)
+	if err != nil {
+		fmt.Println("Error saving goal: ", err)
+		http.Error(w, "Unable to save goal", http.StatusInternalServerError)
+		return
+	}
+	gc.sendGoalsViewResponse(w, true)
+}
+
+func (gc *GoalController) deleteGoal(w http.ResponseWriter, req *http.Request) {
+	goalID, err := utils.StringToUint(req.FormValue("goalID"))
+	if err != nil {
+		http.Error(w, "Unable to parse goal ID", http.StatusBadRequest)
+		return
+	}
+	err = gc.GoalRepository.DeleteGoalByID(goalID)
+	if err != nil {
+		http.Error(w, "Unable to delete goal", http.StatusInternalServerError)
+		return
+	}
+	gc.sendGoalsViewResponse(w, true)
+}
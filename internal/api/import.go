@@ -15,8 +15,10 @@ import (
 
 type ImportController struct {
 	AccountManager        *services.AccountManager
+	AlertService          *services.AlertService
 	ImportService         *services.ImportService
 	TransactionRepository *models.TransactionRepository
+	TransferMatcher       *services.TransferMatcher
 }
 
 //go:embed importStatementForm.html
@@ -34,6 +36,8 @@ type ImportStatusPageDTO struct {
 	ActivePage   string
 	Submission   *models.ImportSubmission
 	Transactions []TransactionDTO
+	// Transfers found between the imported transactions and other accounts, waiting to be reviewed
+	TransfersMatched int
 }
 
 func (ic *ImportController) importStatementFormHandler(w http.ResponseWriter, req *http.Request) {
@@ -86,6 +90,18 @@ func (ic *ImportController) importSubmissionHandler(w http.ResponseWriter, req *
 		return
 	}
 
+	// Suggest the other side of any transfers that were already imported into another account. Failing to match
+	// transfers doesn't fail the import, since they can be matched again from the Transfers page.
+	transfersMatched, err := ic.TransferMatcher.MatchImportedTransfers(importSubmission.ID)
+	if err != nil {
+		fmt.Println("Error matching transfers: ", err)
+	}
+
+	// As with transfers, failing to check alerts doesn't fail the import, they're checked again on a schedule
+	if _, err := ic.AlertService.EvaluateAlerts(); err != nil {
+		fmt.Println("Error evaluating alerts: ", err)
+	}
+
 	transactions, err := ic.TransactionRepository.GetTransactionsByImportSubmission(importSubmission.ID)
 	if err != nil {
 		errorMessage := fmt.Sprintf("Unable to get transactions for import submission: %v", err)
@@ -106,9 +122,10 @@ func (ic *ImportController) importSubmissionHandler(w http.ResponseWriter, req *
 		})
 	}
 	dto := ImportStatusPageDTO{
-		ActivePage:   "importStatus",
-		Submission:   importSubmission,
-		Transactions: transactionDTOs,
+		ActivePage:       "importStatus",
+		Submission:       importSubmission,
+		Transactions:     transactionDTOs,
+		TransfersMatched: transfersMatched,
 	}
 
 	ic.importStatusHandler(w, dto)
@@ -0,0 +1,117 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type InvestmentController struct {
+	InvestmentPerformanceService *services.InvestmentPerformanceService
+	DateRangeService             *services.DateRangeService
+}
+
+//go:embed investments.html
+var investmentsTmpl string
+
+type InvestmentsPageDTO struct {
+	ActivePage string
+	DateRangeDTO
+	Accounts  []InvestmentPerformanceDTO
+	Portfolio InvestmentPerformanceDTO
+	// Set after classifying transactions
+	ClassifiedMessage string
+}
+
+type InvestmentPerformanceDTO struct {
+	AccountID     uint
+	Name          string
+	HasBalances   bool
+	StartDate     string
+	EndDate       string
+	StartValue    string
+	EndValue      string
+	Contributions string
+	Withdrawals   string
+	Dividends     string
+	Interest      string
+	Fees          string
+	Gain          string
+	GainPositive  bool
+	Unclassified  int
+	// Percentages, or empty if they can't be worked out
+	TimeWeightedReturn  string
+	MoneyWeightedReturn string
+}
+
+func investmentPerformanceToDTO(name string, performance services.InvestmentPerformance) InvestmentPerformanceDTO {
+	return InvestmentPerformanceDTO{
+		AccountID:           performance.Account.ID,
+		Name:                name,
+		HasBalances:         performance.EndDate != "",
+		StartDate:           performance.StartDate,
+		EndDate:             performance.EndDate,
+		StartValue:          utils.CentsToDollarStringHumanized(performance.StartValue),
+		EndValue:            utils.CentsToDollarStringHumanized(performance.EndValue),
+		Contributions:       utils.CentsToDollarStringHumanized(performance.Contributions),
+		Withdrawals:         utils.CentsToDollarStringHumanized(performance.Withdrawals),
+		Dividends:           utils.CentsToDollarStringHumanized(performance.Dividends),
+		Interest:            utils.CentsToDollarStringHumanized(performance.Interest),
+		Fees:                utils.CentsToDollarStringHumanized(performance.Fees),
+		Gain:                utils.CentsToDollarStringHumanized(performance.Gain),
+		GainPositive:        performance.Gain >= 0,
+		Unclassified:        performance.Unclassified,
+		TimeWeightedReturn:  percentString(performance.TimeWeightedReturn),
+		MoneyWeightedReturn: percentString(performance.MoneyWeightedReturn),
+	}
+}
+
+func percentString(rate *float64) string {
+	if rate == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f%%", *rate*100)
+}
+
+func (ic *InvestmentController) generateInvestmentsView(w http.ResponseWriter, req *http.Request) {
+	ic.sendInvestmentsViewResponse(w, req, "")
+}
+
+func (ic *InvestmentController) sendInvestmentsViewResponse(w http.ResponseWriter, req *http.Request, classifiedMessage string) {
+	dto := InvestmentsPageDTO{ActivePage: "investments", ClassifiedMessage: classifiedMessage}
+	dateRange, err := parseDateRange(req, ic.DateRangeService, "/investments", services.DateRangeLast12Months, &dto.DateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	performances, portfolio, err := ic.InvestmentPerformanceService.GetInvestmentPerformance(dateRange.StartDate, dateRange.EndDate)
+	if err != nil {
+		fmt.Println("Error getting investment performance: ", err)
+		http.Error(w, "Unable to get investment performance", http.StatusInternalServerError)
+		return
+	}
+	for _, performance := range performances {
+		dto.Accounts = append(dto.Accounts, investmentPerformanceToDTO(performance.Account.Name, performance))
+	}
+	dto.Portfolio = investmentPerformanceToDTO("All investments", portfolio)
+
+	tmpl := template.Must(template.New("investments").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(investmentsTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// classifyInvestmentTransactions classifies investment transactions imported before their activity was known
+func (ic *InvestmentController) classifyInvestmentTransactions(w http.ResponseWriter, req *http.Request) {
+	classified, err := ic.InvestmentPerformanceService.ClassifyInvestmentTransactions()
+	if err != nil {
+		fmt.Println("Error classifying investment transactions: ", err)
+		http.Error(w, "Unable to classify investment transactions", http.StatusInternalServerError)
+		return
+	}
+	ic.sendInvestmentsViewResponse(w, req, fmt.Sprintf("Classified %d transactions", classified))
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+	"gorm.io/gorm"
+)
+
+type LoanController struct {
+	AccountRepository *models.AccountRepository
+	LoanRepository    *models.LoanRepository
+	LoanService       *services.LoanService
+}
+
+//go:embed loans.html
+var loansPageTmpl string
+
+//go:embed loanForm.html
+var loanFormTmpl string
+
+//go:embed loanDetail.html
+var loanDetailTmpl string
+
+type LoansPageDTO struct {
+	ActivePage string
+	Loans      []LoanDTO
+	LoanSaved  bool
+}
+
+// LoanDTO is a loan account, with its terms and progress if they've been entered
+type LoanDTO struct {
+	AccountID   uint
+	AccountName string
+	// LoanID is 0 if the loan's terms haven't been entered
+	LoanID                 uint
+	Principal              string
+	InterestRate           string
+	TermMonths             int
+	StartDate              string
+	MonthlyPayment         string
+	CurrentBalance         string
+	CurrentBalanceDate     string
+	CurrentBalanceReported bool
+	ScheduledBalance       string
+	// Difference is how far the current balance is from the schedule, and AheadOfSchedule is whether less is owed
+	Difference          string
+	AheadOfSchedule     bool
+	OnSchedule          bool
+	ScheduledPayoffDate string
+	ProjectedPayoffDate string
+	PaidOff             bool
+}
+
+type LoanFormDTO struct {
+	ActivePage   string
+	Updating     bool
+	LoanID       string
+	AccountID    string
+	AccountName  string
+	Principal    string
+	InterestRate string
+	TermMonths   string
+	StartDate    string
+	ErrorMessage string
+}
+
+type LoanDetailDTO struct {
+	ActivePage   string
+	Loan         LoanDTO
+	ExtraPayment string
+	Scenarios    []LoanPayoffScenarioDTO
+	Comparisons  []LoanBalanceComparisonDTO
+	Schedule     []LoanPaymentDTO
+	// The chart's labels and datasets line up, with null where a dataset has no value for a month
+	ChartLabels []string
+	Scheduled   []string
+	Reported    []string
+}
+
+type LoanPayoffScenarioDTO struct {
+	ExtraMonthlyPayment string
+	Custom              bool
+	PaysOff             bool
+	Payments            int
+	PayoffDate          string
+	TotalInterest       string
+	MonthsSaved         int
+	InterestSaved       string
+}
+
+type LoanBalanceComparisonDTO struct {
+	Date            string
+	Scheduled       string
+	Actual          string
+	Difference      string
+	AheadOfSchedule bool
+	OnSchedule      bool
+}
+
+type LoanPaymentDTO struct {
+	Number    int
+	Date      string
+	Payment   string
+	Principal string
+	Interest  string
+	Balance   string
+}
+
+func loanToDTO(account models.Account, progress services.LoanProgress) LoanDTO {
+	loan := progress.Loan
+	dto := LoanDTO{
+		AccountID:              account.ID,
+		AccountName:            account.Name,
+		LoanID:                 loan.ID,
+		Principal:              utils.CentsToDollarStringHumanized(loan.Principal),
+		InterestRate:           strconv.FormatFloat(loan.InterestRate, 'f', -1, 64),
+		TermMonths:             loan.TermMonths,
+		StartDate:              humanizedDate(loan.StartDate),
+		MonthlyPayment:         utils.CentsToDollarStringHumanized(progress.MonthlyPayment),
+		CurrentBalance:         utils.CentsToDollarStringHumanized(progress.CurrentBalance),
+		CurrentBalanceDate:     humanizedDate(progress.CurrentBalanceDate),
+		CurrentBalanceReported: progress.CurrentBalanceReported,
+		ScheduledBalance:       utils.CentsToDollarStringHumanized(progress.ScheduledBalance),
+		Difference:             utils.CentsToDollarStringHumanized(absoluteAmount(progress.CurrentBalance - progress.ScheduledBalance)),
+		AheadOfSchedule:        progress.CurrentBalance < progress.ScheduledBalance,
+		OnSchedule:             progress.CurrentBalance == progress.ScheduledBalance,
+		PaidOff:                progress.CurrentBalance == 0,
+	}
+	if !progress.ScheduledPayoffDate.IsZero() {
+		dto.ScheduledPayoffDate = progress.ScheduledPayoffDate.Format("Jan 2, 2006")
+	}
+	if len(progress.Scenarios) > 0 && progress.Scenarios[0].PaysOff {
+		dto.ProjectedPayoffDate = progress.Scenarios[0].PayoffDate.Format("Jan 2, 2006")
+	}
+	return dto
+}
+
+func absoluteAmount(amount int) int {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
+
+func (lc *LoanController) generateLoansView(w http.ResponseWriter, req *http.Request) {
+	lc.sendLoansViewResponse(w, false)
+}
+
+func (lc *LoanController) sendLoansViewResponse(w http.ResponseWriter, saved bool) {
+	accounts, err := lc.AccountRepository.GetAllAccounts()
+	if err != nil {
+		http.Error(w, "Unable to get accounts", http.StatusInternalServerError)
+		return
+	}
+	loans, err := lc.LoanRepository.GetAllLoans()
+	if err != nil {
+		http.Error(w, "Unable to get loans", http.StatusInternalServerError)
+		return
+	}
+	loansByAccount := map[uint]models.Loan{}
+	for _, loan := range loans {
+		loansByAccount[loan.AccountID] = loan
+	}
+
+	dto := LoansPageDTO{ActivePage: "loans", LoanSaved: saved}
+	now := time.Now()
+	for _, account := range accounts {
+		if account.AccountType.AccountCategory != services.LoanAccountCategory {
+			continue
+		}
+		loan, ok := loansByAccount[account.ID]
+		if !ok {
+			dto.Loans = append(dto.Loans, LoanDTO{AccountID: account.ID, AccountName: account.Name})
+			continue
+		}
+		dto.Loans = append(dto.Loans, loanToDTO(account, lc.LoanService.GetLoanProgress(loan, 0, now)))
+	}
+
+	tmpl := template.Must(template.New("loansPage").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(loansPageTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// generateLoanDetailsView shows a loan's amortization schedule against its reported balances, and when it will
+// be paid off with extra payments
+func (lc *LoanController) generateLoanDetailsView(w http.ResponseWriter, req *http.Request) {
+	loanID, err := utils.StringToUint(req.URL.Query().Get("loanID"))
+	if err != nil {
+		http.Error(w, "Unable to parse loan ID", http.StatusBadRequest)
+		return
+	}
+	loan, err := lc.LoanRepository.GetLoanByID(loanID)
+	if err != nil {
+		http.Error(w, "Unable to get loan", http.StatusNotFound)
+		return
+	}
+	extraPayment := strings.TrimSpace(req.URL.Query().Get("extraPayment"))
+	customExtraPayment := 0
+	if extraPayment != "" {
+		amount, err := strconv.ParseFloat(extraPayment, 64)
+		if err != nil || amount < 0 {
+			http.Error(w, "Unable to parse extra payment", http.StatusBadRequest)
+			return
+		}
+		customExtraPayment = utils.DollarStringToCents(extraPayment)
+	}
+	progress := lc.LoanService.GetLoanProgress(loan, customExtraPayment, time.Now())
+
+	dto := LoanDetailDTO{
+		ActivePage:   "loans",
+		Loan:         loanToDTO(loan.Account, progress),
+		ExtraPayment: extraPayment,
+	}
+	for _, scenario := range progress.Scenarios {
+		scenarioDTO := LoanPayoffScenarioDTO{
+			ExtraMonthlyPayment: utils.CentsToDollarStringHumanized(scenario.ExtraMonthlyPayment),
+			Custom:              scenario.ExtraMonthlyPayment == customExtraPayment && customExtraPayment > 0,
+			PaysOff:             scenario.PaysOff,
+			Payments:            scenario.Payments,
+			TotalInterest:       utils.CentsToDollarStringHumanized(scenario.TotalInterest),
+			MonthsSaved:         scenario.MonthsSaved,
+			InterestSaved:       utils.CentsToDollarStringHumanized(scenario.InterestSaved),
+		}
+		if scenario.PaysOff {
+			scenarioDTO.PayoffDate = scenario.PayoffDate.Format("Jan 2, 2006")
+		}
+		dto.Scenarios = append(dto.Scenarios, scenarioDTO)
+	}
+
+	// Reported balances are charted in the month they're from, using the latest one in a month
+	reportedByMonth := map[string]int{}
+	for _, comparison := range progress.Comparisons {
+		dto.Comparisons = append(dto.Comparisons, LoanBalanceComparisonDTO{
+			Date:            humanizedDate(comparison.Date),
+			Scheduled:       utils.CentsToDollarStringHumanized(comparison.Scheduled),
+			Actual:          utils.CentsToDollarStringHumanized(comparison.Actual),
+			Difference:      utils.CentsToDollarStringHumanized(absoluteAmount(comparison.Difference)),
+			AheadOfSchedule: comparison.Difference < 0,
+			OnSchedule:      comparison.Difference == 0,
+		})
+		reportedByMonth[comparison.Date[:7]] = comparison.Actual
+	}
+	for _, payment := range progress.Schedule {
+		month := payment.Date.Format("2006-01")
+		dto.Schedule = append(dto.Schedule, LoanPaymentDTO{
+			Number:    payment.Number,
+			Date:      payment.Date.Format("Jan 2, 2006"),
+			Payment:   utils.CentsToDollarStringHumanized(payment.Payment),
+			Principal: utils.CentsToDollarStringHumanized(payment.Principal),
+			Interest:  utils.CentsToDollarStringHumanized(payment.Interest),
+			Balance:   utils.CentsToDollarStringHumanized(payment.Balance),
+		})
+		dto.ChartLabels = append(dto.ChartLabels, month)
+		dto.Scheduled = append(dto.Scheduled, utils.CentsToDollarStringMachineSafe(payment.Balance))
+		if reported, ok := reportedByMonth[month]; ok {
+			dto.Reported = append(dto.Reported, utils.CentsToDollarStringMachineSafe(reported))
+		} else {
+			dto.Reported = append(dto.Reported, "null")
+		}
+	}
+
+	tmpl := template.Must(template.New("loanDetail").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(loanDetailTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// generateLoanForm shows the terms of a loan, or a blank form for a loan account whose terms haven't been entered
+func (lc *LoanController) generateLoanForm(w http.ResponseWriter, req *http.Request) {
+	dto := LoanFormDTO{}
+	if loanIDQueryParameter := req.URL.Query().Get("loanID"); loanIDQueryParameter != "" {
+		loanID, err := utils.StringToUint(loanIDQueryParameter)
+		if err != nil {
+			http.Error(w, "Unable to parse loan ID", http.StatusBadRequest)
+			return
+		}
+		loan, err := lc.LoanRepository.GetLoanByID(loanID)
+		if err != nil {
+			http.Error(w, "Unable to get loan", http.StatusNotFound)
+			return
+		}
+		dto.Updating = true
+		dto.LoanID = fmt.Sprint(loan.ID)
+		dto.AccountID = fmt.Sprint(loan.AccountID)
+		dto.AccountName = loan.Account.Name
+		dto.Principal = utils.CentsToDollarStringMachineSafe(loan.Principal)
+		dto.InterestRate = strconv.FormatFloat(loan.InterestRate, 'f', -1, 64)
+		dto.TermMonths = fmt.Sprint(loan.TermMonths)
+		dto.StartDate = loan.StartDate
+	} else {
+		accountID, err := utils.StringToUint(req.URL.Query().Get("accountID"))
+		if err != nil {
+			http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+			return
+		}
+		account, err := lc.AccountRepository.GetAccountByID(accountID)
+		if err != nil || account.ID == 0 {
+			http.Error(w, "Unable to get account", http.StatusNotFound)
+			return
+		}
+		dto.AccountID = fmt.Sprint(account.ID)
+		dto.AccountName = account.Name
+	}
+	lc.renderLoanForm(w, dto)
+}
+
+func (lc *LoanController) renderLoanForm(w http.ResponseWriter, dto LoanFormDTO) {
+	dto.ActivePage = "loans"
+	tmpl := template.Must(template.New("loanForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(loanFormTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func (lc *LoanController) upsertLoan(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+
+	loanID := req.FormValue("loanID")
+	dto := LoanFormDTO{
+		Updating:     loanID != "",
+		LoanID:       loanID,
+		AccountID:    req.FormValue("accountID"),
+		AccountName:  req.FormValue("accountName"),
+		Principal:    strings.TrimSpace(req.FormValue("principal")),
+		InterestRate: strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(req.FormValue("interestRate")), "%")),
+		TermMonths:   strings.TrimSpace(req.FormValue("termMonths")),
+		StartDate:    req.FormValue("startDate"),
+	}
+	accountID, err := utils.StringToUint(dto.AccountID)
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+
+	// Show validation problems on the form so the loan can be fixed without retyping it
+	principal, principalErr := strconv.ParseFloat(dto.Principal, 64)
+	interestRate, interestRateErr := strconv.ParseFloat(dto.InterestRate, 64)
+	termMonths, termMonthsErr := strconv.Atoi(dto.TermMonths)
+	_, startDateErr := time.Parse("2006-01-02", dto.StartDate)
+	switch {
+	case principalErr != nil || principal <= 0:
+		dto.ErrorMessage = "The principal must be a dollar amount greater than 0"
+	case interestRateErr != nil || interestRate < 0 || interestRate >= 100:
+		dto.ErrorMessage = "The interest rate must be a percentage from 0 to 100, e.g. 6.5"
+	case termMonthsErr != nil || termMonths <= 0:
+		dto.ErrorMessage = "The term must be a number of months greater than 0, e.g. 360 for 30 years"
+	case startDateErr != nil:
+		dto.ErrorMessage = "The start date must be a real date in YYYY-MM-DD format"
+	}
+	if dto.ErrorMessage != "" {
+		lc.renderLoanForm(w, dto)
+		return
+	}
+
+	loan, err := lc.LoanRepository.GetLoanByAccountID(accountID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Println("Error getting loan: ", err)
+		http.Error(w, "Unable to get loan", http.StatusInternalServerError)
+		return
+	}
+	loan.AccountID = accountID
+	loan.Principal = utils.DollarStringToCents(dto.Principal)
+	loan.InterestRate = interestRate
+	loan.TermMonths = termMonths
+	loan.StartDate = dto.StartDate
+
+	_, err = lc.LoanRepository.Save(loan)
+	if err != nil {
+		fmt.Println("Error saving loan: ", err)
+		http.Error(w, "Unable to save loan", http.StatusInternalServerError)
+		return
+	}
+	lc.sendLoansViewResponse(w, true)
+}
+
+func (lc *LoanController) deleteLoan(w http.ResponseWriter, req *http.Request) {
+	loanID, err := utils.StringToUint(req.FormValue("loanID"))
+	if err != nil {
+		http.Error(w, "Unable to parse loan ID", http.StatusBadRequest)
+		return
+	}
+	err = lc.LoanRepository.DeleteLoanByID(loanID)
+	if err != nil {
+		http.Error(w, "Unable to delete loan", http.StatusInternalServerError)
+		return
+	}
+	lc.sendLoansViewResponse(w, true)
+}
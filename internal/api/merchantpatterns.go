@@ -0,0 +1,140 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type MerchantPatternController struct {
+	Categorizer               *services.MLCategorizer
+	MerchantNormalizer        *services.MerchantNormalizer
+	MerchantPatternRepository *models.MerchantPatternRepository
+}
+
+//go:embed merchantPatterns.html
+var merchantPatternsTmpl string
+
+type MerchantPatternsPageDTO struct {
+	ActivePage       string
+	MerchantPatterns []models.MerchantPattern
+	// Values from a submitted form that failed validation, so they can be corrected
+	Pattern      string
+	MerchantName string
+	ErrorMessage string
+
+	PatternsUpdated        bool
+	PatternsUpdatedMessage string
+}
+
+func (mpc *MerchantPatternController) generateMerchantPatternsView(w http.ResponseWriter, req *http.Request) {
+	mpc.generateMerchantPatternsViewContent(w, MerchantPatternsPageDTO{})
+}
+
+func (mpc *MerchantPatternController) generateMerchantPatternsViewContent(w http.ResponseWriter, dto MerchantPatternsPageDTO) {
+	dto.ActivePage = "merchantPatterns"
+	merchantPatterns, err := mpc.MerchantPatternRepository.GetAllMerchantPatterns()
+	if err != nil {
+		http.Error(w, "Unable to get merchant patterns", http.StatusInternalServerError)
+		return
+	}
+	dto.MerchantPatterns = merchantPatterns
+
+	tmpl := template.Must(template.New("merchantPatterns").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(merchantPatternsTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func (mpc *MerchantPatternController) upsertMerchantPattern(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+	pattern := strings.TrimSpace(req.FormValue("pattern"))
+	merchantName := strings.TrimSpace(req.FormValue("merchantName"))
+
+	// Show validation problems on the page so the pattern can be fixed without retyping it
+	dto := MerchantPatternsPageDTO{Pattern: pattern, MerchantName: merchantName}
+	if pattern == "" || merchantName == "" {
+		dto.ErrorMessage = "Both a pattern and a merchant name are required"
+		mpc.generateMerchantPatternsViewContent(w, dto)
+		return
+	}
+	if _, err := services.CompileMerchantPattern(pattern); err != nil {
+		dto.ErrorMessage = "Invalid pattern: " + err.Error()
+		mpc.generateMerchantPatternsViewContent(w, dto)
+		return
+	}
+
+	_, err := mpc.MerchantPatternRepository.Save(models.MerchantPattern{Pattern: pattern, MerchantName: merchantName})
+	if err != nil {
+		dto.ErrorMessage = "Unable to save pattern, it may already exist"
+		mpc.generateMerchantPatternsViewContent(w, dto)
+		return
+	}
+
+	updated, err := mpc.applyMerchantPatterns()
+	if err != nil {
+		fmt.Println("Error applying merchant patterns: ", err)
+		http.Error(w, "Pattern saved, but unable to update existing transactions", http.StatusInternalServerError)
+		return
+	}
+	mpc.generateMerchantPatternsViewContent(w, MerchantPatternsPageDTO{
+		PatternsUpdated:        true,
+		PatternsUpdatedMessage: fmt.Sprintf("Pattern saved, %v transactions updated", updated),
+	})
+}
+
+func (mpc *MerchantPatternController) deleteMerchantPattern(w http.ResponseWriter, req *http.Request) {
+	merchantPatternID, err := utils.StringToUint(req.FormValue("merchantPatternID"))
+	if err != nil {
+		http.Error(w, "Unable to parse merchantPatternID", http.StatusBadRequest)
+		return
+	}
+	err = mpc.MerchantPatternRepository.DeleteMerchantPatternByID(merchantPatternID)
+	if err != nil {
+		http.Error(w, "Unable to delete merchant pattern", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := mpc.applyMerchantPatterns()
+	if err != nil {
+		fmt.Println("Error applying merchant patterns: ", err)
+		http.Error(w, "Pattern deleted, but unable to update existing transactions", http.StatusInternalServerError)
+		return
+	}
+	mpc.generateMerchantPatternsViewContent(w, MerchantPatternsPageDTO{
+		PatternsUpdated:        true,
+		PatternsUpdatedMessage: fmt.Sprintf("Pattern deleted, %v transactions updated", updated),
+	})
+}
+
+// previewMerchantName returns just the normalized merchant name for a description, so patterns can be
+// tried out before they're saved
+func (mpc *MerchantPatternController) previewMerchantName(w http.ResponseWriter, req *http.Request) {
+	description := req.URL.Query().Get("description")
+	if description == "" {
+		return
+	}
+	fmt.Fprint(w, template.HTMLEscapeString(mpc.MerchantNormalizer.NormalizeMerchantName(description)))
+}
+
+// applyMerchantPatterns reloads the patterns, renames existing transactions and retrains the categorizer
+// if any merchant names changed, since the model is trained on merchant names
+func (mpc *MerchantPatternController) applyMerchantPatterns() (updated int, err error) {
+	err = mpc.MerchantNormalizer.LoadPatterns()
+	if err != nil {
+		return 0, err
+	}
+	updated, err = mpc.MerchantNormalizer.NormalizeAllTransactions()
+	if err != nil || updated == 0 {
+		return updated, err
+	}
+	return updated, mpc.Categorizer.BuildModel()
+}
@@ -6,14 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"text/template"
-	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
 type NetIncomeController struct {
 	TransactionRepository *models.TransactionRepository
+	DateRangeService      *services.DateRangeService
+	LoanService           *services.LoanService
 }
 
 type IncomeAndExpensesDataSet struct {
@@ -27,63 +29,45 @@ type IncomeAndExpensesDataSet struct {
 	TTMAverage                string
 	TTMSeventyFifthPercentile string
 	TTMTwentyFifthPercentile  string
+	// How much of the month's scheduled loan payments went to principal and to interest
+	LoanPrincipalHumanized string
+	LoanInterestHumanized  string
 }
 
 type IncomeAndExpensesDTO struct {
-	ActivePage         string
-	AllTimeActive      bool
-	Last12MonthsActive bool
-	Last6MonthsActive  bool
-	Last3MonthsActive  bool
-	DataSets           []IncomeAndExpensesDataSet
+	ActivePage string
+	DateRangeDTO
+	DataSets []IncomeAndExpensesDataSet
+	// HasLoanPayments is whether any loan payments were scheduled in the time frame
+	HasLoanPayments bool
 }
 
 //go:embed netincome.html
 var netIncomeTmpl string
 
 // netIncomeHandler is the HTTP handler for the net income page
-// TODO: Implement logic for relative date controls
 func (nc *NetIncomeController) netIncomeHandler(w http.ResponseWriter, req *http.Request) {
 	dto := IncomeAndExpensesDTO{
 		ActivePage: "netIncome",
 	}
-	var relativeWindow int
-
-	if req.FormValue("relativeWindow") == "" {
-		relativeWindow = 6
-		dto.Last6MonthsActive = true
-	} else {
-		switch req.FormValue("relativeWindow") {
-		case "12":
-			relativeWindow = 12
-			dto.Last12MonthsActive = true
-		case "6":
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		case "3":
-			relativeWindow = 3
-			dto.Last3MonthsActive = true
-		case "allTime":
-			// 10 years in months, as a useful approximation of all time for now
-			relativeWindow = 120
-			dto.AllTimeActive = true
-		default:
-			fmt.Println("invalid relative window provided, falling back to 6 months")
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		}
+	dateRange, err := parseDateRange(req, nc.DateRangeService, "/net-income", services.DateRangeLast6Months, &dto.DateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// We always start with today's date and work backwards based on relative window value
-	endDate := time.Now()
-	// And calculate start date
-	startDate := endDate.AddDate(0, (relativeWindow * -1), 0)
-
-	netIncomeDataByDate, err := nc.TransactionRepository.GetNetIncomeTotalsByDate(context.TODO(), startDate, endDate)
+	netIncomeDataByDate, err := nc.TransactionRepository.GetNetIncomeTotalsByDate(context.TODO(), dateRange.StartDate, dateRange.EndDate)
 	if err != nil {
 		// TODO: handle correctly
 		fmt.Println("error while getting net income data:", err)
 	}
+	loanPaymentSplits, err := nc.LoanService.GetPaymentSplitsByMonth(dateRange.StartDate, dateRange.EndDate)
+	if err != nil {
+		fmt.Println("Error getting loan payments: ", err)
+		http.Error(w, "Unable to get loan payments", http.StatusInternalServerError)
+		return
+	}
+	dto.HasLoanPayments = len(loanPaymentSplits) > 0
 
 	for _, netIncomeData := range netIncomeDataByDate {
 		netIncomeTTMAverage, twentyFifthPercentile, seventyFifthPercentile, _ := nc.TransactionRepository.GetTTMStatistics(context.TODO(), netIncomeData.Date)
@@ -99,6 +83,8 @@ func (nc *NetIncomeController) netIncomeHandler(w http.ResponseWriter, req *http
 			TTMAverage:                utils.CentsToDollarStringMachineSafe(netIncomeTTMAverage),
 			TTMSeventyFifthPercentile: utils.CentsToDollarStringMachineSafe(seventyFifthPercentile),
 			TTMTwentyFifthPercentile:  utils.CentsToDollarStringMachineSafe(twentyFifthPercentile),
+			LoanPrincipalHumanized:    utils.CentsToDollarStringHumanized(loanPaymentSplits[netIncomeData.Date.Format("2006-01")].Principal),
+			LoanInterestHumanized:     utils.CentsToDollarStringHumanized(loanPaymentSplits[netIncomeData.Date.Format("2006-01")].Interest),
 		}
 		dto.DataSets = append(dto.DataSets, incomeAndExpenses)
 	}
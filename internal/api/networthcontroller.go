@@ -1,129 +1,154 @@
 package api
 
 import (
-	"context"
 	_ "embed"
-	"fmt"
 	"net/http"
 	"text/template"
-	"time"
 
-	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
 type NetWorthController struct {
-	BalanceRepository *models.BalanceRepository
+	NetWorthService  *services.NetWorthService
+	DateRangeService *services.DateRangeService
 }
 
 //go:embed networth.html
 var netWorthTmpl string
 
-// A totalByType entry will be either 'asset': <some int> or 'liablities': <some int>
-// This is used for aggregating balances, but is not the DTO to the front end HTML template
-type totalByType map[string]int
-
-// The DTO is also a map of year-month to amount, but has the total converted to string for
-// expected currency format in the UI
+// A map of 'assets', 'liabilities' and 'netWorth' to the total for a year-month, converted to string for
+// expected currency format in the UI. 'estimated' is "true" if any of the balances were estimated.
 type totalByTypeDTO map[string]string
 
+// Labels for the account categories of the built in account types
+var accountCategoryLabels = map[string]string{
+	"asset":      "Other",
+	"brokerage":  "Brokerage",
+	"checking":   "Checking",
+	"creditCard": "Credit card",
+	"loan":       "Loan",
+	"realEstate": "Real estate",
+	"savings":    "Savings",
+	"vehicle":    "Vehicle",
+}
+
 type netWorthdto struct {
-	ActivePage          string
-	AllTimeActive       bool
-	Last12MonthsActive  bool
-	Last6MonthsActive   bool
-	Last3MonthsActive   bool
+	ActivePage string
+	DateRangeDTO
 	TotalByMonthAndType map[string]totalByTypeDTO
+	// Breakdown is empty for the totals of assets and liabilities, otherwise it's one of the services.NetWorthBy
+	// constants
+	Breakdown string
+	// Months of the breakdown, oldest first for the chart and newest first for the table
+	ChartLabels []string
+	Months      []string
+	Rows        []netWorthRowDTO
+	NetWorth    netWorthRowDTO
+	// HasEstimates is true if any month includes a balance estimated from a valuation rule
+	HasEstimates bool
+}
+
+type netWorthRowDTO struct {
+	Name string
+	// Machine safe amounts for the chart and whether they include an estimated balance, oldest first
+	ChartAmounts   []string
+	ChartEstimated []bool
+	// Humanized amounts and changes for the table, newest first
+	Cells []netWorthCellDTO
+}
+
+type netWorthCellDTO struct {
+	Amount    string
+	Change    string
+	Direction string // up, down or empty if unchanged
+	Estimated bool
+}
+
+func netWorthRowToDTO(name string, amounts []int, changes []int, estimated []bool) netWorthRowDTO {
+	row := netWorthRowDTO{Name: name}
+	for i := range amounts {
+		row.ChartAmounts = append(row.ChartAmounts, utils.CentsToDollarStringMachineSafe(amounts[i]))
+		row.ChartEstimated = append(row.ChartEstimated, estimated[i])
+	}
+	for i := len(amounts) - 1; i >= 0; i-- {
+		cell := netWorthCellDTO{
+			Amount:    utils.CentsToDollarStringHumanized(amounts[i]),
+			Change:    utils.CentsToDollarStringHumanized(changes[i]),
+			Estimated: estimated[i],
+		}
+		if changes[i] > 0 {
+			cell.Direction = "up"
+		} else if changes[i] < 0 {
+			cell.Direction = "down"
+		}
+		row.Cells = append(row.Cells, cell)
+	}
+	return row
 }
 
-// TODO: Consider moving this into a service class that returns just the data needed
 func (nc *NetWorthController) netWorthHandler(w http.ResponseWriter, req *http.Request) {
-	var relativeWindow int
 	dto := netWorthdto{
 		ActivePage: "netWorth",
 	}
-	if req.FormValue("relativeWindow") == "" {
-		relativeWindow = 6
-		dto.Last6MonthsActive = true
-	} else {
-		switch req.FormValue("relativeWindow") {
-		case "12":
-			relativeWindow = 12
-			dto.Last12MonthsActive = true
-		case "6":
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		case "3":
-			relativeWindow = 3
-			dto.Last3MonthsActive = true
-		case "allTime":
-			// 10 years in months, as a useful approximation of all time for now
-			relativeWindow = 120
-			dto.AllTimeActive = true
-		default:
-			fmt.Println("invalid relative window provided, falling back to 6 months")
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		}
+	switch req.FormValue("breakdown") {
+	case services.NetWorthByAccount, services.NetWorthByAccountCategory, services.NetWorthByGroup:
+		dto.Breakdown = req.FormValue("breakdown")
+		dto.DateRangeParams = map[string]string{"breakdown": dto.Breakdown}
+	}
+	dateRange, err := parseDateRange(req, nc.DateRangeService, "/net-worth", services.DateRangeLast6Months, &dto.DateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// We always start with today's date and work backwards based on relative window value
-	endDate := time.Now()
-	// Decrement relativeWindow by 1 (to account for the current month already being included)
-	relativeWindow = relativeWindow - 1
-	// And calculate start date
-	startDate := endDate.AddDate(0, (relativeWindow * -1), 0)
-
-	assetBalances := nc.BalanceRepository.GetBalancesOfAllAssetsByMonth(context.TODO(), startDate, endDate)
-	liabilityBalances := nc.BalanceRepository.GetBalancesOfAllLiabilitiesByMonth(context.TODO(), startDate, endDate)
+	breakdown, err := nc.NetWorthService.GetNetWorthBreakdown(dateRange.StartDate, dateRange.EndDate, dto.Breakdown)
+	if err != nil {
+		http.Error(w, "Unable to get net worth", http.StatusInternalServerError)
+		return
+	}
 
-	// A map of year-month to total by type, so we can aggregate the sum of balances for each year-month
-	totalByMonthAndType := map[string]totalByType{}
 	// A map of year-month to total by type as a string value, so it can be presented in the UI layer
 	// this keeps the aggregation math separate from presentation layer
-	totalByMonthAndTypeDTO := map[string]totalByTypeDTO{}
-
-	for _, balancesByDate := range assetBalances {
-		yearMonth := balancesByDate.Date.Format("2006-01")
-		if totalByMonthAndType[yearMonth] == nil {
-			totalByMonthAndType[yearMonth] = totalByType{}
+	dto.TotalByMonthAndType = map[string]totalByTypeDTO{}
+	for i, month := range breakdown.Months {
+		estimated := ""
+		if breakdown.Estimated[i] {
+			estimated = "true"
+			dto.HasEstimates = true
 		}
-
-		for _, balance := range balancesByDate.Balances {
-			totalByMonthAndType[yearMonth]["assets"] = totalByMonthAndType[yearMonth]["assets"] + balance.Amount
+		dto.TotalByMonthAndType[month.Format("2006-01")] = totalByTypeDTO{
+			"assets":      utils.CentsToDollarStringMachineSafe(breakdown.Assets[i]),
+			"liabilities": utils.CentsToDollarStringMachineSafe(breakdown.Liabilities[i]),
+			"netWorth":    utils.CentsToDollarStringMachineSafe(breakdown.NetWorth[i]),
+			// humanized versions for display
+			"humanizedAssets":      utils.CentsToDollarStringHumanized(breakdown.Assets[i]),
+			"humanizedLiabilities": utils.CentsToDollarStringHumanized(breakdown.Liabilities[i]),
+			"humanizedNetWorth":    utils.CentsToDollarStringHumanized(breakdown.NetWorth[i]),
+			"estimated":            estimated,
 		}
 	}
 
-	for _, balancesByDate := range liabilityBalances {
-		yearMonth := balancesByDate.Date.Format("2006-01")
-		if totalByMonthAndType[yearMonth] == nil {
-			totalByMonthAndType[yearMonth] = totalByType{}
-		}
-		for _, balance := range balancesByDate.Balances {
-			totalByMonthAndType[yearMonth]["liabilities"] = totalByMonthAndType[yearMonth]["liabilities"] - balance.Amount
-		}
+	for _, month := range breakdown.Months {
+		dto.ChartLabels = append(dto.ChartLabels, month.Format("2006-01"))
 	}
-
-	for date := range totalByMonthAndType {
-		totalByMonthAndType[date]["netWorth"] = totalByMonthAndType[date]["assets"] + totalByMonthAndType[date]["liabilities"]
-		// populate the totalByTypeDTO
-		if totalByMonthAndTypeDTO[date] == nil {
-			totalByMonthAndTypeDTO[date] = totalByTypeDTO{}
+	for i := len(breakdown.Months) - 1; i >= 0; i-- {
+		dto.Months = append(dto.Months, breakdown.Months[i].Format("2006-01"))
+	}
+	for _, row := range breakdown.Rows {
+		name := row.Name
+		if dto.Breakdown == services.NetWorthByAccountCategory {
+			if label, ok := accountCategoryLabels[name]; ok {
+				name = label
+			}
 		}
-		totalByMonthAndTypeDTO[date]["assets"] = utils.CentsToDollarStringMachineSafe(totalByMonthAndType[date]["assets"])
-		totalByMonthAndTypeDTO[date]["liabilities"] = utils.CentsToDollarStringMachineSafe(totalByMonthAndType[date]["liabilities"])
-		totalByMonthAndTypeDTO[date]["netWorth"] = utils.CentsToDollarStringMachineSafe(totalByMonthAndType[date]["netWorth"])
-		// humanized versions for display
-		totalByMonthAndTypeDTO[date]["humanizedLiabilities"] = utils.CentsToDollarStringHumanized(totalByMonthAndType[date]["liabilities"])
-		totalByMonthAndTypeDTO[date]["humanizedAssets"] = utils.CentsToDollarStringHumanized(totalByMonthAndType[date]["assets"])
-		totalByMonthAndTypeDTO[date]["humanizedNetWorth"] = utils.CentsToDollarStringHumanized(totalByMonthAndType[date]["netWorth"])
-
+		dto.Rows = append(dto.Rows, netWorthRowToDTO(name, row.Amounts, row.Changes, row.Estimated))
 	}
+	dto.NetWorth = netWorthRowToDTO("Net worth", breakdown.NetWorth, breakdown.NetWorthChanges, breakdown.Estimated)
 
-	dto.TotalByMonthAndType = totalByMonthAndTypeDTO
 	tmpl := template.Must(template.New("netWorthDashboard").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(netWorthTmpl))
-	err := utils.RenderTemplateAsHTML(w, tmpl, dto)
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+	humanize "github.com/dustin/go-humanize"
+)
+
+type NotificationController struct {
+	AlertRuleRepository    *models.AlertRuleRepository
+	AlertService           *services.AlertService
+	NotificationRepository *models.NotificationRepository
+}
+
+//go:embed notifications.html
+var notificationsTmpl string
+
+// recentNotificationsLimit is how many notifications the notification center shows
+const recentNotificationsLimit = 100
+
+// alertKinds are the kinds of alert rule that can be added, in the order they're offered
+var alertKinds = []alertKind{
+	{Kind: models.AlertKindBudgetPercent, Label: "Budget reaches a percentage", ThresholdLabel: "Percent of budget"},
+	{Kind: models.AlertKindLargeTransaction, Label: "Transaction of at least an amount", ThresholdLabel: "Amount ($)"},
+	{Kind: models.AlertKindUnknownTransaction, Label: "New transactions in Unknown"},
+}
+
+type alertKind struct {
+	Kind           string
+	Label          string
+	ThresholdLabel string
+}
+
+type NotificationsPageDTO struct {
+	ActivePage    string
+	Notifications []NotificationDTO
+	UnreadCount   int64
+	AlertRules    []AlertRuleDTO
+	AlertKinds    []alertKind
+	// Values from a submitted alert rule that failed validation, so they can be corrected
+	Kind         string
+	Threshold    string
+	ErrorMessage string
+
+	NotificationsUpdated        bool
+	NotificationsUpdatedMessage string
+}
+
+type NotificationDTO struct {
+	ID      uint
+	Title   string
+	Message string
+	Link    string
+	Read    bool
+	// Humanized time the notification was raised, e.g. 3 hours ago
+	CreatedAt string
+}
+
+type AlertRuleDTO struct {
+	ID          uint
+	Description string
+	Enabled     bool
+}
+
+func (nc *NotificationController) generateNotificationsView(w http.ResponseWriter, req *http.Request) {
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{})
+}
+
+func (nc *NotificationController) generateNotificationsViewContent(w http.ResponseWriter, dto NotificationsPageDTO) {
+	dto.ActivePage = "notifications"
+	dto.AlertKinds = alertKinds
+
+	notifications, err := nc.NotificationRepository.GetRecentNotifications(recentNotificationsLimit)
+	if err != nil {
+		fmt.Println("Error getting notifications: ", err)
+		http.Error(w, "Unable to get notifications", http.StatusInternalServerError)
+		return
+	}
+	for _, notification := range notifications {
+		dto.Notifications = append(dto.Notifications, NotificationDTO{
+			ID:        notification.ID,
+			Title:     notification.Title,
+			Message:   notification.Message,
+			Link:      notification.Link,
+			Read:      notification.Read,
+			CreatedAt: humanize.Time(notification.CreatedAt),
+		})
+		if !notification.Read {
+			dto.UnreadCount++
+		}
+	}
+
+	rules, err := nc.AlertRuleRepository.GetAllAlertRules()
+	if err != nil {
+		fmt.Println("Error getting alert rules: ", err)
+		http.Error(w, "Unable to get alert rules", http.StatusInternalServerError)
+		return
+	}
+	for _, rule := range rules {
+		dto.AlertRules = append(dto.AlertRules, AlertRuleDTO{
+			ID:          rule.ID,
+			Description: describeAlertRule(rule),
+			Enabled:     rule.Enabled,
+		})
+	}
+
+	tmpl := template.Must(template.New("notifications").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(notificationsTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+func describeAlertRule(rule models.AlertRule) string {
+	switch rule.Kind {
+	case models.AlertKindBudgetPercent:
+		return fmt.Sprintf("A budget reaches %d%% of its amount", rule.Threshold)
+	case models.AlertKindLargeTransaction:
+		return fmt.Sprintf("A new transaction of $%v or more", utils.CentsToDollarStringHumanized(rule.Threshold))
+	case models.AlertKindUnknownTransaction:
+		return "New transactions couldn't be categorized"
+	}
+	return rule.Kind
+}
+
+// generateNotificationBadge returns just the unread count for the badge in the navbar, or nothing if there
+// aren't any unread notifications
+func (nc *NotificationController) generateNotificationBadge(w http.ResponseWriter, req *http.Request) {
+	count, err := nc.NotificationRepository.CountUnreadNotifications()
+	if err != nil {
+		fmt.Println("Error counting unread notifications: ", err)
+		http.Error(w, "Unable to count notifications", http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		fmt.Fprint(w, count)
+	}
+}
+
+func (nc *NotificationController) markAllNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	err := nc.NotificationRepository.MarkAllNotificationsRead()
+	if err != nil {
+		http.Error(w, "Unable to mark notifications as read", http.StatusInternalServerError)
+		return
+	}
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{})
+}
+
+func (nc *NotificationController) deleteNotification(w http.ResponseWriter, req *http.Request) {
+	notificationID, err := utils.StringToUint(req.FormValue("notificationID"))
+	if err != nil {
+		http.Error(w, "Unable to parse notificationID", http.StatusBadRequest)
+		return
+	}
+	err = nc.NotificationRepository.DeleteNotificationByID(notificationID)
+	if err != nil {
+		http.Error(w, "Unable to delete notification", http.StatusInternalServerError)
+		return
+	}
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{})
+}
+
+// checkAlerts evaluates the alert rules now rather than waiting for the next scheduled check
+func (nc *NotificationController) checkAlerts(w http.ResponseWriter, req *http.Request) {
+	notifications, err := nc.AlertService.EvaluateAlerts()
+	if err != nil {
+		fmt.Println("Error evaluating alerts: ", err)
+		http.Error(w, "Unable to check alerts", http.StatusInternalServerError)
+		return
+	}
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{
+		NotificationsUpdated:        true,
+		NotificationsUpdatedMessage: fmt.Sprintf("Alerts checked, %v new notifications", len(notifications)),
+	})
+}
+
+func (nc *NotificationController) upsertAlertRule(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+
+	// Existing rules can only be turned on and off, changing a rule means deleting it and adding a new one
+	if req.FormValue("alertRuleID") != "" {
+		alertRuleID, err := utils.StringToUint(req.FormValue("alertRuleID"))
+		if err != nil {
+			http.Error(w, "Unable to parse alertRuleID", http.StatusBadRequest)
+			return
+		}
+		rule, err := nc.AlertRuleRepository.GetAlertRuleByID(alertRuleID)
+		if err != nil {
+			http.Error(w, "Unable to find alert rule", http.StatusNotFound)
+			return
+		}
+		rule.Enabled, err = strconv.ParseBool(req.FormValue("enabled"))
+		if err != nil {
+			http.Error(w, "Invalid value for enabled", http.StatusBadRequest)
+			return
+		}
+		_, err = nc.AlertRuleRepository.Save(rule)
+		if err != nil {
+			http.Error(w, "Unable to save alert rule", http.StatusInternalServerError)
+			return
+		}
+		nc.generateNotificationsViewContent(w, NotificationsPageDTO{})
+		return
+	}
+
+	kind := req.FormValue("kind")
+	threshold := req.FormValue("threshold")
+	dto := NotificationsPageDTO{Kind: kind, Threshold: threshold}
+	rule := models.AlertRule{Kind: kind, Enabled: true}
+	switch kind {
+	case models.AlertKindBudgetPercent:
+		percent, err := strconv.Atoi(threshold)
+		if err != nil || percent <= 0 {
+			dto.ErrorMessage = "The percentage must be a whole number greater than 0"
+			nc.generateNotificationsViewContent(w, dto)
+			return
+		}
+		rule.Threshold = percent
+	case models.AlertKindLargeTransaction:
+		// Checked here since DollarStringToCents panics on anything that isn't a number
+		amount, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || amount <= 0 {
+			dto.ErrorMessage = "The amount must be a dollar amount greater than 0"
+			nc.generateNotificationsViewContent(w, dto)
+			return
+		}
+		rule.Threshold = utils.DollarStringToCents(threshold)
+	case models.AlertKindUnknownTransaction:
+	default:
+		dto.ErrorMessage = "Choose what the alert is for"
+		nc.generateNotificationsViewContent(w, dto)
+		return
+	}
+
+	_, err := nc.AlertRuleRepository.Save(rule)
+	if err != nil {
+		http.Error(w, "Unable to save alert rule", http.StatusInternalServerError)
+		return
+	}
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{
+		NotificationsUpdated:        true,
+		NotificationsUpdatedMessage: "Alert rule added",
+	})
+}
+
+func (nc *NotificationController) deleteAlertRule(w http.ResponseWriter, req *http.Request) {
+	alertRuleID, err := utils.StringToUint(req.FormValue("alertRuleID"))
+	if err != nil {
+		http.Error(w, "Unable to parse alertRuleID", http.StatusBadRequest)
+		return
+	}
+	err = nc.AlertRuleRepository.DeleteAlertRuleByID(alertRuleID)
+	if err != nil {
+		http.Error(w, "Unable to delete alert rule", http.StatusInternalServerError)
+		return
+	}
+	nc.generateNotificationsViewContent(w, NotificationsPageDTO{
+		NotificationsUpdated:        true,
+		NotificationsUpdatedMessage: "Alert rule deleted",
+	})
+}
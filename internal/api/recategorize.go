@@ -0,0 +1,167 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type RecategorizationController struct {
+	AccountRepository       *models.AccountRepository
+	CategoryRepository      *models.CategoryRepository
+	RecategorizationService *services.RecategorizationService
+}
+
+//go:embed recategorize.html
+var recategorizeTmpl string
+
+type recategorizationChangeDTO struct {
+	TransactionID      uint
+	Date               string
+	Description        string
+	Amount             string
+	AccountName        string
+	CurrentCategory    string
+	ProposedCategory   string
+	ProposedCategoryID uint
+}
+
+type RecategorizePageDTO struct {
+	ActivePage         string
+	Accounts           []models.Account
+	Categories         []models.Category
+	SelectedAccountID  uint
+	SelectedCategoryID uint
+	StartDate          string
+	EndDate            string
+
+	Previewed bool
+	Changes   []recategorizationChangeDTO
+
+	TransactionsUpdated        bool
+	TransactionsUpdatedMessage string
+}
+
+func (rc *RecategorizationController) generateRecategorizeView(w http.ResponseWriter, req *http.Request) {
+	dto := RecategorizePageDTO{
+		ActivePage: "recategorize",
+	}
+
+	query := req.URL.Query()
+	var scope services.RecategorizationScope
+	var err error
+	if query.Get("accountID") != "" {
+		scope.AccountID, err = utils.StringToUint(query.Get("accountID"))
+		if err != nil {
+			http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+			return
+		}
+		dto.SelectedAccountID = scope.AccountID
+	}
+	if query.Get("categoryID") != "" {
+		scope.CategoryID, err = utils.StringToUint(query.Get("categoryID"))
+		if err != nil {
+			http.Error(w, "Unable to parse category ID", http.StatusBadRequest)
+			return
+		}
+		dto.SelectedCategoryID = scope.CategoryID
+	}
+	if query.Get("startDate") != "" {
+		startDate := utils.ISO8601DateStringToTime(query.Get("startDate"))
+		scope.StartDate = &startDate
+		dto.StartDate = query.Get("startDate")
+	}
+	if query.Get("endDate") != "" {
+		endDate := utils.ISO8601DateStringToTime(query.Get("endDate"))
+		scope.EndDate = &endDate
+		dto.EndDate = query.Get("endDate")
+	}
+
+	// Only run the dry run once the user has chosen a scope
+	if query.Get("preview") == "true" {
+		changes, err := rc.RecategorizationService.PreviewRecategorization(scope)
+		if err != nil {
+			fmt.Println("Error previewing re-categorization: ", err)
+			http.Error(w, "Unable to preview re-categorization", http.StatusInternalServerError)
+			return
+		}
+		dto.Previewed = true
+		for _, change := range changes {
+			dto.Changes = append(dto.Changes, recategorizationChangeDTO{
+				TransactionID:      change.Transaction.ID,
+				Date:               change.Transaction.Date,
+				Description:        change.Transaction.Description,
+				Amount:             utils.CentsToDollarStringHumanized(change.Transaction.Amount),
+				AccountName:        change.Transaction.Account.Name,
+				CurrentCategory:    change.Transaction.Category.Name,
+				ProposedCategory:   change.ProposedCategory.Name,
+				ProposedCategoryID: change.ProposedCategory.ID,
+			})
+		}
+	}
+
+	rc.renderRecategorizeView(w, dto)
+}
+
+// applyRecategorization updates the transactions the user selected from the preview. Each selected
+// transaction ID is submitted alongside a "categoryID-<transaction ID>" field with the proposed category.
+func (rc *RecategorizationController) applyRecategorization(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+
+	categoryIDsByTransactionID := map[uint]uint{}
+	for _, transactionIDInput := range req.Form["transactionID"] {
+		transactionID, err := utils.StringToUint(transactionIDInput)
+		if err != nil {
+			http.Error(w, "Unable to parse transactionID", http.StatusBadRequest)
+			return
+		}
+		categoryID, err := utils.StringToUint(req.FormValue("categoryID-" + strings.TrimSpace(transactionIDInput)))
+		if err != nil {
+			http.Error(w, "Unable to parse categoryID for transaction "+transactionIDInput, http.StatusBadRequest)
+			return
+		}
+		categoryIDsByTransactionID[transactionID] = categoryID
+	}
+
+	updated, err := rc.RecategorizationService.ApplyRecategorization(categoryIDsByTransactionID)
+	if err != nil {
+		fmt.Println("Error applying re-categorization: ", err)
+		http.Error(w, "Unable to re-categorize transactions", http.StatusInternalServerError)
+		return
+	}
+
+	dto := RecategorizePageDTO{
+		ActivePage:                 "recategorize",
+		TransactionsUpdated:        true,
+		TransactionsUpdatedMessage: fmt.Sprintf("%v transactions re-categorized", updated),
+	}
+	rc.renderRecategorizeView(w, dto)
+}
+
+func (rc *RecategorizationController) renderRecategorizeView(w http.ResponseWriter, dto RecategorizePageDTO) {
+	accounts, err := rc.AccountRepository.GetAllAccounts()
+	if err != nil {
+		http.Error(w, "Unable to get accounts", http.StatusInternalServerError)
+		return
+	}
+	dto.Accounts = accounts
+	categories, err := rc.CategoryRepository.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
+	}
+	dto.Categories = categories
+
+	tmpl := template.Must(template.New("recategorize").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(recategorizeTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
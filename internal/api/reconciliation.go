@@ -0,0 +1,191 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type ReconciliationController struct {
+	AccountRepository     *models.AccountRepository
+	BalanceHistoryService *services.BalanceHistoryService
+}
+
+//go:embed reconciliation.html
+var reconciliationTmpl string
+
+//go:embed reconciliationPeriod.html
+var reconciliationPeriodTmpl string
+
+type ReconciliationPageDTO struct {
+	ActivePage string
+	Accounts   []ReconciliationAccountDTO
+	// The account whose periods are shown, if one is chosen
+	AccountID   uint
+	AccountName string
+	Periods     []ReconciliationPeriodDTO
+}
+
+type ReconciliationAccountDTO struct {
+	ID   uint
+	Name string
+	// Periods is the number of pairs of consecutive reported balances compared
+	Periods           int
+	Discrepancies     int
+	UnexplainedAmount string
+}
+
+type ReconciliationPeriodDTO struct {
+	StartBalanceID   uint
+	EndBalanceID     uint
+	StartDate        string
+	EndDate          string
+	StartAmount      string
+	EndAmount        string
+	BalanceChange    string
+	TransactionsNet  string
+	TransactionCount int
+	Difference       string
+	Reconciles       bool
+}
+
+type ReconciliationPeriodPageDTO struct {
+	ActivePage   string
+	AccountID    uint
+	AccountName  string
+	Period       ReconciliationPeriodDTO
+	Transactions []ReconciledTransactionDTO
+}
+
+type ReconciledTransactionDTO struct {
+	ID            uint
+	Date          string
+	Description   string
+	CategoryName  string
+	Amount        string
+	BalanceChange string
+}
+
+func reconciliationPeriodToDTO(period services.ReconciliationPeriod) ReconciliationPeriodDTO {
+	return ReconciliationPeriodDTO{
+		StartBalanceID:   period.StartBalance.ID,
+		EndBalanceID:     period.EndBalance.ID,
+		StartDate:        period.StartBalance.EffectiveDate,
+		EndDate:          period.EndBalance.EffectiveDate,
+		StartAmount:      utils.CentsToDollarStringHumanized(period.StartBalance.Amount),
+		EndAmount:        utils.CentsToDollarStringHumanized(period.EndBalance.Amount),
+		BalanceChange:    utils.CentsToDollarStringHumanized(period.BalanceChange),
+		TransactionsNet:  utils.CentsToDollarStringHumanized(period.TransactionsNet),
+		TransactionCount: period.TransactionCount,
+		Difference:       utils.CentsToDollarStringHumanized(period.Difference),
+		Reconciles:       period.Reconciles(),
+	}
+}
+
+// generateReconciliationView summarizes how well every account's balances and transactions agree, and lists the
+// periods of the chosen account, newest first
+func (rc *ReconciliationController) generateReconciliationView(w http.ResponseWriter, req *http.Request) {
+	accounts, err := rc.AccountRepository.GetAllAccounts()
+	if err != nil {
+		http.Error(w, "Unable to get accounts", http.StatusInternalServerError)
+		return
+	}
+	dto := ReconciliationPageDTO{ActivePage: "reconciliation"}
+	if accountIDQueryParameter := req.URL.Query().Get("accountID"); accountIDQueryParameter != "" {
+		dto.AccountID, err = utils.StringToUint(accountIDQueryParameter)
+		if err != nil {
+			http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, account := range accounts {
+		periods, err := rc.BalanceHistoryService.ReconcileAccount(account.ID)
+		if err != nil {
+			fmt.Println("Error reconciling account: ", err)
+			http.Error(w, "Unable to reconcile "+account.Name, http.StatusInternalServerError)
+			return
+		}
+		accountDTO := ReconciliationAccountDTO{ID: account.ID, Name: account.Name, Periods: len(periods)}
+		unexplained := 0
+		for _, period := range periods {
+			if !period.Reconciles() {
+				accountDTO.Discrepancies++
+				if period.Difference < 0 {
+					unexplained -= period.Difference
+				} else {
+					unexplained += period.Difference
+				}
+			}
+		}
+		accountDTO.UnexplainedAmount = utils.CentsToDollarStringHumanized(unexplained)
+		dto.Accounts = append(dto.Accounts, accountDTO)
+
+		if account.ID == dto.AccountID {
+			dto.AccountName = account.Name
+			for i := len(periods) - 1; i >= 0; i-- {
+				dto.Periods = append(dto.Periods, reconciliationPeriodToDTO(periods[i]))
+			}
+		}
+	}
+
+	tmpl := template.Must(template.New("reconciliation").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(reconciliationTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// generateReconciliationPeriodView shows the two balances of a period and the transactions between them
+func (rc *ReconciliationController) generateReconciliationPeriodView(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	accountID, err := utils.StringToUint(query.Get("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	startBalanceID, err := utils.StringToUint(query.Get("startBalanceID"))
+	if err != nil {
+		http.Error(w, "Unable to parse start balance ID", http.StatusBadRequest)
+		return
+	}
+	endBalanceID, err := utils.StringToUint(query.Get("endBalanceID"))
+	if err != nil {
+		http.Error(w, "Unable to parse end balance ID", http.StatusBadRequest)
+		return
+	}
+	account, err := rc.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		http.Error(w, "Unable to get account", http.StatusInternalServerError)
+		return
+	}
+	period, transactions, err := rc.BalanceHistoryService.GetReconciliationPeriod(accountID, startBalanceID, endBalanceID)
+	if err != nil {
+		http.Error(w, "Unable to find that reconciliation period", http.StatusNotFound)
+		return
+	}
+
+	dto := ReconciliationPeriodPageDTO{
+		ActivePage:  "reconciliation",
+		AccountID:   accountID,
+		AccountName: account.Name,
+		Period:      reconciliationPeriodToDTO(period),
+	}
+	for _, transaction := range transactions {
+		dto.Transactions = append(dto.Transactions, ReconciledTransactionDTO{
+			ID:            transaction.ID,
+			Date:          transaction.Date,
+			Description:   transaction.Description,
+			CategoryName:  transaction.Category.Name,
+			Amount:        utils.CentsToDollarStringHumanized(transaction.Amount),
+			BalanceChange: utils.CentsToDollarStringHumanized(transaction.BalanceChange),
+		})
+	}
+
+	tmpl := template.Must(template.New("reconciliationPeriod").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(reconciliationPeriodTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
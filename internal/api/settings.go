@@ -2,15 +2,22 @@ package api
 
 import (
 	_ "embed"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
+	humanize "github.com/dustin/go-humanize"
 )
 
 type SettingsController struct {
+	Categorizer        *services.MLCategorizer
 	SettingsRepository *models.SettingsRepository
 }
 
@@ -21,22 +28,60 @@ type SettingsPageDTO struct {
 	ActivePage string
 	// List of settings to be displayed on the page
 	LaunchBrowserOnStartup bool
+	CategorizerBackend     string
+	CategorizerBackends    []services.CategorizerBackendOption
+	AlertWebhookURL        string
+	FiscalYearStartMonth   int
+	Months                 []monthOptionDTO
+
+	// Categorization model details
+	ModelSampleCount   int
+	ModelCategoryCount int
+	ModelLastTrained   string
 
 	SettingsUpdated        bool
 	SettingsUpdatedMessage string
 }
 
+type monthOptionDTO struct {
+	Number int
+	Name   string
+}
+
 func (sc *SettingsController) generateSettingsView(w http.ResponseWriter, req *http.Request) {
+	sc.generateSettingsViewContent(w, "")
+}
+
+func (sc *SettingsController) generateSettingsViewContent(w http.ResponseWriter, settingsUpdatedMessage string) {
 	settings, err := sc.SettingsRepository.GetSettings()
 	if err != nil {
 		http.Error(w, "Unable to retrieve settings", http.StatusInternalServerError)
 		return
 	}
 
+	modelStatus := sc.Categorizer.GetModelStatus()
 	dto := SettingsPageDTO{
 		ActivePage:             "settings",
 		LaunchBrowserOnStartup: settings.LaunchBrowserOnStartup,
+		CategorizerBackend:     modelStatus.Backend,
+		CategorizerBackends:    services.GetCategorizerBackendOptions(),
+		AlertWebhookURL:        settings.AlertWebhookURL,
+		FiscalYearStartMonth:   settings.FiscalYearStartMonth,
+		ModelSampleCount:       modelStatus.SampleCount,
+		ModelCategoryCount:     modelStatus.CategoryCount,
+		ModelLastTrained:       "Never",
+	}
+	for month := time.January; month <= time.December; month++ {
+		dto.Months = append(dto.Months, monthOptionDTO{Number: int(month), Name: month.String()})
+	}
+	if !modelStatus.LastTrained.IsZero() {
+		dto.ModelLastTrained = humanize.Time(modelStatus.LastTrained)
+	}
+	if settingsUpdatedMessage != "" {
+		dto.SettingsUpdated = true
+		dto.SettingsUpdatedMessage = settingsUpdatedMessage
 	}
+
 	tmpl := template.Must(template.New("settingsPage").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(settingsPageTmpl))
 	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
@@ -48,11 +93,14 @@ func (sc *SettingsController) upsertSettings(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	settings, err := sc.SettingsRepository.GetSettings()
+	liveSettings, err := sc.SettingsRepository.GetSettings()
 	if err != nil {
 		http.Error(w, "Unable to retrieve settings", http.StatusInternalServerError)
 		return
 	}
+	// GetSettings returns the settings the rest of Sage is using, so change a copy and only put it in place once
+	// every field is valid and it's saved
+	settings := *liveSettings
 
 	// Extract the settings from the form
 	launchBrowserOnStartupInput, err := strconv.ParseBool(req.FormValue("launchBrowserOnStartup"))
@@ -61,19 +109,72 @@ func (sc *SettingsController) upsertSettings(w http.ResponseWriter, req *http.Re
 		return
 	}
 	settings.LaunchBrowserOnStartup = launchBrowserOnStartupInput
-	err = sc.SettingsRepository.Save(settings)
+
+	alertWebhookURLInput := strings.TrimSpace(req.FormValue("alertWebhookURL"))
+	if alertWebhookURLInput != "" {
+		parsedURL, err := url.ParseRequestURI(alertWebhookURLInput)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			http.Error(w, "Invalid value for alertWebhookURL, it must be an http or https URL", http.StatusBadRequest)
+			return
+		}
+	}
+	settings.AlertWebhookURL = alertWebhookURLInput
+
+	if fiscalYearStartMonthInput := req.FormValue("fiscalYearStartMonth"); fiscalYearStartMonthInput != "" {
+		fiscalYearStartMonth, err := strconv.Atoi(fiscalYearStartMonthInput)
+		if err != nil || fiscalYearStartMonth < 1 || fiscalYearStartMonth > 12 {
+			http.Error(w, "Invalid value for fiscalYearStartMonth, it must be a month from 1 to 12", http.StatusBadRequest)
+			return
+		}
+		settings.FiscalYearStartMonth = fiscalYearStartMonth
+	}
+
+	previousCategorizerBackend := sc.Categorizer.GetModelStatus().Backend
+	categorizerBackendInput := req.FormValue("categorizerBackend")
+	switchingBackend := categorizerBackendInput != "" && categorizerBackendInput != previousCategorizerBackend
+	if switchingBackend {
+		if _, err := services.NewClassifier(categorizerBackendInput); err != nil {
+			http.Error(w, "Invalid value for categorizerBackend", http.StatusBadRequest)
+			return
+		}
+		settings.CategorizerBackend = categorizerBackendInput
+	}
+
+	err = sc.SettingsRepository.Save(&settings)
 	if err != nil {
 		http.Error(w, "Error occurred while savings settings", http.StatusInternalServerError)
 		return
 	}
+	*liveSettings = settings
 
-	dto := SettingsPageDTO{
-		ActivePage:             "settings",
-		LaunchBrowserOnStartup: settings.LaunchBrowserOnStartup,
-		SettingsUpdated:        true,
-		SettingsUpdatedMessage: "Settings saved successfully!",
+	// Switching backends retrains and saves the model, so only do it once the setting is saved. If retraining
+	// fails, put the setting back so it still matches the model.
+	if switchingBackend {
+		err = sc.Categorizer.SetBackend(categorizerBackendInput)
+		if err != nil {
+			fmt.Println("Error switching categorizer backend: ", err)
+			liveSettings.CategorizerBackend = previousCategorizerBackend
+			if err := sc.SettingsRepository.Save(liveSettings); err != nil {
+				fmt.Println("Error restoring categorizer backend setting: ", err)
+			}
+			if err := sc.Categorizer.SetBackend(previousCategorizerBackend); err != nil {
+				fmt.Println("Error restoring categorizer backend: ", err)
+			}
+			http.Error(w, "Unable to switch categorizer backend", http.StatusInternalServerError)
+			return
+		}
 	}
-	tmpl := template.Must(template.New("settingsPage").Parse(pageComponents))
-	tmpl = template.Must(tmpl.Parse(settingsPageTmpl))
-	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+
+	sc.generateSettingsViewContent(w, "Settings saved successfully!")
+}
+
+// rebuildModel retrains the categorization model from scratch using every transaction flagged for training
+func (sc *SettingsController) rebuildModel(w http.ResponseWriter, req *http.Request) {
+	err := sc.Categorizer.BuildModel()
+	if err != nil {
+		http.Error(w, "Error occurred while rebuilding the categorization model", http.StatusInternalServerError)
+		return
+	}
+
+	sc.generateSettingsViewContent(w, "Categorization model rebuilt successfully!")
 }
@@ -5,69 +5,56 @@ import (
 	"fmt"
 	"net/http"
 	"text/template"
-	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
 type SpendingController struct {
+	CategoryRepository    *models.CategoryRepository
 	TransactionRepository *models.TransactionRepository
+	DateRangeService      *services.DateRangeService
 }
 
 //go:embed spendingbycategory.html
 var spendingByCategoryTmpl string
 
+//go:embed spendingbytag.html
+var spendingByTagTmpl string
+
 type spendingByCategory struct {
 	Category        string
 	Amount          string
 	AmountHumanized string
+	// Depth in the category tree, where 0 is a top level category. Amounts include subcategories.
+	Depth int
+}
+
+type spendingByMerchant struct {
+	Merchant        string
+	AmountHumanized string
 }
 
 type SpendingByCategoryDTO struct {
-	ActivePage           string
-	AllTimeActive        bool
-	Last12MonthsActive   bool
-	Last6MonthsActive    bool
-	Last3MonthsActive    bool
+	ActivePage string
+	DateRangeDTO
+	// Top level categories only, so subcategories aren't counted twice in the chart
+	TopLevelSpending     []spendingByCategory
 	SpendingByCategories []spendingByCategory
+	TopMerchants         []spendingByMerchant
 }
 
 func (sc *SpendingController) spendingByCategoryHandler(w http.ResponseWriter, req *http.Request) {
-	// Get time frame
-	var relativeWindow int
 	dto := SpendingByCategoryDTO{
 		ActivePage: "spendingByCategory",
 	}
-	if req.FormValue("relativeWindow") == "" {
-		relativeWindow = 6
-		dto.Last6MonthsActive = true
-	} else {
-		switch req.FormValue("relativeWindow") {
-		case "12":
-			relativeWindow = 12
-			dto.Last12MonthsActive = true
-		case "6":
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		case "3":
-			relativeWindow = 3
-			dto.Last3MonthsActive = true
-		case "allTime":
-			// 10 years in months, as a useful approximation of all time for now
-			relativeWindow = 120
-			dto.AllTimeActive = true
-		default:
-			fmt.Println("invalid relative window provided, falling back to 6 months")
-			relativeWindow = 6
-			dto.Last6MonthsActive = true
-		}
+	dateRange, err := parseDateRange(req, sc.DateRangeService, "/spending-by-category", services.DateRangeLast6Months, &dto.DateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	// We always start with today's date and work backwards based on relative window value
-	endDate := time.Now()
-	// And calculate start date
-	startDate := endDate.AddDate(0, (relativeWindow * -1), 0)
+	startDate, endDate := dateRange.StartDate, dateRange.EndDate
 
 	// Get sum of all transactions in the time frame, grouped by category
 	totalsByCategory, err := sc.TransactionRepository.GetSumOfTransactionsByCategory(startDate, endDate)
@@ -76,11 +63,49 @@ func (sc *SpendingController) spendingByCategoryHandler(w http.ResponseWriter, r
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 
-	// Construct DTO
+	categories, err := sc.CategoryRepository.GetAllCategories()
+	if err != nil {
+		fmt.Println("Error getting categories: ", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Roll subcategories up into their parents
+	totalsByCategoryID := map[uint]int{}
 	for _, total := range totalsByCategory {
-		dto.SpendingByCategories = append(dto.SpendingByCategories, spendingByCategory{
-			Category:        total.Category,
-			Amount:          utils.CentsToDollarStringMachineSafe(total.Amount),
+		totalsByCategoryID[total.CategoryID] = total.Amount
+	}
+	categoryTree := services.BuildCategoryTree(categories)
+	services.RollUpCategoryTotals(categoryTree, totalsByCategoryID)
+	services.SortCategoryTreeByTotal(categoryTree)
+
+	// Construct DTO
+	for _, node := range services.FlattenCategoryTree(categoryTree) {
+		// Skip categories without spending, which includes income, transfer and savings categories
+		if node.Total == 0 {
+			continue
+		}
+		spending := spendingByCategory{
+			Category:        node.Category.Name,
+			Amount:          utils.CentsToDollarStringMachineSafe(node.Total),
+			AmountHumanized: utils.CentsToDollarStringHumanized(node.Total),
+			Depth:           node.Depth,
+		}
+		dto.SpendingByCategories = append(dto.SpendingByCategories, spending)
+		if node.Depth == 0 {
+			dto.TopLevelSpending = append(dto.TopLevelSpending, spending)
+		}
+	}
+
+	totalsByMerchant, err := sc.TransactionRepository.GetSumOfTransactionsByMerchant(startDate, endDate, 10)
+	if err != nil {
+		fmt.Println("Error getting sum of transactions by merchant: ", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, total := range totalsByMerchant {
+		dto.TopMerchants = append(dto.TopMerchants, spendingByMerchant{
+			Merchant:        total.Merchant,
 			AmountHumanized: utils.CentsToDollarStringHumanized(total.Amount),
 		})
 	}
@@ -93,3 +118,49 @@ func (sc *SpendingController) spendingByCategoryHandler(w http.ResponseWriter, r
 		panic(err)
 	}
 }
+
+type spendingByTag struct {
+	TagID           uint
+	Tag             string
+	Amount          string
+	AmountHumanized string
+}
+
+type SpendingByTagDTO struct {
+	ActivePage string
+	DateRangeDTO
+	SpendingByTags []spendingByTag
+}
+
+func (sc *SpendingController) spendingByTagHandler(w http.ResponseWriter, req *http.Request) {
+	dto := SpendingByTagDTO{
+		ActivePage: "spendingByTag",
+	}
+	dateRange, err := parseDateRange(req, sc.DateRangeService, "/spending-by-tag", services.DateRangeLast6Months, &dto.DateRangeDTO)
+	if err != nil {
+		http.Error(w, "Unable to parse date range: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	totalsByTag, err := sc.TransactionRepository.GetSumOfTransactionsByTag(dateRange.StartDate, dateRange.EndDate)
+	if err != nil {
+		fmt.Println("Error getting sum of transactions by tag: ", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, total := range totalsByTag {
+		dto.SpendingByTags = append(dto.SpendingByTags, spendingByTag{
+			TagID:           total.TagID,
+			Tag:             total.Tag,
+			Amount:          utils.CentsToDollarStringMachineSafe(total.Amount),
+			AmountHumanized: utils.CentsToDollarStringHumanized(total.Amount),
+		})
+	}
+
+	tmpl := template.Must(template.New("spendingByTag").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(spendingByTagTmpl))
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
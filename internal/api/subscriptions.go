@@ -0,0 +1,83 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type SubscriptionController struct {
+	RecurringChargeDetector *services.RecurringChargeDetector
+}
+
+//go:embed subscriptions.html
+var subscriptionsTmpl string
+
+type RecurringChargeDTO struct {
+	Merchant         string
+	CategoryName     string
+	Cadence          string
+	Occurrences      int
+	LastDate         string
+	NextExpectedDate string
+	AverageAmount    string
+	AnnualizedCost   string
+	PriceChanged     bool
+	PreviousAmount   string
+	LatestAmount     string
+	Missed           bool
+	DuplicateDates   []string
+}
+
+type SubscriptionsPageDTO struct {
+	ActivePage       string
+	RecurringCharges []RecurringChargeDTO
+	// Sum of the annualized cost of every recurring charge, and the same spread over a month
+	TotalAnnualCost  string
+	TotalMonthlyCost string
+}
+
+func (sc *SubscriptionController) generateSubscriptionsView(w http.ResponseWriter, req *http.Request) {
+	charges, err := sc.RecurringChargeDetector.GetRecurringCharges()
+	if err != nil {
+		fmt.Println("Error detecting recurring charges: ", err)
+		http.Error(w, "Unable to find recurring charges", http.StatusInternalServerError)
+		return
+	}
+
+	dto := SubscriptionsPageDTO{
+		ActivePage: "subscriptions",
+	}
+	totalAnnualCost := 0
+	for _, charge := range charges {
+		totalAnnualCost += charge.AnnualizedCost
+		dto.RecurringCharges = append(dto.RecurringCharges, RecurringChargeDTO{
+			Merchant:         charge.Merchant,
+			CategoryName:     charge.CategoryName,
+			Cadence:          charge.Cadence.Name,
+			Occurrences:      len(charge.Transactions),
+			LastDate:         utils.TimeToISO8601DateString(charge.LastDate),
+			NextExpectedDate: utils.TimeToISO8601DateString(charge.NextExpectedDate),
+			AverageAmount:    utils.CentsToDollarStringHumanized(charge.AverageAmount),
+			AnnualizedCost:   utils.CentsToDollarStringHumanized(charge.AnnualizedCost),
+			PriceChanged:     charge.PriceChanged,
+			PreviousAmount:   utils.CentsToDollarStringHumanized(charge.PreviousAmount),
+			LatestAmount:     utils.CentsToDollarStringHumanized(charge.LatestAmount),
+			Missed:           charge.Missed,
+			DuplicateDates:   charge.DuplicateDates,
+		})
+	}
+	dto.TotalAnnualCost = utils.CentsToDollarStringHumanized(totalAnnualCost)
+	dto.TotalMonthlyCost = utils.CentsToDollarStringHumanized(totalAnnualCost / 12)
+
+	tmpl := template.Must(template.New("subscriptions").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(subscriptionsTmpl))
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// maxAttachmentSize is the largest file that can be attached to a transaction, 10 MB
+const maxAttachmentSize = 10 << 20
+
+type AttachmentDTO struct {
+	ID          uint
+	FileName    string
+	ContentType string
+	Size        string // e.g. "1.2 MB"
+}
+
+func attachmentDTOs(attachments []models.Attachment) []AttachmentDTO {
+	dtos := []AttachmentDTO{}
+	for _, attachment := range attachments {
+		dtos = append(dtos, AttachmentDTO{
+			ID:          attachment.ID,
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Size:        humanizedFileSize(attachment.Size),
+		})
+	}
+	return dtos
+}
+
+func humanizedFileSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
+// uploadAttachment attaches an image or PDF, like a receipt, to a transaction
+func (tc *TransactionController) uploadAttachment(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxAttachmentSize+1<<20)
+	err := req.ParseMultipartForm(maxAttachmentSize)
+	if err != nil {
+		http.Error(w, "Unable to parse form, attachments can be up to 10 MB", http.StatusBadRequest)
+		return
+	}
+	transactionID, err := utils.StringToUint(req.FormValue("transactionID"))
+	if err != nil {
+		http.Error(w, "Unable to parse transactionID", http.StatusBadRequest)
+		return
+	}
+	transaction, err := tc.TransactionRepository.GetTransactionByID(transactionID)
+	if err != nil {
+		fmt.Println("Error getting transaction: ", err)
+		http.Error(w, "Unable to get transaction", http.StatusInternalServerError)
+		return
+	}
+	if transaction.ID == 0 {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	file, header, err := req.FormFile("attachment")
+	if err != nil {
+		tc.renderTransactionForm(w, TransactionFormDTO{TransactionID: transactionID, AttachmentErrorMessage: "Choose a file to attach"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Unable to read attachment", http.StatusBadRequest)
+		return
+	}
+	contentType, errorMessage := validateAttachment(data)
+	if errorMessage != "" {
+		tc.renderTransactionForm(w, TransactionFormDTO{TransactionID: transactionID, AttachmentErrorMessage: errorMessage})
+		return
+	}
+
+	_, err = tc.AttachmentRepository.Create(models.Attachment{
+		TransactionID: transactionID,
+		FileName:      attachmentFileName(header.Filename),
+		ContentType:   contentType,
+		Size:          len(data),
+		Data:          data,
+	})
+	if err != nil {
+		fmt.Println("Error saving attachment: ", err)
+		http.Error(w, "Unable to save attachment", http.StatusInternalServerError)
+		return
+	}
+
+	tc.renderTransactionForm(w, TransactionFormDTO{TransactionID: transactionID})
+}
+
+// validateAttachment returns the content type of an attachment's contents, or a message saying why it can't be
+// attached
+func validateAttachment(data []byte) (contentType string, errorMessage string) {
+	if len(data) > maxAttachmentSize {
+		return "", "Attachments can be up to 10 MB"
+	}
+	// Go by the file's contents rather than the name or the browser's content type
+	contentType = http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/pdf" {
+		return "", "Only images and PDFs can be attached"
+	}
+	return contentType, ""
+}
+
+// attachmentFileName strips any directories from an uploaded file's name, including Windows ones, since some
+// browsers send the full path
+func attachmentFileName(fileName string) string {
+	fileName = filepath.Base(strings.ReplaceAll(fileName, `\`, "/"))
+	if fileName == "." || fileName == "/" {
+		return "attachment"
+	}
+	return fileName
+}
+
+// downloadAttachment serves an attachment so it can be viewed in the browser or saved
+func (tc *TransactionController) downloadAttachment(w http.ResponseWriter, req *http.Request) {
+	attachmentID, err := utils.StringToUint(req.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Unable to parse attachment ID", http.StatusBadRequest)
+		return
+	}
+	attachment, err := tc.AttachmentRepository.GetAttachmentByID(attachmentID)
+	if err != nil {
+		http.Error(w, "Unable to get attachment", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(attachment.Data)
+}
+
+func (tc *TransactionController) deleteAttachment(w http.ResponseWriter, req *http.Request) {
+	attachmentID, err := utils.StringToUint(req.FormValue("attachmentID"))
+	if err != nil {
+		http.Error(w, "Unable to parse attachment ID", http.StatusBadRequest)
+		return
+	}
+	attachment, err := tc.AttachmentRepository.GetAttachmentByID(attachmentID)
+	if err != nil {
+		http.Error(w, "Unable to get attachment", http.StatusBadRequest)
+		return
+	}
+	err = tc.AttachmentRepository.DeleteAttachmentByID(attachmentID)
+	if err != nil {
+		http.Error(w, "Unable to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	tc.renderTransactionForm(w, TransactionFormDTO{TransactionID: attachment.TransactionID})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValidateAttachment(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name                string
+		data                []byte
+		expectedContentType string
+		expectedError       string
+	}{
+		{"image", png, "image/png", ""},
+		{"PDF", []byte("%PDF-1.7\n"), "application/pdf", ""},
+		{"HTML named like an image", []byte("<html><script>alert(1)</script></html>"), "", "Only images and PDFs can be attached"},
+		{"plain text", []byte("just some notes"), "", "Only images and PDFs can be attached"},
+		{"largest allowed", append(png, bytes.Repeat([]byte{0}, maxAttachmentSize-len(png))...), "image/png", ""},
+		{"too large", append(png, bytes.Repeat([]byte{0}, maxAttachmentSize)...), "", "Attachments can be up to 10 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, errorMessage := validateAttachment(tt.data)
+			if contentType != tt.expectedContentType || errorMessage != tt.expectedError {
+				t.Errorf("Expected %q and %q, got %q and %q", tt.expectedContentType, tt.expectedError, contentType, errorMessage)
+			}
+		})
+	}
+}
+
+func TestAttachmentFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected string
+	}{
+		{"receipt.pdf", "receipt.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\Documents\receipt.png`, "receipt.png"},
+		{"<img src=x onerror=alert(1)>.png", "<img src=x onerror=alert(1)>.png"},
+		{"", "attachment"},
+	}
+
+	for _, tt := range tests {
+		if got := attachmentFileName(tt.fileName); got != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.fileName, got)
+		}
+	}
+}
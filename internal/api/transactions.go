@@ -5,17 +5,24 @@ import (
 	_ "embed"
 	"fmt"
 	"net/http"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
 	"github.com/alexdglover/sage/internal/utils"
 )
 
 type TransactionController struct {
-	AccountRepository     *models.AccountRepository
-	CategoryRepository    *models.CategoryRepository
-	TransactionRepository *models.TransactionRepository
+	AccountRepository       *models.AccountRepository
+	AttachmentRepository    *models.AttachmentRepository
+	CategoryRepository      *models.CategoryRepository
+	Categorizer             *services.MLCategorizer
+	MerchantNormalizer      *services.MerchantNormalizer
+	TagRepository           *models.TagRepository
+	TransactionRepository   *models.TransactionRepository
+	TransactionSplitService *services.TransactionSplitService
 }
 
 //go:embed transactions.html
@@ -28,11 +35,15 @@ type TransactionDTO struct {
 	ID                 uint
 	Date               string
 	Description        string
+	MerchantName       string
+	Notes              string
 	Amount             string
 	Excluded           bool
 	AccountName        string
 	CategoryName       string
 	ImportSubmissionID string
+	Splits             []TransactionSplitDTO
+	Tags               []models.Tag
 }
 
 type TransactionsPageDto struct {
@@ -44,6 +55,8 @@ type TransactionsPageDto struct {
 	SelectedAccountID         uint
 	Categories                []models.Category
 	SelectedCategoryID        uint
+	Tags                      []models.Tag
+	SelectedTagID             uint
 	Description               string
 	StartDate                 string
 	EndDate                   string
@@ -54,6 +67,7 @@ type TransactionFormDTO struct {
 	// If we're editing an existing transaction, Editing will be true
 	// If we're creating a new transaction, Editing will be false
 	Editing            bool
+	IsSplit            bool
 	TransactionID      uint
 	Date               string
 	Description        string
@@ -62,8 +76,23 @@ type TransactionFormDTO struct {
 	AccountName        string
 	CategoryName       string
 	ImportSubmissionID string
-	Accounts           []models.Account
-	Categories         []models.Category
+	Notes              string
+	// Comma separated, e.g. "Hawaii trip 2026, reimbursable"
+	Tags        string
+	Attachments []AttachmentDTO
+	Accounts    []models.Account
+	Categories  []models.Category
+	// Existing tags, suggested while typing
+	AllTags []models.Tag
+	// Only transactions in investment accounts have an investment activity
+	IsInvestment         bool
+	InvestmentActivity   string
+	InvestmentActivities []struct {
+		Activity string
+		Label    string
+	}
+	// Set when an attachment couldn't be uploaded
+	AttachmentErrorMessage string
 }
 
 func (tc *TransactionController) generateTransactionsView(w http.ResponseWriter, req *http.Request) {
@@ -77,7 +106,7 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 	}
 
 	// Parse the query parameters
-	var accountID, categoryID uint
+	var accountID, categoryID, tagID uint
 	var descriptionQueryParameter string
 	var startDate, endDate *time.Time
 	var err error
@@ -85,6 +114,7 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 		query := req.URL.Query()
 		accountIDQueryParameter := query.Get("accountID")
 		categoryIDQueryParameter := query.Get("categoryID")
+		tagIDQueryParameter := query.Get("tagID")
 		descriptionQueryParameter = query.Get("description")
 		startDateQueryParameter := query.Get("startDate")
 		endDateQueryParameter := query.Get("endDate")
@@ -107,6 +137,15 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 			dto.SelectedCategoryID = categoryID
 		}
 
+		if tagIDQueryParameter != "" {
+			tagID, err = utils.StringToUint(tagIDQueryParameter)
+			if err != nil {
+				http.Error(w, "Unable to parse tag ID", http.StatusInternalServerError)
+				return
+			}
+			dto.SelectedTagID = tagID
+		}
+
 		dto.Description = descriptionQueryParameter
 
 		if startDateQueryParameter != "" {
@@ -129,7 +168,7 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 	}
 
 	// Get all Transactions with filters
-	transactions, err := tc.TransactionRepository.GetAllTransactions(accountID, categoryID, descriptionQueryParameter, startDate, endDate)
+	transactions, err := tc.TransactionRepository.GetAllTransactions(accountID, categoryID, tagID, descriptionQueryParameter, startDate, endDate)
 	if err != nil {
 		http.Error(w, "Unable to get transactions", http.StatusInternalServerError)
 		return
@@ -137,15 +176,27 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 
 	// Build Transactions DTO
 	for _, txn := range transactions {
+		splits := []TransactionSplitDTO{}
+		for _, split := range txn.Splits {
+			splits = append(splits, TransactionSplitDTO{
+				CategoryName: split.Category.Name,
+				Amount:       utils.CentsToDollarStringHumanized(split.Amount),
+				Note:         split.Note,
+			})
+		}
 		transactionsDTO = append(transactionsDTO, TransactionDTO{
 			ID:                 txn.ID,
 			Date:               txn.Date,
 			Description:        txn.Description,
+			MerchantName:       txn.MerchantName,
+			Notes:              txn.Notes,
 			Amount:             utils.CentsToDollarStringHumanized(txn.Amount),
 			Excluded:           txn.Excluded,
 			AccountName:        txn.Account.Name,
 			CategoryName:       txn.Category.Name,
 			ImportSubmissionID: utils.UintPointerToString(txn.ImportSubmissionID),
+			Splits:             splits,
+			Tags:               txn.Tags,
 		})
 	}
 	dto.Transactions = transactionsDTO
@@ -169,6 +220,13 @@ func (tc *TransactionController) generateTransactionsViewContent(w http.Response
 		return
 	}
 	dto.Categories = categories
+	// Get all tags
+	tags, err := tc.TagRepository.GetAllTags()
+	if err != nil {
+		http.Error(w, "Unable to get tags", http.StatusInternalServerError)
+		return
+	}
+	dto.Tags = tags
 
 	tmpl := template.Must(template.New("TransactionsPage").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Funcs(template.FuncMap{
@@ -192,24 +250,48 @@ func (tc *TransactionController) generateTransactionForm(w http.ResponseWriter,
 			http.Error(w, "Unable to parse transaction ID", http.StatusInternalServerError)
 			return
 		}
-		txn, err := tc.TransactionRepository.GetTransactionByID(txnID)
+		dto.TransactionID = txnID
+	}
+
+	tc.renderTransactionForm(w, dto)
+}
+
+// renderTransactionForm shows the form for adding a transaction, or for editing the transaction with
+// dto.TransactionID if it's set
+func (tc *TransactionController) renderTransactionForm(w http.ResponseWriter, dto TransactionFormDTO) {
+	var accountID uint
+	if dto.TransactionID != 0 {
+		txn, err := tc.TransactionRepository.GetTransactionByID(dto.TransactionID)
 		if err != nil {
 			http.Error(w, "Unable to get Transaction", http.StatusInternalServerError)
 			return
 		}
+		attachments, err := tc.AttachmentRepository.GetAttachmentsForTransaction(txn.ID)
+		if err != nil {
+			http.Error(w, "Unable to get attachments", http.StatusInternalServerError)
+			return
+		}
 
 		dto = TransactionFormDTO{
-			ActivePage:         "transactions",
-			Editing:            true,
-			TransactionID:      txn.ID,
-			Date:               txn.Date,
-			Description:        txn.Description,
-			Amount:             utils.CentsToDollarStringHumanized(txn.Amount),
-			Excluded:           txn.Excluded,
-			AccountName:        txn.Account.Name,
-			CategoryName:       txn.Category.Name,
-			ImportSubmissionID: utils.UintPointerToString(txn.ImportSubmissionID),
+			ActivePage:             "transactions",
+			Editing:                true,
+			IsSplit:                len(txn.Splits) > 0,
+			TransactionID:          txn.ID,
+			Date:                   txn.Date,
+			Description:            txn.Description,
+			Amount:                 utils.CentsToDollarStringHumanized(txn.Amount),
+			Excluded:               txn.Excluded,
+			AccountName:            txn.Account.Name,
+			CategoryName:           txn.Category.Name,
+			ImportSubmissionID:     utils.UintPointerToString(txn.ImportSubmissionID),
+			Notes:                  txn.Notes,
+			Tags:                   tagNames(txn.Tags),
+			Attachments:            attachmentDTOs(attachments),
+			AttachmentErrorMessage: dto.AttachmentErrorMessage,
+			InvestmentActivity:     txn.InvestmentActivity,
+			InvestmentActivities:   models.InvestmentActivities,
 		}
+		accountID = txn.AccountID
 	}
 
 	accounts, err := tc.AccountRepository.GetAllAccounts()
@@ -217,13 +299,27 @@ func (tc *TransactionController) generateTransactionForm(w http.ResponseWriter,
 		http.Error(w, "Unable to get accounts", http.StatusInternalServerError)
 	}
 	dto.Accounts = accounts
+	// The transaction's account doesn't come with its type, so look for it among all the accounts
+	for _, account := range accounts {
+		if accountID != 0 && account.ID == accountID {
+			dto.IsInvestment = account.AccountType.AccountCategory == services.InvestmentAccountCategory
+		}
+	}
 
 	categories, err := tc.CategoryRepository.GetAllCategories()
 	if err != nil {
 		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
 	}
 	dto.Categories = categories
 
+	allTags, err := tc.TagRepository.GetAllTags()
+	if err != nil {
+		http.Error(w, "Unable to get tags", http.StatusInternalServerError)
+		return
+	}
+	dto.AllTags = allTags
+
 	tmpl := template.Must(template.New("transactionForm").Parse(pageComponents))
 	tmpl = template.Must(tmpl.Parse(transactionFormTmpl))
 
@@ -279,6 +375,9 @@ func (tc *TransactionController) upsertTransaction(w http.ResponseWriter, req *h
 	} else {
 		transaction = models.Transaction{}
 	}
+	// Keep a copy of the transaction as it was before this update so the categorizer
+	// can replace what it learned from it
+	previousTransaction := transaction
 
 	category, err := tc.CategoryRepository.GetCategoryByID(categoryID)
 	if err != nil {
@@ -286,8 +385,26 @@ func (tc *TransactionController) upsertTransaction(w http.ResponseWriter, req *h
 		return
 	}
 
+	// The splits of a split transaction have to add up to its amount
+	if len(transaction.Splits) > 0 && utils.DollarStringToCents(amount) != transaction.Amount {
+		http.Error(w, "This transaction is split, remove the split before changing its amount", http.StatusBadRequest)
+		return
+	}
+
+	// The investment activity is only on the form for transactions in investment accounts
+	if req.Form.Has("investmentActivity") {
+		investmentActivity := req.FormValue("investmentActivity")
+		if investmentActivity != "" && !models.IsValidInvestmentActivity(investmentActivity) {
+			http.Error(w, "Invalid investment activity", http.StatusBadRequest)
+			return
+		}
+		transaction.InvestmentActivity = investmentActivity
+	}
+
 	transaction.Date = date
 	transaction.Description = description
+	transaction.MerchantName = tc.MerchantNormalizer.NormalizeMerchantName(description)
+	transaction.Notes = strings.TrimSpace(req.FormValue("notes"))
 	transaction.Amount = utils.DollarStringToCents(amount)
 	transaction.Excluded = excluded
 	transaction.AccountID = accountID
@@ -295,12 +412,29 @@ func (tc *TransactionController) upsertTransaction(w http.ResponseWriter, req *h
 	transaction.Category = category
 	transaction.UseForTraining = true
 
-	_, err = tc.TransactionRepository.Save(transaction)
+	transaction.ID, err = tc.TransactionRepository.Save(transaction)
 	if err != nil {
 		http.Error(w, "Unable to save transaction", http.StatusBadRequest)
 		return
 	}
 
+	tags, err := tc.TagRepository.GetOrCreateTags(parseTagNames(req.FormValue("tags")))
+	if err != nil {
+		http.Error(w, "Unable to save tags", http.StatusInternalServerError)
+		return
+	}
+	err = tc.TagRepository.ReplaceTransactionTags(transaction.ID, tags)
+	if err != nil {
+		http.Error(w, "Unable to save tags", http.StatusInternalServerError)
+		return
+	}
+
+	// The transaction is saved at this point, so a failure to update the model shouldn't fail the request
+	err = tc.Categorizer.LearnTransaction(previousTransaction, transaction)
+	if err != nil {
+		fmt.Println("Error updating categorization model: ", err)
+	}
+
 	tc.generateTransactionsViewContent(w, nil, "Transaction saved successfully")
 }
 
@@ -312,6 +446,12 @@ func (tc *TransactionController) deleteTransaction(w http.ResponseWriter, req *h
 		return
 	}
 
+	transaction, err := tc.TransactionRepository.GetTransactionByID(transactionID)
+	if err != nil {
+		http.Error(w, "Unable to get transaction", http.StatusInternalServerError)
+		return
+	}
+
 	err = tc.TransactionRepository.DeleteTransactionByID(context.TODO(), transactionID)
 
 	if err != nil {
@@ -319,5 +459,33 @@ func (tc *TransactionController) deleteTransaction(w http.ResponseWriter, req *h
 		return
 	}
 
+	err = tc.Categorizer.ForgetTransaction(transaction)
+	if err != nil {
+		fmt.Println("Error updating categorization model: ", err)
+	}
+
 	tc.generateTransactionsViewContent(w, nil, fmt.Sprintf("Transaction %v deleted successfully", transactionID))
 }
+
+// parseTagNames splits a comma separated list of tags, dropping blanks and duplicates
+func parseTagNames(input string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(input, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func tagNames(tags []models.Tag) string {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+func TestParseTagNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"empty", "", []string{}},
+		{"trims names and skips blanks", " Trip ,, Reimbursable , ", []string{"Trip", "Reimbursable"}},
+		{"keeps the first spelling of a duplicate", "Trip, trip, TRIP", []string{"Trip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTagNames(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTagNames(t *testing.T) {
+	if got := tagNames(nil); got != "" {
+		t.Errorf("Expected no names, got %q", got)
+	}
+	tags := []models.Tag{{Name: "Trip"}, {Name: "Reimbursable"}}
+	if got := tagNames(tags); got != "Trip, Reimbursable" {
+		t.Errorf("Expected \"Trip, Reimbursable\", got %q", got)
+	}
+	// The names round trip through the transaction form
+	if got := parseTagNames(tagNames(tags)); !reflect.DeepEqual(got, []string{"Trip", "Reimbursable"}) {
+		t.Errorf("Expected the tag names back, got %v", got)
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+//go:embed transactionSplitForm.html
+var transactionSplitFormTmpl string
+
+type TransactionSplitDTO struct {
+	CategoryID   uint
+	CategoryName string
+	Amount       string
+	Note         string
+}
+
+type TransactionSplitFormDTO struct {
+	ActivePage    string
+	TransactionID uint
+	Date          string
+	Description   string
+	MerchantName  string
+	Amount        string
+	// Amount in dollars without formatting, for totalling the splits as they're edited
+	AmountMachineSafe string
+	IsSplit           bool
+	Splits            []TransactionSplitDTO
+	Categories        []*services.CategoryNode
+	ErrorMessage      string
+}
+
+func (tc *TransactionController) generateTransactionSplitForm(w http.ResponseWriter, req *http.Request) {
+	transactionID, err := utils.StringToUint(req.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Unable to parse transaction ID", http.StatusBadRequest)
+		return
+	}
+	transaction, err := tc.TransactionRepository.GetTransactionByID(transactionID)
+	if err != nil {
+		http.Error(w, "Unable to get Transaction", http.StatusInternalServerError)
+		return
+	}
+
+	splits := []TransactionSplitDTO{}
+	for _, split := range transaction.Splits {
+		splits = append(splits, TransactionSplitDTO{
+			CategoryID:   split.CategoryID,
+			CategoryName: split.Category.Name,
+			Amount:       utils.CentsToDollarStringMachineSafe(split.Amount),
+			Note:         split.Note,
+		})
+	}
+	// Start a new split with the whole amount in the current category and an empty second row
+	if len(splits) == 0 {
+		splits = []TransactionSplitDTO{
+			{CategoryID: transaction.CategoryID, Amount: utils.CentsToDollarStringMachineSafe(transaction.Amount)},
+			{},
+		}
+	}
+
+	tc.renderTransactionSplitForm(w, transaction, splits, "")
+}
+
+func (tc *TransactionController) renderTransactionSplitForm(w http.ResponseWriter, transaction models.Transaction, splits []TransactionSplitDTO, errorMessage string) {
+	categories, err := tc.CategoryRepository.GetAllCategories()
+	if err != nil {
+		http.Error(w, "Unable to get categories", http.StatusInternalServerError)
+		return
+	}
+
+	dto := TransactionSplitFormDTO{
+		ActivePage:        "transactions",
+		TransactionID:     transaction.ID,
+		Date:              transaction.Date,
+		Description:       transaction.Description,
+		MerchantName:      transaction.MerchantName,
+		Amount:            utils.CentsToDollarStringHumanized(transaction.Amount),
+		AmountMachineSafe: utils.CentsToDollarStringMachineSafe(transaction.Amount),
+		IsSplit:           len(transaction.Splits) > 0,
+		Splits:            splits,
+		Categories:        services.FlattenCategoryTree(services.BuildCategoryTree(categories)),
+		ErrorMessage:      errorMessage,
+	}
+
+	tmpl := template.Must(template.New("transactionSplitForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(transactionSplitFormTmpl))
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// saveTransactionSplits replaces a transaction's splits. Each split is submitted as a
+// splitCategoryID, splitAmount and splitNote field, matched up by their order in the form.
+func (tc *TransactionController) saveTransactionSplits(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+	transactionID, err := utils.StringToUint(req.FormValue("transactionID"))
+	if err != nil {
+		http.Error(w, "Unable to parse transactionID", http.StatusBadRequest)
+		return
+	}
+	transaction, err := tc.TransactionRepository.GetTransactionByID(transactionID)
+	if err != nil {
+		http.Error(w, "Unable to get Transaction", http.StatusInternalServerError)
+		return
+	}
+
+	categoryIDs := req.Form["splitCategoryID"]
+	amounts := req.Form["splitAmount"]
+	notes := req.Form["splitNote"]
+	if len(amounts) != len(categoryIDs) || len(notes) != len(categoryIDs) {
+		http.Error(w, "Every split needs a category, amount and note field", http.StatusBadRequest)
+		return
+	}
+
+	splits := []models.TransactionSplit{}
+	splitDTOs := []TransactionSplitDTO{}
+	for i := range categoryIDs {
+		// Skip rows that were left completely empty
+		if strings.TrimSpace(categoryIDs[i]) == "" && strings.TrimSpace(amounts[i]) == "" && strings.TrimSpace(notes[i]) == "" {
+			continue
+		}
+		var categoryID uint
+		if strings.TrimSpace(categoryIDs[i]) != "" {
+			categoryID, err = utils.StringToUint(categoryIDs[i])
+			if err != nil {
+				http.Error(w, "Unable to parse splitCategoryID", http.StatusBadRequest)
+				return
+			}
+		}
+		splits = append(splits, models.TransactionSplit{
+			CategoryID: categoryID,
+			Amount:     utils.DollarStringToCents(strings.TrimSpace(amounts[i])),
+			Note:       strings.TrimSpace(notes[i]),
+		})
+		splitDTOs = append(splitDTOs, TransactionSplitDTO{
+			CategoryID: categoryID,
+			Amount:     strings.TrimSpace(amounts[i]),
+			Note:       strings.TrimSpace(notes[i]),
+		})
+	}
+
+	err = tc.TransactionSplitService.SplitTransaction(transactionID, splits)
+	if err != nil {
+		// Show the problem on the form so the splits can be fixed without re-entering them
+		tc.renderTransactionSplitForm(w, transaction, splitDTOs, "Unable to split transaction: "+err.Error())
+		return
+	}
+
+	tc.generateTransactionsViewContent(w, nil, fmt.Sprintf("Transaction split into %v parts", len(splits)))
+}
+
+func (tc *TransactionController) removeTransactionSplits(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	transactionID, err := utils.StringToUint(req.FormValue("transactionID"))
+	if err != nil {
+		http.Error(w, "Unable to parse transactionID", http.StatusBadRequest)
+		return
+	}
+	err = tc.TransactionSplitService.RemoveSplits(transactionID)
+	if err != nil {
+		http.Error(w, "Unable to remove split", http.StatusInternalServerError)
+		return
+	}
+
+	tc.generateTransactionsViewContent(w, nil, "Transaction is no longer split")
+}
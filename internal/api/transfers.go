@@ -0,0 +1,133 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/services"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+type TransferController struct {
+	TransferMatcher        *services.TransferMatcher
+	TransferPairRepository *models.TransferPairRepository
+}
+
+//go:embed transfers.html
+var transfersTmpl string
+
+type TransferSideDTO struct {
+	TransactionID uint
+	Date          string
+	AccountName   string
+	Description   string
+	Amount        string
+}
+
+type TransferPairDTO struct {
+	ID        uint
+	Suggested bool
+	From      TransferSideDTO
+	To        TransferSideDTO
+}
+
+type TransfersPageDTO struct {
+	ActivePage     string
+	SuggestedPairs []TransferPairDTO
+	ConfirmedPairs []TransferPairDTO
+	Message        string
+	ErrorMessage   string
+}
+
+func (tc *TransferController) generateTransfersView(w http.ResponseWriter, req *http.Request) {
+	tc.generateTransfersViewContent(w, TransfersPageDTO{})
+}
+
+func (tc *TransferController) generateTransfersViewContent(w http.ResponseWriter, dto TransfersPageDTO) {
+	dto.ActivePage = "transfers"
+
+	pairs, err := tc.TransferPairRepository.GetAllTransferPairs()
+	if err != nil {
+		http.Error(w, "Unable to get transfers", http.StatusInternalServerError)
+		return
+	}
+	for _, pair := range pairs {
+		pairDTO := TransferPairDTO{
+			ID:        pair.ID,
+			Suggested: pair.Status == models.TransferPairSuggested,
+			From:      transferSideDTO(pair.Transaction),
+			To:        transferSideDTO(pair.MatchedTransaction),
+		}
+		if pairDTO.Suggested {
+			dto.SuggestedPairs = append(dto.SuggestedPairs, pairDTO)
+		} else {
+			dto.ConfirmedPairs = append(dto.ConfirmedPairs, pairDTO)
+		}
+	}
+
+	tmpl := template.Must(template.New("transfers").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(transfersTmpl))
+	err = utils.RenderTemplateAsHTML(w, tmpl, dto)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func transferSideDTO(txn models.Transaction) TransferSideDTO {
+	description := txn.MerchantName
+	if description == "" {
+		description = txn.Description
+	}
+	return TransferSideDTO{
+		TransactionID: txn.ID,
+		Date:          txn.Date,
+		AccountName:   txn.Account.Name,
+		Description:   description,
+		Amount:        utils.CentsToDollarStringHumanized(txn.Amount),
+	}
+}
+
+// matchTransfers looks for new transfers across all accounts
+func (tc *TransferController) matchTransfers(w http.ResponseWriter, req *http.Request) {
+	matched, err := tc.TransferMatcher.MatchTransfers()
+	if err != nil {
+		fmt.Println("Error matching transfers: ", err)
+		tc.generateTransfersViewContent(w, TransfersPageDTO{ErrorMessage: fmt.Sprintf("Unable to match transfers: %v", err)})
+		return
+	}
+	tc.generateTransfersViewContent(w, TransfersPageDTO{Message: fmt.Sprintf("Found %v new transfers", matched)})
+}
+
+func (tc *TransferController) confirmTransferPair(w http.ResponseWriter, req *http.Request) {
+	transferPairID, err := utils.StringToUint(req.FormValue("transferPairID"))
+	if err != nil {
+		http.Error(w, "Unable to parse transfer ID", http.StatusBadRequest)
+		return
+	}
+	err = tc.TransferMatcher.ConfirmTransferPair(transferPairID)
+	if err != nil {
+		fmt.Println("Error confirming transfer: ", err)
+		http.Error(w, "Unable to confirm transfer", http.StatusInternalServerError)
+		return
+	}
+	tc.generateTransfersViewContent(w, TransfersPageDTO{Message: "Transfer confirmed"})
+}
+
+// unlinkTransferPair puts both transactions back in the categories they had before the transfer was confirmed
+func (tc *TransferController) unlinkTransferPair(w http.ResponseWriter, req *http.Request) {
+	transferPairID, err := utils.StringToUint(req.FormValue("transferPairID"))
+	if err != nil {
+		http.Error(w, "Unable to parse transfer ID", http.StatusBadRequest)
+		return
+	}
+	err = tc.TransferPairRepository.UnlinkTransferPair(transferPairID)
+	if err != nil {
+		fmt.Println("Error unlinking transfer: ", err)
+		http.Error(w, "Unable to unlink transfer", http.StatusInternalServerError)
+		return
+	}
+	tc.generateTransfersViewContent(w, TransfersPageDTO{Message: "Transfer unlinked, and both transactions are in their previous categories"})
+}
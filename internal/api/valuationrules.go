@@ -0,0 +1,202 @@
+package api
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+	"gorm.io/gorm"
+)
+
+//go:embed valuationRuleForm.html
+var valuationRuleFormTmpl string
+
+type ValuationRuleFormDTO struct {
+	ActivePage  string
+	AccountID   uint
+	AccountName string
+	// Updating is true if the account already has a valuation rule
+	Updating         bool
+	ValuationMethods []struct {
+		Method string
+		Label  string
+	}
+	Method       string
+	StartDate    string
+	StartValue   string
+	AnnualRate   string
+	EndDate      string
+	EndValue     string
+	ErrorMessage string
+}
+
+// generateValuationRuleForm shows the account's valuation rule, or a blank form if it doesn't have one
+func (bc *BalanceController) generateValuationRuleForm(w http.ResponseWriter, req *http.Request) {
+	accountID, err := utils.StringToUint(req.URL.Query().Get("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	dto := ValuationRuleFormDTO{AccountID: accountID, Method: models.ValuationMethodAppreciation}
+	rule, err := bc.ValuationRuleRepository.GetValuationRuleForAccount(accountID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Println("Error getting valuation rule: ", err)
+		http.Error(w, "Unable to get valuation rule", http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		dto.Updating = true
+		dto.Method = rule.Method
+		dto.StartDate = rule.StartDate
+		dto.StartValue = utils.CentsToDollarStringMachineSafe(rule.StartValue)
+		if rule.AnnualRate != 0 {
+			dto.AnnualRate = strconv.FormatFloat(rule.AnnualRate, 'f', -1, 64)
+		}
+		dto.EndDate = rule.EndDate
+		if rule.EndValue != 0 || rule.EndDate != "" {
+			dto.EndValue = utils.CentsToDollarStringMachineSafe(rule.EndValue)
+		}
+	}
+	bc.renderValuationRuleForm(w, dto)
+}
+
+func (bc *BalanceController) renderValuationRuleForm(w http.ResponseWriter, dto ValuationRuleFormDTO) {
+	dto.ActivePage = "balances"
+	dto.ValuationMethods = models.ValuationMethods
+	account, err := bc.AccountRepository.GetAccountByID(dto.AccountID)
+	if err != nil || account.ID == 0 {
+		http.Error(w, "Unable to get account", http.StatusNotFound)
+		return
+	}
+	dto.AccountName = account.Name
+
+	tmpl := template.Must(template.New("valuationRuleForm").Parse(pageComponents))
+	tmpl = template.Must(tmpl.Parse(valuationRuleFormTmpl))
+	_ = utils.RenderTemplateAsHTML(w, tmpl, dto)
+}
+
+// upsertValuationRule saves the account's valuation rule and replaces its estimated balances with new ones from it
+func (bc *BalanceController) upsertValuationRule(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Unable to Parse Form ", http.StatusBadRequest)
+		return
+	}
+	accountID, err := utils.StringToUint(req.FormValue("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	dto := ValuationRuleFormDTO{
+		AccountID:  accountID,
+		Updating:   req.FormValue("updating") == "true",
+		Method:     req.FormValue("method"),
+		StartDate:  req.FormValue("startDate"),
+		StartValue: cleanDollarString(req.FormValue("startValue")),
+		AnnualRate: strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(req.FormValue("annualRate")), "%")),
+		EndDate:    req.FormValue("endDate"),
+		EndValue:   cleanDollarString(req.FormValue("endValue")),
+	}
+
+	// Show validation problems on the form so the rule can be fixed without retyping it
+	startValue, startValueErr := strconv.ParseFloat(dto.StartValue, 64)
+	annualRate, annualRateErr := strconv.ParseFloat(dto.AnnualRate, 64)
+	endValue, endValueErr := strconv.ParseFloat(dto.EndValue, 64)
+	_, startDateErr := time.Parse("2006-01-02", dto.StartDate)
+	_, endDateErr := time.Parse("2006-01-02", dto.EndDate)
+	// Declining-balance depreciation doesn't need a salvage value
+	if dto.Method == models.ValuationMethodDecliningBalance && dto.EndValue == "" {
+		endValue, endValueErr = 0, nil
+	}
+	usesRate := dto.Method == models.ValuationMethodAppreciation || dto.Method == models.ValuationMethodDecliningBalance
+	usesEndDate := dto.Method == models.ValuationMethodStraightLine || dto.Method == models.ValuationMethodInterpolation
+	switch {
+	case !models.IsValidValuationMethod(dto.Method):
+		dto.ErrorMessage = "Choose how the account's value changes"
+	case startDateErr != nil:
+		dto.ErrorMessage = "The start date must be a real date in YYYY-MM-DD format"
+	case startValueErr != nil || startValue < 0:
+		dto.ErrorMessage = "The starting value must be a dollar amount of at least 0"
+	case usesRate && (annualRateErr != nil || annualRate <= 0 || annualRate >= 100):
+		dto.ErrorMessage = "The annual rate must be a percentage from 0 to 100, e.g. 3.5"
+	case usesEndDate && (endDateErr != nil || dto.EndDate <= dto.StartDate):
+		dto.ErrorMessage = "The end date must be a real date in YYYY-MM-DD format and after the start date"
+	case dto.Method != models.ValuationMethodAppreciation && (endValueErr != nil || endValue < 0):
+		dto.ErrorMessage = "The ending value must be a dollar amount of at least 0"
+	}
+	if dto.ErrorMessage != "" {
+		bc.renderValuationRuleForm(w, dto)
+		return
+	}
+
+	rule, err := bc.ValuationRuleRepository.GetValuationRuleForAccount(accountID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Println("Error getting valuation rule: ", err)
+		http.Error(w, "Unable to get valuation rule", http.StatusInternalServerError)
+		return
+	}
+	// Only keep the fields the method uses
+	rule.AccountID = accountID
+	rule.Method = dto.Method
+	rule.StartDate = dto.StartDate
+	rule.StartValue = utils.DollarStringToCents(dto.StartValue)
+	rule.AnnualRate = 0
+	if usesRate {
+		rule.AnnualRate = annualRate
+	}
+	rule.EndDate = ""
+	if usesEndDate {
+		rule.EndDate = dto.EndDate
+	}
+	rule.EndValue = 0
+	if dto.Method != models.ValuationMethodAppreciation && dto.EndValue != "" {
+		rule.EndValue = utils.DollarStringToCents(dto.EndValue)
+	}
+
+	_, err = bc.ValuationRuleRepository.Save(rule)
+	if err != nil {
+		fmt.Println("Error saving valuation rule: ", err)
+		http.Error(w, "Unable to save valuation rule", http.StatusInternalServerError)
+		return
+	}
+	estimated, err := bc.ValuationService.ApplyValuationRule(rule, time.Now())
+	if err != nil {
+		fmt.Println("Error applying valuation rule: ", err)
+		http.Error(w, "Unable to estimate balances", http.StatusInternalServerError)
+		return
+	}
+	bc.sendBalancesViewAfterChange(w, accountID, fmt.Sprintf("Valuation rule saved. Estimated %d balances", estimated))
+}
+
+// deleteValuationRule removes the account's valuation rule along with the balances estimated from it
+func (bc *BalanceController) deleteValuationRule(w http.ResponseWriter, req *http.Request) {
+	accountID, err := utils.StringToUint(req.FormValue("accountID"))
+	if err != nil {
+		http.Error(w, "Unable to parse account ID", http.StatusBadRequest)
+		return
+	}
+	err = bc.ValuationRuleRepository.DeleteValuationRuleForAccount(accountID)
+	if err != nil {
+		http.Error(w, "Unable to delete valuation rule", http.StatusInternalServerError)
+		return
+	}
+	err = bc.BalanceRepository.ReplaceEstimatedBalances(accountID, nil)
+	if err != nil {
+		http.Error(w, "Unable to delete estimated balances", http.StatusInternalServerError)
+		return
+	}
+	bc.sendBalancesViewAfterChange(w, accountID, "Valuation rule and estimated balances removed")
+}
+
+// cleanDollarString strips commas, dollar signs and spaces from an amount typed into a form
+func cleanDollarString(amount string) string {
+	amount = strings.Replace(amount, ",", "", -1)
+	amount = strings.Replace(amount, "$", "", -1)
+	return strings.Replace(amount, " ", "", -1)
+}
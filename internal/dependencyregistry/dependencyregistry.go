@@ -19,12 +19,22 @@ type DependencyRegistry struct {
 	Bootstrapper               *models.Bootstrapper
 	AccountRepository          *models.AccountRepository
 	AccountTypeRepository      *models.AccountTypeRepository
+	AttachmentRepository       *models.AttachmentRepository
 	BalanceRepository          *models.BalanceRepository
 	BudgetRepository           *models.BudgetRepository
 	CategoryRepository         *models.CategoryRepository
+	CategorizerModelRepository *models.CategorizerModelRepository
 	SettingsRepository         *models.SettingsRepository
 	ImportSubmissionRepository *models.ImportSubmissionRepository
+	MerchantPatternRepository  *models.MerchantPatternRepository
+	TagRepository              *models.TagRepository
 	TransactionRepository      *models.TransactionRepository
+	TransferPairRepository     *models.TransferPairRepository
+	AlertRuleRepository        *models.AlertRuleRepository
+	NotificationRepository     *models.NotificationRepository
+	GoalRepository             *models.GoalRepository
+	LoanRepository             *models.LoanRepository
+	ValuationRuleRepository    *models.ValuationRuleRepository
 
 	AccountManager  *services.AccountManager
 	BudgetService   *services.BudgetService
@@ -32,6 +42,21 @@ type DependencyRegistry struct {
 	MLCategorizer   *services.MLCategorizer
 	CashFlowService *services.CashFlowService
 
+	MerchantNormalizer      *services.MerchantNormalizer
+	RecategorizationService *services.RecategorizationService
+	TransactionSplitService *services.TransactionSplitService
+	TransferMatcher         *services.TransferMatcher
+	RecurringChargeDetector *services.RecurringChargeDetector
+	AlertService            *services.AlertService
+	GoalService             *services.GoalService
+	BalanceHistoryService   *services.BalanceHistoryService
+	NetWorthService         *services.NetWorthService
+	DateRangeService        *services.DateRangeService
+
+	InvestmentPerformanceService *services.InvestmentPerformanceService
+	LoanService                  *services.LoanService
+	ValuationService             *services.ValuationService
+
 	AccountController     *api.AccountController
 	BalanceController     *api.BalanceController
 	BudgetController      *api.BudgetController
@@ -43,7 +68,18 @@ type DependencyRegistry struct {
 	TransactionController *api.TransactionController
 	SettingsController    *api.SettingsController
 	CashFlowController    *api.CashFlowReportHandler
-	ApiServer             *api.ApiServer
+
+	CategorizerEvaluationController *api.CategorizerEvaluationController
+	RecategorizationController      *api.RecategorizationController
+	MerchantPatternController       *api.MerchantPatternController
+	TransferController              *api.TransferController
+	SubscriptionController          *api.SubscriptionController
+	NotificationController          *api.NotificationController
+	GoalController                  *api.GoalController
+	ReconciliationController        *api.ReconciliationController
+	InvestmentController            *api.InvestmentController
+	LoanController                  *api.LoanController
+	ApiServer                       *api.ApiServer
 }
 
 func (dr *DependencyRegistry) GetBootstrapper() *models.Bootstrapper {
@@ -161,6 +197,136 @@ func (dr *DependencyRegistry) GetCategoryRepository() (*models.CategoryRepositor
 	return dr.CategoryRepository, nil
 }
 
+func (dr *DependencyRegistry) GetMerchantPatternRepository() (*models.MerchantPatternRepository, error) {
+	if dr.MerchantPatternRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.MerchantPatternRepository = &models.MerchantPatternRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.MerchantPatternRepository, nil
+}
+
+func (dr *DependencyRegistry) GetTagRepository() (*models.TagRepository, error) {
+	if dr.TagRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.TagRepository = &models.TagRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.TagRepository, nil
+}
+
+func (dr *DependencyRegistry) GetAttachmentRepository() (*models.AttachmentRepository, error) {
+	if dr.AttachmentRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.AttachmentRepository = &models.AttachmentRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.AttachmentRepository, nil
+}
+
+func (dr *DependencyRegistry) GetTransferPairRepository() (*models.TransferPairRepository, error) {
+	if dr.TransferPairRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.TransferPairRepository = &models.TransferPairRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.TransferPairRepository, nil
+}
+
+func (dr *DependencyRegistry) GetAlertRuleRepository() (*models.AlertRuleRepository, error) {
+	if dr.AlertRuleRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.AlertRuleRepository = &models.AlertRuleRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.AlertRuleRepository, nil
+}
+
+func (dr *DependencyRegistry) GetNotificationRepository() (*models.NotificationRepository, error) {
+	if dr.NotificationRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.NotificationRepository = &models.NotificationRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.NotificationRepository, nil
+}
+
+func (dr *DependencyRegistry) GetGoalRepository() (*models.GoalRepository, error) {
+	if dr.GoalRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.GoalRepository = &models.GoalRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.GoalRepository, nil
+}
+
+func (dr *DependencyRegistry) GetLoanRepository() (*models.LoanRepository, error) {
+	if dr.LoanRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.LoanRepository = &models.LoanRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.LoanRepository, nil
+}
+
+func (dr *DependencyRegistry) GetValuationRuleRepository() (*models.ValuationRuleRepository, error) {
+	if dr.ValuationRuleRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.ValuationRuleRepository = &models.ValuationRuleRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.ValuationRuleRepository, nil
+}
+
+func (dr *DependencyRegistry) GetCategorizerModelRepository() (*models.CategorizerModelRepository, error) {
+	if dr.CategorizerModelRepository == nil {
+		dbConnection, err := dr.GetDbConnection()
+		if err != nil {
+			return nil, err
+		}
+		dr.CategorizerModelRepository = &models.CategorizerModelRepository{
+			DB: dbConnection,
+		}
+	}
+	return dr.CategorizerModelRepository, nil
+}
+
 func (dr *DependencyRegistry) GetSettingsRepository() (*models.SettingsRepository, error) {
 	if dr.SettingsRepository == nil {
 		dbConnection, err := dr.GetDbConnection()
@@ -224,14 +390,37 @@ func (dr *DependencyRegistry) GetAccountManager() (*services.AccountManager, err
 
 func (dr *DependencyRegistry) GetMLCategorizer() (*services.MLCategorizer, error) {
 	if dr.MLCategorizer == nil {
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		categorizerModelRepository, err := dr.GetCategorizerModelRepository()
+		if err != nil {
+			return nil, err
+		}
 		transactionRepository, err := dr.GetTransactionRepository()
 		if err != nil {
 			return nil, err
 		}
+		settingsRepository, err := dr.GetSettingsRepository()
+		if err != nil {
+			return nil, err
+		}
+		settings, err := settingsRepository.GetSettings()
+		if err != nil {
+			return nil, err
+		}
 		mlCategorizer := &services.MLCategorizer{
-			TransactionRepository: transactionRepository,
+			Backend:                    settings.CategorizerBackend,
+			CategoryRepository:         categoryRepository,
+			CategorizerModelRepository: categorizerModelRepository,
+			TransactionRepository:      transactionRepository,
+		}
+		// Load the persisted model once; it is kept up to date incrementally from here on
+		err = mlCategorizer.LoadModel()
+		if err != nil {
+			return nil, err
 		}
-		mlCategorizer.BuildModel()
 		dr.MLCategorizer = mlCategorizer
 	}
 	return dr.MLCategorizer, nil
@@ -251,11 +440,16 @@ func (dr *DependencyRegistry) GetBudgetService() (*services.BudgetService, error
 		if err != nil {
 			return nil, err
 		}
+		recurringChargeDetector, err := dr.GetRecurringChargeDetector()
+		if err != nil {
+			return nil, err
+		}
 
 		dr.BudgetService = &services.BudgetService{
-			BudgetRepository:      budgetRepository,
-			CategoryRepository:    categoryRepository,
-			TransactionRepository: transactionRepository,
+			BudgetRepository:        budgetRepository,
+			CategoryRepository:      categoryRepository,
+			TransactionRepository:   transactionRepository,
+			RecurringChargeDetector: recurringChargeDetector,
 		}
 	}
 	return dr.BudgetService, nil
@@ -284,6 +478,10 @@ func (dr *DependencyRegistry) GetImportService() (*services.ImportService, error
 		if err != nil {
 			return nil, err
 		}
+		merchantNormalizer, err := dr.GetMerchantNormalizer()
+		if err != nil {
+			return nil, err
+		}
 
 		dr.ImportService = &services.ImportService{
 			AccountRepository:          accountRepository,
@@ -291,6 +489,7 @@ func (dr *DependencyRegistry) GetImportService() (*services.ImportService, error
 			ImportSubmissionRepository: importSubmissionRepository,
 			TransactionRepository:      transactionRepository,
 			Categorizer:                mlCategorizer,
+			MerchantNormalizer:         merchantNormalizer,
 		}
 	}
 	return dr.ImportService, nil
@@ -302,7 +501,11 @@ func (dr *DependencyRegistry) GetCashFlowService() (*services.CashFlowService, e
 		if err != nil {
 			return nil, err
 		}
-		dr.CashFlowService = services.NewCashFlowService(transactionRepository)
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.CashFlowService = services.NewCashFlowService(transactionRepository, categoryRepository)
 	}
 	return dr.CashFlowService, nil
 }
@@ -313,7 +516,11 @@ func (dr *DependencyRegistry) GetCashFlowController() (*api.CashFlowReportHandle
 		if err != nil {
 			return nil, err
 		}
-		dr.CashFlowController = api.NewCashFlowReportHandler(cashFlowService)
+		dateRangeService, err := dr.GetDateRangeService()
+		if err != nil {
+			return nil, err
+		}
+		dr.CashFlowController = api.NewCashFlowReportHandler(cashFlowService, dateRangeService)
 	}
 	return dr.CashFlowController, nil
 }
@@ -365,9 +572,24 @@ func (dr *DependencyRegistry) GetBalanceController() (*api.BalanceController, er
 		if err != nil {
 			return nil, err
 		}
+		balanceHistoryService, err := dr.GetBalanceHistoryService()
+		if err != nil {
+			return nil, err
+		}
+		valuationRuleRepository, err := dr.GetValuationRuleRepository()
+		if err != nil {
+			return nil, err
+		}
+		valuationService, err := dr.GetValuationService()
+		if err != nil {
+			return nil, err
+		}
 		dr.BalanceController = &api.BalanceController{
-			AccountRepository: accountRepository,
-			BalanceRepository: balanceRepository,
+			AccountRepository:       accountRepository,
+			BalanceRepository:       balanceRepository,
+			BalanceHistoryService:   balanceHistoryService,
+			ValuationRuleRepository: valuationRuleRepository,
+			ValuationService:        valuationService,
 		}
 	}
 	return dr.BalanceController, nil
@@ -406,9 +628,14 @@ func (dr *DependencyRegistry) GetCategoryController() (*api.CategoryController,
 		if err != nil {
 			return nil, err
 		}
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
 		dr.CategoryController = &api.CategoryController{
 			BalanceRepository:  balanceRepository,
 			CategoryRepository: categoryRepository,
+			Categorizer:        mlCategorizer,
 		}
 	}
 	return dr.CategoryController, nil
@@ -428,10 +655,20 @@ func (dr *DependencyRegistry) GetImportController() (*api.ImportController, erro
 		if err != nil {
 			return nil, err
 		}
+		transferMatcher, err := dr.GetTransferMatcher()
+		if err != nil {
+			return nil, err
+		}
+		alertService, err := dr.GetAlertService()
+		if err != nil {
+			return nil, err
+		}
 		dr.ImportController = &api.ImportController{
 			AccountManager:        accountManager,
+			AlertService:          alertService,
 			ImportService:         importService,
 			TransactionRepository: transactionRepository,
+			TransferMatcher:       transferMatcher,
 		}
 	}
 	return dr.ImportController, nil
@@ -443,8 +680,18 @@ func (dr *DependencyRegistry) GetNetIncomeController() (*api.NetIncomeController
 		if err != nil {
 			return nil, err
 		}
+		dateRangeService, err := dr.GetDateRangeService()
+		if err != nil {
+			return nil, err
+		}
+		loanService, err := dr.GetLoanService()
+		if err != nil {
+			return nil, err
+		}
 		dr.NetIncomeController = &api.NetIncomeController{
 			TransactionRepository: transactionRepository,
+			DateRangeService:      dateRangeService,
+			LoanService:           loanService,
 		}
 	}
 	return dr.NetIncomeController, nil
@@ -452,12 +699,17 @@ func (dr *DependencyRegistry) GetNetIncomeController() (*api.NetIncomeController
 
 func (dr *DependencyRegistry) GetNetWorthController() (*api.NetWorthController, error) {
 	if dr.NetWorthController == nil {
-		balanceRepository, err := dr.GetBalanceRepository()
+		netWorthService, err := dr.GetNetWorthService()
+		if err != nil {
+			return nil, err
+		}
+		dateRangeService, err := dr.GetDateRangeService()
 		if err != nil {
 			return nil, err
 		}
 		dr.NetWorthController = &api.NetWorthController{
-			BalanceRepository: balanceRepository,
+			NetWorthService:  netWorthService,
+			DateRangeService: dateRangeService,
 		}
 	}
 	return dr.NetWorthController, nil
@@ -469,8 +721,18 @@ func (dr *DependencyRegistry) GetSpendingController() (*api.SpendingController,
 		if err != nil {
 			return nil, err
 		}
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		dateRangeService, err := dr.GetDateRangeService()
+		if err != nil {
+			return nil, err
+		}
 		dr.SpendingController = &api.SpendingController{
+			CategoryRepository:    categoryRepository,
 			TransactionRepository: transactionRepository,
+			DateRangeService:      dateRangeService,
 		}
 	}
 	return dr.SpendingController, nil
@@ -482,13 +744,43 @@ func (dr *DependencyRegistry) GetTransactionController() (*api.TransactionContro
 		if err != nil {
 			return nil, err
 		}
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
+		merchantNormalizer, err := dr.GetMerchantNormalizer()
+		if err != nil {
+			return nil, err
+		}
 		transactionRepository, err := dr.GetTransactionRepository()
 		if err != nil {
 			return nil, err
 		}
+		transactionSplitService, err := dr.GetTransactionSplitService()
+		if err != nil {
+			return nil, err
+		}
+		tagRepository, err := dr.GetTagRepository()
+		if err != nil {
+			return nil, err
+		}
+		attachmentRepository, err := dr.GetAttachmentRepository()
+		if err != nil {
+			return nil, err
+		}
 		dr.TransactionController = &api.TransactionController{
-			AccountRepository:     accountRepository,
-			TransactionRepository: transactionRepository,
+			AccountRepository:       accountRepository,
+			AttachmentRepository:    attachmentRepository,
+			CategoryRepository:      categoryRepository,
+			Categorizer:             mlCategorizer,
+			MerchantNormalizer:      merchantNormalizer,
+			TagRepository:           tagRepository,
+			TransactionRepository:   transactionRepository,
+			TransactionSplitService: transactionSplitService,
 		}
 	}
 	return dr.TransactionController, nil
@@ -540,6 +832,46 @@ func (dr *DependencyRegistry) GetApiServer() (*api.ApiServer, error) {
 		if err != nil {
 			return nil, err
 		}
+		categorizerEvaluationController, err := dr.GetCategorizerEvaluationController()
+		if err != nil {
+			return nil, err
+		}
+		recategorizationController, err := dr.GetRecategorizationController()
+		if err != nil {
+			return nil, err
+		}
+		merchantPatternController, err := dr.GetMerchantPatternController()
+		if err != nil {
+			return nil, err
+		}
+		transferController, err := dr.GetTransferController()
+		if err != nil {
+			return nil, err
+		}
+		subscriptionController, err := dr.GetSubscriptionController()
+		if err != nil {
+			return nil, err
+		}
+		notificationController, err := dr.GetNotificationController()
+		if err != nil {
+			return nil, err
+		}
+		goalController, err := dr.GetGoalController()
+		if err != nil {
+			return nil, err
+		}
+		reconciliationController, err := dr.GetReconciliationController()
+		if err != nil {
+			return nil, err
+		}
+		investmentController, err := dr.GetInvestmentController()
+		if err != nil {
+			return nil, err
+		}
+		loanController, err := dr.GetLoanController()
+		if err != nil {
+			return nil, err
+		}
 		dr.ApiServer = &api.ApiServer{
 			AccountController:     accountController,
 			BalanceController:     balanceController,
@@ -552,6 +884,17 @@ func (dr *DependencyRegistry) GetApiServer() (*api.ApiServer, error) {
 			TransactionController: transactionController,
 			SettingsController:    settingsController,
 			CashFlowController:    cashFlowController,
+
+			CategorizerEvaluationController: categorizerEvaluationController,
+			RecategorizationController:      recategorizationController,
+			MerchantPatternController:       merchantPatternController,
+			TransferController:              transferController,
+			SubscriptionController:          subscriptionController,
+			NotificationController:          notificationController,
+			GoalController:                  goalController,
+			ReconciliationController:        reconciliationController,
+			InvestmentController:            investmentController,
+			LoanController:                  loanController,
 		}
 	}
 	return dr.ApiServer, nil
@@ -559,13 +902,483 @@ func (dr *DependencyRegistry) GetApiServer() (*api.ApiServer, error) {
 
 func (dr *DependencyRegistry) GetSettingsController() (*api.SettingsController, error) {
 	if dr.SettingsController == nil {
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
 		settingsRepository, err := dr.GetSettingsRepository()
 		if err != nil {
 			return nil, err
 		}
 		dr.SettingsController = &api.SettingsController{
+			Categorizer:        mlCategorizer,
 			SettingsRepository: settingsRepository,
 		}
 	}
 	return dr.SettingsController, nil
 }
+
+func (dr *DependencyRegistry) GetCategorizerEvaluationController() (*api.CategorizerEvaluationController, error) {
+	if dr.CategorizerEvaluationController == nil {
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
+		dr.CategorizerEvaluationController = &api.CategorizerEvaluationController{
+			Categorizer: mlCategorizer,
+		}
+	}
+	return dr.CategorizerEvaluationController, nil
+}
+
+func (dr *DependencyRegistry) GetRecategorizationService() (*services.RecategorizationService, error) {
+	if dr.RecategorizationService == nil {
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		transferPairRepository, err := dr.GetTransferPairRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.RecategorizationService = &services.RecategorizationService{
+			Categorizer:            mlCategorizer,
+			CategoryRepository:     categoryRepository,
+			TransactionRepository:  transactionRepository,
+			TransferPairRepository: transferPairRepository,
+		}
+	}
+	return dr.RecategorizationService, nil
+}
+
+func (dr *DependencyRegistry) GetRecategorizationController() (*api.RecategorizationController, error) {
+	if dr.RecategorizationController == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		recategorizationService, err := dr.GetRecategorizationService()
+		if err != nil {
+			return nil, err
+		}
+		dr.RecategorizationController = &api.RecategorizationController{
+			AccountRepository:       accountRepository,
+			CategoryRepository:      categoryRepository,
+			RecategorizationService: recategorizationService,
+		}
+	}
+	return dr.RecategorizationController, nil
+}
+
+func (dr *DependencyRegistry) GetMerchantNormalizer() (*services.MerchantNormalizer, error) {
+	if dr.MerchantNormalizer == nil {
+		merchantPatternRepository, err := dr.GetMerchantPatternRepository()
+		if err != nil {
+			return nil, err
+		}
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		merchantNormalizer := &services.MerchantNormalizer{
+			MerchantPatternRepository: merchantPatternRepository,
+			TransactionRepository:     transactionRepository,
+		}
+		err = merchantNormalizer.LoadPatterns()
+		if err != nil {
+			return nil, err
+		}
+		dr.MerchantNormalizer = merchantNormalizer
+	}
+	return dr.MerchantNormalizer, nil
+}
+
+func (dr *DependencyRegistry) GetMerchantPatternController() (*api.MerchantPatternController, error) {
+	if dr.MerchantPatternController == nil {
+		mlCategorizer, err := dr.GetMLCategorizer()
+		if err != nil {
+			return nil, err
+		}
+		merchantNormalizer, err := dr.GetMerchantNormalizer()
+		if err != nil {
+			return nil, err
+		}
+		merchantPatternRepository, err := dr.GetMerchantPatternRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.MerchantPatternController = &api.MerchantPatternController{
+			Categorizer:               mlCategorizer,
+			MerchantNormalizer:        merchantNormalizer,
+			MerchantPatternRepository: merchantPatternRepository,
+		}
+	}
+	return dr.MerchantPatternController, nil
+}
+
+func (dr *DependencyRegistry) GetTransactionSplitService() (*services.TransactionSplitService, error) {
+	if dr.TransactionSplitService == nil {
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.TransactionSplitService = &services.TransactionSplitService{
+			TransactionRepository: transactionRepository,
+		}
+	}
+	return dr.TransactionSplitService, nil
+}
+
+func (dr *DependencyRegistry) GetTransferMatcher() (*services.TransferMatcher, error) {
+	if dr.TransferMatcher == nil {
+		categoryRepository, err := dr.GetCategoryRepository()
+		if err != nil {
+			return nil, err
+		}
+		transferPairRepository, err := dr.GetTransferPairRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.TransferMatcher = &services.TransferMatcher{
+			CategoryRepository:     categoryRepository,
+			TransferPairRepository: transferPairRepository,
+		}
+	}
+	return dr.TransferMatcher, nil
+}
+
+func (dr *DependencyRegistry) GetTransferController() (*api.TransferController, error) {
+	if dr.TransferController == nil {
+		transferMatcher, err := dr.GetTransferMatcher()
+		if err != nil {
+			return nil, err
+		}
+		transferPairRepository, err := dr.GetTransferPairRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.TransferController = &api.TransferController{
+			TransferMatcher:        transferMatcher,
+			TransferPairRepository: transferPairRepository,
+		}
+	}
+	return dr.TransferController, nil
+}
+
+func (dr *DependencyRegistry) GetRecurringChargeDetector() (*services.RecurringChargeDetector, error) {
+	if dr.RecurringChargeDetector == nil {
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.RecurringChargeDetector = &services.RecurringChargeDetector{
+			TransactionRepository: transactionRepository,
+		}
+	}
+	return dr.RecurringChargeDetector, nil
+}
+
+func (dr *DependencyRegistry) GetSubscriptionController() (*api.SubscriptionController, error) {
+	if dr.SubscriptionController == nil {
+		recurringChargeDetector, err := dr.GetRecurringChargeDetector()
+		if err != nil {
+			return nil, err
+		}
+		dr.SubscriptionController = &api.SubscriptionController{
+			RecurringChargeDetector: recurringChargeDetector,
+		}
+	}
+	return dr.SubscriptionController, nil
+}
+
+func (dr *DependencyRegistry) GetAlertService() (*services.AlertService, error) {
+	if dr.AlertService == nil {
+		alertRuleRepository, err := dr.GetAlertRuleRepository()
+		if err != nil {
+			return nil, err
+		}
+		notificationRepository, err := dr.GetNotificationRepository()
+		if err != nil {
+			return nil, err
+		}
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		budgetService, err := dr.GetBudgetService()
+		if err != nil {
+			return nil, err
+		}
+		settingsRepository, err := dr.GetSettingsRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.AlertService = &services.AlertService{
+			AlertRuleRepository:    alertRuleRepository,
+			NotificationRepository: notificationRepository,
+			TransactionRepository:  transactionRepository,
+			BudgetService:          budgetService,
+			SettingsRepository:     settingsRepository,
+			AlertCommand:           os.Getenv("SAGE_ALERT_COMMAND"),
+		}
+	}
+	return dr.AlertService, nil
+}
+
+func (dr *DependencyRegistry) GetNotificationController() (*api.NotificationController, error) {
+	if dr.NotificationController == nil {
+		alertRuleRepository, err := dr.GetAlertRuleRepository()
+		if err != nil {
+			return nil, err
+		}
+		alertService, err := dr.GetAlertService()
+		if err != nil {
+			return nil, err
+		}
+		notificationRepository, err := dr.GetNotificationRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.NotificationController = &api.NotificationController{
+			AlertRuleRepository:    alertRuleRepository,
+			AlertService:           alertService,
+			NotificationRepository: notificationRepository,
+		}
+	}
+	return dr.NotificationController, nil
+}
+
+func (dr *DependencyRegistry) GetGoalService() (*services.GoalService, error) {
+	if dr.GoalService == nil {
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.GoalService = &services.GoalService{
+			BalanceRepository: balanceRepository,
+		}
+	}
+	return dr.GoalService, nil
+}
+
+func (dr *DependencyRegistry) GetGoalController() (*api.GoalController, error) {
+	if dr.GoalController == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		goalRepository, err := dr.GetGoalRepository()
+		if err != nil {
+			return nil, err
+		}
+		goalService, err := dr.GetGoalService()
+		if err != nil {
+			return nil, err
+		}
+		dr.GoalController = &api.GoalController{
+			AccountRepository: accountRepository,
+			GoalRepository:    goalRepository,
+			GoalService:       goalService,
+		}
+	}
+	return dr.GoalController, nil
+}
+
+func (dr *DependencyRegistry) GetBalanceHistoryService() (*services.BalanceHistoryService, error) {
+	if dr.BalanceHistoryService == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		transferPairRepository, err := dr.GetTransferPairRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.BalanceHistoryService = &services.BalanceHistoryService{
+			AccountRepository:      accountRepository,
+			BalanceRepository:      balanceRepository,
+			TransactionRepository:  transactionRepository,
+			TransferPairRepository: transferPairRepository,
+		}
+	}
+	return dr.BalanceHistoryService, nil
+}
+
+func (dr *DependencyRegistry) GetReconciliationController() (*api.ReconciliationController, error) {
+	if dr.ReconciliationController == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceHistoryService, err := dr.GetBalanceHistoryService()
+		if err != nil {
+			return nil, err
+		}
+		dr.ReconciliationController = &api.ReconciliationController{
+			AccountRepository:     accountRepository,
+			BalanceHistoryService: balanceHistoryService,
+		}
+	}
+	return dr.ReconciliationController, nil
+}
+
+func (dr *DependencyRegistry) GetNetWorthService() (*services.NetWorthService, error) {
+	if dr.NetWorthService == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.NetWorthService = &services.NetWorthService{
+			AccountRepository: accountRepository,
+			BalanceRepository: balanceRepository,
+		}
+	}
+	return dr.NetWorthService, nil
+}
+
+func (dr *DependencyRegistry) GetDateRangeService() (*services.DateRangeService, error) {
+	if dr.DateRangeService == nil {
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		settingsRepository, err := dr.GetSettingsRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.DateRangeService = &services.DateRangeService{
+			TransactionRepository: transactionRepository,
+			BalanceRepository:     balanceRepository,
+			SettingsRepository:    settingsRepository,
+		}
+	}
+	return dr.DateRangeService, nil
+}
+
+func (dr *DependencyRegistry) GetInvestmentPerformanceService() (*services.InvestmentPerformanceService, error) {
+	if dr.InvestmentPerformanceService == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		transactionRepository, err := dr.GetTransactionRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.InvestmentPerformanceService = &services.InvestmentPerformanceService{
+			AccountRepository:     accountRepository,
+			BalanceRepository:     balanceRepository,
+			TransactionRepository: transactionRepository,
+		}
+	}
+	return dr.InvestmentPerformanceService, nil
+}
+
+func (dr *DependencyRegistry) GetInvestmentController() (*api.InvestmentController, error) {
+	if dr.InvestmentController == nil {
+		investmentPerformanceService, err := dr.GetInvestmentPerformanceService()
+		if err != nil {
+			return nil, err
+		}
+		dateRangeService, err := dr.GetDateRangeService()
+		if err != nil {
+			return nil, err
+		}
+		dr.InvestmentController = &api.InvestmentController{
+			InvestmentPerformanceService: investmentPerformanceService,
+			DateRangeService:             dateRangeService,
+		}
+	}
+	return dr.InvestmentController, nil
+}
+
+func (dr *DependencyRegistry) GetLoanService() (*services.LoanService, error) {
+	if dr.LoanService == nil {
+		loanRepository, err := dr.GetLoanRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.LoanService = &services.LoanService{
+			LoanRepository:    loanRepository,
+			BalanceRepository: balanceRepository,
+		}
+	}
+	return dr.LoanService, nil
+}
+
+func (dr *DependencyRegistry) GetLoanController() (*api.LoanController, error) {
+	if dr.LoanController == nil {
+		accountRepository, err := dr.GetAccountRepository()
+		if err != nil {
+			return nil, err
+		}
+		loanRepository, err := dr.GetLoanRepository()
+		if err != nil {
+			return nil, err
+		}
+		loanService, err := dr.GetLoanService()
+		if err != nil {
+			return nil, err
+		}
+		dr.LoanController = &api.LoanController{
+			AccountRepository: accountRepository,
+			LoanRepository:    loanRepository,
+			LoanService:       loanService,
+		}
+	}
+	return dr.LoanController, nil
+}
+
+func (dr *DependencyRegistry) GetValuationService() (*services.ValuationService, error) {
+	if dr.ValuationService == nil {
+		valuationRuleRepository, err := dr.GetValuationRuleRepository()
+		if err != nil {
+			return nil, err
+		}
+		balanceRepository, err := dr.GetBalanceRepository()
+		if err != nil {
+			return nil, err
+		}
+		dr.ValuationService = &services.ValuationService{
+			ValuationRuleRepository: valuationRuleRepository,
+			BalanceRepository:       balanceRepository,
+		}
+	}
+	return dr.ValuationService, nil
+}
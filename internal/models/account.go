@@ -13,6 +13,8 @@ type Account struct {
 	Name          string
 	AccountTypeID uint
 	AccountType   AccountType
+	// NetWorthGroup is a group of the user's choosing, like Retirement or Liquid, for breaking down net worth
+	NetWorthGroup string
 }
 
 type AccountRepository struct {
@@ -21,10 +23,17 @@ type AccountRepository struct {
 
 func (ar *AccountRepository) GetAllAccounts() ([]Account, error) {
 	var accounts []Account
-	result := ar.DB.Find(&accounts)
+	result := ar.DB.Preload("AccountType").Find(&accounts)
 	return accounts, result.Error
 }
 
+// GetNetWorthGroups returns the names of the net worth groups in use, alphabetically
+func (ar *AccountRepository) GetNetWorthGroups() ([]string, error) {
+	var groups []string
+	result := ar.DB.Model(&Account{}).Where("net_worth_group <> ''").Distinct().Order("net_worth_group").Pluck("net_worth_group", &groups)
+	return groups, result.Error
+}
+
 func (ar *AccountRepository) GetAccountByID(id uint) (Account, error) {
 	var account Account
 	result := ar.DB.Preload(clause.Associations).Where("id = ?", id).Find(&account)
@@ -54,10 +63,12 @@ func (ar *AccountRepository) Save(account Account) (id uint, err error) {
 	return account.ID, result.Error
 }
 
-// Soft deletes an account and all associated transactions and balances
+// Soft deletes an account and all associated transactions and balances, and deletes its loan terms and valuation rule
 func (ar *AccountRepository) DeleteAccountByID(accountID uint) (err error) {
 	ar.DB.Where("account_id = ?", accountID).Delete(&Balance{})
 	ar.DB.Where("account_id = ?", accountID).Delete(&Transaction{})
+	ar.DB.Unscoped().Where("account_id = ?", accountID).Delete(&Loan{})
+	ar.DB.Unscoped().Where("account_id = ?", accountID).Delete(&ValuationRule{})
 	result := ar.DB.Delete(&Account{}, accountID)
 	return result.Error
 }
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Alert rule kinds decide what an alert rule watches for and what its threshold means
+const (
+	AlertKindBudgetPercent      = "budgetPercent"      // A budget reaching Threshold percent of its amount
+	AlertKindLargeTransaction   = "largeTransaction"   // A new transaction of at least Threshold cents
+	AlertKindUnknownTransaction = "unknownTransaction" // New transactions the categorizer couldn't categorize
+)
+
+// AlertRule is a condition that adds a notification to the notification center when it's met
+type AlertRule struct {
+	gorm.Model
+	Kind      string
+	Threshold int
+	Enabled   bool `gorm:"default:true"`
+	// LastCheckedAt is when transaction rules last looked for new transactions, so each transaction only
+	// triggers an alert once. Rules that haven't been checked yet start from when they were created.
+	LastCheckedAt *time.Time
+}
+
+type AlertRuleRepository struct {
+	DB *gorm.DB
+}
+
+func (arr *AlertRuleRepository) GetAllAlertRules() ([]AlertRule, error) {
+	var rules []AlertRule
+	result := arr.DB.Order("kind asc, threshold asc").Find(&rules)
+	return rules, result.Error
+}
+
+func (arr *AlertRuleRepository) GetEnabledAlertRules() ([]AlertRule, error) {
+	var rules []AlertRule
+	result := arr.DB.Where("enabled = ?", true).Order("kind asc, threshold asc").Find(&rules)
+	return rules, result.Error
+}
+
+func (arr *AlertRuleRepository) GetAlertRuleByID(id uint) (AlertRule, error) {
+	var rule AlertRule
+	result := arr.DB.Where("id = ?", id).First(&rule)
+	return rule, result.Error
+}
+
+// Save is an UPSERT operation, returning the ID of the record and an optional error
+func (arr *AlertRuleRepository) Save(rule AlertRule) (id uint, err error) {
+	result := arr.DB.Save(&rule).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}})
+	return rule.ID, result.Error
+}
+
+func (arr *AlertRuleRepository) SetLastCheckedAt(id uint, lastCheckedAt time.Time) error {
+	result := arr.DB.Model(&AlertRule{}).Where("id = ?", id).Update("last_checked_at", lastCheckedAt)
+	return result.Error
+}
+
+func (arr *AlertRuleRepository) DeleteAlertRuleByID(id uint) error {
+	result := arr.DB.Delete(&AlertRule{}, id)
+	return result.Error
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Attachment is a file kept with a transaction, like a receipt for a warranty or a tax deduction. Files are
+// stored in the database so backing up the database file backs up the attachments too.
+type Attachment struct {
+	gorm.Model
+	TransactionID uint `gorm:"index"`
+	FileName      string
+	ContentType   string
+	Size          int
+	Data          []byte
+}
+
+type AttachmentRepository struct {
+	DB *gorm.DB
+}
+
+// GetAttachmentsForTransaction returns a transaction's attachments without their contents
+func (ar *AttachmentRepository) GetAttachmentsForTransaction(transactionID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	result := ar.DB.Select("id", "created_at", "transaction_id", "file_name", "content_type", "size").
+		Where("transaction_id = ?", transactionID).
+		Order("id asc").
+		Find(&attachments)
+	return attachments, result.Error
+}
+
+func (ar *AttachmentRepository) GetAttachmentByID(id uint) (Attachment, error) {
+	var attachment Attachment
+	result := ar.DB.Where("id = ?", id).First(&attachment)
+	return attachment, result.Error
+}
+
+// Create saves a new attachment, returning its ID and an optional error
+func (ar *AttachmentRepository) Create(attachment Attachment) (id uint, err error) {
+	result := ar.DB.Create(&attachment)
+	return attachment.ID, result.Error
+}
+
+// DeleteAttachmentByID permanently deletes an attachment, rather than soft deleting it, so its contents don't
+// stay in the database
+func (ar *AttachmentRepository) DeleteAttachmentByID(id uint) error {
+	result := ar.DB.Unscoped().Delete(&Attachment{}, id)
+	return result.Error
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAttachmentRepository(t *testing.T) {
+	testDB := newTestDB(t)
+	attachmentRepository := &AttachmentRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	transaction := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Groceries", Amount: 5000, CategoryID: food.ID})
+
+	receiptID, err := attachmentRepository.Create(Attachment{TransactionID: transaction.ID, FileName: "receipt.png", ContentType: "image/png", Size: 3, Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	attachmentRepository.Create(Attachment{TransactionID: transaction.ID, FileName: "warranty.pdf", ContentType: "application/pdf", Size: 2, Data: []byte{4, 5}})
+
+	attachments, err := attachmentRepository.GetAttachmentsForTransaction(transaction.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(attachments) != 2 || attachments[0].FileName != "receipt.png" || attachments[1].FileName != "warranty.pdf" {
+		t.Fatalf("Expected both attachments in the order they were added, got %+v", attachments)
+	}
+	if attachments[0].Data != nil || attachments[0].Size != 3 {
+		t.Errorf("Expected the attachment's size without its contents, got %+v", attachments[0])
+	}
+
+	receipt, err := attachmentRepository.GetAttachmentByID(receiptID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(receipt.Data) != string([]byte{1, 2, 3}) {
+		t.Errorf("Expected the attachment's contents, got %v", receipt.Data)
+	}
+
+	if err := attachmentRepository.DeleteAttachmentByID(receiptID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var count int64
+	testDB.Unscoped().Model(&Attachment{}).Where("id = ?", receiptID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the deleted attachment to be removed from the database")
+	}
+}
+
+func TestDeleteTransactionByID_DeletesAttachments(t *testing.T) {
+	testDB := newTestDB(t)
+	attachmentRepository := &AttachmentRepository{DB: testDB}
+	transactionRepository := &TransactionRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	transaction := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Groceries", Amount: 5000, CategoryID: food.ID})
+	other := createTestTransaction(t, testDB, Transaction{Date: "2026-03-02", Description: "Hardware", Amount: 9000, CategoryID: food.ID})
+	attachmentRepository.Create(Attachment{TransactionID: transaction.ID, FileName: "receipt.png", Data: []byte{1}})
+	attachmentRepository.Create(Attachment{TransactionID: other.ID, FileName: "warranty.pdf", Data: []byte{2}})
+
+	if err := transactionRepository.DeleteTransactionByID(context.Background(), transaction.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var count int64
+	testDB.Unscoped().Model(&Attachment{}).Where("transaction_id = ?", transaction.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the deleted transaction's attachments to be removed from the database, got %d", count)
+	}
+	attachments, _ := attachmentRepository.GetAttachmentsForTransaction(other.ID)
+	if len(attachments) != 1 {
+		t.Errorf("Expected other transactions' attachments to be kept, got %+v", attachments)
+	}
+}
@@ -10,4 +10,10 @@ type Balance struct {
 	Account            Account
 	ImportSubmissionID *uint
 	ImportSubmission   *ImportSubmission
+	// Derived balances are worked out from the account's transactions instead of being reported by the
+	// institution or entered by hand. They never replace a reported balance on the same date.
+	Derived bool
+	// Estimated balances come from the account's valuation rule. Applying the rule skips months with a reported or
+	// derived balance.
+	Estimated bool
 }
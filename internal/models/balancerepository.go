@@ -81,6 +81,14 @@ func (br *BalanceRepository) GetBalancesByMonth(ctx context.Context, ledgerType
 	return result
 }
 
+// GetEarliestBalanceDate returns the effective date of the oldest balance as YYYY-MM-DD, or an empty string if
+// there are no balances
+func (br *BalanceRepository) GetEarliestBalanceDate(ctx context.Context) (string, error) {
+	var date string
+	result := br.DB.Model(&Balance{}).Select("coalesce(min(effective_date), '')").Scan(&date)
+	return date, result.Error
+}
+
 func (br *BalanceRepository) GetLatestBalanceForAccount(ctx context.Context, accountID uint) (balance Balance, err error) {
 	result := br.DB.Where("account_id = ?", accountID).Order("effective_date desc").First(&balance)
 	return balance, result.Error
@@ -98,8 +106,50 @@ func (br *BalanceRepository) GetBalanceByID(ctx context.Context, balanceID uint)
 	return balance
 }
 
-// Save is an UPSERT operation, returning the ID of the record and an optional error
+// Save is an UPSERT operation, returning the ID of the record and an optional error. Saving a reported balance
+// removes any derived or estimated balance for the same account and date, since the reported balance is more
+// accurate.
 func (br *BalanceRepository) Save(balance Balance) (id uint, err error) {
-	result := br.DB.Save(&balance).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}})
-	return balance.ID, result.Error
+	err = br.DB.Transaction(func(tx *gorm.DB) error {
+		if !balance.Derived && !balance.Estimated {
+			err := tx.Unscoped().
+				Where("account_id = ? AND effective_date = ? AND (derived = ? OR estimated = ?) AND id <> ?", balance.AccountID, balance.EffectiveDate, true, true, balance.ID).
+				Delete(&Balance{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(&balance).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).Error
+	})
+	return balance.ID, err
+}
+
+// ReplaceDerivedBalances deletes every derived balance for the account and saves the new ones in their place.
+// Passing no balances just removes the derived balances.
+func (br *BalanceRepository) ReplaceDerivedBalances(accountID uint, balances []Balance) error {
+	return br.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("account_id = ? AND derived = ?", accountID, true).Delete(&Balance{}).Error
+		if err != nil {
+			return err
+		}
+		if len(balances) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).CreateInBatches(&balances, 500).Error
+	})
+}
+
+// ReplaceEstimatedBalances deletes every estimated balance for the account and saves the new ones in their place.
+// Passing no balances just removes the estimated balances.
+func (br *BalanceRepository) ReplaceEstimatedBalances(accountID uint, balances []Balance) error {
+	return br.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("account_id = ? AND estimated = ?", accountID, true).Delete(&Balance{}).Error
+		if err != nil {
+			return err
+		}
+		if len(balances) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).CreateInBatches(&balances, 500).Error
+	})
 }
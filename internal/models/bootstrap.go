@@ -39,6 +39,14 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 		if err != nil {
 			panic("Error dropping Balance table: " + err.Error())
 		}
+		err = b.db.Migrator().DropTable(&AlertRule{})
+		if err != nil {
+			panic("Error dropping AlertRule table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&Notification{})
+		if err != nil {
+			panic("Error dropping Notification table: " + err.Error())
+		}
 		err = b.db.Migrator().DropTable(&Budget{})
 		if err != nil {
 			panic("Error dropping Budget table: " + err.Error())
@@ -47,6 +55,14 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 		if err != nil {
 			panic("Error dropping Category table: " + err.Error())
 		}
+		err = b.db.Migrator().DropTable(&CategorizerModel{})
+		if err != nil {
+			panic("Error dropping CategorizerModel table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&MerchantPattern{})
+		if err != nil {
+			panic("Error dropping MerchantPattern table: " + err.Error())
+		}
 		err = b.db.Migrator().DropTable(&ImportSubmission{})
 		if err != nil {
 			panic("Error dropping ImportSubmission table: " + err.Error())
@@ -59,6 +75,34 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 		if err != nil {
 			panic("Error dropping Transaction table: " + err.Error())
 		}
+		err = b.db.Migrator().DropTable(&TransactionSplit{})
+		if err != nil {
+			panic("Error dropping TransactionSplit table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable("transaction_tags", &Tag{})
+		if err != nil {
+			panic("Error dropping Tag tables: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&TransferPair{})
+		if err != nil {
+			panic("Error dropping TransferPair table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&Attachment{})
+		if err != nil {
+			panic("Error dropping Attachment table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable("goal_accounts", &Goal{})
+		if err != nil {
+			panic("Error dropping Goal tables: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&Loan{})
+		if err != nil {
+			panic("Error dropping Loan table: " + err.Error())
+		}
+		err = b.db.Migrator().DropTable(&ValuationRule{})
+		if err != nil {
+			panic("Error dropping ValuationRule table: " + err.Error())
+		}
 
 	}
 
@@ -83,6 +127,14 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 	if err != nil {
 		panic("Error dropping migrationg Account table: " + err.Error())
 	}
+	err = b.db.AutoMigrate(&CategorizerModel{})
+	if err != nil {
+		panic("Error migrating CategorizerModel table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&MerchantPattern{})
+	if err != nil {
+		panic("Error migrating MerchantPattern table: " + err.Error())
+	}
 	err = b.db.AutoMigrate(&ImportSubmission{})
 	if err != nil {
 		panic("Error dropping migrationg Account table: " + err.Error())
@@ -91,16 +143,59 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 	if err != nil {
 		panic("Error dropping migrationg Account table: " + err.Error())
 	}
+	err = b.db.AutoMigrate(&Tag{})
+	if err != nil {
+		panic("Error migrating Tag table: " + err.Error())
+	}
 	err = b.db.AutoMigrate(&Transaction{})
 	if err != nil {
 		panic("Error dropping migrationg Account table: " + err.Error())
 	}
+	err = b.db.AutoMigrate(&TransactionSplit{})
+	if err != nil {
+		panic("Error migrating TransactionSplit table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&TransferPair{})
+	if err != nil {
+		panic("Error migrating TransferPair table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&Attachment{})
+	if err != nil {
+		panic("Error migrating Attachment table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&AlertRule{})
+	if err != nil {
+		panic("Error migrating AlertRule table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&Notification{})
+	if err != nil {
+		panic("Error migrating Notification table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&Goal{})
+	if err != nil {
+		panic("Error migrating Goal table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&Loan{})
+	if err != nil {
+		panic("Error migrating Loan table: " + err.Error())
+	}
+	err = b.db.AutoMigrate(&ValuationRule{})
+	if err != nil {
+		panic("Error migrating ValuationRule table: " + err.Error())
+	}
+
+	b.backfillCategoryKinds()
 
 	// Seed data for common categories, if they don't exist already
+	seedCategoryKinds := map[string]string{"Transfers": CategoryKindTransfer, "Income": CategoryKindIncome}
 	for _, name := range []string{"Unknown", "Transfers", "Home", "Income", "Auto", "Food", "Dining"} {
+		kind, ok := seedCategoryKinds[name]
+		if !ok {
+			kind = CategoryKindExpense
+		}
 		// The Category table has a unique index on the Name column, so we can use the DoNothing option
 		// to safely attempt to insert a record that may already exist
-		b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Category{Name: name})
+		b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Category{Name: name, Kind: kind})
 	}
 
 	accountTypesData := map[string]map[string]string{
@@ -113,10 +208,13 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 		"Schwab Checking":             {"ledgerType": Asset, "accountCategory": "checking", "defaultParser": "schwabChecking"},
 		"Fidelity Credit Card":        {"ledgerType": Liability, "accountCategory": "creditCard", "defaultParser": "fidelityCreditCard"},
 		"Fidelity Brokerage":          {"ledgerType": Asset, "accountCategory": "brokerage", "defaultParser": "fidelityBrokerage"},
+		"Fidelity Retirement":         {"ledgerType": Asset, "accountCategory": "brokerage", "defaultParser": "fidelityBrokerage"},
+		"Schwab Retirement":           {"ledgerType": Asset, "accountCategory": "brokerage", "defaultParser": "schwabBrokerage"},
 		"Real Estate":                 {"ledgerType": Asset, "accountCategory": "realEstate"},
 		"Mortgage":                    {"ledgerType": Liability, "accountCategory": "loan"},
 		"Target Credit Card":          {"ledgerType": Liability, "accountCategory": "creditCard", "defaultParser": "targetCreditCard"},
 		"UWCU Mortgage":               {"ledgerType": Liability, "accountCategory": "loan", "defaultParser": "uwcuMortgage"},
+		"Vehicle":                     {"ledgerType": Asset, "accountCategory": "vehicle"},
 
 		"Misc Asset":     {"ledgerType": Asset, "accountCategory": Asset},
 		"Misc Liability": {"ledgerType": Liability, "accountCategory": Asset},
@@ -182,9 +280,33 @@ func (b *Bootstrapper) BootstrapDatabase(ctx context.Context) {
 		// b.db.Create(&Balance{EffectiveDate: "2024-01-17", Amount: 1250, AccountID: 4})
 	}
 
+	// Seed default alert rules. Fixed IDs mean rules the user has deleted aren't added back.
+	b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&[]AlertRule{
+		{Model: gorm.Model{ID: 1}, Kind: AlertKindBudgetPercent, Threshold: 80, Enabled: true},
+		{Model: gorm.Model{ID: 2}, Kind: AlertKindBudgetPercent, Threshold: 100, Enabled: true},
+		{Model: gorm.Model{ID: 3}, Kind: AlertKindUnknownTransaction, Enabled: true},
+	})
+
 	// Seed default settings data
 	// The Settings table has a unique index on the Name column, so we can use the DoNothing option
 	// to safely attempt to insert a record that may already exist
 	b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Settings{Model: gorm.Model{ID: 1}, LaunchBrowserOnStartup: true})
 
 }
+
+// backfillCategoryKinds gives a kind to categories created before categories had kinds. They were classified by
+// name, so carry that over. Subcategories of Income and Transfers were counted with their parents, so they get
+// the same kind, and everything else is an expense.
+func (b *Bootstrapper) backfillCategoryKinds() {
+	for name, kind := range map[string]string{"Income": CategoryKindIncome, "Transfers": CategoryKindTransfer} {
+		var category Category
+		b.db.Where("name = ?", name).Limit(1).Find(&category)
+		if category.ID == 0 {
+			continue
+		}
+		b.db.Exec(`UPDATE categories SET kind = ?
+			WHERE coalesce(kind, '') = ''
+			AND id IN (`+categorySubtreeSQL+`)`, kind, category.ID)
+	}
+	b.db.Exec(`UPDATE categories SET kind = ? WHERE coalesce(kind, '') = ''`, CategoryKindExpense)
+}
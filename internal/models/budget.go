@@ -1,17 +1,84 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+const (
+	BudgetPeriodWeekly    = "weekly"
+	BudgetPeriodMonthly   = "monthly"
+	BudgetPeriodQuarterly = "quarterly"
+	BudgetPeriodAnnual    = "annual"
+)
+
+// BudgetPeriods are the periods a budget can cover, in the order they're offered
+var BudgetPeriods = []string{BudgetPeriodWeekly, BudgetPeriodMonthly, BudgetPeriodQuarterly, BudgetPeriodAnnual}
+
+// BudgetPeriodsPerYear is how many of each budget period fit in a year, used to compare budgets with
+// different periods
+var BudgetPeriodsPerYear = map[string]int{
+	BudgetPeriodWeekly:    52,
+	BudgetPeriodMonthly:   12,
+	BudgetPeriodQuarterly: 4,
+	BudgetPeriodAnnual:    1,
+}
+
+// A Budget is the amount to spend in a category each period while the budget is in force. Changing a
+// budget from a date onwards ends the old budget the day before and starts a new one, so past periods
+// are still compared against the amount that applied to them.
 type Budget struct {
 	gorm.Model
-	Amount     int
+	Amount int
+	Period string `gorm:"default:monthly"`
+	// StartDate is the first day the budget applies, in YYYY-MM-DD format. If empty, the budget applies to
+	// every period before its end date.
+	StartDate string
+	// EndDate is the last day the budget applies, in YYYY-MM-DD format. If empty, the budget is still in force.
+	EndDate string
+	// Rollover carries unspent budget into the next period, and takes overspending out of it
+	Rollover   bool
 	CategoryID uint
 	Category   Category
 }
 
+// InForceOn returns true if the budget applies on the date, in YYYY-MM-DD format
+func (b Budget) InForceOn(date string) bool {
+	return (b.StartDate == "" || b.StartDate <= date) && (b.EndDate == "" || b.EndDate >= date)
+}
+
+// AmountPer returns the budget's amount spread over a different period, e.g. an annual budget as a monthly
+// amount. Budgets without a period are monthly.
+func (b Budget) AmountPer(period string) int {
+	budgetPeriod := b.Period
+	if budgetPeriod == "" {
+		budgetPeriod = BudgetPeriodMonthly
+	}
+	if budgetPeriod == period || BudgetPeriodsPerYear[period] == 0 {
+		return b.Amount
+	}
+	return b.Amount * BudgetPeriodsPerYear[budgetPeriod] / BudgetPeriodsPerYear[period]
+}
+
+// BudgetPeriodStart returns the first day of the budget period that contains the date. Weeks start on
+// Monday, and quarters and years follow the calendar.
+func BudgetPeriodStart(period string, date time.Time) time.Time {
+	year, month, day := date.Date()
+	switch period {
+	case BudgetPeriodWeekly:
+		daysSinceMonday := (int(date.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, date.Location())
+	case BudgetPeriodQuarterly:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, date.Location())
+	case BudgetPeriodAnnual:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, date.Location())
+	default:
+		return time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+	}
+}
+
 type BudgetRepository struct {
 	DB *gorm.DB
 }
@@ -22,6 +89,26 @@ func (*BudgetRepository) GetAllBudgets() ([]Budget, error) {
 	return budgets, result.Error
 }
 
+// GetBudgetsInForce returns the budgets that apply on the date, in YYYY-MM-DD format
+func (br *BudgetRepository) GetBudgetsInForce(date string) ([]Budget, error) {
+	var budgets []Budget
+	result := br.DB.Preload(clause.Associations).
+		Where(`coalesce(start_date, '') = '' OR start_date <= ?`, date).
+		Where(`coalesce(end_date, '') = '' OR end_date >= ?`, date).
+		Find(&budgets)
+	return budgets, result.Error
+}
+
+// GetBudgetsForCategory returns every budget a category has had, oldest first
+func (br *BudgetRepository) GetBudgetsForCategory(categoryID uint) ([]Budget, error) {
+	var budgets []Budget
+	result := br.DB.Preload(clause.Associations).
+		Where("category_id = ?", categoryID).
+		Order(`coalesce(start_date, '') asc`).
+		Find(&budgets)
+	return budgets, result.Error
+}
+
 func (*BudgetRepository) GetBudgetByID(id uint) (Budget, error) {
 	var budget Budget
 	result := db.Preload(clause.Associations).Where("id = ?", id).First(&budget)
@@ -35,7 +122,93 @@ func (*BudgetRepository) Save(budget Budget) (id uint, err error) {
 	return budget.ID, result.Error
 }
 
+// CreateBudgets saves several new budgets at once, so either all of them are created or none are
+func (br *BudgetRepository) CreateBudgets(budgets []Budget) error {
+	if len(budgets) == 0 {
+		return nil
+	}
+	return br.DB.Create(&budgets).Error
+}
+
+// Supersede replaces a budget from the replacement's start date onwards. The old budget ends the day before,
+// and any of the category's budgets that would have started on or after that date are deleted, since the
+// replacement takes their place. Returns the ID of the replacement and an optional error.
+func (br *BudgetRepository) Supersede(old Budget, replacement Budget) (id uint, err error) {
+	err = br.DB.Transaction(func(tx *gorm.DB) error {
+		replacement, err = supersedeBudget(tx, old, replacement)
+		return err
+	})
+	return replacement.ID, err
+}
+
+// supersedeBudget does the work of Supersede inside a database transaction that's already been started
+func supersedeBudget(tx *gorm.DB, old Budget, replacement Budget) (Budget, error) {
+	startDate, err := time.Parse("2006-01-02", replacement.StartDate)
+	if err != nil {
+		return replacement, err
+	}
+	dayBefore := startDate.AddDate(0, 0, -1).Format("2006-01-02")
+
+	err = tx.Where("category_id = ? AND start_date >= ?", old.CategoryID, replacement.StartDate).Delete(&Budget{}).Error
+	if err != nil {
+		return replacement, err
+	}
+	if old.EndDate == "" || old.EndDate > dayBefore {
+		if err := tx.Model(&old).Update("end_date", dayBefore).Error; err != nil {
+			return replacement, err
+		}
+	}
+	replacement.EndDate = ""
+	err = tx.Omit(clause.Associations).Create(&replacement).Error
+	return replacement, err
+}
+
+// clipBudget returns the parts of a budget's date range that none of the other budgets cover, as copies of
+// the budget. Empty start and end dates are open ended, like they are for budgets.
+func clipBudget(budget Budget, others []Budget) []Budget {
+	pieces := []Budget{budget}
+	for _, other := range others {
+		remaining := []Budget{}
+		for _, piece := range pieces {
+			overlaps := (piece.StartDate == "" || other.EndDate == "" || piece.StartDate <= other.EndDate) &&
+				(other.StartDate == "" || piece.EndDate == "" || other.StartDate <= piece.EndDate)
+			if !overlaps {
+				remaining = append(remaining, piece)
+				continue
+			}
+			if other.StartDate != "" && (piece.StartDate == "" || piece.StartDate < other.StartDate) {
+				before := piece
+				before.EndDate = addDays(other.StartDate, -1)
+				remaining = append(remaining, before)
+			}
+			if other.EndDate != "" && (piece.EndDate == "" || piece.EndDate > other.EndDate) {
+				after := piece
+				after.StartDate = addDays(other.EndDate, 1)
+				remaining = append(remaining, after)
+			}
+		}
+		pieces = remaining
+	}
+	return pieces
+}
+
+// addDays moves a YYYY-MM-DD date by a number of days
+func addDays(date string, days int) string {
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return parsed.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// DeleteByID deletes a budget along with the category's earlier and later budgets, so the category no
+// longer has a budget at all
 func (br *BudgetRepository) DeleteByID(budgetID uint) (err error) {
-	result := br.DB.Delete(&Budget{}, budgetID)
+	var budget Budget
+	result := br.DB.Where("id = ?", budgetID).First(&budget)
+	if result.Error != nil {
+		return result.Error
+	}
+	result = br.DB.Where("category_id = ?", budget.CategoryID).Delete(&Budget{})
 	return result.Error
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudgetPeriodStart(t *testing.T) {
+	date := time.Date(2026, 8, 19, 15, 30, 0, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		period   string
+		expected time.Time
+	}{
+		{"weekly", time.Date(2026, 8, 17, 0, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"quarterly", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"annual", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if start := BudgetPeriodStart(tt.period, date); !start.Equal(tt.expected) {
+			t.Errorf("%v: expected %v, got %v", tt.period, tt.expected, start)
+		}
+	}
+	// Sundays belong to the week that started the Monday before
+	sunday := time.Date(2026, 8, 23, 0, 0, 0, 0, time.UTC)
+	if start := BudgetPeriodStart("weekly", sunday); !start.Equal(time.Date(2026, 8, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected Sunday's week to start on Monday the 17th, got %v", start)
+	}
+}
+
+func TestClipBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		others   []Budget
+		expected [][2]string
+	}{
+		{"no other budgets", Budget{StartDate: "2026-01-01"}, nil, [][2]string{{"2026-01-01", ""}}},
+		{"no overlap", Budget{StartDate: "2026-01-01", EndDate: "2026-03-31"}, []Budget{{StartDate: "2026-04-01"}}, [][2]string{{"2026-01-01", "2026-03-31"}}},
+		{"other covers the middle", Budget{}, []Budget{{StartDate: "2026-02-01", EndDate: "2026-02-28"}}, [][2]string{{"", "2026-01-31"}, {"2026-03-01", ""}}},
+		{"other covers the start", Budget{StartDate: "2026-01-01"}, []Budget{{EndDate: "2026-05-31"}}, [][2]string{{"2026-06-01", ""}}},
+		{"others cover everything", Budget{StartDate: "2026-01-01", EndDate: "2026-12-31"}, []Budget{{EndDate: "2026-06-30"}, {StartDate: "2026-07-01"}}, [][2]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := clipBudget(tt.budget, tt.others)
+			if len(pieces) != len(tt.expected) {
+				t.Fatalf("Expected %d pieces, got %+v", len(tt.expected), pieces)
+			}
+			for i, piece := range pieces {
+				if piece.StartDate != tt.expected[i][0] || piece.EndDate != tt.expected[i][1] {
+					t.Errorf("Expected %v, got %q to %q", tt.expected[i], piece.StartDate, piece.EndDate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CategorizerModel persists the state of a trained categorization model so it
+// doesn't have to be rebuilt from every training transaction on startup
+type CategorizerModel struct {
+	gorm.Model
+	Backend     string `gorm:"uniqueIndex"` // name of the categorizer backend that produced the model
+	TrainingSet string // JSON-encoded training data for the backend
+	SampleCount int
+}
+
+type CategorizerModelRepository struct {
+	DB *gorm.DB
+}
+
+func (cmr *CategorizerModelRepository) GetCategorizerModel(backend string) (CategorizerModel, error) {
+	var model CategorizerModel
+	result := cmr.DB.Where("backend = ?", backend).First(&model)
+	return model, result.Error
+}
+
+// Save is an UPSERT operation keyed on the backend name, returning the ID of the record and an optional error
+func (cmr *CategorizerModelRepository) Save(model CategorizerModel) (id uint, err error) {
+	result := cmr.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "backend"}},
+		DoUpdates: clause.AssignmentColumns([]string{"training_set", "sample_count", "updated_at"}),
+	}).Create(&model)
+	return model.ID, result.Error
+}
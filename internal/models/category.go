@@ -1,15 +1,74 @@
 package models
 
 import (
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// Category kinds decide how reports treat a category's transactions, regardless of its name
+const (
+	CategoryKindExpense  = "expense"
+	CategoryKindIncome   = "income"
+	CategoryKindTransfer = "transfer" // Money moving between accounts, which is neither income nor spending
+	CategoryKindSavings  = "savings"  // Contributions to savings and investments
+)
+
+// CategoryKinds lists every kind along with a label, in the order they're offered to users
+var CategoryKinds = []struct {
+	Kind  string
+	Label string
+}{
+	{CategoryKindExpense, "Expense"},
+	{CategoryKindIncome, "Income"},
+	{CategoryKindTransfer, "Transfer"},
+	{CategoryKindSavings, "Savings & investments"},
+}
+
+func IsValidCategoryKind(kind string) bool {
+	for _, categoryKind := range CategoryKinds {
+		if categoryKind.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Categories form a tree. Top level categories have no ParentID, and reports and budgets roll the
+// transactions of subcategories up into their parents.
 type Category struct {
 	gorm.Model
-	Name string `gorm:"uniqueIndex"`
+	Name     string `gorm:"uniqueIndex"`
+	ParentID *uint
+	Kind     string
 }
 
+// IsExpense returns true if the category's transactions are spending. Categories without a kind are treated
+// as expenses, matching expenseCategoryIDsSQL.
+func (c Category) IsExpense() bool {
+	return c.Kind != CategoryKindIncome && c.Kind != CategoryKindTransfer && c.Kind != CategoryKindSavings
+}
+
+// categorySubtreeSQL selects the ID of a category and all of its descendants, for rolling subcategories
+// up into their parents. It takes the category ID as its only parameter.
+const categorySubtreeSQL = `WITH RECURSIVE subtree(id) AS (
+		SELECT ?
+		UNION
+		SELECT child.id FROM categories child JOIN subtree ON child.parent_id = subtree.id
+		WHERE child.deleted_at IS NULL
+	)
+	SELECT id FROM subtree`
+
+// incomeCategoryIDsSQL selects the IDs of the income categories
+const incomeCategoryIDsSQL = `SELECT id FROM categories WHERE kind = '` + CategoryKindIncome + `'`
+
+// expenseCategoryIDsSQL selects the IDs of the categories that are spending. Categories without a kind
+// are treated as expenses.
+const expenseCategoryIDsSQL = `SELECT id FROM categories
+	WHERE coalesce(kind, '') NOT IN ('` + CategoryKindIncome + `', '` + CategoryKindTransfer + `', '` + CategoryKindSavings + `')`
+
 type CategoryAndBudgetStatus struct {
 	Category
 	HasBudget bool
@@ -29,12 +88,16 @@ func (cr *CategoryRepository) GetAllCategoriesAndBudgetStatus() (categories []Ca
 	cr.DB.Raw(`SELECT
 		c.ID,
 		c.Name,
-		CASE
-			WHEN b.amount IS NOT NULL THEN true
-			ELSE false
-		END AS has_budget
+		c.parent_id,
+		c.kind,
+		EXISTS (
+			SELECT 1 FROM budgets b
+			WHERE b.category_id = c.ID
+			AND b.deleted_at IS NULL
+			AND (coalesce(b.start_date, '') = '' OR b.start_date <= date('now', 'localtime'))
+			AND (coalesce(b.end_date, '') = '' OR b.end_date >= date('now', 'localtime'))
+		) AS has_budget
 		FROM categories c
-		LEFT JOIN budgets b ON c.ID = b.category_id and b.deleted_at IS NULL
 		WHERE c.deleted_at IS NULL;`).Scan(&categories)
 	return categories, nil
 }
@@ -45,6 +108,13 @@ func (cr *CategoryRepository) GetCategoryByID(id uint) (Category, error) {
 	return category, result.Error
 }
 
+// GetCategoriesByKind returns the categories of a kind in the order they were created
+func (cr *CategoryRepository) GetCategoriesByKind(kind string) ([]Category, error) {
+	var categories []Category
+	result := cr.DB.Where("kind = ?", kind).Order("id asc").Find(&categories)
+	return categories, result.Error
+}
+
 func (cr *CategoryRepository) GetCategoryByName(name string) (Category, error) {
 	var category Category
 	result := db.Where("name = ?", name).First(&category)
@@ -57,12 +127,178 @@ func (cr *CategoryRepository) Save(category Category) (id uint, err error) {
 	return category.ID, result.Error
 }
 
-// Soft deletes a category and sets all associated transactions to Unknown
+// Soft deletes a category and sets all associated transactions to Unknown. Subcategories are moved
+// up to the deleted category's parent.
 func (cr *CategoryRepository) DeleteCategoryByID(categoryID uint) (err error) {
-	// delete any associated budgets
-	cr.DB.Where("category_id = ?", categoryID).Delete(&Budget{})
-	// bulk update all transactions to "Unknown" category
-	cr.DB.Model(&Transaction{}).Where("category_id = ?", categoryID).Update("category_id", 1)
-	result := cr.DB.Delete(&Category{}, categoryID)
-	return result.Error
+	return cr.DB.Transaction(func(tx *gorm.DB) error {
+		var category Category
+		if err := tx.Where("id = ?", categoryID).First(&category).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Category{}).Where("parent_id = ?", categoryID).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		// delete any associated budgets
+		if err := tx.Where("category_id = ?", categoryID).Delete(&Budget{}).Error; err != nil {
+			return err
+		}
+		// bulk update all transactions to "Unknown" category
+		if err := tx.Model(&Transaction{}).Where("category_id = ?", categoryID).Update("category_id", 1).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&TransactionSplit{}).Where("category_id = ?", categoryID).Update("category_id", 1).Error; err != nil {
+			return err
+		}
+		if err := remapTransferPairCategories(tx, categoryID, 1); err != nil {
+			return err
+		}
+		return tx.Delete(&Category{}, categoryID).Error
+	})
+}
+
+// CategoryMergeCounts are the rows that merging a category moves into another category
+type CategoryMergeCounts struct {
+	Transactions int64
+	// Transactions categorized by hand, which the categorizer is trained on. These are also counted in Transactions.
+	TrainingLabels int64
+	Splits         int64
+	Budgets        int64
+	Subcategories  int64
+}
+
+// GetCategoryMergeCounts counts the rows that would be moved if the category was merged into another
+func (cr *CategoryRepository) GetCategoryMergeCounts(categoryID uint) (counts CategoryMergeCounts, err error) {
+	err = cr.DB.Model(&Transaction{}).Where("category_id = ?", categoryID).Count(&counts.Transactions).Error
+	if err != nil {
+		return counts, err
+	}
+	err = cr.DB.Model(&Transaction{}).Where("category_id = ? AND use_for_training = ?", categoryID, true).Count(&counts.TrainingLabels).Error
+	if err != nil {
+		return counts, err
+	}
+	err = cr.DB.Model(&TransactionSplit{}).Where("category_id = ?", categoryID).Count(&counts.Splits).Error
+	if err != nil {
+		return counts, err
+	}
+	err = cr.DB.Model(&Budget{}).Where("category_id = ?", categoryID).Count(&counts.Budgets).Error
+	if err != nil {
+		return counts, err
+	}
+	err = cr.DB.Model(&Category{}).Where("parent_id = ?", categoryID).Count(&counts.Subcategories).Error
+	return counts, err
+}
+
+// ErrMergeIntoSubtree is returned when a category would be merged into itself or one of its subcategories
+var ErrMergeIntoSubtree = errors.New("a category can't be merged into itself or one of its subcategories")
+
+// MergeCategories moves every transaction, split, budget and subcategory from the source category into the
+// target category and then soft deletes the source, all in a single database transaction. If both categories
+// have a budget in force, the source's amount is added to the target's budget from the start of the current
+// period. Otherwise the source's budgets are moved to the dates the target had no budget. The target keeps its
+// own name, parent and kind, and can't be the source or one of its subcategories.
+func (cr *CategoryRepository) MergeCategories(sourceID uint, targetID uint) (counts CategoryMergeCounts, err error) {
+	err = cr.DB.Transaction(func(tx *gorm.DB) error {
+		var source, target Category
+		if err := tx.Where("id = ?", sourceID).First(&source).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", targetID).First(&target).Error; err != nil {
+			return err
+		}
+		// Moving the subcategories underneath one of themselves would create a cycle
+		var targetInSubtree int64
+		err := tx.Raw(`SELECT count(*) FROM (`+categorySubtreeSQL+`) WHERE id = ?`, sourceID, targetID).Scan(&targetInSubtree).Error
+		if err != nil {
+			return err
+		}
+		if targetInSubtree > 0 {
+			return ErrMergeIntoSubtree
+		}
+
+		// Count training labels before they're moved, since the transactions are indistinguishable afterwards
+		err = tx.Model(&Transaction{}).Where("category_id = ? AND use_for_training = ?", sourceID, true).Count(&counts.TrainingLabels).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&Transaction{}).Where("category_id = ?", sourceID).Update("category_id", targetID)
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Transactions = result.RowsAffected
+		result = tx.Model(&TransactionSplit{}).Where("category_id = ?", sourceID).Update("category_id", targetID)
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Splits = result.RowsAffected
+		if err := remapTransferPairCategories(tx, sourceID, targetID); err != nil {
+			return err
+		}
+
+		var sourceBudgets, targetBudgets []Budget
+		if err := tx.Where("category_id = ?", sourceID).Find(&sourceBudgets).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("category_id = ?", targetID).Find(&targetBudgets).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		today := now.Format("2006-01-02")
+		var targetBudget *Budget
+		for i := range targetBudgets {
+			if targetBudgets[i].InForceOn(today) {
+				targetBudget = &targetBudgets[i]
+			}
+		}
+		if targetBudget != nil {
+			// Only the budget in force today is added to the target's, converted to the target's period. The
+			// combined amount starts with the current period, so past periods keep the amounts they had.
+			amount := 0
+			for _, sourceBudget := range sourceBudgets {
+				if sourceBudget.InForceOn(today) {
+					amount += sourceBudget.AmountPer(targetBudget.Period)
+				}
+			}
+			startDate := BudgetPeriodStart(targetBudget.Period, now).Format("2006-01-02")
+			if targetBudget.StartDate > startDate {
+				startDate = targetBudget.StartDate
+			}
+			if amount != 0 {
+				_, err := supersedeBudget(tx, *targetBudget, Budget{
+					Amount:     targetBudget.Amount + amount,
+					Period:     targetBudget.Period,
+					Rollover:   targetBudget.Rollover,
+					StartDate:  startDate,
+					CategoryID: targetID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			// The source's budgets only apply to the target when it had no budget of its own, so no two
+			// budgets apply at once
+			for _, sourceBudget := range sourceBudgets {
+				for _, piece := range clipBudget(sourceBudget, targetBudgets) {
+					piece.ID = 0
+					piece.CategoryID = targetID
+					if err := tx.Omit(clause.Associations).Create(&piece).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err := tx.Where("category_id = ?", sourceID).Delete(&Budget{}).Error; err != nil {
+			return err
+		}
+		counts.Budgets = int64(len(sourceBudgets))
+
+		result = tx.Model(&Category{}).Where("parent_id = ?", sourceID).Update("parent_id", targetID)
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Subcategories = result.RowsAffected
+
+		return tx.Delete(&Category{}, sourceID).Error
+	})
+	return counts, err
 }
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCategoryIsExpense(t *testing.T) {
+	tests := []struct {
+		kind     string
+		expected bool
+	}{
+		{CategoryKindExpense, true},
+		{"", true},
+		{CategoryKindIncome, false},
+		{CategoryKindTransfer, false},
+		{CategoryKindSavings, false},
+	}
+
+	for _, tt := range tests {
+		if got := (Category{Kind: tt.kind}).IsExpense(); got != tt.expected {
+			t.Errorf("Expected IsExpense of %q to be %v, got %v", tt.kind, tt.expected, got)
+		}
+	}
+	if !IsValidCategoryKind(CategoryKindSavings) || IsValidCategoryKind("") || IsValidCategoryKind("Income") {
+		t.Errorf("Expected only the CategoryKind constants to be valid")
+	}
+}
+
+func TestGetCategoriesByKind(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	salary := createTestCategory(t, testDB, "Salary", CategoryKindIncome, nil)
+	createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	bonus := createTestCategory(t, testDB, "Bonus", CategoryKindIncome, nil)
+
+	categories, err := categoryRepository.GetCategoriesByKind(CategoryKindIncome)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(categories) != 2 || categories[0].ID != salary.ID || categories[1].ID != bonus.ID {
+		t.Errorf("Expected Salary then Bonus, got %+v", categories)
+	}
+}
+
+func TestSumsByCategoryKind(t *testing.T) {
+	testDB := newTestDB(t)
+	transactionRepository := &TransactionRepository{DB: testDB}
+	// Named like the categories that used to be left out by name, to show the kind is what counts
+	food := createTestCategory(t, testDB, "Transfers", CategoryKindExpense, nil)
+	kindless := createTestCategory(t, testDB, "Uncategorized", "", nil)
+	paycheck := createTestCategory(t, testDB, "Paycheck", CategoryKindIncome, nil)
+	creditCardPayment := createTestCategory(t, testDB, "Credit card payment", CategoryKindTransfer, nil)
+	retirement := createTestCategory(t, testDB, "401k", CategoryKindSavings, nil)
+	for _, transaction := range []Transaction{
+		{Date: "2026-03-01", Description: "Groceries", Amount: 5000, CategoryID: food.ID},
+		{Date: "2026-03-02", Description: "Mystery", Amount: 1000, CategoryID: kindless.ID},
+		{Date: "2026-03-03", Description: "Paycheck", Amount: 300000, CategoryID: paycheck.ID},
+		{Date: "2026-03-04", Description: "Card payment", Amount: 70000, CategoryID: creditCardPayment.ID},
+		{Date: "2026-03-05", Description: "401k contribution", Amount: 50000, CategoryID: retirement.ID},
+	} {
+		createTestTransaction(t, testDB, transaction)
+	}
+	startDate := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	totals, err := transactionRepository.GetSumOfTransactionsByCategory(startDate, endDate)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(totals) != 2 || totals[0].CategoryID != food.ID || totals[1].CategoryID != kindless.ID {
+		t.Errorf("Expected spending in only the expense and kindless categories, got %+v", totals)
+	}
+
+	for kind, expected := range map[string]int{CategoryKindIncome: 300000, CategoryKindTransfer: 70000, CategoryKindSavings: 50000} {
+		sum, err := transactionRepository.GetSumOfTransactionsByKind(kind, startDate, endDate)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if sum != expected {
+			t.Errorf("Expected %d of %s, got %d", expected, kind, sum)
+		}
+	}
+}
+
+func TestBackfillCategoryKinds(t *testing.T) {
+	testDB := newTestDB(t)
+	income := createTestCategory(t, testDB, "Income", "", nil)
+	bonus := createTestCategory(t, testDB, "Bonus", "", &income.ID)
+	transfers := createTestCategory(t, testDB, "Transfers", "", nil)
+	food := createTestCategory(t, testDB, "Food", "", nil)
+	// Already has a kind, so it's left alone
+	refunds := createTestCategory(t, testDB, "Refunds", CategoryKindExpense, &income.ID)
+
+	NewBootstrapper(testDB).backfillCategoryKinds()
+
+	expected := map[uint]string{
+		income.ID:    CategoryKindIncome,
+		bonus.ID:     CategoryKindIncome,
+		transfers.ID: CategoryKindTransfer,
+		food.ID:      CategoryKindExpense,
+		refunds.ID:   CategoryKindExpense,
+	}
+	for id, kind := range expected {
+		var category Category
+		testDB.First(&category, id)
+		if category.Kind != kind {
+			t.Errorf("Expected %s to be %s, got %q", category.Name, kind, category.Kind)
+		}
+	}
+}
+
+func TestMergeCategories(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	dining := createTestCategory(t, testDB, "Dining", CategoryKindExpense, nil)
+	restaurants := createTestCategory(t, testDB, "Restaurants", CategoryKindExpense, nil)
+	takeout := createTestCategory(t, testDB, "Takeout", CategoryKindExpense, &dining.ID)
+	labelled := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Pizza", Amount: 2500, CategoryID: dining.ID, UseForTraining: true})
+	createTestTransaction(t, testDB, Transaction{Date: "2026-03-02", Description: "Burgers", Amount: 1500, CategoryID: dining.ID})
+	split := TransactionSplit{TransactionID: labelled.ID, CategoryID: dining.ID, Amount: 1000}
+	testDB.Create(&split)
+	budget := Budget{Amount: 30000, Period: "monthly", CategoryID: dining.ID}
+	testDB.Omit("Category").Create(&budget)
+
+	counts, err := categoryRepository.GetCategoryMergeCounts(dining.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := CategoryMergeCounts{Transactions: 2, TrainingLabels: 1, Splits: 1, Budgets: 1, Subcategories: 1}
+	if counts != expected {
+		t.Errorf("Expected counts %+v before merging, got %+v", expected, counts)
+	}
+
+	counts, err = categoryRepository.MergeCategories(dining.ID, restaurants.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if counts != expected {
+		t.Errorf("Expected counts %+v after merging, got %+v", expected, counts)
+	}
+
+	var transactionCount, splitCount, budgetCount int64
+	testDB.Model(&Transaction{}).Where("category_id = ?", restaurants.ID).Count(&transactionCount)
+	testDB.Model(&TransactionSplit{}).Where("category_id = ?", restaurants.ID).Count(&splitCount)
+	testDB.Model(&Budget{}).Where("category_id = ? AND amount = ?", restaurants.ID, 30000).Count(&budgetCount)
+	if transactionCount != 2 || splitCount != 1 || budgetCount != 1 {
+		t.Errorf("Expected 2 transactions, 1 split and 1 budget in Restaurants, got %d, %d and %d", transactionCount, splitCount, budgetCount)
+	}
+	var subcategory Category
+	testDB.First(&subcategory, takeout.ID)
+	if subcategory.ParentID == nil || *subcategory.ParentID != restaurants.ID {
+		t.Errorf("Expected Takeout to move under Restaurants, got parent %v", subcategory.ParentID)
+	}
+	if _, err := categoryRepository.GetCategoryByID(dining.ID); err == nil {
+		t.Errorf("Expected Dining to be deleted")
+	}
+}
+
+func TestMergeCategories_IntoSubtree(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	dining := createTestCategory(t, testDB, "Dining", CategoryKindExpense, nil)
+	takeout := createTestCategory(t, testDB, "Takeout", CategoryKindExpense, &dining.ID)
+	pizza := createTestCategory(t, testDB, "Pizza", CategoryKindExpense, &takeout.ID)
+	createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Pizza", Amount: 2500, CategoryID: dining.ID})
+
+	for _, target := range []Category{dining, takeout, pizza} {
+		_, err := categoryRepository.MergeCategories(dining.ID, target.ID)
+		if !errors.Is(err, ErrMergeIntoSubtree) {
+			t.Errorf("Expected merging Dining into %s to fail with ErrMergeIntoSubtree, got %v", target.Name, err)
+		}
+	}
+
+	// Nothing is moved when the merge is rejected
+	var transactionCount int64
+	testDB.Model(&Transaction{}).Where("category_id = ?", dining.ID).Count(&transactionCount)
+	if transactionCount != 1 {
+		t.Errorf("Expected the transaction to stay in Dining, got %d", transactionCount)
+	}
+	if _, err := categoryRepository.GetCategoryByID(dining.ID); err != nil {
+		t.Errorf("Expected Dining to still exist, got %v", err)
+	}
+}
+
+func TestMergeCategories_CombinesBudgets(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	dining := createTestCategory(t, testDB, "Dining", CategoryKindExpense, nil)
+	restaurants := createTestCategory(t, testDB, "Restaurants", CategoryKindExpense, nil)
+	testDB.Create(&Budget{Amount: 120000, Period: BudgetPeriodAnnual, CategoryID: dining.ID})
+	testDB.Create(&Budget{Amount: 30000, Period: BudgetPeriodMonthly, StartDate: "2020-01-01", CategoryID: restaurants.ID})
+
+	if _, err := categoryRepository.MergeCategories(dining.ID, restaurants.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var budgets []Budget
+	testDB.Where("category_id = ?", restaurants.ID).Order("start_date asc").Find(&budgets)
+	thisMonth := BudgetPeriodStart(BudgetPeriodMonthly, time.Now())
+	if len(budgets) != 2 {
+		t.Fatalf("Expected the old budget and the combined one, got %+v", budgets)
+	}
+	if budgets[0].Amount != 30000 || budgets[0].EndDate != thisMonth.AddDate(0, 0, -1).Format("2006-01-02") {
+		t.Errorf("Expected the old budget to end before this month, got %+v", budgets[0])
+	}
+	// $1,200 a year is $100 a month
+	if budgets[1].Amount != 40000 || budgets[1].StartDate != thisMonth.Format("2006-01-02") || budgets[1].EndDate != "" {
+		t.Errorf("Expected a $400 budget from this month on, got %+v", budgets[1])
+	}
+}
+
+func TestMergeCategories_ClipsBudgets(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	dining := createTestCategory(t, testDB, "Dining", CategoryKindExpense, nil)
+	restaurants := createTestCategory(t, testDB, "Restaurants", CategoryKindExpense, nil)
+	testDB.Create(&Budget{Amount: 20000, Period: BudgetPeriodMonthly, StartDate: "2020-01-01", CategoryID: dining.ID})
+	// The target's budget ended, so it has none in force
+	testDB.Create(&Budget{Amount: 30000, Period: BudgetPeriodMonthly, StartDate: "2021-01-01", EndDate: "2021-12-31", CategoryID: restaurants.ID})
+
+	if _, err := categoryRepository.MergeCategories(dining.ID, restaurants.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var budgets []Budget
+	testDB.Where("category_id = ?", restaurants.ID).Order("start_date asc").Find(&budgets)
+	expected := [][3]string{{"2020-01-01", "2020-12-31", "20000"}, {"2021-01-01", "2021-12-31", "30000"}, {"2022-01-01", "", "20000"}}
+	if len(budgets) != len(expected) {
+		t.Fatalf("Expected %d budgets, got %+v", len(expected), budgets)
+	}
+	for i, budget := range budgets {
+		if budget.StartDate != expected[i][0] || budget.EndDate != expected[i][1] || fmt.Sprint(budget.Amount) != expected[i][2] {
+			t.Errorf("Expected %v, got %+v", expected[i], budget)
+		}
+	}
+	var sourceBudgets int64
+	testDB.Model(&Budget{}).Where("category_id = ?", dining.ID).Count(&sourceBudgets)
+	if sourceBudgets != 0 {
+		t.Errorf("Expected Dining's budgets to be gone, got %d", sourceBudgets)
+	}
+}
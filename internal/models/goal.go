@@ -0,0 +1,58 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Goal is something being saved up for, like a down payment, in one or more accounts. Progress towards the
+// goal is the sum of the latest balances of its accounts.
+type Goal struct {
+	gorm.Model
+	Name         string
+	TargetAmount int
+	// TargetDate is when the goal should be reached, in YYYY-MM-DD format
+	TargetDate string
+	Accounts   []Account `gorm:"many2many:goal_accounts;"`
+}
+
+type GoalRepository struct {
+	DB *gorm.DB
+}
+
+// GetAllGoals returns every goal with its accounts, soonest target date first
+func (gr *GoalRepository) GetAllGoals() ([]Goal, error) {
+	var goals []Goal
+	result := gr.DB.Preload("Accounts").Order("target_date asc, name asc").Find(&goals)
+	return goals, result.Error
+}
+
+func (gr *GoalRepository) GetGoalByID(id uint) (Goal, error) {
+	var goal Goal
+	result := gr.DB.Preload("Accounts").Where("id = ?", id).First(&goal)
+	return goal, result.Error
+}
+
+// Save is an UPSERT operation, returning the ID of the record and an optional error. The goal's accounts
+// are replaced with goal.Accounts.
+func (gr *GoalRepository) Save(goal Goal) (id uint, err error) {
+	accounts := goal.Accounts
+	goal.Accounts = nil
+	err = gr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&goal).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&goal).Association("Accounts").Replace(accounts)
+	})
+	return goal.ID, err
+}
+
+func (gr *GoalRepository) DeleteGoalByID(id uint) error {
+	goal := Goal{Model: gorm.Model{ID: id}}
+	return gr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&goal).Association("Accounts").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&goal).Error
+	})
+}
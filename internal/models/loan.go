@@ -0,0 +1,54 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Loan is the terms of a loan account, which its amortization schedule is generated from
+type Loan struct {
+	gorm.Model
+	AccountID uint `gorm:"uniqueIndex"`
+	Account   Account
+	Principal int
+	// InterestRate is the annual interest rate as a percentage, e.g. 6.5
+	InterestRate float64
+	TermMonths   int
+	// StartDate is when the loan started, in YYYY-MM-DD format. The first payment is due a month later.
+	StartDate string
+}
+
+type LoanRepository struct {
+	DB *gorm.DB
+}
+
+// GetAllLoans returns every loan with its account, in the order they were added
+func (lr *LoanRepository) GetAllLoans() ([]Loan, error) {
+	var loans []Loan
+	result := lr.DB.Preload("Account").Order("id asc").Find(&loans)
+	return loans, result.Error
+}
+
+func (lr *LoanRepository) GetLoanByID(id uint) (Loan, error) {
+	var loan Loan
+	result := lr.DB.Preload("Account").Where("id = ?", id).First(&loan)
+	return loan, result.Error
+}
+
+// GetLoanByAccountID returns the loan of an account, or gorm.ErrRecordNotFound if its terms haven't been entered
+func (lr *LoanRepository) GetLoanByAccountID(accountID uint) (Loan, error) {
+	var loan Loan
+	result := lr.DB.Preload("Account").Where("account_id = ?", accountID).First(&loan)
+	return loan, result.Error
+}
+
+// Save is an UPSERT operation, returning the ID of the record and an optional error
+func (lr *LoanRepository) Save(loan Loan) (id uint, err error) {
+	loan.Account = Account{}
+	result := lr.DB.Omit("Account").Save(&loan)
+	return loan.ID, result.Error
+}
+
+// DeleteLoanByID deletes a loan for good, so its account's terms can be entered again
+func (lr *LoanRepository) DeleteLoanByID(id uint) error {
+	return lr.DB.Unscoped().Delete(&Loan{}, id).Error
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MerchantPattern is a user-defined rule for normalizing transaction descriptions. When Pattern
+// (a case-insensitive regular expression) matches a description, the transaction's merchant
+// name is set to MerchantName instead of the result of the built-in clean up.
+type MerchantPattern struct {
+	gorm.Model
+	Pattern      string `gorm:"uniqueIndex"`
+	MerchantName string
+}
+
+type MerchantPatternRepository struct {
+	DB *gorm.DB
+}
+
+// GetAllMerchantPatterns returns patterns in the order they were created, which is the order they are applied in
+func (mpr *MerchantPatternRepository) GetAllMerchantPatterns() ([]MerchantPattern, error) {
+	var patterns []MerchantPattern
+	result := mpr.DB.Order("id asc").Find(&patterns)
+	return patterns, result.Error
+}
+
+// Save is an UPSERT operation, returning the ID of the record and an optional error
+func (mpr *MerchantPatternRepository) Save(pattern MerchantPattern) (id uint, err error) {
+	result := mpr.DB.Save(&pattern).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}})
+	return pattern.ID, result.Error
+}
+
+// DeleteMerchantPatternByID permanently deletes a pattern so the same pattern can be added again later
+func (mpr *MerchantPatternRepository) DeleteMerchantPatternByID(id uint) error {
+	result := mpr.DB.Unscoped().Delete(&MerchantPattern{}, id)
+	return result.Error
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Notification is an alert shown in the notification center
+type Notification struct {
+	gorm.Model
+	AlertRuleID uint
+	Title       string
+	Message     string
+	// Link is a page with more detail, e.g. the budget that was exceeded
+	Link string
+	// Key identifies what the notification is about, so the same alert isn't raised twice, e.g. a budget
+	// reaching 80% in a particular month
+	Key  string `gorm:"index"`
+	Read bool
+}
+
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+// GetRecentNotifications returns the latest notifications, newest first
+func (nr *NotificationRepository) GetRecentNotifications(limit int) ([]Notification, error) {
+	var notifications []Notification
+	result := nr.DB.Order("created_at desc, id desc").Limit(limit).Find(&notifications)
+	return notifications, result.Error
+}
+
+func (nr *NotificationRepository) CountUnreadNotifications() (count int64, err error) {
+	result := nr.DB.Model(&Notification{}).Where("read = ?", false).Count(&count)
+	return count, result.Error
+}
+
+// ExistsByKey returns true if a notification with the key was ever raised, even if it's been dismissed since
+func (nr *NotificationRepository) ExistsByKey(key string) (bool, error) {
+	var count int64
+	result := nr.DB.Unscoped().Model(&Notification{}).Where("key = ?", key).Count(&count)
+	return count > 0, result.Error
+}
+
+// Create saves a new notification, returning its ID and an optional error
+func (nr *NotificationRepository) Create(notification Notification) (id uint, err error) {
+	result := nr.DB.Create(&notification)
+	return notification.ID, result.Error
+}
+
+func (nr *NotificationRepository) MarkAllNotificationsRead() error {
+	result := nr.DB.Model(&Notification{}).Where("read = ?", false).Update("read", true)
+	return result.Error
+}
+
+func (nr *NotificationRepository) DeleteNotificationByID(id uint) error {
+	result := nr.DB.Delete(&Notification{}, id)
+	return result.Error
+}
@@ -6,7 +6,12 @@ import (
 
 type Settings struct {
 	gorm.Model
-	LaunchBrowserOnStartup bool `gorm:"default:true"`
+	LaunchBrowserOnStartup bool   `gorm:"default:true"`
+	CategorizerBackend     string `gorm:"default:bayes"`
+	// New notifications are also posted to this, if it's set
+	AlertWebhookURL string
+	// Month the fiscal year starts in, from 1 for January to 12 for December
+	FiscalYearStartMonth int `gorm:"default:1"`
 }
 
 type SettingsRepository struct {
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Tag labels transactions with something that cuts across categories, like a trip or a person
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex"`
+}
+
+type TotalByTag struct {
+	Amount int
+	TagID  uint
+	Tag    string
+}
+
+type TagRepository struct {
+	DB *gorm.DB
+}
+
+func (tr *TagRepository) GetAllTags() ([]Tag, error) {
+	var tags []Tag
+	result := tr.DB.Order("name asc").Find(&tags)
+	return tags, result.Error
+}
+
+// GetOrCreateTags returns the tags with the given names, creating any that don't exist yet. Names are
+// matched case-insensitively, so "Reimbursable" and "reimbursable" are the same tag.
+func (tr *TagRepository) GetOrCreateTags(names []string) ([]Tag, error) {
+	tags := []Tag{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var tag Tag
+		result := tr.DB.Where("LOWER(name) = LOWER(?)", name).Limit(1).Find(&tag)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if tag.ID == 0 {
+			tag = Tag{Name: name}
+			result = tr.DB.Create(&tag)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// ReplaceTransactionTags sets the tags of a transaction, removing any it had before
+func (tr *TagRepository) ReplaceTransactionTags(transactionID uint, tags []Tag) error {
+	transaction := Transaction{Model: gorm.Model{ID: transactionID}}
+	return tr.DB.Model(&transaction).Association("Tags").Replace(tags)
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetOrCreateTags(t *testing.T) {
+	testDB := newTestDB(t)
+	tagRepository := &TagRepository{DB: testDB}
+
+	existing, err := tagRepository.GetOrCreateTags([]string{"Reimbursable"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tags, err := tagRepository.GetOrCreateTags([]string{" reimbursable ", "", "Hawaii 2026"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %+v", tags)
+	}
+	if tags[0].ID != existing[0].ID || tags[0].Name != "Reimbursable" {
+		t.Errorf("Expected the existing Reimbursable tag to be matched case-insensitively, got %+v", tags[0])
+	}
+	if tags[1].ID == 0 || tags[1].Name != "Hawaii 2026" {
+		t.Errorf("Expected Hawaii 2026 to be created, got %+v", tags[1])
+	}
+
+	allTags, err := tagRepository.GetAllTags()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(allTags) != 2 || allTags[0].Name != "Hawaii 2026" || allTags[1].Name != "Reimbursable" {
+		t.Errorf("Expected both tags sorted by name, got %+v", allTags)
+	}
+}
+
+func TestReplaceTransactionTags(t *testing.T) {
+	testDB := newTestDB(t)
+	tagRepository := &TagRepository{DB: testDB}
+	transactionRepository := &TransactionRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	transaction := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Groceries", Amount: 5000, CategoryID: food.ID})
+
+	tags, _ := tagRepository.GetOrCreateTags([]string{"Trip", "Reimbursable"})
+	if err := tagRepository.ReplaceTransactionTags(transaction.ID, tags); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := tagRepository.ReplaceTransactionTags(transaction.ID, tags[1:]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved, err := transactionRepository.GetTransactionByID(transaction.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(saved.Tags) != 1 || saved.Tags[0].Name != "Reimbursable" {
+		t.Errorf("Expected only the Reimbursable tag to be left, got %+v", saved.Tags)
+	}
+}
+
+func TestGetAllTransactions_TagFilter(t *testing.T) {
+	testDB := newTestDB(t)
+	tagRepository := &TagRepository{DB: testDB}
+	transactionRepository := &TransactionRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	tagged := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Dinner in Maui", Amount: 8000, CategoryID: food.ID})
+	createTestTransaction(t, testDB, Transaction{Date: "2026-03-02", Description: "Dinner at home", Amount: 2000, CategoryID: food.ID})
+	tags, _ := tagRepository.GetOrCreateTags([]string{"Hawaii 2026"})
+	if err := tagRepository.ReplaceTransactionTags(tagged.ID, tags); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	transactions, err := transactionRepository.GetAllTransactions(0, 0, tags[0].ID, "", nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].ID != tagged.ID {
+		t.Errorf("Expected only the tagged transaction, got %+v", transactions)
+	}
+	if len(transactions) == 1 && (len(transactions[0].Tags) != 1 || transactions[0].Tags[0].Name != "Hawaii 2026") {
+		t.Errorf("Expected the transaction's tags to be loaded, got %+v", transactions[0].Tags)
+	}
+}
+
+func TestGetSumOfTransactionsByTag(t *testing.T) {
+	testDB := newTestDB(t)
+	tagRepository := &TagRepository{DB: testDB}
+	transactionRepository := &TransactionRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	income := createTestCategory(t, testDB, "Income", CategoryKindIncome, nil)
+	tags, _ := tagRepository.GetOrCreateTags([]string{"Trip", "Reimbursable"})
+	trip, reimbursable := tags[0], tags[1]
+
+	tagTransaction := func(transaction Transaction, tags ...Tag) Transaction {
+		transaction = createTestTransaction(t, testDB, transaction)
+		if err := tagRepository.ReplaceTransactionTags(transaction.ID, tags); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return transaction
+	}
+	// Counts towards both tags
+	tagTransaction(Transaction{Date: "2026-03-01", Description: "Hotel", Amount: 30000, CategoryID: food.ID}, trip, reimbursable)
+	tagTransaction(Transaction{Date: "2026-03-15", Description: "Dinner", Amount: 5000, CategoryID: food.ID}, trip)
+	// Income isn't spending
+	tagTransaction(Transaction{Date: "2026-03-20", Description: "Refund", Amount: 10000, CategoryID: income.ID}, reimbursable)
+	// Outside the time frame
+	tagTransaction(Transaction{Date: "2026-04-01", Description: "Taxi", Amount: 4000, CategoryID: food.ID}, trip)
+
+	totals, err := transactionRepository.GetSumOfTransactionsByTag(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("Expected totals for 2 tags, got %+v", totals)
+	}
+	if totals[0].Tag != "Trip" || totals[0].TagID != trip.ID || totals[0].Amount != 35000 {
+		t.Errorf("Expected $350 on Trip first, got %+v", totals[0])
+	}
+	if totals[1].Tag != "Reimbursable" || totals[1].Amount != 30000 {
+		t.Errorf("Expected $300 on Reimbursable, got %+v", totals[1])
+	}
+}
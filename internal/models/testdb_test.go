@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an empty in-memory database with every table migrated. Some repository methods still use
+// the package-wide db, so it's pointed at the test database too.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Unable to open test database: %v", err)
+	}
+	// Every connection to :memory: is a new database, so only ever use one
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatalf("Unable to get test database connection: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = testDB.AutoMigrate(&AccountType{}, &Account{}, &Balance{}, &Budget{}, &Category{}, &ImportSubmission{},
+		&Settings{}, &Tag{}, &Transaction{}, &TransactionSplit{}, &TransferPair{}, &Attachment{}, &AlertRule{},
+		&Notification{}, &Goal{}, &Loan{}, &ValuationRule{})
+	if err != nil {
+		t.Fatalf("Unable to migrate test database: %v", err)
+	}
+	db = testDB
+	return testDB
+}
+
+// createTestCategory saves a category for a test, failing the test if it can't
+func createTestCategory(t *testing.T, testDB *gorm.DB, name string, kind string, parentID *uint) Category {
+	t.Helper()
+	category := Category{Name: name, Kind: kind, ParentID: parentID}
+	if err := testDB.Create(&category).Error; err != nil {
+		t.Fatalf("Unable to create category %s: %v", name, err)
+	}
+	return category
+}
+
+// createTestTransaction saves a transaction for a test, failing the test if it can't
+func createTestTransaction(t *testing.T, testDB *gorm.DB, transaction Transaction) Transaction {
+	t.Helper()
+	if err := testDB.Omit("Account", "Category").Create(&transaction).Error; err != nil {
+		t.Fatalf("Unable to create transaction %s: %v", transaction.Description, err)
+	}
+	return transaction
+}
@@ -13,6 +13,8 @@ type Transaction struct {
 	gorm.Model
 	Date               string
 	Description        string
+	MerchantName       string // Description cleaned up by the merchant name normalizer
+	Notes              string // Why the transaction happened, searched along with the description
 	Amount             int
 	Excluded           bool // Will be stored as 0 or 1 in SQLite
 	Hash               string
@@ -23,6 +25,44 @@ type Transaction struct {
 	Category           Category
 	ImportSubmissionID *uint
 	ImportSubmission   *ImportSubmission
+	Splits             []TransactionSplit // Empty unless the transaction is split across categories
+	Tags               []Tag              `gorm:"many2many:transaction_tags;"`
+	// What a transaction in an investment account did, one of the InvestmentActivity constants. Empty if it
+	// isn't in an investment account or couldn't be classified.
+	InvestmentActivity string
+}
+
+// Investment activities tell investment returns which transactions moved money in or out of an account and
+// which were earned inside it
+const (
+	InvestmentActivityContribution = "contribution"
+	InvestmentActivityWithdrawal   = "withdrawal"
+	InvestmentActivityDividend     = "dividend"
+	InvestmentActivityInterest     = "interest"
+	InvestmentActivityFee          = "fee"
+	InvestmentActivityTrade        = "trade" // Buying or selling within the account, which doesn't change its value
+)
+
+// InvestmentActivities lists every investment activity along with a label, in the order they're offered to users
+var InvestmentActivities = []struct {
+	Activity string
+	Label    string
+}{
+	{InvestmentActivityContribution, "Contribution"},
+	{InvestmentActivityWithdrawal, "Withdrawal"},
+	{InvestmentActivityDividend, "Dividend"},
+	{InvestmentActivityInterest, "Interest"},
+	{InvestmentActivityFee, "Fee"},
+	{InvestmentActivityTrade, "Buy or sell"},
+}
+
+func IsValidInvestmentActivity(activity string) bool {
+	for _, investmentActivity := range InvestmentActivities {
+		if investmentActivity.Activity == activity {
+			return true
+		}
+	}
+	return false
 }
 
 type TransactionsByDate struct {
@@ -43,8 +83,14 @@ type TTMAverageByDate struct {
 }
 
 type TotalByCategory struct {
+	Amount     int
+	CategoryID uint
+	Category   string
+}
+
+type TotalByMerchant struct {
 	Amount   int
-	Category string
+	Merchant string
 }
 
 type TotalByMonth struct {
@@ -52,6 +98,22 @@ type TotalByMonth struct {
 	Month  time.Time
 }
 
+// TotalByPeriod is the sum of transactions in a budget period, keyed by the period's first day in YYYY-MM-DD
+// format
+type TotalByPeriod struct {
+	Amount      int
+	PeriodStart string
+}
+
+// budgetPeriodStartSQL computes the first day of the budget period that contains t.date, matching
+// BudgetPeriodStart
+var budgetPeriodStartSQL = map[string]string{
+	BudgetPeriodWeekly:    `date(t.date, 'weekday 0', '-6 days')`,
+	BudgetPeriodMonthly:   `strftime('%Y-%m-01', t.date)`,
+	BudgetPeriodQuarterly: `strftime('%Y-', t.date) || printf('%02d', (cast(strftime('%m', t.date) AS integer) - 1) / 3 * 3 + 1) || '-01'`,
+	BudgetPeriodAnnual:    `strftime('%Y-01-01', t.date)`,
+}
+
 type AverageByMonth struct {
 	Average int
 	Month   time.Time
@@ -61,19 +123,23 @@ type TransactionRepository struct {
 	DB *gorm.DB
 }
 
-func (tr *TransactionRepository) GetAllTransactions(accountID uint, categoryID uint, description string, startDate *time.Time, endDate *time.Time) ([]Transaction, error) {
+func (tr *TransactionRepository) GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]Transaction, error) {
 	// TODO: Need to implement pagination
 	var txns []Transaction
 
-	gormTxn := tr.DB.Preload(clause.Associations).Order("date desc")
+	gormTxn := tr.DB.Preload(clause.Associations).Preload("Splits.Category").Order("date desc")
 	if accountID != 0 {
 		gormTxn = gormTxn.Where("account_id = ?", accountID)
 	}
 	if categoryID != 0 {
-		gormTxn = gormTxn.Where("category_id = ?", categoryID)
+		// Split transactions match if any of their splits are in the category
+		gormTxn = gormTxn.Where("(category_id IN ("+categorySubtreeSQL+") OR id IN (SELECT transaction_id FROM transaction_splits WHERE deleted_at IS NULL AND category_id IN ("+categorySubtreeSQL+")))", categoryID, categoryID)
+	}
+	if tagID != 0 {
+		gormTxn = gormTxn.Where("id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id = ?)", tagID)
 	}
 	if description != "" {
-		gormTxn = gormTxn.Where("description LIKE ?", "%"+description+"%")
+		gormTxn = gormTxn.Where("(description LIKE ? OR merchant_name LIKE ? OR notes LIKE ?)", "%"+description+"%", "%"+description+"%", "%"+description+"%")
 	}
 	if startDate != nil {
 		gormTxn = gormTxn.Where("date >= ?", *startDate)
@@ -91,11 +157,20 @@ func (tr *TransactionRepository) GetLatestTransactionForAccount(accountID uint)
 	return txn, result.Error
 }
 
+// GetEarliestTransactionDate returns the date of the oldest transaction as YYYY-MM-DD, or an empty string if
+// there are no transactions
+func (tr *TransactionRepository) GetEarliestTransactionDate() (string, error) {
+	var date string
+	result := tr.DB.Model(&Transaction{}).Select("coalesce(min(date), '')").Scan(&date)
+	return date, result.Error
+}
+
+// GetSumOfTransactionsByCategoryID returns the sum of transactions in a category, including its subcategories
 func (tr *TransactionRepository) GetSumOfTransactionsByCategoryID(categoryID uint, startDate time.Time, endDate time.Time) (int, error) {
 	var sum int
 	queryResult := tr.DB.Raw(`SELECT coalesce(sum(amount), 0)
-		FROM transactions
-		WHERE category_id=?
+		FROM (`+transactionAllocationsSQL+`)
+		WHERE category_id IN (`+categorySubtreeSQL+`)
 		AND deleted_at IS NULL
 		AND date >= ?
 		AND date <= ?`, categoryID, startDate, endDate).Scan(&sum)
@@ -105,25 +180,78 @@ func (tr *TransactionRepository) GetSumOfTransactionsByCategoryID(categoryID uin
 func (tr *TransactionRepository) GetSumOfTransactionsByCategory(startDate time.Time, endDate time.Time) (totals []TotalByCategory, err error) {
 	startDateISO := utils.TimeToISO8601DateString(startDate)
 	endDateISO := utils.TimeToISO8601DateString(endDate)
-	queryResult := tr.DB.Raw(`SELECT c.name AS Category, coalesce(sum(t.amount), 0) AS Amount
-		FROM transactions t JOIN categories c ON t.category_id = c.id
+	queryResult := tr.DB.Raw(`SELECT c.id AS CategoryID, c.name AS Category, coalesce(sum(t.amount), 0) AS Amount
+		FROM (`+transactionAllocationsSQL+`) t JOIN categories c ON t.category_id = c.id
 		AND t.date >= ?
 		AND t.date <= ?
-		AND c.name NOT IN ("Income", "Transfers")
+		AND c.id IN (`+expenseCategoryIDsSQL+`)
 		AND t.deleted_at IS NULL
-		GROUP BY c.name
+		GROUP BY c.id
 		ORDER BY Amount desc`, startDateISO, endDateISO).Scan(&totals)
 
 	return totals, queryResult.Error
 }
 
+// GetSumOfTransactionsByMerchant returns the merchants with the most spending in the time frame
+func (tr *TransactionRepository) GetSumOfTransactionsByMerchant(startDate time.Time, endDate time.Time, limit int) (totals []TotalByMerchant, err error) {
+	startDateISO := utils.TimeToISO8601DateString(startDate)
+	endDateISO := utils.TimeToISO8601DateString(endDate)
+	queryResult := tr.DB.Raw(`SELECT coalesce(nullif(t.merchant_name, ''), t.description) AS Merchant, coalesce(sum(t.amount), 0) AS Amount
+		FROM (`+transactionAllocationsSQL+`) t JOIN categories c ON t.category_id = c.id
+		AND t.date >= ?
+		AND t.date <= ?
+		AND c.id IN (`+expenseCategoryIDsSQL+`)
+		AND t.deleted_at IS NULL
+		GROUP BY Merchant
+		ORDER BY Amount desc
+		LIMIT ?`, startDateISO, endDateISO, limit).Scan(&totals)
+
+	return totals, queryResult.Error
+}
+
+// GetSumOfTransactionsByKind returns the sum of transactions in every category of a kind, e.g. all income
+func (tr *TransactionRepository) GetSumOfTransactionsByKind(kind string, startDate time.Time, endDate time.Time) (int, error) {
+	var sum int
+	startDateISO := utils.TimeToISO8601DateString(startDate)
+	endDateISO := utils.TimeToISO8601DateString(endDate)
+	queryResult := tr.DB.Raw(`SELECT coalesce(sum(t.amount), 0)
+		FROM (`+transactionAllocationsSQL+`) t JOIN categories c ON t.category_id = c.id
+		WHERE c.kind = ?
+		AND t.deleted_at IS NULL
+		AND t.date >= ?
+		AND t.date <= ?`, kind, startDateISO, endDateISO).Scan(&sum)
+	return sum, queryResult.Error
+}
+
+// GetSumOfTransactionsByTag returns the spending on each tag in the time frame. A transaction with several
+// tags counts towards each of them.
+func (tr *TransactionRepository) GetSumOfTransactionsByTag(startDate time.Time, endDate time.Time) (totals []TotalByTag, err error) {
+	startDateISO := utils.TimeToISO8601DateString(startDate)
+	endDateISO := utils.TimeToISO8601DateString(endDate)
+	queryResult := tr.DB.Raw(`SELECT tg.id AS TagID, tg.name AS Tag, coalesce(sum(t.amount), 0) AS Amount
+		FROM (`+transactionAllocationsSQL+`) t
+		JOIN transaction_tags tt ON tt.transaction_id = t.id
+		JOIN tags tg ON tg.id = tt.tag_id
+		WHERE t.date >= ?
+		AND t.date <= ?
+		AND t.category_id IN (`+expenseCategoryIDsSQL+`)
+		AND t.deleted_at IS NULL
+		AND tg.deleted_at IS NULL
+		GROUP BY tg.id
+		ORDER BY Amount desc`, startDateISO, endDateISO).Scan(&totals)
+
+	return totals, queryResult.Error
+}
+
+// GetSumOfTransactionsByCategoryAndMonth returns the monthly sums of transactions in a category, including
+// its subcategories
 func (tr *TransactionRepository) GetSumOfTransactionsByCategoryAndMonth(categoryID uint, startDate time.Time, endDate time.Time) (totals []TotalByMonth, err error) {
 	startDateISO := utils.TimeToISO8601DateString(startDate)
 	endDateISO := utils.TimeToISO8601DateString(endDate)
 	queryResult := tr.DB.Raw(`SELECT coalesce(sum(t.amount), 0) as amount,
 		STRFTIME('%Y-%m', t.date) as yearmonth
-		FROM transactions t JOIN categories c ON t.category_id = c.id
-		AND c.id = ?
+		FROM (`+transactionAllocationsSQL+`) t JOIN categories c ON t.category_id = c.id
+		AND c.id IN (`+categorySubtreeSQL+`)
 		AND t.date >= ?
 		AND t.date <= ?
 		AND t.deleted_at IS NULL
@@ -132,6 +260,28 @@ func (tr *TransactionRepository) GetSumOfTransactionsByCategoryAndMonth(category
 	return totals, queryResult.Error
 }
 
+// GetSumOfTransactionsByCategoryAndPeriod returns the sums of transactions in a category, including its
+// subcategories, for each budget period that has any, oldest first
+func (tr *TransactionRepository) GetSumOfTransactionsByCategoryAndPeriod(categoryID uint, period string, startDate time.Time, endDate time.Time) (totals []TotalByPeriod, err error) {
+	periodStartSQL, ok := budgetPeriodStartSQL[period]
+	if !ok {
+		periodStartSQL = budgetPeriodStartSQL[BudgetPeriodMonthly]
+	}
+	startDateISO := utils.TimeToISO8601DateString(startDate)
+	endDateISO := utils.TimeToISO8601DateString(endDate)
+	queryResult := tr.DB.Raw(`SELECT coalesce(sum(t.amount), 0) AS amount,
+		`+periodStartSQL+` AS period_start
+		FROM (`+transactionAllocationsSQL+`) t
+		WHERE t.category_id IN (`+categorySubtreeSQL+`)
+		AND t.date >= ?
+		AND t.date <= ?
+		AND t.deleted_at IS NULL
+		GROUP BY period_start
+		ORDER BY period_start`, categoryID, startDateISO, endDateISO).Scan(&totals)
+
+	return totals, queryResult.Error
+}
+
 func (tr *TransactionRepository) GetTransactionsByHash(hash string, submissionID uint) ([]Transaction, error) {
 	// Implement GORM query to look up transactions by hash
 	var transactions []Transaction
@@ -151,6 +301,12 @@ func (tr *TransactionRepository) Save(txn Transaction) (id uint, err error) {
 	return txn.ID, result.Error
 }
 
+// UpdateInvestmentActivity sets what a transaction in an investment account did
+func (tr *TransactionRepository) UpdateInvestmentActivity(id uint, activity string) error {
+	result := tr.DB.Model(&Transaction{}).Where("id = ?", id).Update("investment_activity", activity)
+	return result.Error
+}
+
 func (tr *TransactionRepository) GetTransactionsByImportSubmission(id uint) ([]Transaction, error) {
 	var transactions []Transaction
 	result := tr.DB.Preload(clause.Associations).Where("import_submission_id = ?", id).Find(&transactions)
@@ -159,16 +315,56 @@ func (tr *TransactionRepository) GetTransactionsByImportSubmission(id uint) ([]T
 
 func (tr *TransactionRepository) GetTransactionByID(id uint) (Transaction, error) {
 	var transaction Transaction
-	result := tr.DB.Preload(clause.Associations).Where("id = ?", id).Find(&transaction)
+	result := tr.DB.Preload(clause.Associations).Preload("Splits.Category").Where("id = ?", id).Find(&transaction)
 	return transaction, result.Error
 }
 
+// GetAllTransactionsForNormalization returns every transaction with just the fields needed to
+// recompute its merchant name
+func (tr *TransactionRepository) GetAllTransactionsForNormalization() ([]Transaction, error) {
+	var transactions []Transaction
+	result := tr.DB.Select("id", "description", "merchant_name").Find(&transactions)
+	return transactions, result.Error
+}
+
+func (tr *TransactionRepository) UpdateMerchantName(id uint, merchantName string) error {
+	result := tr.DB.Model(&Transaction{}).Where("id = ?", id).Update("merchant_name", merchantName)
+	return result.Error
+}
+
 func (tr *TransactionRepository) GetTransactionsForTraining() ([]Transaction, error) {
 	var transactions []Transaction
 	result := tr.DB.Preload(clause.Associations).Where("use_for_training = ?", 1).Find(&transactions)
 	return transactions, result.Error
 }
 
+// GetTransactionsForRecurringDetection returns the spending since startDate, oldest first, for finding
+// recurring charges. Excluded transactions and transactions in income, transfer and savings categories
+// are left out.
+func (tr *TransactionRepository) GetTransactionsForRecurringDetection(startDate time.Time) ([]Transaction, error) {
+	var transactions []Transaction
+	result := tr.DB.Preload("Category").
+		Where("date >= ?", utils.TimeToISO8601DateString(startDate)).
+		Where("excluded = ?", false).
+		Where("category_id IN (" + expenseCategoryIDsSQL + ")").
+		Order("date asc").
+		Find(&transactions)
+	return transactions, result.Error
+}
+
+// GetSpendingCreatedSince returns transactions in expense categories, including Unknown, that were added
+// since the time, oldest first. Excluded transactions are left out.
+func (tr *TransactionRepository) GetSpendingCreatedSince(since time.Time) ([]Transaction, error) {
+	var transactions []Transaction
+	result := tr.DB.Preload("Category").
+		Where("created_at > ?", since).
+		Where("excluded = ?", false).
+		Where("category_id IN (" + expenseCategoryIDsSQL + ")").
+		Order("created_at asc, id asc").
+		Find(&transactions)
+	return transactions, result.Error
+}
+
 func (tr *TransactionRepository) GetNetIncomeTotalsByDate(ctx context.Context, startYearMonth time.Time, endYearMonth time.Time) (NITByDate []NetIncomeDataByDate, err error) {
 	type netIncomeDataSet struct {
 		Income    int
@@ -177,7 +373,9 @@ func (tr *TransactionRepository) GetNetIncomeTotalsByDate(ctx context.Context, s
 	}
 	var netIncomeData netIncomeDataSet
 
-	for month := startYearMonth; month.Before(endYearMonth); month = month.AddDate(0, 1, 0) {
+	// Start on the first of the month, so the last month isn't skipped when startYearMonth is later in the month
+	// than endYearMonth
+	for month := startYearMonth.AddDate(0, 0, 1-startYearMonth.Day()); month.Before(endYearMonth); month = month.AddDate(0, 1, 0) {
 		// reset netIncomeData for each month
 		netIncomeData = netIncomeDataSet{}
 
@@ -189,10 +387,10 @@ func (tr *TransactionRepository) GetNetIncomeTotalsByDate(ctx context.Context, s
 		netIncomeQuery := tr.DB.Raw(`WITH income AS (
 				SELECT sum(t.amount) as amount,
 				STRFTIME('%Y-%m', t.date) as yearmonth
-				FROM transactions AS t
+				FROM (`+transactionAllocationsSQL+`) AS t
 				JOIN categories AS c
 				ON c.id=t.category_id
-				WHERE c.name="Income"
+				WHERE c.id IN (`+incomeCategoryIDsSQL+`)
 				AND t.deleted_at IS NULL
 				AND t.date >= (?)
 				AND t.date <= (?)
@@ -201,10 +399,10 @@ func (tr *TransactionRepository) GetNetIncomeTotalsByDate(ctx context.Context, s
 			expenses AS (
 				SELECT sum(t.amount) as amount,
 				STRFTIME('%Y-%m', t.date) as yearmonth
-				FROM transactions AS t
+				FROM (`+transactionAllocationsSQL+`) AS t
 				JOIN categories AS c
 				ON c.id=t.category_id
-				WHERE c.name not in ("Income", "Transfers")
+				WHERE c.id IN (`+expenseCategoryIDsSQL+`)
 				AND t.deleted_at IS NULL
 				AND t.date >= (?)
 				AND t.date <= (?)
@@ -246,10 +444,10 @@ func (tr *TransactionRepository) GetTTMStatistics(ctx context.Context, yearMonth
 		netIncomeQuery := tr.DB.Raw(`WITH income AS (
 				SELECT sum(t.amount) as amount,
 				STRFTIME('%Y-%m', t.date) as yearmonth
-				FROM transactions AS t
+				FROM (`+transactionAllocationsSQL+`) AS t
 				JOIN categories AS c
 				ON c.id=t.category_id
-				WHERE c.name="Income"
+				WHERE c.id IN (`+incomeCategoryIDsSQL+`)
 				AND t.deleted_at IS NULL
 				AND t.date >= (?)
 				AND t.date <= (?)
@@ -258,10 +456,10 @@ func (tr *TransactionRepository) GetTTMStatistics(ctx context.Context, yearMonth
 			expenses AS (
 				SELECT sum(t.amount) as amount,
 				STRFTIME('%Y-%m', t.date) as yearmonth
-				FROM transactions AS t
+				FROM (`+transactionAllocationsSQL+`) AS t
 				JOIN categories AS c
 				ON c.id=t.category_id
-				WHERE c.name not in ("Income", "Transfers")
+				WHERE c.id IN (`+expenseCategoryIDsSQL+`)
 				AND t.deleted_at IS NULL
 				AND t.date >= (?)
 				AND t.date <= (?)
@@ -300,8 +498,13 @@ func (tr *TransactionRepository) GetTTMStatistics(ctx context.Context, yearMonth
 	return average, twentyFifthPercentile, seventyFifthPercentile, nil
 }
 
-// Soft deletes a transaction
+// DeleteTransactionByID soft deletes a transaction and permanently deletes its attachments, so deleted receipts
+// don't stay in the database
 func (tr *TransactionRepository) DeleteTransactionByID(ctx context.Context, id uint) (err error) {
-	result := tr.DB.Delete(&Transaction{}, id)
-	return result.Error
+	return tr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("transaction_id = ?", id).Delete(&Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Transaction{}, id).Error
+	})
 }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSumOfTransactionsByCategoryAndPeriod(t *testing.T) {
+	testDB := newTestDB(t)
+	transactionRepository := &TransactionRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	groceries := createTestCategory(t, testDB, "Groceries", CategoryKindExpense, &food.ID)
+	other := createTestCategory(t, testDB, "Other", CategoryKindExpense, nil)
+	for _, transaction := range []Transaction{
+		{Date: "2026-08-17", Description: "Monday", Amount: 1000, CategoryID: food.ID},
+		{Date: "2026-08-23", Description: "Sunday", Amount: 2000, CategoryID: groceries.ID},
+		{Date: "2026-08-24", Description: "Next Monday", Amount: 4000, CategoryID: food.ID},
+		{Date: "2026-09-30", Description: "End of the quarter", Amount: 8000, CategoryID: food.ID},
+		{Date: "2026-08-20", Description: "Another category", Amount: 16000, CategoryID: other.ID},
+	} {
+		createTestTransaction(t, testDB, transaction)
+	}
+	startDate := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		period   string
+		expected []TotalByPeriod
+	}{
+		{BudgetPeriodWeekly, []TotalByPeriod{{3000, "2026-08-17"}, {4000, "2026-08-24"}, {8000, "2026-09-28"}}},
+		{BudgetPeriodMonthly, []TotalByPeriod{{7000, "2026-08-01"}, {8000, "2026-09-01"}}},
+		{BudgetPeriodQuarterly, []TotalByPeriod{{15000, "2026-07-01"}}},
+		{BudgetPeriodAnnual, []TotalByPeriod{{15000, "2026-01-01"}}},
+	}
+	for _, tt := range tests {
+		totals, err := transactionRepository.GetSumOfTransactionsByCategoryAndPeriod(food.ID, tt.period, startDate, endDate)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(totals) != len(tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.period, tt.expected, totals)
+			continue
+		}
+		for i, total := range totals {
+			if total != tt.expected[i] {
+				t.Errorf("%s: expected %+v, got %+v", tt.period, tt.expected[i], total)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// TransactionSplit allocates part of a transaction to a category, e.g. the groceries and the gifts in a
+// single Costco charge. The splits of a transaction sum to its amount, and reports count the splits
+// instead of the transaction itself.
+type TransactionSplit struct {
+	gorm.Model
+	TransactionID uint
+	CategoryID    uint
+	Category      Category
+	Amount        int
+	Note          string
+}
+
+// transactionAllocationsSQL selects each transaction that isn't split, plus one row per split with the
+// split's category and amount. Aggregate queries read from it instead of the transactions table so that
+// split transactions are counted by their splits.
+const transactionAllocationsSQL = `SELECT t.id, t.date, t.description, t.merchant_name, t.amount, t.excluded,
+		t.account_id, t.category_id, t.deleted_at
+		FROM transactions t
+		WHERE NOT EXISTS (
+			SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id AND s.deleted_at IS NULL
+		)
+	UNION ALL
+	SELECT t.id, t.date, t.description, t.merchant_name, s.amount, t.excluded,
+		t.account_id, s.category_id, t.deleted_at
+		FROM transactions t JOIN transaction_splits s ON s.transaction_id = t.id
+		WHERE s.deleted_at IS NULL`
+
+// ReplaceSplits replaces all of a transaction's splits. Passing no splits removes the split, so the
+// transaction is counted in its own category again.
+func (tr *TransactionRepository) ReplaceSplits(transactionID uint, splits []TransactionSplit) error {
+	return tr.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("transaction_id = ?", transactionID).Delete(&TransactionSplit{})
+		if result.Error != nil {
+			return result.Error
+		}
+		for _, split := range splits {
+			split.ID = 0
+			split.TransactionID = transactionID
+			result = tx.Omit("Category").Create(&split)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Transfer pair statuses. Suggested pairs were found by the transfer matcher and haven't been reviewed yet.
+// Unlinked pairs are kept so the same transactions aren't suggested again.
+const (
+	TransferPairSuggested = "suggested"
+	TransferPairConfirmed = "confirmed"
+	TransferPairUnlinked  = "unlinked"
+)
+
+// TransferPair links the two sides of a transfer between accounts, like a credit card payment that shows up in
+// both the checking account and the credit card account. Once the pair is confirmed, both transactions are put in a
+// transfer category so neither is counted as income or spending.
+type TransferPair struct {
+	gorm.Model
+	TransactionID        uint
+	Transaction          Transaction
+	MatchedTransactionID uint
+	MatchedTransaction   Transaction
+	Status               string
+	// Categories the transactions had before the pair was confirmed, which are restored if the pair is unlinked
+	PreviousCategoryID        uint
+	MatchedPreviousCategoryID uint
+}
+
+type TransferPairRepository struct {
+	DB *gorm.DB
+}
+
+// GetAllTransferPairs returns the suggested and confirmed pairs, most recent first
+func (tpr *TransferPairRepository) GetAllTransferPairs() ([]TransferPair, error) {
+	var pairs []TransferPair
+	result := tpr.DB.Preload("Transaction.Account").Preload("MatchedTransaction.Account").
+		Joins("JOIN transactions ON transactions.id = transfer_pairs.transaction_id").
+		Where("transfer_pairs.status <> ? AND transactions.deleted_at IS NULL", TransferPairUnlinked).
+		Order("transactions.date desc").
+		Find(&pairs)
+	return pairs, result.Error
+}
+
+// GetTransferPairsForAccount returns the suggested and confirmed pairs with a transaction in the account, with
+// the accounts of both transactions loaded
+func (tpr *TransferPairRepository) GetTransferPairsForAccount(accountID uint) ([]TransferPair, error) {
+	var pairs []TransferPair
+	result := tpr.DB.Preload("Transaction.Account.AccountType").Preload("MatchedTransaction.Account.AccountType").
+		Where("status <> ?", TransferPairUnlinked).
+		Where("(transaction_id IN (SELECT id FROM transactions WHERE account_id = ?) OR matched_transaction_id IN (SELECT id FROM transactions WHERE account_id = ?))", accountID, accountID).
+		Find(&pairs)
+	return pairs, result.Error
+}
+
+// GetConfirmedTransferTransactionIDs returns the IDs of both transactions of every confirmed pair
+func (tpr *TransferPairRepository) GetConfirmedTransferTransactionIDs() ([]uint, error) {
+	var pairs []TransferPair
+	result := tpr.DB.Where("status = ?", TransferPairConfirmed).Find(&pairs)
+	ids := []uint{}
+	for _, pair := range pairs {
+		ids = append(ids, pair.TransactionID, pair.MatchedTransactionID)
+	}
+	return ids, result.Error
+}
+
+func (tpr *TransferPairRepository) GetTransferPairByID(id uint) (TransferPair, error) {
+	var pair TransferPair
+	result := tpr.DB.Where("id = ?", id).First(&pair)
+	return pair, result.Error
+}
+
+// GetTransactionsForTransferMatching returns the transactions that could be one side of a transfer, which
+// excludes excluded and split transactions and any transaction that has already been paired, even if the
+// pair was unlinked. If importSubmissionID isn't 0, only transactions within maxDaysApart days of the
+// submission's transactions are returned, since nothing further away can be paired with them. The accounts and
+// categories are loaded so the matcher can tell which way money moved.
+func (tpr *TransferPairRepository) GetTransactionsForTransferMatching(importSubmissionID uint, maxDaysApart int) ([]Transaction, error) {
+	var txns []Transaction
+	query := tpr.DB.Preload("Account.AccountType").Preload("Category").
+		Where("excluded = ?", false).
+		Where("id NOT IN (SELECT transaction_id FROM transaction_splits WHERE deleted_at IS NULL)").
+		Where("id NOT IN (SELECT transaction_id FROM transfer_pairs WHERE deleted_at IS NULL)").
+		Where("id NOT IN (SELECT matched_transaction_id FROM transfer_pairs WHERE deleted_at IS NULL)")
+	if importSubmissionID != 0 {
+		query = query.Where(`date BETWEEN
+			(SELECT date(min(date), ?) FROM transactions WHERE import_submission_id = ? AND deleted_at IS NULL) AND
+			(SELECT date(max(date), ?) FROM transactions WHERE import_submission_id = ? AND deleted_at IS NULL)`,
+			fmt.Sprintf("-%d days", maxDaysApart), importSubmissionID, fmt.Sprintf("+%d days", maxDaysApart), importSubmissionID)
+	}
+	result := query.Order("date asc").Find(&txns)
+	return txns, result.Error
+}
+
+// CreateTransferPair saves a new suggested pair. Its transactions keep their categories until it's confirmed.
+func (tpr *TransferPairRepository) CreateTransferPair(pair TransferPair) (id uint, err error) {
+	result := tpr.DB.Omit(clause.Associations).Create(&pair)
+	return pair.ID, result.Error
+}
+
+// ConfirmTransferPair marks a suggested pair as reviewed and moves both of its transactions into the transfer
+// category, remembering the categories they had so they can be restored if the pair is unlinked
+func (tpr *TransferPairRepository) ConfirmTransferPair(id uint, transferCategoryID uint) error {
+	return tpr.DB.Transaction(func(tx *gorm.DB) error {
+		var pair TransferPair
+		if err := tx.Where("id = ? AND status = ?", id, TransferPairSuggested).First(&pair).Error; err != nil {
+			return err
+		}
+		var transaction, matchedTransaction Transaction
+		if err := tx.Where("id = ?", pair.TransactionID).First(&transaction).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", pair.MatchedTransactionID).First(&matchedTransaction).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&pair).Updates(map[string]interface{}{
+			"status":                       TransferPairConfirmed,
+			"previous_category_id":         transaction.CategoryID,
+			"matched_previous_category_id": matchedTransaction.CategoryID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Transaction{}).
+			Where("id IN ?", []uint{pair.TransactionID, pair.MatchedTransactionID}).
+			Update("category_id", transferCategoryID).Error
+	})
+}
+
+// UnlinkTransferPair restores the categories the transactions had before the pair was confirmed. Suggested
+// pairs never changed their categories, so there's nothing to restore. The pair is kept as unlinked so the
+// matcher doesn't suggest it again.
+func (tpr *TransferPairRepository) UnlinkTransferPair(id uint) error {
+	return tpr.DB.Transaction(func(tx *gorm.DB) error {
+		var pair TransferPair
+		if err := tx.Where("id = ?", id).First(&pair).Error; err != nil {
+			return err
+		}
+		if pair.Status == TransferPairConfirmed {
+			err := tx.Model(&Transaction{}).Where("id = ?", pair.TransactionID).Update("category_id", pair.PreviousCategoryID).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&Transaction{}).Where("id = ?", pair.MatchedTransactionID).Update("category_id", pair.MatchedPreviousCategoryID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&pair).Update("status", TransferPairUnlinked).Error
+	})
+}
+
+// remapTransferPairCategories points the categories that pairs restore when they're unlinked at another
+// category, for when a category is merged or deleted
+func remapTransferPairCategories(tx *gorm.DB, fromCategoryID uint, toCategoryID uint) error {
+	err := tx.Model(&TransferPair{}).Where("previous_category_id = ?", fromCategoryID).Update("previous_category_id", toCategoryID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&TransferPair{}).Where("matched_previous_category_id = ?", fromCategoryID).Update("matched_previous_category_id", toCategoryID).Error
+}
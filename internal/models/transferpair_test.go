@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestConfirmAndUnlinkTransferPair(t *testing.T) {
+	testDB := newTestDB(t)
+	transferPairRepository := &TransferPairRepository{DB: testDB}
+	food := createTestCategory(t, testDB, "Food", CategoryKindExpense, nil)
+	transfers := createTestCategory(t, testDB, "Transfers", CategoryKindTransfer, nil)
+	payment := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Card payment", Amount: 5000, AccountID: 1, CategoryID: food.ID})
+	received := createTestTransaction(t, testDB, Transaction{Date: "2026-03-02", Description: "Payment received", Amount: 5000, AccountID: 2, CategoryID: food.ID})
+
+	id, err := transferPairRepository.CreateTransferPair(TransferPair{TransactionID: payment.ID, MatchedTransactionID: received.ID, Status: TransferPairSuggested})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if category := transactionCategoryID(t, testDB, payment.ID); category != food.ID {
+		t.Errorf("Expected a suggested pair to keep its category, got %d", category)
+	}
+
+	if err := transferPairRepository.ConfirmTransferPair(id, transfers.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if payment, received := transactionCategoryID(t, testDB, payment.ID), transactionCategoryID(t, testDB, received.ID); payment != transfers.ID || received != transfers.ID {
+		t.Errorf("Expected both transactions in Transfers once confirmed, got %d and %d", payment, received)
+	}
+
+	if err := transferPairRepository.UnlinkTransferPair(id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if payment, received := transactionCategoryID(t, testDB, payment.ID), transactionCategoryID(t, testDB, received.ID); payment != food.ID || received != food.ID {
+		t.Errorf("Expected both transactions back in Food once unlinked, got %d and %d", payment, received)
+	}
+}
+
+func TestTransferPairCategoriesFollowMergesAndDeletes(t *testing.T) {
+	testDB := newTestDB(t)
+	categoryRepository := &CategoryRepository{DB: testDB}
+	transferPairRepository := &TransferPairRepository{DB: testDB}
+	createTestCategory(t, testDB, "Unknown", CategoryKindExpense, nil)
+	dining := createTestCategory(t, testDB, "Dining", CategoryKindExpense, nil)
+	restaurants := createTestCategory(t, testDB, "Restaurants", CategoryKindExpense, nil)
+	gifts := createTestCategory(t, testDB, "Gifts", CategoryKindExpense, nil)
+	transfers := createTestCategory(t, testDB, "Transfers", CategoryKindTransfer, nil)
+	sent := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Transfer out", Amount: 5000, AccountID: 1, CategoryID: dining.ID})
+	received := createTestTransaction(t, testDB, Transaction{Date: "2026-03-01", Description: "Transfer in", Amount: 5000, AccountID: 2, CategoryID: gifts.ID})
+	id, _ := transferPairRepository.CreateTransferPair(TransferPair{TransactionID: sent.ID, MatchedTransactionID: received.ID, Status: TransferPairSuggested})
+	if err := transferPairRepository.ConfirmTransferPair(id, transfers.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := categoryRepository.MergeCategories(dining.ID, restaurants.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := categoryRepository.DeleteCategoryByID(gifts.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := transferPairRepository.UnlinkTransferPair(id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if category := transactionCategoryID(t, testDB, sent.ID); category != restaurants.ID {
+		t.Errorf("Expected the merged category's transaction to go back to Restaurants, got %d", category)
+	}
+	if category := transactionCategoryID(t, testDB, received.ID); category != 1 {
+		t.Errorf("Expected the deleted category's transaction to go back to Unknown, got %d", category)
+	}
+}
+
+func TestGetTransactionsForTransferMatching_ImportSubmission(t *testing.T) {
+	testDB := newTestDB(t)
+	transferPairRepository := &TransferPairRepository{DB: testDB}
+	submission := ImportSubmission{}
+	testDB.Create(&submission)
+	imported := createTestTransaction(t, testDB, Transaction{Date: "2026-03-10", Description: "Imported", Amount: 5000, AccountID: 1, ImportSubmissionID: &submission.ID})
+	nearby := createTestTransaction(t, testDB, Transaction{Date: "2026-03-06", Description: "Nearby", Amount: 5000, AccountID: 2})
+	createTestTransaction(t, testDB, Transaction{Date: "2026-03-05", Description: "Too early", Amount: 5000, AccountID: 2})
+	createTestTransaction(t, testDB, Transaction{Date: "2026-03-15", Description: "Too late", Amount: 5000, AccountID: 2})
+
+	transactions, err := transferPairRepository.GetTransactionsForTransferMatching(submission.ID, 4)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].ID != nearby.ID || transactions[1].ID != imported.ID {
+		t.Errorf("Expected only the transactions within 4 days of the submission, got %+v", transactions)
+	}
+}
+
+// transactionCategoryID returns the category a saved transaction is in
+func transactionCategoryID(t *testing.T, testDB *gorm.DB, transactionID uint) uint {
+	t.Helper()
+	var transaction Transaction
+	if err := testDB.First(&transaction, transactionID).Error; err != nil {
+		t.Fatalf("Unable to get transaction %d: %v", transactionID, err)
+	}
+	return transaction.CategoryID
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Ways of estimating what an account is worth when there's no reported balance
+const (
+	ValuationMethodAppreciation     = "appreciation"     // Grows by a fixed percentage each year
+	ValuationMethodStraightLine     = "straightLine"     // Loses value evenly until it's worth its salvage value
+	ValuationMethodDecliningBalance = "decliningBalance" // Loses a fixed percentage of what's left each year
+	ValuationMethodInterpolation    = "interpolation"    // Moves in a straight line between two appraisals
+)
+
+// ValuationMethods lists every valuation method along with a label, in the order they're offered to users
+var ValuationMethods = []struct {
+	Method string
+	Label  string
+}{
+	{ValuationMethodAppreciation, "Fixed annual appreciation"},
+	{ValuationMethodStraightLine, "Straight-line depreciation"},
+	{ValuationMethodDecliningBalance, "Declining-balance depreciation"},
+	{ValuationMethodInterpolation, "Between two appraisals"},
+}
+
+func IsValidValuationMethod(method string) bool {
+	for _, valuationMethod := range ValuationMethods {
+		if valuationMethod.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ValuationRule estimates the balance of an account that's only valued by hand, like a house or a car, so its
+// value changes between the balances entered for it
+type ValuationRule struct {
+	gorm.Model
+	AccountID uint `gorm:"uniqueIndex"`
+	Account   Account
+	// Method is one of the ValuationMethod constants
+	Method string
+	// StartDate and StartValue are what the account was worth when the rule starts, like the purchase price or the
+	// first appraisal. StartDate is in YYYY-MM-DD format.
+	StartDate  string
+	StartValue int
+	// AnnualRate is the percentage gained each year for appreciation, or lost each year for declining-balance
+	// depreciation, e.g. 3.5
+	AnnualRate float64
+	// EndDate and EndValue are the second appraisal when interpolating, or the end of the useful life and the
+	// salvage value for straight-line depreciation. Declining-balance depreciation never goes below EndValue.
+	EndDate  string
+	EndValue int
+}
+
+type ValuationRuleRepository struct {
+	DB *gorm.DB
+}
+
+func (vrr *ValuationRuleRepository) GetAllValuationRules() ([]ValuationRule, error) {
+	var rules []ValuationRule
+	result := vrr.DB.Order("id asc").Find(&rules)
+	return rules, result.Error
+}
+
+// GetValuationRuleForAccount returns the valuation rule of an account, or gorm.ErrRecordNotFound if it doesn't
+// have one
+func (vrr *ValuationRuleRepository) GetValuationRuleForAccount(accountID uint) (ValuationRule, error) {
+	var rule ValuationRule
+	result := vrr.DB.Where("account_id = ?", accountID).First(&rule)
+	return rule, result.Error
+}
+
+// Save is an UPSERT operation, returning the ID of the record and an optional error
+func (vrr *ValuationRuleRepository) Save(rule ValuationRule) (id uint, err error) {
+	rule.Account = Account{}
+	result := vrr.DB.Omit("Account").Save(&rule)
+	return rule.ID, result.Error
+}
+
+// DeleteValuationRuleForAccount deletes an account's valuation rule for good, so a new one can be added
+func (vrr *ValuationRuleRepository) DeleteValuationRuleForAccount(accountID uint) error {
+	return vrr.DB.Unscoped().Where("account_id = ?", accountID).Delete(&ValuationRule{}).Error
+}
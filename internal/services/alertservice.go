@@ -0,0 +1,352 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// AlertRuleRepositoryInterface specifically for AlertService
+type AlertRuleRepositoryInterface interface {
+	GetEnabledAlertRules() ([]models.AlertRule, error)
+	SetLastCheckedAt(id uint, lastCheckedAt time.Time) error
+}
+
+// NotificationRepositoryInterface specifically for AlertService
+type NotificationRepositoryInterface interface {
+	ExistsByKey(key string) (bool, error)
+	Create(notification models.Notification) (uint, error)
+}
+
+// AlertTransactionRepositoryInterface specifically for AlertService
+type AlertTransactionRepositoryInterface interface {
+	GetSpendingCreatedSince(since time.Time) ([]models.Transaction, error)
+}
+
+// AlertBudgetServiceInterface specifically for AlertService
+type AlertBudgetServiceInterface interface {
+	GetAllBudgetsAndCurrentSpend() ([]BudgetAndSpend, error)
+}
+
+// AlertSettingsRepositoryInterface specifically for AlertService
+type AlertSettingsRepositoryInterface interface {
+	GetSettings() (*models.Settings, error)
+}
+
+// alertCommandTimeout is how long the alert command can run before it's stopped
+const alertCommandTimeout = 30 * time.Second
+
+// alertWebhookTimeout is how long the alert webhook has to respond
+const alertWebhookTimeout = 30 * time.Second
+
+// AlertService checks the alert rules, adding a notification to the notification center for each alert and
+// sending it to the webhook in the settings and the alert command
+type AlertService struct {
+	AlertRuleRepository    AlertRuleRepositoryInterface
+	NotificationRepository NotificationRepositoryInterface
+	TransactionRepository  AlertTransactionRepositoryInterface
+	BudgetService          AlertBudgetServiceInterface
+	SettingsRepository     AlertSettingsRepositoryInterface
+	HTTPClient             *http.Client // Defaults to a client that times out after alertWebhookTimeout
+	// AlertCommand is run for each alert if it's set. It comes from the SAGE_ALERT_COMMAND environment variable
+	// rather than the settings, so it can't be changed by anything that can reach the web server.
+	AlertCommand string
+
+	// Alerts are checked on a schedule and after imports, which mustn't overlap or they'd raise the same alert
+	mutex sync.Mutex
+	// Alerts are delivered in the background, so a slow webhook or command doesn't hold up imports or the
+	// next check
+	deliveries sync.WaitGroup
+}
+
+// alertWebhookPayload is the JSON body posted to the alert webhook
+type alertWebhookPayload struct {
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Link      string    `json:"link"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StartSchedule checks the alert rules now and then every interval, until the context is cancelled
+func (as *AlertService) StartSchedule(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := as.EvaluateAlerts(); err != nil {
+				fmt.Println("Error evaluating alerts: ", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// EvaluateAlerts checks every enabled alert rule and returns the notifications it raised. Each alert is only
+// raised once, so this is safe to run as often as needed. The notifications are sent to the webhook and
+// command in the background.
+func (as *AlertService) EvaluateAlerts() (notifications []models.Notification, err error) {
+	notifications, err = as.raiseAlerts()
+	if len(notifications) > 0 {
+		as.deliveries.Add(1)
+		go func() {
+			defer as.deliveries.Done()
+			as.deliver(notifications)
+		}()
+	}
+	return notifications, err
+}
+
+// raiseAlerts checks every enabled alert rule and saves a notification for each alert it raises
+func (as *AlertService) raiseAlerts() (notifications []models.Notification, err error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	rules, err := as.AlertRuleRepository.GetEnabledAlertRules()
+	if err != nil {
+		return notifications, err
+	}
+
+	var budgetRules, transactionRules []models.AlertRule
+	for _, rule := range rules {
+		switch rule.Kind {
+		case models.AlertKindBudgetPercent:
+			budgetRules = append(budgetRules, rule)
+		case models.AlertKindLargeTransaction, models.AlertKindUnknownTransaction:
+			transactionRules = append(transactionRules, rule)
+		}
+	}
+
+	if len(budgetRules) > 0 {
+		budgetNotifications, err := as.evaluateBudgetRules(budgetRules)
+		if err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, budgetNotifications...)
+	}
+	if len(transactionRules) > 0 {
+		transactionNotifications, err := as.evaluateTransactionRules(transactionRules)
+		if err != nil {
+			return notifications, err
+		}
+		notifications = append(notifications, transactionNotifications...)
+	}
+
+	for i := range notifications {
+		notifications[i].CreatedAt = time.Now()
+		notifications[i].ID, err = as.NotificationRepository.Create(notifications[i])
+		if err != nil {
+			return notifications[:i], err
+		}
+	}
+	return notifications, nil
+}
+
+// evaluateBudgetRules raises an alert for each budget that has reached a rule's percentage this period. Only
+// the highest percentage reached is raised, so a budget that goes straight past 100% doesn't also raise 80%.
+func (as *AlertService) evaluateBudgetRules(rules []models.AlertRule) (notifications []models.Notification, err error) {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Threshold > rules[j].Threshold
+	})
+
+	budgetsAndSpend, err := as.BudgetService.GetAllBudgetsAndCurrentSpend()
+	if err != nil {
+		return notifications, err
+	}
+	for _, budget := range budgetsAndSpend {
+		for _, rule := range rules {
+			if budget.PercentUsed < rule.Threshold {
+				continue
+			}
+			// Keyed by category rather than budget, since changing a budget mid-period starts a new one
+			key := fmt.Sprintf("budget-%v-%v-%d", budget.CategoryName, utils.TimeToISO8601DateString(budget.PeriodStart), rule.Threshold)
+			exists, err := as.NotificationRepository.ExistsByKey(key)
+			if err != nil {
+				return notifications, err
+			}
+			if !exists {
+				notifications = append(notifications, models.Notification{
+					AlertRuleID: rule.ID,
+					Title:       fmt.Sprintf("%v budget is %d%% used", budget.CategoryName, budget.PercentUsed),
+					Message: fmt.Sprintf("You've spent $%v of your %v budget of $%v this period.", utils.CentsToDollarStringHumanized(budget.Spend),
+						budget.CategoryName, utils.CentsToDollarStringHumanized(budget.Amount+budget.CarriedIn)),
+					Link: fmt.Sprintf("/budgetDetails?budgetID=%d", budget.ID),
+					Key:  key,
+				})
+			}
+			break
+		}
+	}
+	return notifications, nil
+}
+
+// evaluateTransactionRules raises alerts for transactions added since each rule was last checked
+func (as *AlertService) evaluateTransactionRules(rules []models.AlertRule) (notifications []models.Notification, err error) {
+	checkedAt := time.Now()
+	since := checkedAt
+	for _, rule := range rules {
+		if ruleSince := alertRuleCheckedSince(rule); ruleSince.Before(since) {
+			since = ruleSince
+		}
+	}
+	transactions, err := as.TransactionRepository.GetSpendingCreatedSince(since)
+	if err != nil {
+		return notifications, err
+	}
+
+	for _, rule := range rules {
+		ruleSince := alertRuleCheckedSince(rule)
+		unknownCount, unknownCategoryID := 0, uint(0)
+		for _, transaction := range transactions {
+			if !transaction.CreatedAt.After(ruleSince) {
+				continue
+			}
+			switch rule.Kind {
+			case models.AlertKindLargeTransaction:
+				if transaction.Amount >= rule.Threshold {
+					notifications = append(notifications, models.Notification{
+						AlertRuleID: rule.ID,
+						Title:       fmt.Sprintf("Large transaction: $%v", utils.CentsToDollarStringHumanized(transaction.Amount)),
+						Message:     fmt.Sprintf("%v on %v", recurringMerchantName(transaction), transaction.Date),
+						Link:        fmt.Sprintf("/transactionForm?id=%d", transaction.ID),
+						Key:         fmt.Sprintf("large-%d-%d", rule.ID, transaction.ID),
+					})
+				}
+			case models.AlertKindUnknownTransaction:
+				if transaction.Category.Name == "Unknown" {
+					unknownCount++
+					unknownCategoryID = transaction.CategoryID
+				}
+			}
+		}
+		if unknownCount > 0 {
+			title := "A new transaction needs a category"
+			if unknownCount > 1 {
+				title = fmt.Sprintf("%d new transactions need a category", unknownCount)
+			}
+			notifications = append(notifications, models.Notification{
+				AlertRuleID: rule.ID,
+				Title:       title,
+				Message:     "The categorizer wasn't confident enough to categorize them, so they're in Unknown.",
+				Link:        fmt.Sprintf("/transactions?categoryID=%d", unknownCategoryID),
+				Key:         fmt.Sprintf("unknown-%d-%d", rule.ID, checkedAt.Unix()),
+			})
+		}
+
+		err = as.AlertRuleRepository.SetLastCheckedAt(rule.ID, checkedAt)
+		if err != nil {
+			return notifications, err
+		}
+	}
+	return notifications, nil
+}
+
+func alertRuleCheckedSince(rule models.AlertRule) time.Time {
+	if rule.LastCheckedAt != nil {
+		return *rule.LastCheckedAt
+	}
+	return rule.CreatedAt
+}
+
+// deliver sends notifications to the webhook in the settings and the alert command. Failures are logged rather
+// than returned, since the notifications are already in the notification center.
+func (as *AlertService) deliver(notifications []models.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	webhookURL := ""
+	if as.SettingsRepository != nil {
+		settings, err := as.SettingsRepository.GetSettings()
+		if err != nil {
+			fmt.Println("Error getting settings to deliver alerts: ", err)
+		} else {
+			webhookURL = settings.AlertWebhookURL
+		}
+	}
+	for _, notification := range notifications {
+		if webhookURL != "" {
+			if err := as.postToWebhook(webhookURL, notification); err != nil {
+				fmt.Println("Error sending alert to webhook: ", err)
+			}
+		}
+		if as.AlertCommand != "" {
+			if err := runAlertCommand(as.AlertCommand, notification); err != nil {
+				fmt.Println("Error running alert command: ", err)
+			}
+		}
+	}
+}
+
+func alertPayload(notification models.Notification) alertWebhookPayload {
+	return alertWebhookPayload{
+		Title:     notification.Title,
+		Message:   notification.Message,
+		Link:      "http://localhost:8080" + notification.Link,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+// postToWebhook posts the notification to the webhook URL as JSON
+func (as *AlertService) postToWebhook(url string, notification models.Notification) error {
+	body, err := json.Marshal(alertPayload(notification))
+	if err != nil {
+		return err
+	}
+	client := as.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: alertWebhookTimeout}
+	}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %v", response.Status)
+	}
+	return nil
+}
+
+// runAlertCommand runs the command with the system shell. The notification is passed in the SAGE_ALERT_TITLE,
+// SAGE_ALERT_MESSAGE and SAGE_ALERT_LINK environment variables, and as JSON on standard input.
+func runAlertCommand(command string, notification models.Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), alertCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	payload := alertPayload(notification)
+	cmd.Env = append(os.Environ(),
+		"SAGE_ALERT_TITLE="+payload.Title,
+		"SAGE_ALERT_MESSAGE="+payload.Message,
+		"SAGE_ALERT_LINK="+payload.Link,
+	)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockAlertRuleRepository struct {
+	Rules       []models.AlertRule
+	LastChecked map[uint]time.Time
+}
+
+func (m *MockAlertRuleRepository) GetEnabledAlertRules() ([]models.AlertRule, error) {
+	return m.Rules, nil
+}
+
+func (m *MockAlertRuleRepository) SetLastCheckedAt(id uint, lastCheckedAt time.Time) error {
+	if m.LastChecked == nil {
+		m.LastChecked = map[uint]time.Time{}
+	}
+	m.LastChecked[id] = lastCheckedAt
+	return nil
+}
+
+type MockNotificationRepository struct {
+	ExistingKeys map[string]bool
+	Created      []models.Notification
+}
+
+func (m *MockNotificationRepository) ExistsByKey(key string) (bool, error) {
+	return m.ExistingKeys[key], nil
+}
+
+func (m *MockNotificationRepository) Create(notification models.Notification) (uint, error) {
+	m.Created = append(m.Created, notification)
+	return uint(len(m.Created)), nil
+}
+
+type MockAlertBudgetService struct {
+	BudgetsAndSpend []BudgetAndSpend
+}
+
+func (m *MockAlertBudgetService) GetAllBudgetsAndCurrentSpend() ([]BudgetAndSpend, error) {
+	return m.BudgetsAndSpend, nil
+}
+
+type MockSettingsRepository struct {
+	Settings models.Settings
+}
+
+func (m *MockSettingsRepository) GetSettings() (*models.Settings, error) {
+	return &m.Settings, nil
+}
+
+func (m *MockTransactionRepository) GetSpendingCreatedSince(since time.Time) ([]models.Transaction, error) {
+	return m.Txns, m.Err
+}
+
+func TestEvaluateAlerts_Budgets(t *testing.T) {
+	periodStart := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	budget := func(id uint, name string, percentUsed int) BudgetAndSpend {
+		return BudgetAndSpend{ID: id, CategoryName: name, PercentUsed: percentUsed, Amount: 10000, Spend: percentUsed * 100, PeriodStart: periodStart}
+	}
+	notificationRepository := &MockNotificationRepository{ExistingKeys: map[string]bool{"budget-Dining-2026-10-01-80": true}}
+	alertService := &AlertService{
+		AlertRuleRepository: &MockAlertRuleRepository{Rules: []models.AlertRule{
+			{Model: gorm.Model{ID: 1}, Kind: models.AlertKindBudgetPercent, Threshold: 80},
+			{Model: gorm.Model{ID: 2}, Kind: models.AlertKindBudgetPercent, Threshold: 100},
+		}},
+		NotificationRepository: notificationRepository,
+		BudgetService: &MockAlertBudgetService{BudgetsAndSpend: []BudgetAndSpend{
+			budget(1, "Food", 85),
+			budget(2, "Home", 120),   // Only the highest threshold reached is raised
+			budget(3, "Dining", 90),  // Already raised this month
+			budget(4, "Auto", 40),    // Under every threshold
+			budget(5, "Travel", 100), // Exactly at the threshold
+		}},
+	}
+
+	notifications, err := alertService.EvaluateAlerts()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expectedKeys := []string{"budget-Food-2026-10-01-80", "budget-Home-2026-10-01-100", "budget-Travel-2026-10-01-100"}
+	if len(notifications) != len(expectedKeys) {
+		t.Fatalf("Expected %d notifications, got %+v", len(expectedKeys), notifications)
+	}
+	for i, key := range expectedKeys {
+		if notifications[i].Key != key {
+			t.Errorf("Expected notification %d to have key %v, got %v", i, key, notifications[i].Key)
+		}
+	}
+	if len(notificationRepository.Created) != 3 {
+		t.Errorf("Expected 3 notifications to be saved, got %d", len(notificationRepository.Created))
+	}
+	if notifications[0].Link != "/budgetDetails?budgetID=1" {
+		t.Errorf("Expected a link to the budget, got %v", notifications[0].Link)
+	}
+}
+
+func TestEvaluateAlerts_Transactions(t *testing.T) {
+	ruleCreatedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	lastChecked := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	unknown := models.Category{Model: gorm.Model{ID: 1}, Name: "Unknown"}
+	transaction := func(id uint, createdAt time.Time, amount int, category models.Category) models.Transaction {
+		return models.Transaction{Model: gorm.Model{ID: id, CreatedAt: createdAt}, Date: "2026-10-09", Description: "STORE", Amount: amount,
+			CategoryID: category.ID, Category: category}
+	}
+	ruleRepository := &MockAlertRuleRepository{Rules: []models.AlertRule{
+		{Model: gorm.Model{ID: 3, CreatedAt: ruleCreatedAt}, Kind: models.AlertKindLargeTransaction, Threshold: 50000},
+		{Model: gorm.Model{ID: 4, CreatedAt: ruleCreatedAt}, Kind: models.AlertKindUnknownTransaction, LastCheckedAt: &lastChecked},
+	}}
+	alertService := &AlertService{
+		AlertRuleRepository:    ruleRepository,
+		NotificationRepository: &MockNotificationRepository{},
+		TransactionRepository: &MockTransactionRepository{Txns: []models.Transaction{
+			transaction(1, ruleCreatedAt.AddDate(0, 0, -1), 90000, unknown),       // Before either rule existed
+			transaction(2, ruleCreatedAt.AddDate(0, 0, 2), 75000, unknown),        // Large, but the unknown rule has already seen it
+			transaction(3, lastChecked.AddDate(0, 0, 1), 1000, unknown),           // A new unknown transaction
+			transaction(4, lastChecked.AddDate(0, 0, 1), 2000, unknown),           // Another new unknown transaction
+			transaction(5, lastChecked.AddDate(0, 0, 1), 1000, models.Category{}), // Categorized and small
+		}},
+	}
+
+	notifications, err := alertService.EvaluateAlerts()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %+v", notifications)
+	}
+	if notifications[0].Key != "large-3-2" || notifications[0].Link != "/transactionForm?id=2" {
+		t.Errorf("Expected an alert for the large transaction, got %+v", notifications[0])
+	}
+	if notifications[1].Title != "2 new transactions need a category" || notifications[1].Link != "/transactions?categoryID=1" {
+		t.Errorf("Expected an alert for the unknown transactions, got %+v", notifications[1])
+	}
+	if len(ruleRepository.LastChecked) != 2 {
+		t.Errorf("Expected both rules to be marked as checked, got %v", ruleRepository.LastChecked)
+	}
+}
+
+func TestEvaluateAlerts_Delivery(t *testing.T) {
+	var received alertWebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewDecoder(req.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	settings := models.Settings{AlertWebhookURL: server.URL}
+	commandOutput := filepath.Join(t.TempDir(), "alert.txt")
+	alertCommand := ""
+	if runtime.GOOS != "windows" {
+		alertCommand = `printf '%s' "$SAGE_ALERT_TITLE" > ` + commandOutput
+	}
+	alertService := &AlertService{
+		AlertRuleRepository: &MockAlertRuleRepository{Rules: []models.AlertRule{
+			{Model: gorm.Model{ID: 1}, Kind: models.AlertKindBudgetPercent, Threshold: 80},
+		}},
+		NotificationRepository: &MockNotificationRepository{},
+		BudgetService:          &MockAlertBudgetService{BudgetsAndSpend: []BudgetAndSpend{{ID: 1, CategoryName: "Food", PercentUsed: 90}}},
+		SettingsRepository:     &MockSettingsRepository{Settings: settings},
+		AlertCommand:           alertCommand,
+	}
+
+	_, err := alertService.EvaluateAlerts()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	alertService.deliveries.Wait()
+	if received.Title != "Food budget is 90% used" || received.Link != "http://localhost:8080/budgetDetails?budgetID=1" {
+		t.Errorf("Expected the webhook to receive the notification, got %+v", received)
+	}
+	if runtime.GOOS != "windows" {
+		output, err := os.ReadFile(commandOutput)
+		if err != nil {
+			t.Fatalf("Expected the command to run, got %v", err)
+		}
+		if string(output) != "Food budget is 90% used" {
+			t.Errorf("Expected the command to get the notification's title, got %q", output)
+		}
+	}
+}
+
+func TestEvaluateAlerts_SlowWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	alertService := &AlertService{
+		AlertRuleRepository: &MockAlertRuleRepository{Rules: []models.AlertRule{
+			{Model: gorm.Model{ID: 1}, Kind: models.AlertKindBudgetPercent, Threshold: 80},
+		}},
+		NotificationRepository: &MockNotificationRepository{},
+		BudgetService:          &MockAlertBudgetService{BudgetsAndSpend: []BudgetAndSpend{{ID: 1, CategoryName: "Food", PercentUsed: 90}}},
+		SettingsRepository:     &MockSettingsRepository{Settings: models.Settings{AlertWebhookURL: server.URL}},
+	}
+
+	// Neither the check nor the next one waits for the webhook to respond
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := alertService.EvaluateAlerts(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if _, err := alertService.EvaluateAlerts(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected checking alerts not to wait for the webhook")
+	}
+}
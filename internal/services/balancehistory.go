@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// How often balances are derived
+const (
+	BalanceHistoryDaily    = "daily"    // At the end of every day with transactions
+	BalanceHistoryMonthEnd = "monthEnd" // At the end of every month
+)
+
+// BalanceHistoryAccountRepositoryInterface specifically for BalanceHistoryService
+type BalanceHistoryAccountRepositoryInterface interface {
+	GetAccountByID(id uint) (models.Account, error)
+}
+
+// BalanceHistoryBalanceRepositoryInterface specifically for BalanceHistoryService
+type BalanceHistoryBalanceRepositoryInterface interface {
+	GetBalanceByID(ctx context.Context, balanceID uint) models.Balance
+	GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance
+	ReplaceDerivedBalances(accountID uint, balances []models.Balance) error
+}
+
+// BalanceHistoryTransactionRepositoryInterface specifically for BalanceHistoryService
+type BalanceHistoryTransactionRepositoryInterface interface {
+	GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]models.Transaction, error)
+}
+
+// BalanceHistoryTransferPairRepositoryInterface specifically for BalanceHistoryService
+type BalanceHistoryTransferPairRepositoryInterface interface {
+	GetTransferPairsForAccount(accountID uint) ([]models.TransferPair, error)
+}
+
+// BalanceHistoryService fills in the balance history of accounts whose statements don't include balances, by
+// rolling their transactions forwards and backwards from one known balance
+type BalanceHistoryService struct {
+	AccountRepository      BalanceHistoryAccountRepositoryInterface
+	BalanceRepository      BalanceHistoryBalanceRepositoryInterface
+	TransactionRepository  BalanceHistoryTransactionRepositoryInterface
+	TransferPairRepository BalanceHistoryTransferPairRepositoryInterface
+}
+
+// DeriveBalances replaces the account's derived balances with new ones worked out from the anchor balance, which
+// must be a reported balance of the account. Dates that already have a reported balance are skipped. It returns
+// the number of balances derived.
+func (bhs *BalanceHistoryService) DeriveBalances(accountID uint, anchorBalanceID uint, granularity string, today time.Time) (int, error) {
+	if granularity != BalanceHistoryDaily && granularity != BalanceHistoryMonthEnd {
+		return 0, errors.New("balances can only be derived daily or at the end of each month")
+	}
+	anchor := bhs.BalanceRepository.GetBalanceByID(context.TODO(), anchorBalanceID)
+	if anchor.ID == 0 || anchor.AccountID != accountID {
+		return 0, errors.New("the balance to start from isn't one of the account's balances")
+	}
+	if anchor.Derived || anchor.Estimated {
+		return 0, errors.New("the balance to start from must be a reported balance, not a derived or estimated one")
+	}
+	account, err := bhs.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+	transactions, err := bhs.TransactionRepository.GetAllTransactions(accountID, 0, 0, "", nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	transactionChanges, err := bhs.transactionBalanceChanges(account, transactions)
+	if err != nil {
+		return 0, err
+	}
+	reportedDates := map[string]bool{}
+	for _, balance := range bhs.BalanceRepository.GetBalancesForAccount(context.TODO(), accountID) {
+		if !balance.Derived && !balance.Estimated {
+			reportedDates[balance.EffectiveDate] = true
+		}
+	}
+
+	changes := map[string]int{}
+	for _, transaction := range transactions {
+		changes[transaction.Date] += transactionChanges[transaction.ID]
+	}
+
+	var derived []models.Balance
+	for _, date := range balanceHistoryDates(anchor.EffectiveDate, changes, granularity, today) {
+		if date == anchor.EffectiveDate || reportedDates[date] {
+			continue
+		}
+		derived = append(derived, models.Balance{
+			EffectiveDate: date,
+			Amount:        anchor.Amount + balanceChangeBetween(changes, anchor.EffectiveDate, date),
+			AccountID:     accountID,
+			Derived:       true,
+		})
+	}
+
+	err = bhs.BalanceRepository.ReplaceDerivedBalances(accountID, derived)
+	if err != nil {
+		return 0, err
+	}
+	return len(derived), nil
+}
+
+// transactionBalanceChanges works out how much each of an account's transactions moved its balance, keyed by
+// transaction ID
+func (bhs *BalanceHistoryService) transactionBalanceChanges(account models.Account, transactions []models.Transaction) (map[uint]int, error) {
+	pairs, err := bhs.TransferPairRepository.GetTransferPairsForAccount(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Which side of each of the account's transfers its transaction is on. The pair's transaction is the side
+	// the money left from, and its matched transaction is the side it arrived in.
+	transferReceived := map[uint]bool{}
+	for _, pair := range pairs {
+		transferReceived[pair.TransactionID] = false
+		transferReceived[pair.MatchedTransactionID] = true
+	}
+
+	changes := map[uint]int{}
+	for _, transaction := range transactions {
+		var received *bool
+		if side, ok := transferReceived[transaction.ID]; ok {
+			received = &side
+		}
+		changes[transaction.ID] = balanceChange(account.AccountType, transaction, received)
+	}
+	return changes, nil
+}
+
+// balanceChange is how much a transaction moves its account's balance. Liability balances are the amount owed,
+// so spending raises them and payments lower them. Most statement parsers store amounts without a sign, so the
+// direction comes from the transaction's category kind and a negative amount reverses it. The direction of a
+// transfer can't be told from its category, so it comes from the transfer pair instead: transferReceived is
+// whether the money arrived in this account, or nil if the transaction isn't paired, in which case it's
+// treated as money leaving the account.
+func balanceChange(accountType models.AccountType, transaction models.Transaction, transferReceived *bool) int {
+	var raisesBalance bool
+	isAsset := accountType.LedgerType == models.Asset
+	switch transaction.Category.Kind {
+	case models.CategoryKindIncome:
+		raisesBalance = isAsset
+	case models.CategoryKindTransfer:
+		if transferReceived != nil {
+			// Money arriving raises an asset and pays down a liability, and money leaving does the opposite.
+			// The pair already says which way it went, so the amount's sign doesn't.
+			amount := transaction.Amount
+			if amount < 0 {
+				amount = -amount
+			}
+			if isAsset == *transferReceived {
+				return amount
+			}
+			return -amount
+		}
+	case models.CategoryKindSavings:
+		// Contributions leave checking accounts and arrive in savings and brokerage accounts
+		raisesBalance = !isAsset || accountType.AccountCategory == "savings" || accountType.AccountCategory == "brokerage"
+	default:
+		raisesBalance = !isAsset
+	}
+	if raisesBalance {
+		return transaction.Amount
+	}
+	return -transaction.Amount
+}
+
+// balanceChangeBetween is the total change from the end of the from date to the end of the to date, which is
+// negative when rolling backwards from a later date to an earlier one
+func balanceChangeBetween(changes map[string]int, from string, to string) int {
+	total := 0
+	for date, change := range changes {
+		if date > from && date <= to {
+			total += change
+		} else if date > to && date <= from {
+			total -= change
+		}
+	}
+	return total
+}
+
+// balanceHistoryDates are the dates balances are derived for, oldest first. They cover every transaction and
+// the anchor date, but never go past today.
+func balanceHistoryDates(anchorDate string, changes map[string]int, granularity string, today time.Time) []string {
+	todayDate := utils.TimeToISO8601DateString(today)
+	if granularity == BalanceHistoryDaily {
+		dates := []string{}
+		for date := range changes {
+			if date <= todayDate {
+				dates = append(dates, date)
+			}
+		}
+		sort.Strings(dates)
+		return dates
+	}
+
+	first, last := anchorDate, anchorDate
+	for date := range changes {
+		if date < first {
+			first = date
+		}
+		if date > last {
+			last = date
+		}
+	}
+	if !utils.DateValid(first) || !utils.DateValid(last) {
+		return nil
+	}
+	firstDate := utils.ISO8601DateStringToTime(first)
+	monthStart := time.Date(firstDate.Year(), firstDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	dates := []string{}
+	for {
+		monthEnd := utils.TimeToISO8601DateString(monthStart.AddDate(0, 1, -1))
+		if monthEnd > todayDate {
+			break
+		}
+		dates = append(dates, monthEnd)
+		if monthEnd >= last {
+			break
+		}
+		monthStart = monthStart.AddDate(0, 1, 0)
+	}
+	return dates
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockBalanceHistoryBalanceRepository struct {
+	Balances []models.Balance
+	Replaced []models.Balance
+	Err      error
+}
+
+func (m *MockBalanceHistoryBalanceRepository) GetBalanceByID(ctx context.Context, balanceID uint) models.Balance {
+	for _, balance := range m.Balances {
+		if balance.ID == balanceID {
+			return balance
+		}
+	}
+	return models.Balance{}
+}
+
+func (m *MockBalanceHistoryBalanceRepository) GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance {
+	balances := []models.Balance{}
+	for _, balance := range m.Balances {
+		if balance.AccountID == accountID {
+			balances = append(balances, balance)
+		}
+	}
+	return balances
+}
+
+func (m *MockBalanceHistoryBalanceRepository) ReplaceDerivedBalances(accountID uint, balances []models.Balance) error {
+	m.Replaced = balances
+	return m.Err
+}
+
+func (m *MockTransferPairRepository) GetTransferPairsForAccount(accountID uint) ([]models.TransferPair, error) {
+	return m.Pairs, m.Err
+}
+
+func balanceHistoryTestTransaction(id uint, date string, amount int, kind string) models.Transaction {
+	return models.Transaction{Model: gorm.Model{ID: id}, AccountID: 1, Date: date, Amount: amount, Category: models.Category{Kind: kind}}
+}
+
+func TestBalanceChange(t *testing.T) {
+	checking := models.AccountType{LedgerType: models.Asset, AccountCategory: "checking"}
+	savings := models.AccountType{LedgerType: models.Asset, AccountCategory: "savings"}
+	creditCard := models.AccountType{LedgerType: models.Liability, AccountCategory: "creditCard"}
+	sent, received := false, true
+
+	tests := []struct {
+		name             string
+		accountType      models.AccountType
+		transaction      models.Transaction
+		transferReceived *bool
+		expected         int
+	}{
+		{"spending from checking", checking, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindExpense), nil, -1000},
+		{"paycheck into checking", checking, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindIncome), nil, 1000},
+		{"negative amount reverses direction", checking, balanceHistoryTestTransaction(1, "2026-03-02", -1000, models.CategoryKindExpense), nil, 1000},
+		{"spending on a credit card", creditCard, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindExpense), nil, 1000},
+		{"refund on a credit card", creditCard, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindIncome), nil, -1000},
+		{"unpaired credit card payment", creditCard, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindTransfer), nil, -1000},
+		{"savings contribution from checking", checking, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindSavings), nil, -1000},
+		{"savings contribution into savings", savings, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindSavings), nil, 1000},
+		{"unpaired transfer", checking, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindTransfer), nil, -1000},
+		{"paying a credit card from checking", checking, balanceHistoryTestTransaction(1, "2026-03-01", 1000, models.CategoryKindTransfer), &sent, -1000},
+		{"credit card payment received", creditCard, balanceHistoryTestTransaction(1, "2026-03-02", 1000, models.CategoryKindTransfer), &received, -1000},
+		{"cash advance from a credit card", creditCard, balanceHistoryTestTransaction(1, "2026-03-01", 1000, models.CategoryKindTransfer), &sent, 1000},
+		{"same-day transfer into savings", savings, balanceHistoryTestTransaction(1, "2026-03-01", 1000, models.CategoryKindTransfer), &received, 1000},
+		{"same-day transfer out of checking", checking, balanceHistoryTestTransaction(1, "2026-03-01", 1000, models.CategoryKindTransfer), &sent, -1000},
+		{"signed transfer out of checking", checking, balanceHistoryTestTransaction(1, "2026-03-01", -1000, models.CategoryKindTransfer), &sent, -1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := balanceChange(tt.accountType, tt.transaction, tt.transferReceived)
+			if change != tt.expected {
+				t.Errorf("Expected a change of %d, got %d", tt.expected, change)
+			}
+		})
+	}
+}
+
+func TestDeriveBalances(t *testing.T) {
+	today := time.Date(2026, 4, 15, 12, 0, 0, 0, time.UTC)
+	transactions := []models.Transaction{
+		balanceHistoryTestTransaction(1, "2026-01-10", 5000, models.CategoryKindExpense),
+		balanceHistoryTestTransaction(2, "2026-02-05", 2000, models.CategoryKindExpense),
+		balanceHistoryTestTransaction(3, "2026-02-20", 10000, models.CategoryKindTransfer),
+		balanceHistoryTestTransaction(4, "2026-03-12", 3000, models.CategoryKindExpense),
+		balanceHistoryTestTransaction(5, "2026-04-02", 1000, models.CategoryKindExpense),
+	}
+	balances := []models.Balance{
+		// The anchor is the amount owed on the card at the end of February 28th
+		{Model: gorm.Model{ID: 10}, AccountID: 1, EffectiveDate: "2026-02-28", Amount: 20000},
+		{Model: gorm.Model{ID: 11}, AccountID: 1, EffectiveDate: "2026-02-05", Amount: 99999},
+		{Model: gorm.Model{ID: 12}, AccountID: 1, EffectiveDate: "2026-03-31", Amount: 12345, Derived: true},
+		{Model: gorm.Model{ID: 13}, AccountID: 2, EffectiveDate: "2026-03-31", Amount: 500},
+	}
+	account := models.Account{AccountType: models.AccountType{LedgerType: models.Liability, AccountCategory: "creditCard"}}
+
+	tests := []struct {
+		name            string
+		anchorBalanceID uint
+		granularity     string
+		expected        map[string]int
+		expectErr       bool
+	}{
+		{
+			name:            "month end",
+			anchorBalanceID: 10,
+			granularity:     BalanceHistoryMonthEnd,
+			// April hasn't ended yet, so it has no balance
+			expected: map[string]int{"2026-01-31": 28000, "2026-03-31": 23000},
+		},
+		{
+			name:            "daily",
+			anchorBalanceID: 10,
+			granularity:     BalanceHistoryDaily,
+			// February 5th already has a reported balance
+			expected: map[string]int{"2026-01-10": 28000, "2026-02-20": 20000, "2026-03-12": 23000, "2026-04-02": 24000},
+		},
+		{name: "derived anchor", anchorBalanceID: 12, granularity: BalanceHistoryDaily, expectErr: true},
+		{name: "another account's anchor", anchorBalanceID: 13, granularity: BalanceHistoryDaily, expectErr: true},
+		{name: "unknown granularity", anchorBalanceID: 10, granularity: "hourly", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanceRepository := &MockBalanceHistoryBalanceRepository{Balances: balances}
+			service := &BalanceHistoryService{
+				AccountRepository:      &MockAccountRepository{Account: account},
+				BalanceRepository:      balanceRepository,
+				TransactionRepository:  &MockTransactionRepository{Txns: transactions},
+				TransferPairRepository: &MockTransferPairRepository{},
+			}
+			derived, err := service.DeriveBalances(1, tt.anchorBalanceID, tt.granularity, today)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %d balances", derived)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if derived != len(tt.expected) || len(balanceRepository.Replaced) != len(tt.expected) {
+				t.Fatalf("Expected %d balances, got %+v", len(tt.expected), balanceRepository.Replaced)
+			}
+			for _, balance := range balanceRepository.Replaced {
+				if !balance.Derived || balance.AccountID != 1 {
+					t.Errorf("Expected a derived balance for account 1, got %+v", balance)
+				}
+				if balance.Amount != tt.expected[balance.EffectiveDate] {
+					t.Errorf("Expected %d on %v, got %d", tt.expected[balance.EffectiveDate], balance.EffectiveDate, balance.Amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+	"gonum.org/v1/gonum/stat"
+)
+
+// forecastHistoryPeriods is how many past periods a budget forecast learns the category's spending pattern from
+const forecastHistoryPeriods = 6
+
+// BudgetForecast projects a budget's spending to the end of its current period
+type BudgetForecast struct {
+	ProjectedSpend int
+	// Recurring charges in the budget's category that are still expected before the period ends
+	UpcomingCharges      []RecurringCharge
+	UpcomingChargesTotal int
+	// ProjectedToExceed is set when the projected spending is over the budget, whether or not it already is
+	ProjectedToExceed bool
+}
+
+// ProjectSpend projects spending to the end of a period from what's been spent so far, how far through the
+// period we are, and what was spent by the same point and in total in past periods.
+//
+// The rest of the period's spending is estimated two ways. The current pace extrapolates what's been spent so
+// far, shaped by how much of a period's spending has usually happened by now, so categories that spend early
+// in the period like rent aren't projected to keep going. The historical estimate is the average spent in the
+// rest of past periods. Early in the period there's little to extrapolate from, so the estimates are weighted
+// by how far through the period we are. Recurring charges still expected set a floor on the rest of the
+// period's spending.
+func ProjectSpend(spentSoFar int, elapsedFraction float64, pastSpentByNow []float64, pastTotals []float64, upcomingRecurring int) int {
+	elapsedFraction = math.Max(0, math.Min(1, elapsedFraction))
+
+	// The share of a period's spending that has usually happened by now, or the share of the period that has
+	// elapsed if there's no history to go by
+	usualFractionByNow := elapsedFraction
+	fractions := []float64{}
+	for i := range pastTotals {
+		if pastTotals[i] > 0 {
+			fractions = append(fractions, math.Min(1, pastSpentByNow[i]/pastTotals[i]))
+		}
+	}
+	if len(fractions) > 0 {
+		usualFractionByNow = stat.Mean(fractions, nil)
+	}
+	paceRemaining := 0.0
+	if usualFractionByNow > 0 {
+		paceRemaining = float64(spentSoFar) * (1 - usualFractionByNow) / usualFractionByNow
+	}
+
+	historicalRemaining := paceRemaining
+	if len(pastTotals) > 0 {
+		remaining := make([]float64, len(pastTotals))
+		for i := range pastTotals {
+			remaining[i] = math.Max(0, pastTotals[i]-pastSpentByNow[i])
+		}
+		historicalRemaining = stat.Mean(remaining, nil)
+	}
+
+	projectedRemaining := elapsedFraction*paceRemaining + (1-elapsedFraction)*historicalRemaining
+	projectedRemaining = math.Max(projectedRemaining, float64(upcomingRecurring))
+	return spentSoFar + int(math.Round(projectedRemaining))
+}
+
+// forecastSpending projects a budget's spending to the end of the period containing asOf. Recurring charges
+// are passed in so they're only detected once when forecasting every budget.
+func (bs *BudgetService) forecastSpending(budget models.Budget, spentSoFar int, limit int, recurringCharges []RecurringCharge, asOf time.Time) (forecast BudgetForecast, err error) {
+	period := budgetPeriod(budget)
+	periodStart := models.BudgetPeriodStart(period, asOf)
+	nextPeriodStart := addBudgetPeriods(period, periodStart, 1)
+	periodEnd := nextPeriodStart.AddDate(0, 0, -1)
+	periodDays := nextPeriodStart.Sub(periodStart).Hours() / 24
+	elapsedDays := int(asOf.Sub(periodStart).Hours()/24) + 1
+
+	// Compare against the same number of days into each past period
+	var pastSpentByNow, pastTotals []float64
+	for i := 1; i <= forecastHistoryPeriods; i++ {
+		pastStart := addBudgetPeriods(period, periodStart, -i)
+		pastEnd := addBudgetPeriods(period, pastStart, 1).AddDate(0, 0, -1)
+		pastNow := pastStart.AddDate(0, 0, elapsedDays-1)
+		if pastNow.After(pastEnd) {
+			pastNow = pastEnd
+		}
+		spentByNow, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(budget.CategoryID, pastStart, pastNow)
+		if err != nil {
+			return forecast, err
+		}
+		total, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(budget.CategoryID, pastStart, pastEnd)
+		if err != nil {
+			return forecast, err
+		}
+		pastSpentByNow = append(pastSpentByNow, float64(spentByNow))
+		pastTotals = append(pastTotals, float64(total))
+	}
+
+	if len(recurringCharges) > 0 {
+		categories, err := bs.CategoryRepository.GetAllCategories()
+		if err != nil {
+			return forecast, err
+		}
+		budgetCategoryIDs := CategoryAndDescendantIDs(BuildCategoryTree(categories), budget.CategoryID)
+		for _, charge := range recurringCharges {
+			latest := charge.Transactions[len(charge.Transactions)-1]
+			if charge.Missed || !budgetCategoryIDs[latest.CategoryID] {
+				continue
+			}
+			// Charges expected today haven't come in yet, or the next one would be expected later
+			nextExpectedDate := utils.TimeToISO8601DateString(charge.NextExpectedDate)
+			if nextExpectedDate >= utils.TimeToISO8601DateString(asOf) && nextExpectedDate <= utils.TimeToISO8601DateString(periodEnd) {
+				forecast.UpcomingCharges = append(forecast.UpcomingCharges, charge)
+				forecast.UpcomingChargesTotal += charge.LatestAmount
+			}
+		}
+	}
+
+	forecast.ProjectedSpend = ProjectSpend(spentSoFar, float64(elapsedDays)/periodDays, pastSpentByNow, pastTotals, forecast.UpcomingChargesTotal)
+	forecast.ProjectedToExceed = forecast.ProjectedSpend > limit
+	return forecast, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestProjectSpend(t *testing.T) {
+	tests := []struct {
+		name              string
+		spentSoFar        int
+		elapsedFraction   float64
+		pastSpentByNow    []float64
+		pastTotals        []float64
+		upcomingRecurring int
+		expected          int
+	}{
+		{
+			name:            "no history extrapolates the current pace",
+			spentSoFar:      300,
+			elapsedFraction: 0.5,
+			expected:        600,
+		},
+		{
+			name:            "spending that usually happens early isn't extrapolated",
+			spentSoFar:      2000,
+			elapsedFraction: 0.5,
+			pastSpentByNow:  []float64{2000, 2000, 2000},
+			pastTotals:      []float64{2000, 2000, 2000},
+			expected:        2000,
+		},
+		{
+			name:            "early in the period leans on history",
+			spentSoFar:      0,
+			elapsedFraction: 0.1,
+			pastSpentByNow:  []float64{100, 100},
+			pastTotals:      []float64{1100, 1100},
+			expected:        900,
+		},
+		{
+			name:              "upcoming recurring charges are a floor",
+			spentSoFar:        100,
+			elapsedFraction:   0.5,
+			pastSpentByNow:    []float64{100},
+			pastTotals:        []float64{200},
+			upcomingRecurring: 1500,
+			expected:          1600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected := ProjectSpend(tt.spentSoFar, tt.elapsedFraction, tt.pastSpentByNow, tt.pastTotals, tt.upcomingRecurring)
+			if projected != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, projected)
+			}
+		})
+	}
+}
+
+func TestForecastSpending_UpcomingCharges(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	parentID := uint(3)
+	categories := []models.Category{
+		{Model: gorm.Model{ID: 3}, Name: "Home"},
+		{Model: gorm.Model{ID: 8}, Name: "Insurance", ParentID: &parentID},
+		{Model: gorm.Model{ID: 6}, Name: "Food"},
+	}
+	charge := func(categoryID uint, nextExpectedDate time.Time, amount int) RecurringCharge {
+		return RecurringCharge{
+			Transactions:     []models.Transaction{{CategoryID: categoryID, Amount: amount}},
+			NextExpectedDate: nextExpectedDate,
+			LatestAmount:     amount,
+		}
+	}
+	recurringCharges := []RecurringCharge{
+		charge(8, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), 12000), // a subcategory, later this month
+		charge(3, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), 3000),  // expected today but not charged yet
+		charge(3, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), 5000),   // next month
+		charge(6, time.Date(2026, 10, 28, 0, 0, 0, 0, time.UTC), 1500),  // another category
+	}
+	service := &BudgetService{
+		CategoryRepository:    &MockCategoryRepository{Categories: categories},
+		TransactionRepository: &MockTransactionRepository{},
+	}
+	budget := models.Budget{Amount: 10000, Period: "monthly", CategoryID: 3}
+
+	forecast, err := service.forecastSpending(budget, 0, budget.Amount, recurringCharges, asOf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(forecast.UpcomingCharges) != 2 || forecast.UpcomingChargesTotal != 15000 {
+		t.Errorf("Expected 2 upcoming charges totalling 15000, got %d totalling %d", len(forecast.UpcomingCharges), forecast.UpcomingChargesTotal)
+	}
+	if forecast.ProjectedSpend != 15000 || !forecast.ProjectedToExceed {
+		t.Errorf("Expected a projection of 15000 over the budget, got %+v", forecast)
+	}
+}
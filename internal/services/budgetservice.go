@@ -5,20 +5,24 @@ import (
 	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
 	"gonum.org/v1/gonum/stat"
 )
 
 type BudgetRepositoryInterface interface {
 	GetBudgetByID(id uint) (models.Budget, error)
-	GetAllBudgets() ([]models.Budget, error)
+	GetBudgetsInForce(date string) ([]models.Budget, error)
+	GetBudgetsForCategory(categoryID uint) ([]models.Budget, error)
 }
 
 type TransactionRepositoryInterface interface {
 	GetSumOfTransactionsByCategoryAndMonth(categoryID uint, startDate time.Time, endDate time.Time) ([]models.TotalByMonth, error)
 	GetSumOfTransactionsByCategoryID(categoryID uint, startDate time.Time, endDate time.Time) (int, error)
+	GetSumOfTransactionsByCategoryAndPeriod(categoryID uint, period string, startDate time.Time, endDate time.Time) ([]models.TotalByPeriod, error)
 }
 
 type CategoryRepositoryInterface interface {
+	GetAllCategories() ([]models.Category, error)
 	GetCategoryByID(id uint) (models.Category, error)
 }
 
@@ -26,27 +30,108 @@ type BudgetService struct {
 	BudgetRepository      BudgetRepositoryInterface
 	CategoryRepository    CategoryRepositoryInterface
 	TransactionRepository TransactionRepositoryInterface
+	// Optional, used to include recurring charges that are still expected in spending forecasts
+	RecurringChargeDetector *RecurringChargeDetector
 }
 
 type BudgetAndSpend struct {
 	ID           uint
 	CategoryName string
-	Amount       int
-	Spend        int
-	PercentUsed  int
-	Month        time.Time
+	Period       string
+	// HasBudget is false for periods before the category had a budget, in which case Amount is zero
+	HasBudget   bool
+	Amount      int
+	Spend       int
+	PercentUsed int
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// For rollover budgets, the balance carried in from earlier periods and what's left to spend this period
+	Rollover  bool
+	CarriedIn int
+	Available int
+	// Only set for the current period
+	Forecast BudgetForecast
 }
 
+// RolloverPeriod is one period of a rollover budget, where unspent budget carries into the next period and
+// overspending is taken out of it
+type RolloverPeriod struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Allocated   int
+	CarriedIn   int
+	Spent       int
+	// Available is Allocated + CarriedIn - Spent, which carries into the next period
+	Available int
+}
+
+// budgetPeriod returns a budget's period, treating budgets without one as monthly
+func budgetPeriod(budget models.Budget) string {
+	if budget.Period == "" {
+		return models.BudgetPeriodMonthly
+	}
+	return budget.Period
+}
+
+// addBudgetPeriods moves a period's start date forward, or backward for negative n, by n periods
+func addBudgetPeriods(period string, start time.Time, n int) time.Time {
+	switch period {
+	case models.BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case models.BudgetPeriodQuarterly:
+		return start.AddDate(0, 3*n, 0)
+	case models.BudgetPeriodAnnual:
+		return start.AddDate(n, 0, 0)
+	default:
+		return start.AddDate(0, n, 0)
+	}
+}
+
+// budgetInForce returns the budget that applied on the date, preferring the one that started most recently
+// if budgets overlap, and false if none of them applied
+func budgetInForce(budgets []models.Budget, date time.Time) (models.Budget, bool) {
+	isoDate := utils.TimeToISO8601DateString(date)
+	var inForce models.Budget
+	found := false
+	for _, budget := range budgets {
+		if budget.InForceOn(isoDate) && (!found || budget.StartDate >= inForce.StartDate) {
+			inForce = budget
+			found = true
+		}
+	}
+	return inForce, found
+}
+
+// percentUsed returns how much of the amount has been spent, counting any spending from an amount that's
+// zero or overdrawn as fully used
+func percentUsed(spend int, amount int) int {
+	if amount <= 0 {
+		if spend > 0 {
+			return 100
+		}
+		return 0
+	}
+	return int(float64(spend) / float64(amount) * 100)
+}
+
+// GetAllBudgetsAndCurrentSpend returns the budgets in force today, with spending so far in each budget's
+// current period and a forecast of spending by the end of it
 func (bs *BudgetService) GetAllBudgetsAndCurrentSpend() (budgetsAndSpend []BudgetAndSpend, err error) {
-	budgets, err := bs.BudgetRepository.GetAllBudgets()
+	now := time.Now()
+	budgets, err := bs.BudgetRepository.GetBudgetsInForce(utils.TimeToISO8601DateString(now))
 	if err != nil {
 		fmt.Println("Unable to get budgets:", err)
 		return budgetsAndSpend, err
 	}
 
-	now := time.Now()
-	firstOfMonth := now.AddDate(0, 0, 1-now.Day())
-	lastOfMonth := now.AddDate(0, 1, 0-now.Day())
+	var recurringCharges []RecurringCharge
+	if bs.RecurringChargeDetector != nil && len(budgets) > 0 {
+		recurringCharges, err = bs.RecurringChargeDetector.GetRecurringCharges()
+		if err != nil {
+			fmt.Println("Unable to get recurring charges:", err)
+			return budgetsAndSpend, err
+		}
+	}
 
 	for _, budget := range budgets {
 		category, err := bs.CategoryRepository.GetCategoryByID(budget.CategoryID)
@@ -54,71 +139,226 @@ func (bs *BudgetService) GetAllBudgetsAndCurrentSpend() (budgetsAndSpend []Budge
 			fmt.Printf("Unable to get category by ID %v: %v\n", budget.CategoryID, err)
 			return budgetsAndSpend, err
 		}
-		sum, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(category.ID, firstOfMonth, lastOfMonth)
+		period := budgetPeriod(budget)
+		periodStart := models.BudgetPeriodStart(period, now)
+		periodEnd := addBudgetPeriods(period, periodStart, 1).AddDate(0, 0, -1)
+		sum, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(category.ID, periodStart, periodEnd)
 		if err != nil {
 			fmt.Printf("Unable to get transactions with category ID %v: %v", category.ID, err)
 			return budgetsAndSpend, err
 		}
-		budgetsAndSpend = append(budgetsAndSpend, BudgetAndSpend{
+		budgetAndSpend := BudgetAndSpend{
 			ID:           budget.ID,
 			CategoryName: budget.Category.Name,
+			Period:       period,
+			HasBudget:    true,
 			Amount:       budget.Amount,
 			Spend:        sum,
-			PercentUsed:  int(float64(sum) / float64(budget.Amount) * 100),
-		})
+			PercentUsed:  percentUsed(sum, budget.Amount),
+			PeriodStart:  periodStart,
+			PeriodEnd:    periodEnd,
+		}
+		if budget.Rollover {
+			rolloverHistory, err := bs.GetRolloverHistory(budget)
+			if err != nil {
+				fmt.Printf("Unable to get rollover history for budget %v: %v\n", budget.ID, err)
+				return budgetsAndSpend, err
+			}
+			if len(rolloverHistory) == 0 {
+				return budgetsAndSpend, fmt.Errorf("budget %v doesn't have any periods to roll over", budget.ID)
+			}
+			currentPeriod := rolloverHistory[len(rolloverHistory)-1]
+			budgetAndSpend.Rollover = true
+			budgetAndSpend.CarriedIn = currentPeriod.CarriedIn
+			budgetAndSpend.Available = currentPeriod.Available
+			budgetAndSpend.PercentUsed = percentUsed(sum, currentPeriod.Allocated+currentPeriod.CarriedIn)
+		}
+
+		limit := budget.Amount
+		if budgetAndSpend.Rollover {
+			limit = budgetAndSpend.Available + sum
+		}
+		budgetAndSpend.Forecast, err = bs.forecastSpending(budget, sum, limit, recurringCharges, now)
+		if err != nil {
+			fmt.Printf("Unable to forecast spending for budget %v: %v\n", budget.ID, err)
+			return budgetsAndSpend, err
+		}
+		budgetsAndSpend = append(budgetsAndSpend, budgetAndSpend)
 	}
 	return budgetsAndSpend, nil
 }
 
-func (bs *BudgetService) GetBudgetAndMonthlySpend(budgetID uint, numOfMonths int) (budgetsAndSpend []BudgetAndSpend, err error) {
+// GetRolloverHistory returns every period of a rollover budget up to and including the current one, oldest
+// first. The running balance starts with the earliest of the category's budgets that has rolled over ever
+// since, from its start date or, if it has none, from when it was created.
+func (bs *BudgetService) GetRolloverHistory(budget models.Budget) (history []RolloverPeriod, err error) {
+	categoryBudgets, err := bs.BudgetRepository.GetBudgetsForCategory(budget.CategoryID)
+	if err != nil {
+		fmt.Println("Unable to get budgets:", err)
+		return history, err
+	}
+
+	// Budgets are sorted by start date, so walk back from this budget until one doesn't roll over
+	rolloverStart := budget
+	for i := len(categoryBudgets) - 1; i >= 0; i-- {
+		categoryBudget := categoryBudgets[i]
+		if categoryBudget.StartDate > budget.StartDate {
+			continue
+		}
+		if !categoryBudget.Rollover {
+			break
+		}
+		rolloverStart = categoryBudget
+	}
+	startDate := rolloverStart.CreatedAt
+	if rolloverStart.StartDate != "" {
+		startDate, err = time.Parse("2006-01-02", rolloverStart.StartDate)
+		if err != nil {
+			fmt.Printf("Unable to parse start date of budget %v: %v\n", rolloverStart.ID, err)
+			return history, err
+		}
+	}
+
+	now := time.Now()
+	period := budgetPeriod(budget)
+	currentPeriodStart := models.BudgetPeriodStart(period, now)
+	periodStart := models.BudgetPeriodStart(period, startDate)
+	// Spending in every period is fetched at once, since old weekly budgets can have hundreds of periods
+	totals, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryAndPeriod(budget.CategoryID, period,
+		periodStart, addBudgetPeriods(period, currentPeriodStart, 1).AddDate(0, 0, -1))
+	if err != nil {
+		fmt.Printf("Unable to get transactions with category ID %v: %v", budget.CategoryID, err)
+		return history, err
+	}
+	spentByPeriod := map[string]int{}
+	for _, total := range totals {
+		spentByPeriod[total.PeriodStart] = total.Amount
+	}
+
+	carriedIn := 0
+	for !periodStart.After(currentPeriodStart) {
+		nextPeriodStart := addBudgetPeriods(period, periodStart, 1)
+		periodEnd := nextPeriodStart.AddDate(0, 0, -1)
+		spent := spentByPeriod[utils.TimeToISO8601DateString(periodStart)]
+
+		inForceOn := periodEnd
+		if now.Before(inForceOn) {
+			inForceOn = now
+		}
+		allocated := 0
+		if budgetInPeriod, ok := budgetInForce(categoryBudgets, inForceOn); ok {
+			allocated = budgetInPeriod.AmountPer(period)
+		}
+		rolloverPeriod := RolloverPeriod{
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			Allocated:   allocated,
+			CarriedIn:   carriedIn,
+			Spent:       spent,
+			Available:   allocated + carriedIn - spent,
+		}
+		history = append(history, rolloverPeriod)
+		carriedIn = rolloverPeriod.Available
+		periodStart = nextPeriodStart
+	}
+	return history, nil
+}
+
+// GetBudgetAndSpendByPeriod compares spending against the budget for the last numOfPeriods periods of the
+// budget's period, including the current one. Each period is compared against the category's budget that
+// was in force at the end of that period, or today for the current period, converted to this budget's
+// period if it was different.
+func (bs *BudgetService) GetBudgetAndSpendByPeriod(budgetID uint, numOfPeriods int) (budgetsAndSpend []BudgetAndSpend, err error) {
 	budget, err := bs.BudgetRepository.GetBudgetByID(budgetID)
 	if err != nil {
 		fmt.Println("Unable to get budgets:", err)
 		return budgetsAndSpend, err
 	}
+	categoryBudgets, err := bs.BudgetRepository.GetBudgetsForCategory(budget.CategoryID)
+	if err != nil {
+		fmt.Println("Unable to get budgets:", err)
+		return budgetsAndSpend, err
+	}
 
 	now := time.Now()
-	firstOfMonth := now.AddDate(0, -int(numOfMonths-1), 1-now.Day())
-	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+	period := budgetPeriod(budget)
+	periodStart := addBudgetPeriods(period, models.BudgetPeriodStart(period, now), -(numOfPeriods - 1))
 
-	for i := int(0); i < numOfMonths; i++ {
-		sum, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(budget.CategoryID, firstOfMonth, lastOfMonth)
+	for i := int(0); i < numOfPeriods; i++ {
+		nextPeriodStart := addBudgetPeriods(period, periodStart, 1)
+		periodEnd := nextPeriodStart.AddDate(0, 0, -1)
+		sum, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(budget.CategoryID, periodStart, periodEnd)
 		if err != nil {
 			fmt.Printf("Unable to get transactions with category ID %v: %v", budget.CategoryID, err)
 			return budgetsAndSpend, err
 		}
+
+		inForceOn := periodEnd
+		if now.Before(inForceOn) {
+			inForceOn = now
+		}
+		amount, used := 0, 0
+		budgetInPeriod, hasBudget := budgetInForce(categoryBudgets, inForceOn)
+		if hasBudget {
+			amount = budgetInPeriod.AmountPer(period)
+			used = percentUsed(sum, amount)
+		}
 		budgetsAndSpend = append(budgetsAndSpend, BudgetAndSpend{
 			ID:           budget.ID,
 			CategoryName: budget.Category.Name,
-			Amount:       budget.Amount,
+			Period:       period,
+			HasBudget:    hasBudget,
+			Amount:       amount,
 			Spend:        sum,
-			PercentUsed:  int(float64(sum) / float64(budget.Amount) * 100),
-			Month:        firstOfMonth,
+			PercentUsed:  used,
+			PeriodStart:  periodStart,
+			PeriodEnd:    periodEnd,
 		})
-		firstOfMonth = firstOfMonth.AddDate(0, 1, 0)
-		lastOfMonth = lastOfMonth.AddDate(0, 1, 0)
+		periodStart = nextPeriodStart
 	}
 	return budgetsAndSpend, nil
 }
 
-func (bs *BudgetService) GetMeanAndStandardDeviation(budgetID uint, numOfMonths int) (averageSpend int, standardDeviation int, err error) {
+// GetMeanAndStandardDeviation returns the average and standard deviation of spending per budget period over
+// the last numOfPeriods periods and the current one
+func (bs *BudgetService) GetMeanAndStandardDeviation(budgetID uint, numOfPeriods int) (averageSpend int, standardDeviation int, err error) {
 	budget, err := bs.BudgetRepository.GetBudgetByID(budgetID)
 	if err != nil {
 		fmt.Println("Unable to get budgets:", err)
 		return averageSpend, standardDeviation, err
 	}
 
+	var amounts []float64
 	now := time.Now()
-	endDate := now.AddDate(0, 1, 0-now.Day())
-	startDate := now.AddDate(0, -int(numOfMonths), 1-now.Day())
+	period := budgetPeriod(budget)
+	if period == models.BudgetPeriodMonthly {
+		endDate := now.AddDate(0, 1, 0-now.Day())
+		startDate := now.AddDate(0, -int(numOfPeriods), 1-now.Day())
 
-	spendByMonth, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryAndMonth(budget.CategoryID, startDate, endDate)
-	var amounts []float64
-	for i := range spendByMonth {
-		amounts = append(amounts, float64(spendByMonth[i].Amount))
+		spendByMonth, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryAndMonth(budget.CategoryID, startDate, endDate)
+		if err != nil {
+			return averageSpend, standardDeviation, err
+		}
+		for i := range spendByMonth {
+			amounts = append(amounts, float64(spendByMonth[i].Amount))
+		}
+	} else {
+		periodStart := addBudgetPeriods(period, models.BudgetPeriodStart(period, now), -numOfPeriods)
+		for i := 0; i <= numOfPeriods; i++ {
+			nextPeriodStart := addBudgetPeriods(period, periodStart, 1)
+			sum, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryID(budget.CategoryID, periodStart, nextPeriodStart.AddDate(0, 0, -1))
+			if err != nil {
+				return averageSpend, standardDeviation, err
+			}
+			amounts = append(amounts, float64(sum))
+			periodStart = nextPeriodStart
+		}
+	}
+	if len(amounts) == 0 {
+		return 0, 0, nil
 	}
 	averageSpendFloat, standardDeviationFloat := stat.MeanStdDev(amounts, nil)
 	averageSpend = int(averageSpendFloat)
 	standardDeviation = int(standardDeviationFloat)
-	return averageSpend, standardDeviation, err
+	return averageSpend, standardDeviation, nil
 }
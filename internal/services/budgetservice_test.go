@@ -6,23 +6,36 @@ import (
 	"time"
 
 	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
 	"gorm.io/gorm"
 )
 
 type MockBudgetRepository struct {
 	Budget models.Budget
-	Err    error
+	// The category's budgets over time. If nil, Budget is the category's only budget.
+	CategoryBudgets []models.Budget
+	Err             error
 }
 
 func (m *MockBudgetRepository) GetBudgetByID(id uint) (models.Budget, error) {
 	return m.Budget, m.Err
 }
 
-func (m *MockBudgetRepository) GetAllBudgets() ([]models.Budget, error) {
+func (m *MockBudgetRepository) GetBudgetsInForce(date string) ([]models.Budget, error) {
 	return []models.Budget{m.Budget}, m.Err
 }
 
+func (m *MockBudgetRepository) GetBudgetsForCategory(categoryID uint) ([]models.Budget, error) {
+	if m.CategoryBudgets == nil {
+		return []models.Budget{m.Budget}, m.Err
+	}
+	return m.CategoryBudgets, m.Err
+}
+
 func (m *MockTransactionRepository) GetSumOfTransactionsByCategoryAndMonth(categoryID uint, startDate time.Time, endDate time.Time) ([]models.TotalByMonth, error) {
+	if m.TotalsByCategory != nil {
+		return m.TotalsByCategory[categoryID], m.Err
+	}
 	return m.Totals, m.Err
 }
 
@@ -30,10 +43,23 @@ func (m *MockTransactionRepository) GetSumOfTransactionsByCategoryID(categoryID
 	return m.Sum, m.Err
 }
 
+// GetSumOfTransactionsByCategoryAndPeriod returns Sum for every period in the time frame
+func (m *MockTransactionRepository) GetSumOfTransactionsByCategoryAndPeriod(categoryID uint, period string, startDate time.Time, endDate time.Time) ([]models.TotalByPeriod, error) {
+	totals := []models.TotalByPeriod{}
+	for periodStart := models.BudgetPeriodStart(period, startDate); !periodStart.After(endDate); periodStart = addBudgetPeriods(period, periodStart, 1) {
+		totals = append(totals, models.TotalByPeriod{Amount: m.Sum, PeriodStart: utils.TimeToISO8601DateString(periodStart)})
+	}
+	return totals, m.Err
+}
+
 func (m *MockCategoryRepository) GetCategoryByID(id uint) (models.Category, error) {
 	return m.Category, m.Err
 }
 
+func (m *MockCategoryRepository) GetAllCategories() ([]models.Category, error) {
+	return m.Categories, m.Err
+}
+
 func TestGetMeanAndStandardDeviation(t *testing.T) {
 	budget := models.Budget{
 		Model:      gorm.Model{ID: 1},
@@ -169,7 +195,7 @@ func TestGetAllBudgetsAndCurrentSpend_ErrorOnGetSum(t *testing.T) {
 	}
 }
 
-func TestGetBudgetAndMonthlySpend(t *testing.T) {
+func TestGetBudgetAndSpendByPeriod(t *testing.T) {
 	budget := models.Budget{
 		Model:      gorm.Model{ID: 1},
 		Amount:     1000,
@@ -182,7 +208,7 @@ func TestGetBudgetAndMonthlySpend(t *testing.T) {
 		BudgetRepository:      mockBudgetRepo,
 		TransactionRepository: mockTxnRepo,
 	}
-	budgetsAndSpend, err := service.GetBudgetAndMonthlySpend(1, 2)
+	budgetsAndSpend, err := service.GetBudgetAndSpendByPeriod(1, 2)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -199,20 +225,20 @@ func TestGetBudgetAndMonthlySpend(t *testing.T) {
 	}
 }
 
-func TestGetBudgetAndMonthlySpend_ErrorOnGetBudget(t *testing.T) {
+func TestGetBudgetAndSpendByPeriod_ErrorOnGetBudget(t *testing.T) {
 	mockBudgetRepo := &MockBudgetRepository{Err: errors.New("fail budget")}
 	mockTxnRepo := &MockTransactionRepository{}
 	service := &BudgetService{
 		BudgetRepository:      mockBudgetRepo,
 		TransactionRepository: mockTxnRepo,
 	}
-	_, err := service.GetBudgetAndMonthlySpend(1, 2)
+	_, err := service.GetBudgetAndSpendByPeriod(1, 2)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
 }
 
-func TestGetBudgetAndMonthlySpend_ErrorOnGetSum(t *testing.T) {
+func TestGetBudgetAndSpendByPeriod_ErrorOnGetSum(t *testing.T) {
 	budget := models.Budget{
 		Model:      gorm.Model{ID: 1},
 		Amount:     1000,
@@ -225,8 +251,206 @@ func TestGetBudgetAndMonthlySpend_ErrorOnGetSum(t *testing.T) {
 		BudgetRepository:      mockBudgetRepo,
 		TransactionRepository: mockTxnRepo,
 	}
-	_, err := service.GetBudgetAndMonthlySpend(1, 2)
+	_, err := service.GetBudgetAndSpendByPeriod(1, 2)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
 }
+
+func TestGetBudgetAndSpendByPeriod_UsesBudgetInForce(t *testing.T) {
+	now := time.Now()
+	thisMonth := models.BudgetPeriodStart("monthly", now)
+	// The budget was raised at the start of last month, and didn't exist three months ago
+	lastMonth := utils.TimeToISO8601DateString(thisMonth.AddDate(0, -1, 0))
+	twoMonthsAgo := utils.TimeToISO8601DateString(thisMonth.AddDate(0, -2, 0))
+	oldBudget := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     1000,
+		Period:     "monthly",
+		StartDate:  twoMonthsAgo,
+		EndDate:    utils.TimeToISO8601DateString(thisMonth.AddDate(0, -1, -1)),
+		CategoryID: 2,
+	}
+	currentBudget := models.Budget{
+		Model:      gorm.Model{ID: 2},
+		Amount:     1500,
+		Period:     "monthly",
+		StartDate:  lastMonth,
+		CategoryID: 2,
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: currentBudget, CategoryBudgets: []models.Budget{oldBudget, currentBudget}},
+		TransactionRepository: &MockTransactionRepository{Sum: 1200},
+	}
+
+	budgetsAndSpend, err := service.GetBudgetAndSpendByPeriod(2, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedAmounts := []int{0, 1000, 1500, 1500}
+	expectedHasBudget := []bool{false, true, true, true}
+	for i, b := range budgetsAndSpend {
+		if b.Amount != expectedAmounts[i] || b.HasBudget != expectedHasBudget[i] {
+			t.Errorf("period %d: expected amount %d and HasBudget %v, got %d and %v", i, expectedAmounts[i], expectedHasBudget[i], b.Amount, b.HasBudget)
+		}
+	}
+	if !budgetsAndSpend[3].PeriodStart.Equal(thisMonth) {
+		t.Errorf("expected the last period to start on %v, got %v", thisMonth, budgetsAndSpend[3].PeriodStart)
+	}
+	if budgetsAndSpend[0].PercentUsed != 0 {
+		t.Errorf("expected percent used 0 without a budget, got %d", budgetsAndSpend[0].PercentUsed)
+	}
+}
+
+func TestGetBudgetAndSpendByPeriod_ConvertsPeriods(t *testing.T) {
+	// An annual budget that replaced a monthly one is compared against the monthly amount as a yearly total
+	thisYear := models.BudgetPeriodStart("annual", time.Now())
+	monthlyBudget := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     100,
+		Period:     "monthly",
+		EndDate:    utils.TimeToISO8601DateString(thisYear.AddDate(0, 0, -1)),
+		CategoryID: 2,
+	}
+	annualBudget := models.Budget{
+		Model:      gorm.Model{ID: 2},
+		Amount:     1500,
+		Period:     "annual",
+		StartDate:  utils.TimeToISO8601DateString(thisYear),
+		CategoryID: 2,
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: annualBudget, CategoryBudgets: []models.Budget{monthlyBudget, annualBudget}},
+		TransactionRepository: &MockTransactionRepository{Sum: 1200},
+	}
+
+	budgetsAndSpend, err := service.GetBudgetAndSpendByPeriod(2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if budgetsAndSpend[0].Amount != 1200 || budgetsAndSpend[1].Amount != 1500 {
+		t.Errorf("expected amounts 1200 and 1500, got %d and %d", budgetsAndSpend[0].Amount, budgetsAndSpend[1].Amount)
+	}
+	if budgetsAndSpend[0].Period != "annual" {
+		t.Errorf("expected annual periods, got %v", budgetsAndSpend[0].Period)
+	}
+}
+
+func TestGetRolloverHistory(t *testing.T) {
+	thisMonth := models.BudgetPeriodStart("monthly", time.Now())
+	budget := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     1000,
+		Period:     "monthly",
+		StartDate:  utils.TimeToISO8601DateString(thisMonth.AddDate(0, -3, 0)),
+		Rollover:   true,
+		CategoryID: 2,
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: budget},
+		TransactionRepository: &MockTransactionRepository{Sum: 1200},
+	}
+
+	history, err := service.GetRolloverHistory(budget)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("expected 4 periods, got %d", len(history))
+	}
+	// Overspending by 200 each month is taken out of the next month's budget
+	expectedCarriedIn := []int{0, -200, -400, -600}
+	for i, period := range history {
+		if period.CarriedIn != expectedCarriedIn[i] {
+			t.Errorf("period %d: expected carried in %d, got %d", i, expectedCarriedIn[i], period.CarriedIn)
+		}
+		if period.Available != period.Allocated+period.CarriedIn-period.Spent {
+			t.Errorf("period %d: expected available to be allocated + carried in - spent, got %+v", i, period)
+		}
+	}
+	if !history[3].PeriodStart.Equal(thisMonth) || history[3].Available != -800 {
+		t.Errorf("expected this month to have -800 available, got %+v", history[3])
+	}
+}
+
+func TestGetRolloverHistory_StartsWhenRolloverWasTurnedOn(t *testing.T) {
+	thisMonth := models.BudgetPeriodStart("monthly", time.Now())
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+	withoutRollover := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     1000,
+		Period:     "monthly",
+		EndDate:    utils.TimeToISO8601DateString(lastMonth.AddDate(0, 0, -1)),
+		CategoryID: 2,
+	}
+	withRollover := models.Budget{
+		Model:      gorm.Model{ID: 2},
+		Amount:     1500,
+		Period:     "monthly",
+		StartDate:  utils.TimeToISO8601DateString(lastMonth),
+		Rollover:   true,
+		CategoryID: 2,
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: withRollover, CategoryBudgets: []models.Budget{withoutRollover, withRollover}},
+		TransactionRepository: &MockTransactionRepository{Sum: 1000},
+	}
+
+	history, err := service.GetRolloverHistory(withRollover)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 || !history[0].PeriodStart.Equal(lastMonth) {
+		t.Fatalf("expected rollover to start last month, got %+v", history)
+	}
+	if history[1].CarriedIn != 500 || history[1].Available != 1000 {
+		t.Errorf("expected 500 carried in and 1000 available this month, got %+v", history[1])
+	}
+}
+
+func TestGetRolloverHistory_InvalidStartDate(t *testing.T) {
+	budget := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     1000,
+		Period:     "monthly",
+		StartDate:  "2026-02-30",
+		Rollover:   true,
+		CategoryID: 2,
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: budget},
+		TransactionRepository: &MockTransactionRepository{Sum: 1000},
+	}
+
+	if _, err := service.GetRolloverHistory(budget); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestGetAllBudgetsAndCurrentSpend_Rollover(t *testing.T) {
+	budget := models.Budget{
+		Model:      gorm.Model{ID: 1},
+		Amount:     1000,
+		Period:     "monthly",
+		StartDate:  utils.TimeToISO8601DateString(models.BudgetPeriodStart("monthly", time.Now()).AddDate(0, -1, 0)),
+		Rollover:   true,
+		CategoryID: 2,
+		Category:   models.Category{Model: gorm.Model{ID: 2}, Name: "Car maintenance"},
+	}
+	service := &BudgetService{
+		BudgetRepository:      &MockBudgetRepository{Budget: budget},
+		CategoryRepository:    &MockCategoryRepository{Category: budget.Category},
+		TransactionRepository: &MockTransactionRepository{Sum: 250},
+	}
+
+	budgetsAndSpend, err := service.GetAllBudgetsAndCurrentSpend()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !budgetsAndSpend[0].Rollover || budgetsAndSpend[0].CarriedIn != 750 || budgetsAndSpend[0].Available != 1500 {
+		t.Errorf("expected 750 carried in and 1500 available, got %+v", budgetsAndSpend[0])
+	}
+	if budgetsAndSpend[0].PercentUsed != 14 {
+		t.Errorf("expected percent used 14, got %d", budgetsAndSpend[0].PercentUsed)
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Ways of turning a category's monthly spending into a suggested budget
+const (
+	SuggestionMethodMean       = "mean"
+	SuggestionMethodMedian     = "median"
+	SuggestionMethodPercentile = "percentile"
+)
+
+// suggestionRounding is what suggested budgets are rounded up to, $10
+const suggestionRounding = 1000
+
+// BudgetSuggestion is a proposed monthly budget for a category, based on its spending over recent months
+type BudgetSuggestion struct {
+	CategoryID   uint
+	CategoryPath string // e.g. "Food > Groceries"
+	// MonthlySpend is the spending in each of the months looked at, including months without any
+	MonthlySpend []int
+	Mean         int
+	Median       int
+	Max          int
+	// SuggestedAmount is the chosen statistic, rounded up
+	SuggestedAmount int
+}
+
+// SuggestBudgets proposes a monthly budget for every expense category that had spending in the last
+// numOfMonths full months and doesn't have a budget yet. The suggestion is the mean, median or a percentile
+// (1-100) of the monthly spending, rounded up to the next $10. Subcategory spending is included in their
+// parents, as it is for budgets.
+func (bs *BudgetService) SuggestBudgets(numOfMonths int, method string, percentile int) (suggestions []BudgetSuggestion, err error) {
+	if numOfMonths < 1 {
+		return suggestions, fmt.Errorf("number of months must be at least 1, got %d", numOfMonths)
+	}
+	if method == SuggestionMethodPercentile && (percentile < 1 || percentile > 100) {
+		return suggestions, fmt.Errorf("percentile must be between 1 and 100, got %d", percentile)
+	}
+
+	now := time.Now()
+	budgets, err := bs.BudgetRepository.GetBudgetsInForce(utils.TimeToISO8601DateString(now))
+	if err != nil {
+		return suggestions, err
+	}
+	budgeted := map[uint]bool{}
+	for _, budget := range budgets {
+		budgeted[budget.CategoryID] = true
+	}
+
+	categories, err := bs.CategoryRepository.GetAllCategories()
+	if err != nil {
+		return suggestions, err
+	}
+
+	// Only full months, since the current month's spending isn't finished yet
+	startDate := now.AddDate(0, -numOfMonths, 1-now.Day())
+	endDate := now.AddDate(0, 0, -now.Day())
+	for _, node := range FlattenCategoryTree(BuildCategoryTree(categories)) {
+		category := node.Category
+		if !category.IsExpense() || category.Name == "Unknown" || budgeted[category.ID] {
+			continue
+		}
+		totals, err := bs.TransactionRepository.GetSumOfTransactionsByCategoryAndMonth(category.ID, startDate, endDate)
+		if err != nil {
+			return suggestions, err
+		}
+		suggestion, ok := suggestBudget(totals, numOfMonths, method, percentile)
+		if !ok {
+			continue
+		}
+		suggestion.CategoryID = category.ID
+		suggestion.CategoryPath = node.Path
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// suggestBudget works out the suggestion from the months that had spending, returning false if there wasn't
+// any spending at all. Months without spending count as zero.
+func suggestBudget(totals []models.TotalByMonth, numOfMonths int, method string, percentile int) (suggestion BudgetSuggestion, ok bool) {
+	monthlySpend := make([]int, 0, numOfMonths)
+	for _, total := range totals {
+		if total.Amount > 0 {
+			monthlySpend = append(monthlySpend, total.Amount)
+		}
+	}
+	if len(monthlySpend) == 0 {
+		return suggestion, false
+	}
+	for len(monthlySpend) < numOfMonths {
+		monthlySpend = append(monthlySpend, 0)
+	}
+
+	sorted := make([]float64, len(monthlySpend))
+	for i, amount := range monthlySpend {
+		sorted[i] = float64(amount)
+	}
+	sort.Float64s(sorted)
+	suggestion = BudgetSuggestion{
+		MonthlySpend: monthlySpend,
+		Mean:         int(stat.Mean(sorted, nil)),
+		Median:       int(median(sorted)),
+		Max:          int(sorted[len(sorted)-1]),
+	}
+
+	var amount int
+	switch method {
+	case SuggestionMethodMedian:
+		amount = suggestion.Median
+	case SuggestionMethodPercentile:
+		amount = utils.Percentile(monthlySpend, float64(percentile)/100)
+	default:
+		amount = suggestion.Mean
+	}
+	suggestion.SuggestedAmount = roundUpToMultiple(amount, suggestionRounding)
+	return suggestion, true
+}
+
+// median returns the middle of the sorted values, or the mean of the two middle values if there's an even
+// number of them
+func median(sorted []float64) float64 {
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func roundUpToMultiple(amount int, multiple int) int {
+	if amount%multiple == 0 {
+		return amount
+	}
+	return (amount/multiple + 1) * multiple
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func monthlyTotals(amounts ...int) []models.TotalByMonth {
+	totals := []models.TotalByMonth{}
+	for _, amount := range amounts {
+		totals = append(totals, models.TotalByMonth{Amount: amount})
+	}
+	return totals
+}
+
+func TestSuggestBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		totals     []models.TotalByMonth
+		method     string
+		percentile int
+		expected   int
+	}{
+		{
+			name:     "mean is rounded up to the next $10",
+			totals:   monthlyTotals(10000, 20000, 31000, 20000),
+			method:   SuggestionMethodMean,
+			expected: 21000,
+		},
+		{
+			name:     "median of an even number of months",
+			totals:   monthlyTotals(10000, 20000, 30000, 90000),
+			method:   SuggestionMethodMedian,
+			expected: 25000,
+		},
+		{
+			name:       "percentile",
+			totals:     monthlyTotals(10000, 20000, 30000, 40000),
+			method:     SuggestionMethodPercentile,
+			percentile: 75,
+			expected:   30000,
+		},
+		{
+			name:     "months without spending count as zero",
+			totals:   monthlyTotals(60000),
+			method:   SuggestionMethodMedian,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion, ok := suggestBudget(tt.totals, 4, tt.method, tt.percentile)
+			if !ok {
+				t.Fatalf("Expected a suggestion")
+			}
+			if suggestion.SuggestedAmount != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, suggestion.SuggestedAmount)
+			}
+			if len(suggestion.MonthlySpend) != 4 {
+				t.Errorf("Expected 4 months of spending, got %v", suggestion.MonthlySpend)
+			}
+		})
+	}
+
+	if _, ok := suggestBudget(monthlyTotals(), 4, SuggestionMethodMean, 0); ok {
+		t.Errorf("Expected no suggestion without any spending")
+	}
+}
+
+func TestSuggestBudgets(t *testing.T) {
+	foodID := uint(6)
+	categories := []models.Category{
+		{Model: gorm.Model{ID: 1}, Name: "Unknown", Kind: models.CategoryKindExpense},
+		{Model: gorm.Model{ID: 3}, Name: "Home", Kind: models.CategoryKindExpense},
+		{Model: gorm.Model{ID: 4}, Name: "Income", Kind: models.CategoryKindIncome},
+		{Model: gorm.Model{ID: 5}, Name: "Auto", Kind: models.CategoryKindExpense},
+		{Model: gorm.Model{ID: 6}, Name: "Food", Kind: models.CategoryKindExpense},
+		{Model: gorm.Model{ID: 7}, Name: "Dining", Kind: models.CategoryKindExpense, ParentID: &foodID},
+	}
+	service := &BudgetService{
+		// Home already has a budget
+		BudgetRepository:   &MockBudgetRepository{Budget: models.Budget{CategoryID: 3, Amount: 100000}},
+		CategoryRepository: &MockCategoryRepository{Categories: categories},
+		TransactionRepository: &MockTransactionRepository{TotalsByCategory: map[uint][]models.TotalByMonth{
+			1: monthlyTotals(5000, 5000, 5000),
+			3: monthlyTotals(150000, 150000, 150000),
+			4: monthlyTotals(500000, 500000, 500000),
+			6: monthlyTotals(40000, 45000, 50000),
+			7: monthlyTotals(10000, 12500, 15000),
+		}},
+	}
+
+	suggestions, err := service.SuggestBudgets(3, SuggestionMethodMean, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Auto has no spending, and Unknown, Income and Home are skipped
+	if len(suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions, got %+v", suggestions)
+	}
+	if suggestions[0].CategoryPath != "Food" || suggestions[0].SuggestedAmount != 45000 {
+		t.Errorf("Expected Food to be suggested 45000, got %+v", suggestions[0])
+	}
+	if suggestions[1].CategoryPath != "Food > Dining" || suggestions[1].SuggestedAmount != 13000 {
+		t.Errorf("Expected Dining to be suggested 13000, got %+v", suggestions[1])
+	}
+
+	if _, err := service.SuggestBudgets(3, SuggestionMethodPercentile, 0); err == nil {
+		t.Errorf("Expected an error for a percentile of 0")
+	}
+}
@@ -10,12 +10,17 @@ import (
 type CashFlowData struct {
 	TotalIncome   int
 	TotalExpenses int
+	TotalSavings  int // Contributions to savings and investment categories, which aren't expenses
 	Expenses      []ExpenseByCategory
 }
 
+// ExpenseByCategory is the spending in a category including its subcategories. Subcategories have the
+// name and amount of their parent, so they can be shown as flowing out of it.
 type ExpenseByCategory struct {
-	Name   string
-	Amount int
+	Name         string
+	Amount       int
+	ParentName   string
+	ParentAmount int
 }
 
 type CashFlowService struct {
@@ -23,20 +28,21 @@ type CashFlowService struct {
 	categoryRepo    *models.CategoryRepository
 }
 
-func NewCashFlowService(tr *models.TransactionRepository) *CashFlowService {
+func NewCashFlowService(tr *models.TransactionRepository, cr *models.CategoryRepository) *CashFlowService {
 	return &CashFlowService{
 		transactionRepo: tr,
+		categoryRepo:    cr,
 	}
 }
 
 func (s *CashFlowService) GetCashFlowData(ctx context.Context, startDate time.Time, endDate time.Time) (cashFlowData *CashFlowData, err error) {
 	cashFlowData = &CashFlowData{} // Initialize the struct
 
-	incomeCategory, err := s.categoryRepo.GetCategoryByName("Income")
+	cashFlowData.TotalIncome, err = s.transactionRepo.GetSumOfTransactionsByKind(models.CategoryKindIncome, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
-	cashFlowData.TotalIncome, err = s.transactionRepo.GetSumOfTransactionsByCategoryID(incomeCategory.ID, startDate, endDate)
+	cashFlowData.TotalSavings, err = s.transactionRepo.GetSumOfTransactionsByKind(models.CategoryKindSavings, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -44,15 +50,47 @@ func (s *CashFlowService) GetCashFlowData(ctx context.Context, startDate time.Ti
 	if err != nil {
 		return nil, err
 	}
-	totalExpenses := 0
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	// Roll subcategories up into their parents
+	totalsByCategoryID := map[uint]int{}
 	for _, expense := range expensesByCategory {
-		totalExpenses += expense.Amount
-		cashFlowData.Expenses = append(cashFlowData.Expenses, ExpenseByCategory{
-			Name:   expense.Category,
-			Amount: expense.Amount,
-		})
+		totalsByCategoryID[expense.CategoryID] = expense.Amount
+	}
+	categoryTree := BuildCategoryTree(categories)
+	RollUpCategoryTotals(categoryTree, totalsByCategoryID)
+	SortCategoryTreeByTotal(categoryTree)
+
+	totalExpenses := 0
+	for _, node := range categoryTree {
+		totalExpenses += node.Total
 	}
 	cashFlowData.TotalExpenses = totalExpenses
 
+	var addExpenses func(nodes []*CategoryNode, parent *CategoryNode)
+	addExpenses = func(nodes []*CategoryNode, parent *CategoryNode) {
+		for _, node := range nodes {
+			// Income, transfer and savings categories have no spending, so they're left out along with
+			// unused categories
+			if node.Total == 0 {
+				continue
+			}
+			expense := ExpenseByCategory{
+				Name:   node.Category.Name,
+				Amount: node.Total,
+			}
+			if parent != nil {
+				expense.ParentName = parent.Category.Name
+				expense.ParentAmount = parent.Total
+			}
+			cashFlowData.Expenses = append(cashFlowData.Expenses, expense)
+			addExpenses(node.Children, node)
+		}
+	}
+	addExpenses(categoryTree, nil)
+
 	return cashFlowData, nil
 }
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/GopherML/bag"
+	"gonum.org/v1/gonum/floats"
+)
+
+// Names of the supported categorizer backends. The name is stored in settings and used
+// as the key for persisted models
+const BayesBackend = "bayes"
+const KNNBackend = "knn"
+const LogisticBackend = "logistic"
+
+// SamplesByLabel maps a label (a category ID) to the descriptions trained under it
+type SamplesByLabel map[string][]string
+
+// TrainingSet is the backend-independent training data persisted for a model
+type TrainingSet struct {
+	Samples SamplesByLabel
+}
+
+// Classifier is implemented by every categorizer backend
+type Classifier interface {
+	// Train replaces anything the classifier has learned with the provided samples
+	Train(samples SamplesByLabel) error
+	// Predict returns the most likely label for a description, with ok set to false
+	// when the classifier isn't confident enough to pick one
+	Predict(description string) (label string, ok bool)
+}
+
+// IncrementalClassifier is implemented by backends that can learn a single sample
+// without being retrained on the whole training set
+type IncrementalClassifier interface {
+	Classifier
+	Learn(description string, label string)
+}
+
+type categorizerBackend struct {
+	Label         string
+	NewClassifier func() Classifier
+}
+
+var categorizerBackends = map[string]categorizerBackend{
+	BayesBackend:    {Label: "Naive Bayes (bag of words)", NewClassifier: func() Classifier { return &bayesClassifier{} }},
+	KNNBackend:      {Label: "TF-IDF nearest neighbours", NewClassifier: func() Classifier { return &knnClassifier{k: 5, minSimilarity: 0.2} }},
+	LogisticBackend: {Label: "Logistic regression", NewClassifier: func() Classifier { return &logisticClassifier{epochs: 30, learningRate: 0.5, minProbability: 0.35} }},
+}
+
+type CategorizerBackendOption struct {
+	Name  string
+	Label string
+}
+
+// GetCategorizerBackendOptions returns all selectable backends, sorted by name
+func GetCategorizerBackendOptions() []CategorizerBackendOption {
+	options := []CategorizerBackendOption{}
+	for name, backend := range categorizerBackends {
+		options = append(options, CategorizerBackendOption{Name: name, Label: backend.Label})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Name < options[j].Name })
+	return options
+}
+
+func NewClassifier(backend string) (Classifier, error) {
+	categorizerBackend, ok := categorizerBackends[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown categorizer backend %q", backend)
+	}
+	return categorizerBackend.NewClassifier(), nil
+}
+
+//
+// Naive Bayes, backed by GopherML/bag
+//
+
+type bayesClassifier struct {
+	bag *bag.Bag
+}
+
+func (bc *bayesClassifier) Train(samples SamplesByLabel) (err error) {
+	bc.bag, err = bag.NewFromTrainingSet(bag.TrainingSet{Samples: toBagSamples(samples)})
+	return err
+}
+
+func (bc *bayesClassifier) Learn(description string, label string) {
+	bc.bag.Train(description, label)
+}
+
+func (bc *bayesClassifier) Predict(description string) (label string, ok bool) {
+	if bc.bag == nil {
+		return "", false
+	}
+	results := bc.bag.GetResults(description)
+	label = results.GetHighestProbability()
+	// If the probability of the highest probability result is less than -8, then we're better off
+	// not picking a category at all
+	if label == "" || results[label] < -8 {
+		return "", false
+	}
+	return label, true
+}
+
+func toBagSamples(samples SamplesByLabel) bag.SamplesByLabel {
+	bagSamples := bag.SamplesByLabel{}
+	for label, descriptions := range samples {
+		bagSamples[label] = descriptions
+	}
+	return bagSamples
+}
+
+//
+// TF-IDF with k nearest neighbours
+//
+
+type knnClassifier struct {
+	k             int
+	minSimilarity float64
+
+	vectorizer tfidfVectorizer
+	vectors    []sparseVector
+	labels     []string
+}
+
+func (kc *knnClassifier) Train(samples SamplesByLabel) error {
+	documents, labels := flattenSamples(samples)
+	kc.vectorizer = newTFIDFVectorizer(documents)
+	kc.labels = labels
+	kc.vectors = make([]sparseVector, len(documents))
+	for i, document := range documents {
+		kc.vectors[i] = kc.vectorizer.transform(document)
+	}
+	return nil
+}
+
+func (kc *knnClassifier) Predict(description string) (label string, ok bool) {
+	query := kc.vectorizer.transform(tokenize(description))
+	if len(query) == 0 {
+		return "", false
+	}
+
+	type neighbour struct {
+		label      string
+		similarity float64
+	}
+	neighbours := []neighbour{}
+	for i, vector := range kc.vectors {
+		// Vectors are unit length, so the dot product is the cosine similarity
+		similarity := query.dot(vector)
+		if similarity > 0 {
+			neighbours = append(neighbours, neighbour{label: kc.labels[i], similarity: similarity})
+		}
+	}
+	if len(neighbours) == 0 {
+		return "", false
+	}
+	sort.SliceStable(neighbours, func(i, j int) bool { return neighbours[i].similarity > neighbours[j].similarity })
+	if neighbours[0].similarity < kc.minSimilarity {
+		return "", false
+	}
+	if len(neighbours) > kc.k {
+		neighbours = neighbours[:kc.k]
+	}
+
+	// Each of the nearest neighbours votes for its label, weighted by similarity
+	votes := map[string]float64{}
+	for _, n := range neighbours {
+		votes[n.label] += n.similarity
+	}
+	return highestScoringLabel(votes), true
+}
+
+//
+// Multinomial logistic regression over TF-IDF features
+//
+
+type logisticClassifier struct {
+	epochs         int
+	learningRate   float64
+	minProbability float64
+
+	vectorizer tfidfVectorizer
+	labels     []string
+	// One row of weights per label, with the bias term stored after the vocabulary weights
+	weights [][]float64
+}
+
+func (lc *logisticClassifier) Train(samples SamplesByLabel) error {
+	documents, documentLabels := flattenSamples(samples)
+	lc.vectorizer = newTFIDFVectorizer(documents)
+
+	lc.labels = []string{}
+	labelIndex := map[string]int{}
+	for _, label := range documentLabels {
+		if _, ok := labelIndex[label]; !ok {
+			labelIndex[label] = len(lc.labels)
+			lc.labels = append(lc.labels, label)
+		}
+	}
+
+	vocabularySize := len(lc.vectorizer.vocabulary)
+	lc.weights = make([][]float64, len(lc.labels))
+	for i := range lc.weights {
+		lc.weights[i] = make([]float64, vocabularySize+1)
+	}
+
+	vectors := make([]sparseVector, len(documents))
+	for i, document := range documents {
+		vectors[i] = lc.vectorizer.transform(document)
+	}
+
+	// Stochastic gradient descent on the cross-entropy loss, visiting samples in a
+	// fixed pseudo-random order so training is deterministic
+	random := rand.New(rand.NewSource(1))
+	order := random.Perm(len(vectors))
+	scores := make([]float64, len(lc.labels))
+	for epoch := 0; epoch < lc.epochs; epoch++ {
+		random.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		rate := lc.learningRate / (1 + float64(epoch)*0.1)
+		for _, i := range order {
+			lc.probabilities(vectors[i], scores)
+			for class := range lc.labels {
+				gradient := scores[class]
+				if class == labelIndex[documentLabels[i]] {
+					gradient -= 1
+				}
+				for feature, value := range vectors[i] {
+					lc.weights[class][feature] -= rate * gradient * value
+				}
+				lc.weights[class][vocabularySize] -= rate * gradient
+			}
+		}
+	}
+	return nil
+}
+
+func (lc *logisticClassifier) Predict(description string) (label string, ok bool) {
+	if len(lc.labels) == 0 {
+		return "", false
+	}
+	vector := lc.vectorizer.transform(tokenize(description))
+	if len(vector) == 0 {
+		return "", false
+	}
+	probabilities := make([]float64, len(lc.labels))
+	lc.probabilities(vector, probabilities)
+	best := floats.MaxIdx(probabilities)
+	if probabilities[best] < lc.minProbability {
+		return "", false
+	}
+	return lc.labels[best], true
+}
+
+// probabilities writes the softmax probability of each label for the vector into dst
+func (lc *logisticClassifier) probabilities(vector sparseVector, dst []float64) {
+	biasIndex := len(lc.vectorizer.vocabulary)
+	for class, weights := range lc.weights {
+		score := weights[biasIndex]
+		for feature, value := range vector {
+			score += weights[feature] * value
+		}
+		dst[class] = score
+	}
+	normalizer := floats.LogSumExp(dst)
+	for class := range dst {
+		dst[class] = math.Exp(dst[class] - normalizer)
+	}
+}
+
+//
+// Shared text processing
+//
+
+type sparseVector map[int]float64
+
+func (sv sparseVector) dot(other sparseVector) float64 {
+	// Iterate over the smaller vector
+	if len(other) < len(sv) {
+		sv, other = other, sv
+	}
+	total := 0.0
+	for index, value := range sv {
+		total += value * other[index]
+	}
+	return total
+}
+
+type tfidfVectorizer struct {
+	vocabulary map[string]int
+	idf        []float64
+}
+
+func newTFIDFVectorizer(documents [][]string) tfidfVectorizer {
+	vectorizer := tfidfVectorizer{vocabulary: map[string]int{}}
+	documentFrequency := []int{}
+	for _, document := range documents {
+		seen := map[string]bool{}
+		for _, token := range document {
+			if seen[token] {
+				continue
+			}
+			seen[token] = true
+			index, ok := vectorizer.vocabulary[token]
+			if !ok {
+				index = len(documentFrequency)
+				vectorizer.vocabulary[token] = index
+				documentFrequency = append(documentFrequency, 0)
+			}
+			documentFrequency[index]++
+		}
+	}
+	// Smoothed inverse document frequency
+	vectorizer.idf = make([]float64, len(documentFrequency))
+	for i, frequency := range documentFrequency {
+		vectorizer.idf[i] = math.Log(float64(1+len(documents))/float64(1+frequency)) + 1
+	}
+	return vectorizer
+}
+
+// transform converts tokens to a unit length TF-IDF vector, ignoring tokens outside the vocabulary
+func (tv tfidfVectorizer) transform(tokens []string) sparseVector {
+	vector := sparseVector{}
+	for _, token := range tokens {
+		if index, ok := tv.vocabulary[token]; ok {
+			vector[index] += tv.idf[index]
+		}
+	}
+	norm := 0.0
+	for _, value := range vector {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+	for index := range vector {
+		vector[index] /= norm
+	}
+	return vector
+}
+
+// tokenize splits a description into lowercase words, dropping numbers and single characters
+// since store numbers and dates don't help identify a category
+func tokenize(description string) []string {
+	tokens := []string{}
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		hasLetter := strings.IndexFunc(word, unicode.IsLetter) >= 0
+		if len(word) > 1 && hasLetter {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// flattenSamples returns tokenized documents and their labels in a stable order
+func flattenSamples(samples SamplesByLabel) (documents [][]string, labels []string) {
+	sortedLabels := make([]string, 0, len(samples))
+	for label := range samples {
+		sortedLabels = append(sortedLabels, label)
+	}
+	sort.Strings(sortedLabels)
+	for _, label := range sortedLabels {
+		for _, description := range samples[label] {
+			documents = append(documents, tokenize(description))
+			labels = append(labels, label)
+		}
+	}
+	return documents, labels
+}
+
+func highestScoringLabel(scores map[string]float64) (label string) {
+	best := math.Inf(-1)
+	for candidate, score := range scores {
+		// Break ties on the label so results are deterministic
+		if score > best || (score == best && candidate < label) {
+			best = score
+			label = candidate
+		}
+	}
+	return label
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testSamples = SamplesByLabel{
+	"2": {"whole foods market", "safeway grocery", "trader joes", "whole foods 1234", "safeway store"},
+	"3": {"shell gas station", "chevron gas", "jiffy lube oil change", "shell oil 0042", "chevron station"},
+}
+
+func TestClassifiers_PredictTrainedCategories(t *testing.T) {
+	for _, backend := range GetCategorizerBackendOptions() {
+		t.Run(backend.Name, func(t *testing.T) {
+			classifier, err := NewClassifier(backend.Name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err = classifier.Train(testSamples); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tests := map[string]string{
+				"WHOLE FOODS MARKET #99": "2",
+				"Chevron gas 0101":       "3",
+			}
+			for description, expected := range tests {
+				label, ok := classifier.Predict(description)
+				if !ok || label != expected {
+					t.Errorf("Predict(%q) = %q, %v; want %q, true", description, label, ok, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifiers_UntrainedReturnsNoPrediction(t *testing.T) {
+	for _, backend := range GetCategorizerBackendOptions() {
+		t.Run(backend.Name, func(t *testing.T) {
+			classifier, _ := NewClassifier(backend.Name)
+			if err := classifier.Train(SamplesByLabel{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if label, ok := classifier.Predict("whole foods market"); ok {
+				t.Errorf("expected no prediction from an empty model, got %q", label)
+			}
+		})
+	}
+}
+
+func TestNewClassifier_UnknownBackend(t *testing.T) {
+	if _, err := NewClassifier("astrology"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("SQ *BLUE BOTTLE 0423 Oakland, CA 7-11")
+	expected := []string{"sq", "blue", "bottle", "oakland", "ca"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected %v, got %v", expected, tokens)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const DefaultEvaluationFolds = 5
+
+// CategoryEvaluation holds the cross-validation metrics for a single category
+type CategoryEvaluation struct {
+	CategoryName string
+	Precision    float64
+	Recall       float64
+	F1           float64
+	// Number of samples that actually belong to the category
+	Support int
+}
+
+// CategorizerEvaluation is the result of cross-validating a backend against the training set
+type CategorizerEvaluation struct {
+	Backend        string
+	Folds          int
+	SampleCount    int
+	Accuracy       float64
+	MacroPrecision float64
+	MacroRecall    float64
+	Categories     []CategoryEvaluation
+	// ConfusionMatrix[i][j] counts samples of Categories[i] that were predicted as Categories[j]
+	ConfusionMatrix [][]int
+}
+
+// EvaluateBackend runs k-fold cross-validation of a backend over the current training set.
+// Predictions the backend isn't confident enough to make are counted as "Unknown", the same
+// as they would be when categorizing an imported transaction.
+func (mc *MLCategorizer) EvaluateBackend(backend string, folds int) (evaluation CategorizerEvaluation, err error) {
+	if _, err = NewClassifier(backend); err != nil {
+		return evaluation, err
+	}
+	samples := mc.snapshotSamples()
+
+	unknownCategory, err := mc.CategoryRepository.GetCategoryByName("Unknown")
+	if err != nil {
+		return evaluation, err
+	}
+	unknownLabel := categoryLabel(unknownCategory.ID)
+
+	// Order the labels by category name so the report is easy to scan
+	names := map[string]string{unknownLabel: unknownCategory.Name}
+	labels := []string{unknownLabel}
+	for label := range samples {
+		if label == unknownLabel {
+			continue
+		}
+		labels = append(labels, label)
+		names[label] = "Category " + label
+		if categoryID, err := parseCategoryLabel(label); err == nil {
+			if category, err := mc.CategoryRepository.GetCategoryByID(categoryID); err == nil {
+				names[label] = category.Name
+			}
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return names[labels[i]] < names[labels[j]] })
+
+	evaluation, err = crossValidate(backend, samples, labels, unknownLabel, folds)
+	if err != nil {
+		return evaluation, err
+	}
+	for i := range evaluation.Categories {
+		evaluation.Categories[i].CategoryName = names[labels[i]]
+	}
+	return evaluation, nil
+}
+
+// crossValidate trains the backend on all but one fold and predicts the held-out fold, once for
+// each fold. labels fixes the order of categories in the result and must include every label in
+// samples as well as unknownLabel.
+func crossValidate(backend string, samples SamplesByLabel, labels []string, unknownLabel string, folds int) (evaluation CategorizerEvaluation, err error) {
+	evaluation = CategorizerEvaluation{Backend: backend, Folds: folds}
+	if folds < 2 {
+		return evaluation, errors.New("cross-validation needs at least 2 folds")
+	}
+
+	// Assign samples to folds round-robin, one label at a time, so every fold gets a
+	// similar mix of categories. Shuffling with a fixed seed keeps results repeatable.
+	type sample struct {
+		description string
+		label       string
+		fold        int
+	}
+	sortedLabels := make([]string, 0, len(samples))
+	for label := range samples {
+		sortedLabels = append(sortedLabels, label)
+	}
+	sort.Strings(sortedLabels)
+	random := rand.New(rand.NewSource(1))
+	allSamples := []sample{}
+	for _, label := range sortedLabels {
+		descriptions := append([]string{}, samples[label]...)
+		random.Shuffle(len(descriptions), func(i, j int) { descriptions[i], descriptions[j] = descriptions[j], descriptions[i] })
+		for _, description := range descriptions {
+			allSamples = append(allSamples, sample{description: description, label: label, fold: len(allSamples) % folds})
+		}
+	}
+	evaluation.SampleCount = len(allSamples)
+	if evaluation.SampleCount < folds {
+		return evaluation, fmt.Errorf("cross-validation needs at least %d training samples, only %d available", folds, evaluation.SampleCount)
+	}
+
+	labelIndex := map[string]int{}
+	for i, label := range labels {
+		labelIndex[label] = i
+	}
+	evaluation.ConfusionMatrix = make([][]int, len(labels))
+	for i := range evaluation.ConfusionMatrix {
+		evaluation.ConfusionMatrix[i] = make([]int, len(labels))
+	}
+
+	for fold := 0; fold < folds; fold++ {
+		trainingSamples := SamplesByLabel{}
+		for _, s := range allSamples {
+			if s.fold != fold {
+				trainingSamples[s.label] = append(trainingSamples[s.label], s.description)
+			}
+		}
+		classifier, err := NewClassifier(backend)
+		if err != nil {
+			return evaluation, err
+		}
+		if err = classifier.Train(trainingSamples); err != nil {
+			return evaluation, err
+		}
+
+		for _, s := range allSamples {
+			if s.fold != fold {
+				continue
+			}
+			predicted, ok := classifier.Predict(s.description)
+			if !ok {
+				predicted = unknownLabel
+			}
+			actualIndex, ok := labelIndex[s.label]
+			if !ok {
+				return evaluation, fmt.Errorf("label %q is missing from the evaluation labels", s.label)
+			}
+			predictedIndex, ok := labelIndex[predicted]
+			if !ok {
+				return evaluation, fmt.Errorf("label %q is missing from the evaluation labels", predicted)
+			}
+			evaluation.ConfusionMatrix[actualIndex][predictedIndex]++
+		}
+	}
+
+	correct := 0
+	evaluatedCategories := 0
+	for i := range labels {
+		actual, predicted := 0, 0
+		for j := range labels {
+			actual += evaluation.ConfusionMatrix[i][j]
+			predicted += evaluation.ConfusionMatrix[j][i]
+		}
+		truePositives := evaluation.ConfusionMatrix[i][i]
+		correct += truePositives
+
+		categoryEvaluation := CategoryEvaluation{Support: actual}
+		if predicted > 0 {
+			categoryEvaluation.Precision = float64(truePositives) / float64(predicted)
+		}
+		if actual > 0 {
+			categoryEvaluation.Recall = float64(truePositives) / float64(actual)
+		}
+		if categoryEvaluation.Precision+categoryEvaluation.Recall > 0 {
+			categoryEvaluation.F1 = 2 * categoryEvaluation.Precision * categoryEvaluation.Recall / (categoryEvaluation.Precision + categoryEvaluation.Recall)
+		}
+		// Categories without any samples (usually "Unknown") don't count towards the macro averages
+		if actual > 0 {
+			evaluatedCategories++
+			evaluation.MacroPrecision += categoryEvaluation.Precision
+			evaluation.MacroRecall += categoryEvaluation.Recall
+		}
+		evaluation.Categories = append(evaluation.Categories, categoryEvaluation)
+	}
+	evaluation.Accuracy = float64(correct) / float64(evaluation.SampleCount)
+	if evaluatedCategories > 0 {
+		evaluation.MacroPrecision /= float64(evaluatedCategories)
+		evaluation.MacroRecall /= float64(evaluatedCategories)
+	}
+	return evaluation, nil
+}
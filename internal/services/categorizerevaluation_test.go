@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+func TestCrossValidate_ConfusionMatrixCoversEverySample(t *testing.T) {
+	evaluation, err := crossValidate(KNNBackend, testSamples, []string{"1", "2", "3"}, "1", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evaluation.SampleCount != 10 {
+		t.Errorf("expected 10 samples, got %d", evaluation.SampleCount)
+	}
+	total := 0
+	for _, row := range evaluation.ConfusionMatrix {
+		for _, count := range row {
+			total += count
+		}
+	}
+	if total != evaluation.SampleCount {
+		t.Errorf("expected the confusion matrix to count %d samples, got %d", evaluation.SampleCount, total)
+	}
+	if len(evaluation.Categories) != 3 {
+		t.Fatalf("expected 3 categories, got %d", len(evaluation.Categories))
+	}
+	if evaluation.Categories[1].Support != 5 || evaluation.Categories[2].Support != 5 {
+		t.Errorf("expected 5 samples per category, got %d and %d", evaluation.Categories[1].Support, evaluation.Categories[2].Support)
+	}
+	if evaluation.Accuracy < 0.5 {
+		t.Errorf("expected accuracy of at least 50%% on separable data, got %f", evaluation.Accuracy)
+	}
+}
+
+func TestCrossValidate_PrecisionAndRecall(t *testing.T) {
+	samples := SamplesByLabel{
+		"2": {"whole foods", "whole foods", "whole foods"},
+		"3": {"shell gas", "shell gas", "shell gas"},
+	}
+	evaluation, err := crossValidate(BayesBackend, samples, []string{"1", "2", "3"}, "1", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evaluation.Accuracy != 1 {
+		t.Errorf("expected perfect accuracy, got %f", evaluation.Accuracy)
+	}
+	for _, category := range evaluation.Categories[1:] {
+		if category.Precision != 1 || category.Recall != 1 {
+			t.Errorf("expected perfect precision and recall, got %f and %f", category.Precision, category.Recall)
+		}
+	}
+	if evaluation.MacroPrecision != 1 || evaluation.MacroRecall != 1 {
+		t.Errorf("expected macro averages to ignore the empty Unknown category, got %f and %f", evaluation.MacroPrecision, evaluation.MacroRecall)
+	}
+}
+
+func TestCrossValidate_TooFewSamples(t *testing.T) {
+	_, err := crossValidate(BayesBackend, SamplesByLabel{"2": {"whole foods"}}, []string{"1", "2"}, "1", 5)
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestEvaluateBackend_NamesCategories(t *testing.T) {
+	trainingTxns := []models.Transaction{}
+	for label, descriptions := range testSamples {
+		categoryID, _ := parseCategoryLabel(label)
+		for _, description := range descriptions {
+			trainingTxns = append(trainingTxns, models.Transaction{Description: description, CategoryID: categoryID, UseForTraining: true})
+		}
+	}
+	categorizer := newTestCategorizer(trainingTxns, &MockCategorizerModelRepository{})
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	evaluation, err := categorizer.EvaluateBackend(LogisticBackend, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, category := range evaluation.Categories {
+		names = append(names, category.CategoryName)
+	}
+	expected := []string{"Auto", "Food", "Unknown"}
+	if len(names) != len(expected) || names[0] != expected[0] || names[1] != expected[1] || names[2] != expected[2] {
+		t.Errorf("expected categories %v, got %v", expected, names)
+	}
+}
+
+func TestEvaluateBackend_UnknownBackend(t *testing.T) {
+	categorizer := newTestCategorizer(nil, &MockCategorizerModelRepository{})
+	if _, err := categorizer.EvaluateBackend("astrology", 5); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
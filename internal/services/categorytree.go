@@ -0,0 +1,137 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// CategoryNode is a category along with its subcategories. Total is the category's own total plus the
+// totals of all of its descendants, once RollUpCategoryTotals has been called.
+type CategoryNode struct {
+	Category models.Category
+	Depth    int
+	Path     string // e.g. "Food > Groceries"
+	Children []*CategoryNode
+	Total    int
+}
+
+// BuildCategoryTree arranges categories into a tree, returning the top level categories sorted by name.
+// Categories whose parent is missing (e.g. it was deleted) are treated as top level categories.
+func BuildCategoryTree(categories []models.Category) []*CategoryNode {
+	nodesByID := map[uint]*CategoryNode{}
+	for _, category := range categories {
+		nodesByID[category.ID] = &CategoryNode{Category: category}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodesByID[category.ID]
+		parent, ok := nodesByParentID(nodesByID, category.ParentID)
+		if !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	// A cycle in the data would leave categories unreachable from the roots, so promote them
+	reachable := map[uint]bool{}
+	var visit func(nodes []*CategoryNode, depth int, parentPath string)
+	visit = func(nodes []*CategoryNode, depth int, parentPath string) {
+		sortCategoryNodes(nodes)
+		for _, node := range nodes {
+			reachable[node.Category.ID] = true
+			node.Depth = depth
+			node.Path = node.Category.Name
+			if parentPath != "" {
+				node.Path = parentPath + " > " + node.Category.Name
+			}
+			visit(node.Children, depth+1, node.Path)
+		}
+	}
+	visit(roots, 0, "")
+	for _, category := range categories {
+		if !reachable[category.ID] {
+			node := nodesByID[category.ID]
+			if parent, ok := nodesByParentID(nodesByID, category.ParentID); ok {
+				parent.Children = removeCategoryNode(parent.Children, node)
+			}
+			roots = append(roots, node)
+			visit([]*CategoryNode{node}, 0, "")
+		}
+	}
+	sortCategoryNodes(roots)
+	return roots
+}
+
+// FlattenCategoryTree lists the categories depth first, so each category is followed by its subcategories
+func FlattenCategoryTree(roots []*CategoryNode) []*CategoryNode {
+	nodes := []*CategoryNode{}
+	for _, root := range roots {
+		nodes = append(nodes, root)
+		nodes = append(nodes, FlattenCategoryTree(root.Children)...)
+	}
+	return nodes
+}
+
+// RollUpCategoryTotals sets the Total of each category to its own total plus the totals of its descendants
+func RollUpCategoryTotals(roots []*CategoryNode, totalsByCategoryID map[uint]int) {
+	for _, root := range roots {
+		RollUpCategoryTotals(root.Children, totalsByCategoryID)
+		root.Total = totalsByCategoryID[root.Category.ID]
+		for _, child := range root.Children {
+			root.Total += child.Total
+		}
+	}
+}
+
+// CategoryAndDescendantIDs returns the ID of a category and all of its descendants, which is useful for
+// preventing a category from being moved underneath itself
+func CategoryAndDescendantIDs(roots []*CategoryNode, categoryID uint) map[uint]bool {
+	ids := map[uint]bool{}
+	for _, node := range FlattenCategoryTree(roots) {
+		if node.Category.ID == categoryID {
+			for _, descendant := range FlattenCategoryTree([]*CategoryNode{node}) {
+				ids[descendant.Category.ID] = true
+			}
+			break
+		}
+	}
+	return ids
+}
+
+func nodesByParentID(nodesByID map[uint]*CategoryNode, parentID *uint) (*CategoryNode, bool) {
+	if parentID == nil {
+		return nil, false
+	}
+	parent, ok := nodesByID[*parentID]
+	return parent, ok
+}
+
+func sortCategoryNodes(nodes []*CategoryNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Category.Name) < strings.ToLower(nodes[j].Category.Name)
+	})
+}
+
+func removeCategoryNode(nodes []*CategoryNode, target *CategoryNode) []*CategoryNode {
+	remaining := []*CategoryNode{}
+	for _, node := range nodes {
+		if node != target {
+			remaining = append(remaining, node)
+		}
+	}
+	return remaining
+}
+
+// SortCategoryTreeByTotal orders the categories at each level of the tree from the largest total to the smallest
+func SortCategoryTreeByTotal(roots []*CategoryNode) {
+	sort.SliceStable(roots, func(i, j int) bool {
+		return roots[i].Total > roots[j].Total
+	})
+	for _, root := range roots {
+		SortCategoryTreeByTotal(root.Children)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func uintPointer(value uint) *uint {
+	return &value
+}
+
+func testCategories() []models.Category {
+	return []models.Category{
+		{Model: gorm.Model{ID: 1}, Name: "Unknown"},
+		{Model: gorm.Model{ID: 2}, Name: "Food"},
+		{Model: gorm.Model{ID: 3}, Name: "Groceries", ParentID: uintPointer(2)},
+		{Model: gorm.Model{ID: 4}, Name: "Dining", ParentID: uintPointer(2)},
+		{Model: gorm.Model{ID: 5}, Name: "Coffee", ParentID: uintPointer(4)},
+		{Model: gorm.Model{ID: 6}, Name: "Auto"},
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	roots := BuildCategoryTree(testCategories())
+
+	nodes := FlattenCategoryTree(roots)
+	expectedPaths := []string{"Auto", "Food", "Food > Dining", "Food > Dining > Coffee", "Food > Groceries", "Unknown"}
+	expectedDepths := []int{0, 0, 1, 2, 1, 0}
+	if len(nodes) != len(expectedPaths) {
+		t.Fatalf("Expected %d categories, got %d", len(expectedPaths), len(nodes))
+	}
+	for i, node := range nodes {
+		if node.Path != expectedPaths[i] {
+			t.Errorf("Expected category %d to have path %q, got %q", i, expectedPaths[i], node.Path)
+		}
+		if node.Depth != expectedDepths[i] {
+			t.Errorf("Expected %q to have depth %d, got %d", node.Path, expectedDepths[i], node.Depth)
+		}
+	}
+}
+
+func TestBuildCategoryTree_BreaksCycles(t *testing.T) {
+	categories := []models.Category{
+		{Model: gorm.Model{ID: 1}, Name: "A", ParentID: uintPointer(2)},
+		{Model: gorm.Model{ID: 2}, Name: "B", ParentID: uintPointer(1)},
+		{Model: gorm.Model{ID: 3}, Name: "C", ParentID: uintPointer(99)},
+	}
+
+	nodes := FlattenCategoryTree(BuildCategoryTree(categories))
+	if len(nodes) != 3 {
+		t.Fatalf("Expected every category to be in the tree, got %d", len(nodes))
+	}
+	if nodes[0].Path != "A" || nodes[1].Path != "A > B" || nodes[2].Path != "C" {
+		t.Errorf("Unexpected paths %q, %q, %q", nodes[0].Path, nodes[1].Path, nodes[2].Path)
+	}
+}
+
+func TestRollUpCategoryTotals(t *testing.T) {
+	roots := BuildCategoryTree(testCategories())
+
+	RollUpCategoryTotals(roots, map[uint]int{2: 100, 3: 2000, 4: 500, 5: 250, 6: 4000})
+
+	expectedTotals := map[string]int{
+		"Auto":                   4000,
+		"Food":                   2850,
+		"Food > Dining":          750,
+		"Food > Dining > Coffee": 250,
+		"Food > Groceries":       2000,
+		"Unknown":                0,
+	}
+	for _, node := range FlattenCategoryTree(roots) {
+		if node.Total != expectedTotals[node.Path] {
+			t.Errorf("Expected %q to total %d, got %d", node.Path, expectedTotals[node.Path], node.Total)
+		}
+	}
+}
+
+func TestCategoryAndDescendantIDs(t *testing.T) {
+	roots := BuildCategoryTree(testCategories())
+
+	ids := CategoryAndDescendantIDs(roots, 2)
+	for _, id := range []uint{2, 3, 4, 5} {
+		if !ids[id] {
+			t.Errorf("Expected category %d to be in Food's subtree", id)
+		}
+	}
+	if ids[1] || ids[6] {
+		t.Errorf("Expected only Food and its subcategories, got %v", ids)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// Time frames the reports can be shown for. The last N months are calendar months, counting the current one.
+const (
+	DateRangeAllTime          = "allTime"
+	DateRangeLastYear         = "lastYear"
+	DateRangeYearToDate       = "ytd"
+	DateRangeFiscalYearToDate = "fiscalYtd"
+	DateRangeLast12Months     = "12"
+	DateRangeLast6Months      = "6"
+	DateRangeLast3Months      = "3"
+	DateRangeCustom           = "custom"
+)
+
+const dateRangeDateFormat = "2006-01-02"
+
+// DateRangeWindow is one of the time frames offered by the date range controls
+type DateRangeWindow struct {
+	Value string
+	Label string
+}
+
+// DateRangeWindows are the time frames offered by the date range controls, in the order they're shown. Custom
+// ranges are picked with start and end dates instead.
+var DateRangeWindows = []DateRangeWindow{
+	{Value: DateRangeAllTime, Label: "All time"},
+	{Value: DateRangeLastYear, Label: "Last year"},
+	{Value: DateRangeFiscalYearToDate, Label: "Fiscal YTD"},
+	{Value: DateRangeYearToDate, Label: "YTD"},
+	{Value: DateRangeLast12Months, Label: "Last 12 months"},
+	{Value: DateRangeLast6Months, Label: "Last 6 months"},
+	{Value: DateRangeLast3Months, Label: "Last 3 months"},
+}
+
+// DateRangeTransactionRepositoryInterface specifically for DateRangeService
+type DateRangeTransactionRepositoryInterface interface {
+	GetEarliestTransactionDate() (string, error)
+}
+
+// DateRangeBalanceRepositoryInterface specifically for DateRangeService
+type DateRangeBalanceRepositoryInterface interface {
+	GetEarliestBalanceDate(ctx context.Context) (string, error)
+}
+
+// DateRangeSettingsRepositoryInterface specifically for DateRangeService
+type DateRangeSettingsRepositoryInterface interface {
+	GetSettings() (*models.Settings, error)
+}
+
+type DateRangeService struct {
+	TransactionRepository DateRangeTransactionRepositoryInterface
+	BalanceRepository     DateRangeBalanceRepositoryInterface
+	SettingsRepository    DateRangeSettingsRepositoryInterface
+}
+
+// DateRange is the time frame a report covers. StartDate is the start of its first day and EndDate is the end of
+// its last day, so both days are included.
+type DateRange struct {
+	Window    string
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// GetDateRange works out the time frame of a window as of today. Custom ranges use startDate and endDate, as
+// YYYY-MM-DD. An empty window is a custom range if either date is given, otherwise defaultWindow is used, and an
+// unknown window falls back to the last 6 months.
+func (drs *DateRangeService) GetDateRange(window string, startDate string, endDate string, defaultWindow string, today time.Time) (DateRange, error) {
+	if window == "" {
+		window = defaultWindow
+		if startDate != "" || endDate != "" {
+			window = DateRangeCustom
+		}
+	}
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	thisMonth := day.AddDate(0, 0, 1-day.Day())
+	dateRange := DateRange{Window: window, EndDate: endOfDay(day)}
+
+	switch window {
+	case DateRangeLast12Months:
+		dateRange.StartDate = thisMonth.AddDate(0, -11, 0)
+	case DateRangeLast6Months:
+		dateRange.StartDate = thisMonth.AddDate(0, -5, 0)
+	case DateRangeLast3Months:
+		dateRange.StartDate = thisMonth.AddDate(0, -2, 0)
+	case DateRangeYearToDate:
+		dateRange.StartDate = time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, day.Location())
+	case DateRangeFiscalYearToDate:
+		settings, err := drs.SettingsRepository.GetSettings()
+		if err != nil {
+			return DateRange{}, err
+		}
+		dateRange.StartDate = fiscalYearStart(day, settings.FiscalYearStartMonth)
+	case DateRangeLastYear:
+		dateRange.StartDate = time.Date(day.Year()-1, time.January, 1, 0, 0, 0, 0, day.Location())
+		dateRange.EndDate = endOfDay(time.Date(day.Year()-1, time.December, 31, 0, 0, 0, 0, day.Location()))
+	case DateRangeAllTime:
+		earliest, err := drs.earliestDate(day)
+		if err != nil {
+			return DateRange{}, err
+		}
+		dateRange.StartDate = earliest
+	case DateRangeCustom:
+		if startDate == "" || endDate == "" {
+			return DateRange{}, errors.New("a custom date range needs a start and an end date")
+		}
+		start, err := time.ParseInLocation(dateRangeDateFormat, startDate, day.Location())
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid start date %q", startDate)
+		}
+		end, err := time.ParseInLocation(dateRangeDateFormat, endDate, day.Location())
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid end date %q", endDate)
+		}
+		if end.Before(start) {
+			return DateRange{}, errors.New("the start date must be on or before the end date")
+		}
+		dateRange.StartDate = start
+		dateRange.EndDate = endOfDay(end)
+	default:
+		fmt.Println("invalid relative window provided, falling back to 6 months")
+		return drs.GetDateRange(DateRangeLast6Months, "", "", DateRangeLast6Months, today)
+	}
+	return dateRange, nil
+}
+
+// earliestDate is the date of the oldest transaction or balance, or the start of the current month if there
+// aren't any
+func (drs *DateRangeService) earliestDate(day time.Time) (time.Time, error) {
+	earliest := day.AddDate(0, 0, 1-day.Day())
+	transactionDate, err := drs.TransactionRepository.GetEarliestTransactionDate()
+	if err != nil {
+		return time.Time{}, err
+	}
+	balanceDate, err := drs.BalanceRepository.GetEarliestBalanceDate(context.TODO())
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, date := range []string{transactionDate, balanceDate} {
+		if date == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation(dateRangeDateFormat, date, day.Location())
+		if err != nil {
+			return time.Time{}, err
+		}
+		if parsed.Before(earliest) {
+			earliest = parsed
+		}
+	}
+	return earliest, nil
+}
+
+// fiscalYearStart is the first day of the fiscal year that day falls in, for fiscal years starting on the first
+// of startMonth
+func fiscalYearStart(day time.Time, startMonth int) time.Time {
+	if startMonth < 1 || startMonth > 12 {
+		startMonth = int(time.January)
+	}
+	start := time.Date(day.Year(), time.Month(startMonth), 1, 0, 0, 0, 0, day.Location())
+	if start.After(day) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+func endOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+type MockDateRangeTransactionRepository struct {
+	EarliestDate string
+}
+
+func (m *MockDateRangeTransactionRepository) GetEarliestTransactionDate() (string, error) {
+	return m.EarliestDate, nil
+}
+
+type MockDateRangeBalanceRepository struct {
+	EarliestDate string
+}
+
+func (m *MockDateRangeBalanceRepository) GetEarliestBalanceDate(ctx context.Context) (string, error) {
+	return m.EarliestDate, nil
+}
+
+func TestGetDateRange(t *testing.T) {
+	today := time.Date(2026, 3, 15, 14, 30, 0, 0, time.UTC)
+	service := &DateRangeService{
+		TransactionRepository: &MockDateRangeTransactionRepository{EarliestDate: "2021-06-03"},
+		BalanceRepository:     &MockDateRangeBalanceRepository{EarliestDate: "2022-01-01"},
+		SettingsRepository:    &MockSettingsRepository{Settings: models.Settings{FiscalYearStartMonth: 7}},
+	}
+
+	tests := []struct {
+		name           string
+		window         string
+		startDate      string
+		endDate        string
+		expectedWindow string
+		expectedStart  string
+		expectedEnd    string
+	}{
+		{"default window", "", "", "", DateRangeLast6Months, "2025-10-01", "2026-03-15"},
+		{"last 12 months", DateRangeLast12Months, "", "", DateRangeLast12Months, "2025-04-01", "2026-03-15"},
+		{"last 3 months", DateRangeLast3Months, "", "", DateRangeLast3Months, "2026-01-01", "2026-03-15"},
+		{"year to date", DateRangeYearToDate, "", "", DateRangeYearToDate, "2026-01-01", "2026-03-15"},
+		{"fiscal year to date", DateRangeFiscalYearToDate, "", "", DateRangeFiscalYearToDate, "2025-07-01", "2026-03-15"},
+		{"last year", DateRangeLastYear, "", "", DateRangeLastYear, "2025-01-01", "2025-12-31"},
+		{"all time starts with the oldest transaction or balance", DateRangeAllTime, "", "", DateRangeAllTime, "2021-06-03", "2026-03-15"},
+		{"custom", DateRangeCustom, "2024-02-10", "2024-05-20", DateRangeCustom, "2024-02-10", "2024-05-20"},
+		{"dates without a window are custom", "", "2024-02-10", "2024-02-10", DateRangeCustom, "2024-02-10", "2024-02-10"},
+		{"unknown window", "fortnight", "", "", DateRangeLast6Months, "2025-10-01", "2026-03-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dateRange, err := service.GetDateRange(tt.window, tt.startDate, tt.endDate, DateRangeLast6Months, today)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dateRange.Window != tt.expectedWindow {
+				t.Errorf("expected window %s, got %s", tt.expectedWindow, dateRange.Window)
+			}
+			if got := dateRange.StartDate.Format("2006-01-02"); got != tt.expectedStart {
+				t.Errorf("expected start date %s, got %s", tt.expectedStart, got)
+			}
+			if got := dateRange.EndDate.Format("2006-01-02"); got != tt.expectedEnd {
+				t.Errorf("expected end date %s, got %s", tt.expectedEnd, got)
+			}
+			if !dateRange.StartDate.Equal(time.Date(dateRange.StartDate.Year(), dateRange.StartDate.Month(), dateRange.StartDate.Day(), 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("expected the start date to be the start of the day, got %v", dateRange.StartDate)
+			}
+			if dateRange.EndDate.Hour() != 23 {
+				t.Errorf("expected the end date to be the end of the day, got %v", dateRange.EndDate)
+			}
+		})
+	}
+}
+
+func TestGetDateRange_Errors(t *testing.T) {
+	today := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	service := &DateRangeService{}
+
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   string
+	}{
+		{"missing end date", "2026-01-01", ""},
+		{"invalid start date", "01/01/2026", "2026-02-01"},
+		{"end before start", "2026-02-01", "2026-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetDateRange(DateRangeCustom, tt.startDate, tt.endDate, DateRangeLast6Months, today)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestGetDateRange_AllTimeWithoutData(t *testing.T) {
+	today := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	service := &DateRangeService{
+		TransactionRepository: &MockDateRangeTransactionRepository{},
+		BalanceRepository:     &MockDateRangeBalanceRepository{},
+	}
+
+	dateRange, err := service.GetDateRange(DateRangeAllTime, "", "", DateRangeLast6Months, today)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := dateRange.StartDate.Format("2006-01-02"); got != "2026-03-01" {
+		t.Errorf("expected all time to start this month without any data, got %s", got)
+	}
+}
+
+func TestFiscalYearStart(t *testing.T) {
+	tests := []struct {
+		day        string
+		startMonth int
+		expected   string
+	}{
+		{"2026-03-15", 1, "2026-01-01"},
+		{"2026-03-15", 10, "2025-10-01"},
+		{"2026-10-01", 10, "2026-10-01"},
+		{"2026-03-15", 0, "2026-01-01"},
+	}
+
+	for _, tt := range tests {
+		day, _ := time.Parse("2006-01-02", tt.day)
+		if got := fiscalYearStart(day, tt.startMonth).Format("2006-01-02"); got != tt.expected {
+			t.Errorf("fiscalYearStart(%s, %d): expected %s, got %s", tt.day, tt.startMonth, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// GoalBalanceRepositoryInterface specifically for GoalService
+type GoalBalanceRepositoryInterface interface {
+	GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance
+}
+
+// Whether a goal is expected to be reached by its target date
+const (
+	GoalStatusAchieved  = "achieved"
+	GoalStatusOnTrack   = "onTrack"
+	GoalStatusBehind    = "behind"
+	GoalStatusOverdue   = "overdue"   // The target date has passed without reaching the goal
+	GoalStatusNoHistory = "noHistory" // Not enough balance history to tell how fast the goal is growing
+)
+
+const (
+	// goalHistoryMonths is how many months of balances are shown for a goal
+	goalHistoryMonths = 12
+	// goalGrowthMonths is how many recent months of balances the growth rate is based on
+	goalGrowthMonths = 6
+	// goalProjectionLimit caps how many months are projected, for goals that are years away
+	goalProjectionLimit = 120
+	daysPerMonth        = 365.25 / 12
+)
+
+type GoalService struct {
+	BalanceRepository GoalBalanceRepositoryInterface
+}
+
+// GoalAmountOnDate is the combined balance of a goal's accounts on a date
+type GoalAmountOnDate struct {
+	Date   time.Time
+	Amount int
+}
+
+type GoalProgress struct {
+	Goal            models.Goal
+	CurrentAmount   int
+	PercentComplete int
+	Remaining       int
+	// MonthsLeft is the number of months until the target date, rounded up, or 0 if it has passed
+	MonthsLeft int
+	// RequiredMonthlyContribution is how much has to be added each month to reach the goal by the target date
+	RequiredMonthlyContribution int
+	// MonthlyGrowth is how much the goal's balance has grown per month recently
+	MonthlyGrowth int
+	// ProjectedAmount is the balance expected by the target date if growth continues at the same rate
+	ProjectedAmount int
+	// ProjectedCompletionDate is when the goal is expected to be reached at the current rate, if that's within
+	// goalProjectionLimit months
+	ProjectedCompletionDate *time.Time
+	Status                  string
+	// History is the goal's balance at the end of each recent month and today, oldest first
+	History []GoalAmountOnDate
+	// Projection is the expected balance at the end of each month until the target date, starting from today
+	Projection []GoalAmountOnDate
+}
+
+// GetGoalProgress works out how close a goal is to its target from the latest balances of its accounts, and
+// projects its balance forward based on how fast it has grown recently
+func (gs *GoalService) GetGoalProgress(goal models.Goal, asOf time.Time) GoalProgress {
+	balancesByAccount := map[uint][]models.Balance{}
+	for _, account := range goal.Accounts {
+		balancesByAccount[account.ID] = gs.BalanceRepository.GetBalancesForAccount(context.Background(), account.ID)
+	}
+	return projectGoal(goal, balancesByAccount, asOf)
+}
+
+// projectGoal does the work of GetGoalProgress. Each account's balances are newest first.
+func projectGoal(goal models.Goal, balancesByAccount map[uint][]models.Balance, asOf time.Time) GoalProgress {
+	today := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, asOf.Location())
+	progress := GoalProgress{Goal: goal}
+
+	// Month ends before today, then today, skipping dates before any of the accounts had a balance
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	for i := goalHistoryMonths; i >= 0; i-- {
+		date := monthStart.AddDate(0, 1-i, -1)
+		if i == 0 {
+			date = today
+		}
+		amount, ok := goalAmountOn(balancesByAccount, date)
+		if ok {
+			progress.History = append(progress.History, GoalAmountOnDate{Date: date, Amount: amount})
+		}
+	}
+	if len(progress.History) > 0 {
+		progress.CurrentAmount = progress.History[len(progress.History)-1].Amount
+	}
+	progress.Remaining = max(goal.TargetAmount-progress.CurrentAmount, 0)
+	if goal.TargetAmount > 0 {
+		progress.PercentComplete = min(int(float64(progress.CurrentAmount)/float64(goal.TargetAmount)*100), 100)
+	}
+
+	// Growth is measured from the oldest balance in the last few months, so a goal that was only set up
+	// recently uses what history it has
+	growthKnown := false
+	growthStart := monthStart.AddDate(0, -goalGrowthMonths, -1)
+	for _, point := range progress.History {
+		if point.Date.Before(growthStart) || !point.Date.Before(today) {
+			continue
+		}
+		months := today.Sub(point.Date).Hours() / 24 / daysPerMonth
+		progress.MonthlyGrowth = int(float64(progress.CurrentAmount-point.Amount) / months)
+		growthKnown = true
+		break
+	}
+
+	// A goal without a valid target date is treated as overdue
+	targetDate, _ := time.ParseInLocation("2006-01-02", goal.TargetDate, today.Location())
+	monthsLeft := 0.0
+	if targetDate.After(today) {
+		monthsLeft = targetDate.Sub(today).Hours() / 24 / daysPerMonth
+		progress.MonthsLeft = calendarMonthsBetween(today, targetDate)
+		progress.RequiredMonthlyContribution = int(math.Ceil(float64(progress.Remaining) / float64(progress.MonthsLeft)))
+	} else {
+		progress.RequiredMonthlyContribution = progress.Remaining
+	}
+	progress.ProjectedAmount = progress.CurrentAmount + int(float64(progress.MonthlyGrowth)*monthsLeft)
+
+	// Growth too slow to reach the goal within the projection limit doesn't get a completion date
+	if progress.Remaining > 0 && progress.MonthlyGrowth > 0 {
+		monthsToGo := float64(progress.Remaining) / float64(progress.MonthlyGrowth)
+		if monthsToGo <= goalProjectionLimit {
+			completionDate := today.AddDate(0, 0, int(math.Ceil(monthsToGo*daysPerMonth)))
+			progress.ProjectedCompletionDate = &completionDate
+		}
+	}
+
+	switch {
+	case progress.Remaining == 0:
+		progress.Status = GoalStatusAchieved
+	case progress.MonthsLeft == 0:
+		progress.Status = GoalStatusOverdue
+	case !growthKnown:
+		progress.Status = GoalStatusNoHistory
+	case progress.ProjectedAmount >= goal.TargetAmount:
+		progress.Status = GoalStatusOnTrack
+	default:
+		progress.Status = GoalStatusBehind
+	}
+
+	if progress.Status != GoalStatusAchieved && progress.Status != GoalStatusOverdue {
+		progress.Projection = append(progress.Projection, GoalAmountOnDate{Date: today, Amount: progress.CurrentAmount})
+		for i := 1; i <= goalProjectionLimit; i++ {
+			date := monthStart.AddDate(0, i, -1)
+			if !date.After(today) {
+				continue
+			}
+			if date.After(targetDate) {
+				date = targetDate
+			}
+			months := date.Sub(today).Hours() / 24 / daysPerMonth
+			progress.Projection = append(progress.Projection, GoalAmountOnDate{
+				Date:   date,
+				Amount: progress.CurrentAmount + int(float64(progress.MonthlyGrowth)*months),
+			})
+			if !date.Before(targetDate) {
+				break
+			}
+		}
+	}
+	return progress
+}
+
+// calendarMonthsBetween counts the months from one date to a later one, counting a part month as a whole one,
+// e.g. Oct 19 to Dec 19 is 2 months and Oct 19 to Dec 20 is 3
+func calendarMonthsBetween(from time.Time, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() > from.Day() {
+		months++
+	}
+	return max(months, 1)
+}
+
+// goalAmountOn adds up each account's latest balance on or before the date, returning false if none of the
+// accounts had a balance by then
+func goalAmountOn(balancesByAccount map[uint][]models.Balance, date time.Time) (amount int, ok bool) {
+	isoDate := utils.TimeToISO8601DateString(date)
+	for _, balances := range balancesByAccount {
+		for _, balance := range balances {
+			if balance.EffectiveDate <= isoDate {
+				amount += balance.Amount
+				ok = true
+				break
+			}
+		}
+	}
+	return amount, ok
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockGoalBalanceRepository struct {
+	BalancesByAccount map[uint][]models.Balance
+}
+
+func (m *MockGoalBalanceRepository) GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance {
+	return m.BalancesByAccount[accountID]
+}
+
+func TestGetGoalProgress(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	service := &GoalService{BalanceRepository: &MockGoalBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {
+			{EffectiveDate: "2026-10-10", Amount: 300000},
+			{EffectiveDate: "2026-09-15", Amount: 250000},
+			{EffectiveDate: "2026-04-01", Amount: 100000},
+		},
+		2: {
+			{EffectiveDate: "2026-10-01", Amount: 50000},
+		},
+		3: {
+			{EffectiveDate: "2026-10-18", Amount: 50000},
+		},
+	}}}
+	savings := []models.Account{{Model: gorm.Model{ID: 1}}, {Model: gorm.Model{ID: 2}}}
+
+	tests := []struct {
+		name                string
+		goal                models.Goal
+		expectedStatus      string
+		expectedCurrent     int
+		expectedRequired    int
+		expectedMonthsLeft  int
+		expectProjectionEnd string
+	}{
+		{
+			name:                "growing fast enough",
+			goal:                models.Goal{TargetAmount: 600000, TargetDate: "2027-04-19", Accounts: savings},
+			expectedStatus:      GoalStatusOnTrack,
+			expectedCurrent:     350000,
+			expectedRequired:    41667,
+			expectedMonthsLeft:  6,
+			expectProjectionEnd: "2027-04-19",
+		},
+		{
+			name:                "not growing fast enough",
+			goal:                models.Goal{TargetAmount: 600000, TargetDate: "2027-01-19", Accounts: savings},
+			expectedStatus:      GoalStatusBehind,
+			expectedCurrent:     350000,
+			expectedRequired:    83334,
+			expectedMonthsLeft:  3,
+			expectProjectionEnd: "2027-01-19",
+		},
+		{
+			name:            "target reached",
+			goal:            models.Goal{TargetAmount: 300000, TargetDate: "2027-01-19", Accounts: savings},
+			expectedStatus:  GoalStatusAchieved,
+			expectedCurrent: 350000,
+			// Still counting down to the target date
+			expectedMonthsLeft: 3,
+		},
+		{
+			name:             "target date passed",
+			goal:             models.Goal{TargetAmount: 600000, TargetDate: "2026-10-01", Accounts: savings},
+			expectedStatus:   GoalStatusOverdue,
+			expectedCurrent:  350000,
+			expectedRequired: 250000,
+		},
+		{
+			name:                "new account without history",
+			goal:                models.Goal{TargetAmount: 100000, TargetDate: "2026-12-19", Accounts: []models.Account{{Model: gorm.Model{ID: 3}}}},
+			expectedStatus:      GoalStatusNoHistory,
+			expectedCurrent:     50000,
+			expectedRequired:    25000,
+			expectedMonthsLeft:  2,
+			expectProjectionEnd: "2026-12-19",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := service.GetGoalProgress(tt.goal, asOf)
+			if progress.Status != tt.expectedStatus {
+				t.Errorf("Expected status %v, got %v", tt.expectedStatus, progress.Status)
+			}
+			if progress.CurrentAmount != tt.expectedCurrent {
+				t.Errorf("Expected current amount %d, got %d", tt.expectedCurrent, progress.CurrentAmount)
+			}
+			if progress.RequiredMonthlyContribution != tt.expectedRequired {
+				t.Errorf("Expected required contribution %d, got %d", tt.expectedRequired, progress.RequiredMonthlyContribution)
+			}
+			if progress.MonthsLeft != tt.expectedMonthsLeft {
+				t.Errorf("Expected %d months left, got %d", tt.expectedMonthsLeft, progress.MonthsLeft)
+			}
+			if tt.expectProjectionEnd == "" {
+				if len(progress.Projection) != 0 {
+					t.Errorf("Expected no projection, got %v", progress.Projection)
+				}
+				return
+			}
+			end := progress.Projection[len(progress.Projection)-1]
+			if end.Date.Format("2006-01-02") != tt.expectProjectionEnd {
+				t.Errorf("Expected the projection to end on %v, got %v", tt.expectProjectionEnd, end.Date)
+			}
+			if tt.expectedStatus != GoalStatusNoHistory && end.Amount != progress.ProjectedAmount {
+				t.Errorf("Expected the projection to end at %d, got %d", progress.ProjectedAmount, end.Amount)
+			}
+		})
+	}
+}
+
+func TestGetGoalProgress_History(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	service := &GoalService{BalanceRepository: &MockGoalBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {
+			{EffectiveDate: "2026-09-15", Amount: 250000},
+			{EffectiveDate: "2026-04-01", Amount: 100000},
+		},
+	}}}
+	goal := models.Goal{TargetAmount: 600000, TargetDate: "2027-04-19", Accounts: []models.Account{{Model: gorm.Model{ID: 1}}}}
+
+	progress := service.GetGoalProgress(goal, asOf)
+	// April through September month ends, then today
+	if len(progress.History) != 7 {
+		t.Fatalf("Expected 7 points of history, got %v", progress.History)
+	}
+	if progress.History[0].Date.Format("2006-01-02") != "2026-04-30" || progress.History[0].Amount != 100000 {
+		t.Errorf("Expected history to start at the end of April, got %+v", progress.History[0])
+	}
+	if progress.MonthlyGrowth <= 0 || progress.ProjectedCompletionDate == nil {
+		t.Errorf("Expected growth and a completion date, got %d and %v", progress.MonthlyGrowth, progress.ProjectedCompletionDate)
+	}
+}
+
+func TestGetGoalProgress_Projection(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	service := &GoalService{BalanceRepository: &MockGoalBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {{EffectiveDate: "2026-10-19", Amount: 200000}, {EffectiveDate: "2026-07-19", Amount: 100000}},
+	}}}
+	goal := models.Goal{TargetAmount: 600000, TargetDate: "2027-01-19", Accounts: []models.Account{{Model: gorm.Model{ID: 1}}}}
+
+	progress := service.GetGoalProgress(goal, asOf)
+	expectedDates := []string{"2026-10-19", "2026-10-31", "2026-11-30", "2026-12-31", "2027-01-19"}
+	if len(progress.Projection) != len(expectedDates) {
+		t.Fatalf("Expected %d points in the projection, got %v", len(expectedDates), progress.Projection)
+	}
+	for i, date := range expectedDates {
+		if progress.Projection[i].Date.Format("2006-01-02") != date {
+			t.Errorf("Expected point %d to be on %v, got %v", i, date, progress.Projection[i].Date)
+		}
+		if i > 0 && progress.Projection[i].Amount <= progress.Projection[i-1].Amount {
+			t.Errorf("Expected the projection to keep growing, got %v", progress.Projection)
+		}
+	}
+}
+
+func TestGetGoalProgress_SlowGrowth(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	service := &GoalService{BalanceRepository: &MockGoalBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {{EffectiveDate: "2026-10-19", Amount: 100100}, {EffectiveDate: "2026-07-19", Amount: 100000}},
+	}}}
+	goal := models.Goal{TargetAmount: 10000000, TargetDate: "2027-01-19", Accounts: []models.Account{{Model: gorm.Model{ID: 1}}}}
+
+	progress := service.GetGoalProgress(goal, asOf)
+	if progress.MonthlyGrowth <= 0 {
+		t.Fatalf("Expected the goal to be growing, got %d", progress.MonthlyGrowth)
+	}
+	if progress.ProjectedCompletionDate != nil {
+		t.Errorf("Expected no completion date for a goal centuries away, got %v", progress.ProjectedCompletionDate)
+	}
+}
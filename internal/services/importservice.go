@@ -39,6 +39,7 @@ type ImportService struct {
 	BalanceRepository          BalanceRepositoryInterface
 	Categorizer                CategorizerInterface
 	ImportSubmissionRepository ImportSubmissionRepositoryInterface
+	MerchantNormalizer         MerchantNormalizerInterface
 	TransactionRepository      ImportTransactionRepositoryInterface
 }
 
@@ -142,10 +143,10 @@ func (is *ImportService) ImportStatement(filename string, statement string, acco
 		transaction.Hash = hashHex
 		transaction.ImportSubmissionID = &submission.ID
 
+		transaction.MerchantName = is.MerchantNormalizer.NormalizeMerchantName(transaction.Description)
+
 		// TODO: Add a check for the category and set it to the default category if it is not set
 
-		// Rebuild the model first. Consider making this optional
-		is.Categorizer.BuildModel()
 		category, err := is.Categorizer.CategorizeTransaction(&transaction)
 		if err != nil {
 			fmt.Println("error while categorizing transaction")
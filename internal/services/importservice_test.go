@@ -11,15 +11,17 @@ func TestImportStatement_Success(t *testing.T) {
 	parserName := "mock"
 	account := models.Account{Name: "Test Account", AccountTypeID: 1, AccountType: models.AccountType{DefaultParser: &parserName}}
 	parsersByInstitution[parserName] = &MockParser{
-		Txns:     []models.Transaction{{Amount: 100, Date: "2024-01-01", Description: "Test txn"}},
+		Txns:     []models.Transaction{{Amount: 100, Date: "2024-01-01", Description: "SQ *BLUE BOTTLE 0423 OAKLAND CA"}},
 		Balances: []models.Balance{{Amount: 1000}},
 	}
+	transactionRepo := &MockTransactionRepository{TxnsByHash: map[string][]models.Transaction{}}
 	is := &ImportService{
 		AccountRepository:          &MockAccountRepository{Account: account},
 		BalanceRepository:          &MockBalanceRepository{},
 		ImportSubmissionRepository: &MockImportSubmissionRepository{},
-		TransactionRepository:      &MockTransactionRepository{TxnsByHash: map[string][]models.Transaction{}},
+		TransactionRepository:      transactionRepo,
 		Categorizer:                &MockCategorizer{Category: models.Category{Name: "Test Category"}},
+		MerchantNormalizer:         &MerchantNormalizer{},
 	}
 	res, err := is.ImportStatement("file.csv", "statement", 1)
 	if err != nil {
@@ -28,6 +30,9 @@ func TestImportStatement_Success(t *testing.T) {
 	if res == nil {
 		t.Error("expected result, got nil")
 	}
+	if len(transactionRepo.SavedTxns) != 1 || transactionRepo.SavedTxns[0].MerchantName != "Blue Bottle" {
+		t.Errorf("expected the transaction to be saved with a normalized merchant name, got %v", transactionRepo.SavedTxns)
+	}
 }
 
 func TestImportStatement_AccountNotFound(t *testing.T) {
@@ -79,6 +84,7 @@ func TestImportStatement_DuplicateTransaction(t *testing.T) {
 		ImportSubmissionRepository: &MockImportSubmissionRepository{},
 		TransactionRepository:      &MockTransactionRepository{TxnsByHash: map[string][]models.Transaction{hash: {{}}}},
 		Categorizer:                &MockCategorizer{Category: models.Category{Name: "Test Category"}},
+		MerchantNormalizer:         &MerchantNormalizer{},
 	}
 	_, err := is.ImportStatement("file.csv", "statement", 1)
 	if err != nil {
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// investmentActivityRules match words in the action column of brokerage statements, like Schwab's "Qualified
+// Dividend" or Fidelity's "YOU BOUGHT". They're checked in order, so fees and trades come before dividends and
+// interest, e.g. "Margin Interest" is a fee and "Reinvest Shares" is a trade.
+var investmentActivityRules = []struct {
+	activity string
+	words    []string
+}{
+	// Only phrases that can't be part of a security's name, since Fidelity's actions include the name
+	{models.InvestmentActivityFee, []string{"FEE", "FEES", "COMMISSION", "MARGIN INTEREST", "FOREIGN TAX"}},
+	{models.InvestmentActivityTrade, []string{"BUY", "SELL", "BOUGHT", "SOLD", "REINVEST SHARES", "REINVESTMENT", "EXCHANGE", "REDEMPTION", "SPLIT"}},
+	{models.InvestmentActivityDividend, []string{"DIVIDEND", "DIV", "CAP GAIN", "CAPITAL GAIN"}},
+	{models.InvestmentActivityInterest, []string{"INTEREST"}},
+	// Money moving in or out of the account, where the direction comes from the amount
+	{models.InvestmentActivityContribution, []string{"TRANSFER", "MONEYLINK", "WIRE", "JOURNAL", "DEPOSIT", "CONTRIBUTION", "CONTR", "ROLLOVER", "FUNDS RECEIVED", "WITHDRAWAL", "DISTRIBUTION", "CHECK"}},
+}
+
+// ClassifyInvestmentActivity works out what a brokerage transaction did from its action, e.g. "Qualified
+// Dividend", and its signed amount, where money leaving the account is negative. It returns an empty string if
+// the action isn't recognized.
+func ClassifyInvestmentActivity(action string, amount int) string {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToUpper(action), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ") + " "
+	for _, rule := range investmentActivityRules {
+		for _, word := range rule.words {
+			if !strings.Contains(words, " "+word+" ") {
+				continue
+			}
+			if rule.activity == models.InvestmentActivityContribution && amount < 0 {
+				return models.InvestmentActivityWithdrawal
+			}
+			if rule.activity == models.InvestmentActivityContribution && amount == 0 {
+				return ""
+			}
+			return rule.activity
+		}
+	}
+	return ""
+}
+
+// investmentAction returns the action part of a stored transaction description. The Schwab parser stores the
+// action and the security's name as "Buy - TAIWAN SEMICONDUCTOR SPONS ADR", and words in the name mustn't decide
+// the activity.
+func investmentAction(description string) string {
+	action, _, _ := strings.Cut(description, " - ")
+	return action
+}
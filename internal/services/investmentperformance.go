@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// InvestmentAccountCategory is the account category whose accounts are treated as investments, which includes
+// retirement accounts
+const InvestmentAccountCategory = "brokerage"
+
+// InvestmentAccountRepositoryInterface specifically for InvestmentPerformanceService
+type InvestmentAccountRepositoryInterface interface {
+	GetAllAccounts() ([]models.Account, error)
+}
+
+// InvestmentBalanceRepositoryInterface specifically for InvestmentPerformanceService
+type InvestmentBalanceRepositoryInterface interface {
+	GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance
+}
+
+// InvestmentTransactionRepositoryInterface specifically for InvestmentPerformanceService
+type InvestmentTransactionRepositoryInterface interface {
+	GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]models.Transaction, error)
+	UpdateInvestmentActivity(id uint, activity string) error
+}
+
+type InvestmentPerformanceService struct {
+	AccountRepository     InvestmentAccountRepositoryInterface
+	BalanceRepository     InvestmentBalanceRepositoryInterface
+	TransactionRepository InvestmentTransactionRepositoryInterface
+}
+
+// InvestmentPerformance is how an investment account, or all of them together, did over a time frame. It's
+// measured between balances, so StartDate and EndDate are the dates of the balances it starts and ends with,
+// which can be inside the time frame that was asked for.
+type InvestmentPerformance struct {
+	Account       models.Account // Empty for the whole portfolio
+	StartDate     string
+	EndDate       string
+	StartValue    int
+	EndValue      int
+	Contributions int
+	Withdrawals   int
+	Dividends     int
+	Interest      int
+	Fees          int
+	// Unclassified is the number of transactions whose investment activity isn't known
+	Unclassified int
+	// Gain is the change in value that didn't come from contributions or withdrawals
+	Gain int
+	// TimeWeightedReturn is the return over the whole time frame regardless of when money was added or taken
+	// out, or nil if there aren't enough balances to tell
+	TimeWeightedReturn *float64
+	// MoneyWeightedReturn is the annualized internal rate of return (XIRR) of the money put in and taken out,
+	// or nil if it can't be worked out
+	MoneyWeightedReturn *float64
+}
+
+// investmentFlow is money moving into (positive) or out of (negative) an investment account
+type investmentFlow struct {
+	date   string
+	amount int
+}
+
+// investmentHistory is the balances and transactions of one or more investment accounts
+type investmentHistory struct {
+	balancesByAccount map[uint][]models.Balance // Oldest first
+	transactions      []models.Transaction
+}
+
+// GetInvestmentPerformance measures the performance of every investment account from startDate to endDate, along
+// with the portfolio of all of them together
+func (ips *InvestmentPerformanceService) GetInvestmentPerformance(startDate time.Time, endDate time.Time) ([]InvestmentPerformance, InvestmentPerformance, error) {
+	accounts, err := ips.getInvestmentAccounts()
+	if err != nil {
+		return nil, InvestmentPerformance{}, err
+	}
+	start := utils.TimeToISO8601DateString(startDate)
+	end := utils.TimeToISO8601DateString(endDate)
+
+	performances := []InvestmentPerformance{}
+	portfolio := investmentHistory{balancesByAccount: map[uint][]models.Balance{}}
+	for _, account := range accounts {
+		balances := ips.BalanceRepository.GetBalancesForAccount(context.TODO(), account.ID)
+		sort.SliceStable(balances, func(i, j int) bool { return balances[i].EffectiveDate < balances[j].EffectiveDate })
+		transactions, err := ips.TransactionRepository.GetAllTransactions(account.ID, 0, 0, "", nil, nil)
+		if err != nil {
+			return nil, InvestmentPerformance{}, err
+		}
+		history := investmentHistory{balancesByAccount: map[uint][]models.Balance{account.ID: balances}, transactions: transactions}
+		performance := history.measure(start, end)
+		performance.Account = account
+		performances = append(performances, performance)
+
+		portfolio.balancesByAccount[account.ID] = balances
+		portfolio.transactions = append(portfolio.transactions, transactions...)
+	}
+	return performances, portfolio.measure(start, end), nil
+}
+
+// ClassifyInvestmentTransactions classifies the transactions of investment accounts whose investment activity
+// isn't known yet, e.g. ones imported before activities were classified. It returns how many were classified.
+func (ips *InvestmentPerformanceService) ClassifyInvestmentTransactions() (int, error) {
+	accounts, err := ips.getInvestmentAccounts()
+	if err != nil {
+		return 0, err
+	}
+	classified := 0
+	for _, account := range accounts {
+		transactions, err := ips.TransactionRepository.GetAllTransactions(account.ID, 0, 0, "", nil, nil)
+		if err != nil {
+			return classified, err
+		}
+		for _, transaction := range transactions {
+			if transaction.InvestmentActivity != "" {
+				continue
+			}
+			activity := ClassifyInvestmentActivity(investmentAction(transaction.Description), transaction.Amount)
+			if activity == "" {
+				continue
+			}
+			err = ips.TransactionRepository.UpdateInvestmentActivity(transaction.ID, activity)
+			if err != nil {
+				return classified, err
+			}
+			classified++
+		}
+	}
+	return classified, nil
+}
+
+func (ips *InvestmentPerformanceService) getInvestmentAccounts() ([]models.Account, error) {
+	accounts, err := ips.AccountRepository.GetAllAccounts()
+	if err != nil {
+		return nil, err
+	}
+	investmentAccounts := []models.Account{}
+	for _, account := range accounts {
+		if account.AccountType.AccountCategory == InvestmentAccountCategory {
+			investmentAccounts = append(investmentAccounts, account)
+		}
+	}
+	return investmentAccounts, nil
+}
+
+// valueOn is the combined latest balance of the accounts on or before date, and false if none of them had a
+// balance yet
+func (ih investmentHistory) valueOn(date string) (int, bool) {
+	value := 0
+	found := false
+	for _, balances := range ih.balancesByAccount {
+		for i := len(balances) - 1; i >= 0; i-- {
+			if balances[i].EffectiveDate <= date {
+				value += balances[i].Amount
+				found = true
+				break
+			}
+		}
+	}
+	return value, found
+}
+
+// measure works out the performance between the balances closest to start and end. It starts with the balance
+// on the day before start, or the first balance after that if the accounts are newer, and ends with the latest
+// balance on or before end.
+func (ih investmentHistory) measure(start string, end string) InvestmentPerformance {
+	performance := InvestmentPerformance{}
+	dayBefore := utils.TimeToISO8601DateString(utils.ISO8601DateStringToTime(start).AddDate(0, 0, -1))
+	balanceDates := map[string]bool{}
+	for _, balances := range ih.balancesByAccount {
+		for _, balance := range balances {
+			if balance.EffectiveDate <= end {
+				balanceDates[balance.EffectiveDate] = true
+			}
+		}
+	}
+	// Valuation dates are where the time frame is split up for the time-weighted return
+	valuationDates := []string{}
+	for date := range balanceDates {
+		if date > dayBefore {
+			valuationDates = append(valuationDates, date)
+		}
+	}
+	sort.Strings(valuationDates)
+	if _, ok := ih.valueOn(dayBefore); ok {
+		valuationDates = append([]string{dayBefore}, valuationDates...)
+	}
+	if len(valuationDates) == 0 {
+		return performance
+	}
+	performance.StartDate = valuationDates[0]
+	performance.EndDate = valuationDates[len(valuationDates)-1]
+	performance.StartValue, _ = ih.valueOn(performance.StartDate)
+	performance.EndValue, _ = ih.valueOn(performance.EndDate)
+
+	flows := []investmentFlow{}
+	for _, transaction := range ih.transactions {
+		if transaction.Date <= performance.StartDate || transaction.Date > performance.EndDate {
+			continue
+		}
+		amount := transaction.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		switch transaction.InvestmentActivity {
+		case models.InvestmentActivityContribution:
+			performance.Contributions += amount
+			flows = append(flows, investmentFlow{date: transaction.Date, amount: amount})
+		case models.InvestmentActivityWithdrawal:
+			performance.Withdrawals += amount
+			flows = append(flows, investmentFlow{date: transaction.Date, amount: -amount})
+		case models.InvestmentActivityDividend:
+			performance.Dividends += amount
+		case models.InvestmentActivityInterest:
+			performance.Interest += amount
+		case models.InvestmentActivityFee:
+			performance.Fees += amount
+		case models.InvestmentActivityTrade:
+		default:
+			performance.Unclassified++
+		}
+	}
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].date < flows[j].date })
+	performance.Gain = performance.EndValue - performance.StartValue - performance.Contributions + performance.Withdrawals
+	if len(valuationDates) < 2 {
+		return performance
+	}
+
+	values := make([]int, len(valuationDates))
+	for i, date := range valuationDates {
+		values[i], _ = ih.valueOn(date)
+	}
+	performance.TimeWeightedReturn = timeWeightedReturn(valuationDates, values, flows)
+	performance.MoneyWeightedReturn = xirr(performance.StartDate, performance.StartValue, performance.EndDate, performance.EndValue, flows)
+	return performance
+}
+
+// timeWeightedReturn chains together the returns between consecutive valuations, each worked out with the
+// Modified Dietz method so money added or taken out partway through only counts for the time it was invested.
+// Periods with nothing invested are skipped.
+func timeWeightedReturn(dates []string, values []int, flows []investmentFlow) *float64 {
+	growth := 1.0
+	measured := false
+	for i := 1; i < len(dates); i++ {
+		periodStart := utils.ISO8601DateStringToTime(dates[i-1])
+		periodDays := utils.ISO8601DateStringToTime(dates[i]).Sub(periodStart).Hours() / 24
+		netFlow := 0.0
+		weightedFlow := 0.0
+		for _, flow := range flows {
+			if flow.date <= dates[i-1] || flow.date > dates[i] {
+				continue
+			}
+			daysInvested := periodDays - utils.ISO8601DateStringToTime(flow.date).Sub(periodStart).Hours()/24
+			netFlow += float64(flow.amount)
+			weightedFlow += float64(flow.amount) * daysInvested / periodDays
+		}
+		invested := float64(values[i-1]) + weightedFlow
+		if invested <= 0 {
+			continue
+		}
+		growth *= 1 + (float64(values[i]-values[i-1])-netFlow)/invested
+		measured = true
+	}
+	if !measured {
+		return nil
+	}
+	result := growth - 1
+	return &result
+}
+
+// xirr is the annualized rate of return that makes the money put in, the money taken out and the value at the
+// end add up to nothing, treating the starting value as money put in on the start date. It returns nil if there
+// isn't a rate between -100% and 1000% that does.
+func xirr(startDate string, startValue int, endDate string, endValue int, flows []investmentFlow) *float64 {
+	// From the investor's point of view, so contributions are negative
+	cashFlows := []investmentFlow{{date: startDate, amount: -startValue}}
+	for _, flow := range flows {
+		cashFlows = append(cashFlows, investmentFlow{date: flow.date, amount: -flow.amount})
+	}
+	cashFlows = append(cashFlows, investmentFlow{date: endDate, amount: endValue})
+	start := utils.ISO8601DateStringToTime(startDate)
+	if !utils.ISO8601DateStringToTime(endDate).After(start) {
+		return nil
+	}
+
+	netPresentValue := func(rate float64) float64 {
+		total := 0.0
+		for _, cashFlow := range cashFlows {
+			years := utils.ISO8601DateStringToTime(cashFlow.date).Sub(start).Hours() / 24 / 365
+			total += float64(cashFlow.amount) / math.Pow(1+rate, years)
+		}
+		return total
+	}
+
+	low, high := -0.9999, 10.0
+	lowValue, highValue := netPresentValue(low), netPresentValue(high)
+	if math.IsNaN(lowValue) || math.IsNaN(highValue) || lowValue*highValue > 0 {
+		return nil
+	}
+	for i := 0; i < 200; i++ {
+		middle := (low + high) / 2
+		middleValue := netPresentValue(middle)
+		if (middleValue < 0) == (lowValue < 0) {
+			low, lowValue = middle, middleValue
+		} else {
+			high = middle
+		}
+	}
+	result := (low + high) / 2
+	return &result
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockInvestmentAccountRepository struct {
+	Accounts []models.Account
+}
+
+func (m *MockInvestmentAccountRepository) GetAllAccounts() ([]models.Account, error) {
+	return m.Accounts, nil
+}
+
+type MockInvestmentBalanceRepository struct {
+	BalancesByAccount map[uint][]models.Balance
+}
+
+func (m *MockInvestmentBalanceRepository) GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance {
+	return m.BalancesByAccount[accountID]
+}
+
+type MockInvestmentTransactionRepository struct {
+	TransactionsByAccount map[uint][]models.Transaction
+	Updated               map[uint]string
+}
+
+func (m *MockInvestmentTransactionRepository) GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]models.Transaction, error) {
+	return m.TransactionsByAccount[accountID], nil
+}
+
+func (m *MockInvestmentTransactionRepository) UpdateInvestmentActivity(id uint, activity string) error {
+	if m.Updated == nil {
+		m.Updated = map[uint]string{}
+	}
+	m.Updated[id] = activity
+	return nil
+}
+
+func investmentAccount(id uint, category string) models.Account {
+	return models.Account{Model: gorm.Model{ID: id}, AccountType: models.AccountType{AccountCategory: category}}
+}
+
+func TestClassifyInvestmentActivity(t *testing.T) {
+	tests := []struct {
+		action   string
+		amount   int
+		expected string
+	}{
+		{"MoneyLink Transfer", 50000, models.InvestmentActivityContribution},
+		{"MoneyLink Transfer", -50000, models.InvestmentActivityWithdrawal},
+		{"Electronic Funds Transfer Received (Cash)", 50000, models.InvestmentActivityContribution},
+		{"PARTIC CONTR CURRENT YEAR", 25000, models.InvestmentActivityContribution},
+		{"Qualified Dividend", 1234, models.InvestmentActivityDividend},
+		{"Reinvest Dividend", 1234, models.InvestmentActivityDividend},
+		{"Reinvest Shares", -1234, models.InvestmentActivityTrade},
+		{"DIVIDEND RECEIVED FIDELITY 500 INDEX FUND", 1234, models.InvestmentActivityDividend},
+		{"REINVESTMENT FIDELITY 500 INDEX FUND", -1234, models.InvestmentActivityTrade},
+		{"Long Term Cap Gain", 500, models.InvestmentActivityDividend},
+		{"Bank Interest", 12, models.InvestmentActivityInterest},
+		{"INTEREST EARNED FDIC INSURED DEPOSIT", 12, models.InvestmentActivityInterest},
+		{"Margin Interest", -300, models.InvestmentActivityFee},
+		{"ADR Mgmt Fee", -10, models.InvestmentActivityFee},
+		{"DIVIDEND RECEIVED TAIWAN SEMICONDUCTOR MANUFACTURING SPONS ADR", 1234, models.InvestmentActivityDividend},
+		{"YOU BOUGHT TAIWAN SEMICONDUCTOR MANUFACTURING SPONS ADR", -25000, models.InvestmentActivityTrade},
+		{"Buy", -100000, models.InvestmentActivityTrade},
+		{"YOU SOLD VANGUARD TOTAL STOCK MKT", 100000, models.InvestmentActivityTrade},
+		{"Stock Plan Activity", 0, ""},
+		{"INDIVIDUAL RETIREMENT ACCOUNT", 100, ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyInvestmentActivity(tt.action, tt.amount); got != tt.expected {
+			t.Errorf("ClassifyInvestmentActivity(%q, %d): expected %q, got %q", tt.action, tt.amount, tt.expected, got)
+		}
+	}
+}
+
+func TestGetInvestmentPerformance(t *testing.T) {
+	service := &InvestmentPerformanceService{
+		AccountRepository: &MockInvestmentAccountRepository{Accounts: []models.Account{
+			investmentAccount(1, InvestmentAccountCategory),
+			investmentAccount(2, InvestmentAccountCategory),
+			investmentAccount(3, "checking"),
+		}},
+		BalanceRepository: &MockInvestmentBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+			1: {
+				{EffectiveDate: "2026-12-31", Amount: 176000},
+				{EffectiveDate: "2025-12-31", Amount: 100000},
+				{EffectiveDate: "2026-06-30", Amount: 160000},
+			},
+			// Opened partway through the year, with nothing added since
+			2: {
+				{EffectiveDate: "2026-03-01", Amount: 20000},
+				{EffectiveDate: "2026-12-31", Amount: 22000},
+			},
+		}},
+		TransactionRepository: &MockInvestmentTransactionRepository{TransactionsByAccount: map[uint][]models.Transaction{
+			1: {
+				{Date: "2025-12-15", Amount: 99999, InvestmentActivity: models.InvestmentActivityContribution},
+				{Date: "2026-06-30", Amount: 50000, InvestmentActivity: models.InvestmentActivityContribution},
+				{Date: "2026-06-30", Amount: -50000, InvestmentActivity: models.InvestmentActivityTrade},
+				{Date: "2026-09-15", Amount: 3000, InvestmentActivity: models.InvestmentActivityDividend},
+				{Date: "2026-09-30", Amount: -200, InvestmentActivity: models.InvestmentActivityFee},
+				{Date: "2026-10-01", Amount: 40, InvestmentActivity: models.InvestmentActivityInterest},
+				{Date: "2026-11-01", Amount: 100},
+			},
+		}},
+	}
+	startDate := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	performances, portfolio, err := service.GetInvestmentPerformance(startDate, endDate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(performances) != 2 {
+		t.Fatalf("expected 2 investment accounts, got %d", len(performances))
+	}
+
+	brokerage := performances[0]
+	if brokerage.StartDate != "2025-12-31" || brokerage.EndDate != "2026-12-31" {
+		t.Errorf("expected to measure from 2025-12-31 to 2026-12-31, got %s to %s", brokerage.StartDate, brokerage.EndDate)
+	}
+	if brokerage.StartValue != 100000 || brokerage.EndValue != 176000 {
+		t.Errorf("expected values 100000 to 176000, got %d to %d", brokerage.StartValue, brokerage.EndValue)
+	}
+	if brokerage.Contributions != 50000 || brokerage.Withdrawals != 0 {
+		t.Errorf("expected 50000 contributed and nothing withdrawn, got %d and %d", brokerage.Contributions, brokerage.Withdrawals)
+	}
+	if brokerage.Dividends != 3000 || brokerage.Fees != 200 || brokerage.Interest != 40 || brokerage.Unclassified != 1 {
+		t.Errorf("expected 3000 dividends, 200 fees, 40 interest and 1 unclassified, got %d, %d, %d and %d",
+			brokerage.Dividends, brokerage.Fees, brokerage.Interest, brokerage.Unclassified)
+	}
+	if brokerage.Gain != 26000 {
+		t.Errorf("expected a gain of 26000, got %d", brokerage.Gain)
+	}
+	// 10% in each half of the year, regardless of the contribution in the middle
+	expectReturn(t, "time-weighted return", brokerage.TimeWeightedReturn, 0.21)
+	expectReturn(t, "money-weighted return", brokerage.MoneyWeightedReturn, 0.2096)
+
+	newer := performances[1]
+	if newer.StartDate != "2026-03-01" || newer.StartValue != 20000 {
+		t.Errorf("expected a newer account to start with its first balance, got %d on %s", newer.StartValue, newer.StartDate)
+	}
+	expectReturn(t, "time-weighted return of the newer account", newer.TimeWeightedReturn, 0.10)
+
+	if portfolio.StartValue != 100000 || portfolio.EndValue != 198000 {
+		t.Errorf("expected portfolio values 100000 to 198000, got %d to %d", portfolio.StartValue, portfolio.EndValue)
+	}
+	if portfolio.Gain != 48000 {
+		t.Errorf("expected a portfolio gain of 48000, got %d", portfolio.Gain)
+	}
+	if portfolio.TimeWeightedReturn == nil || portfolio.MoneyWeightedReturn == nil {
+		t.Error("expected portfolio returns")
+	}
+}
+
+func TestGetInvestmentPerformance_NotEnoughBalances(t *testing.T) {
+	service := &InvestmentPerformanceService{
+		AccountRepository: &MockInvestmentAccountRepository{Accounts: []models.Account{investmentAccount(1, InvestmentAccountCategory)}},
+		BalanceRepository: &MockInvestmentBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+			1: {{EffectiveDate: "2026-05-01", Amount: 100000}},
+		}},
+		TransactionRepository: &MockInvestmentTransactionRepository{},
+	}
+
+	performances, _, err := service.GetInvestmentPerformance(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if performances[0].TimeWeightedReturn != nil || performances[0].MoneyWeightedReturn != nil {
+		t.Error("expected no returns from a single balance")
+	}
+	if performances[0].EndValue != 100000 {
+		t.Errorf("expected the end value to be the only balance, got %d", performances[0].EndValue)
+	}
+}
+
+func TestClassifyInvestmentTransactions(t *testing.T) {
+	transactionRepository := &MockInvestmentTransactionRepository{TransactionsByAccount: map[uint][]models.Transaction{
+		1: {
+			{Model: gorm.Model{ID: 1}, Description: "Qualified Dividend - VANGUARD TOTAL STOCK MKT", Amount: 1200},
+			{Model: gorm.Model{ID: 2}, Description: "MoneyLink Transfer - Tfr SCHWAB BANK", Amount: -5000},
+			// Already classified, e.g. by hand
+			{Model: gorm.Model{ID: 3}, Description: "Bank Interest", Amount: 10, InvestmentActivity: models.InvestmentActivityDividend},
+			{Model: gorm.Model{ID: 4}, Description: "Journaled Shares", Amount: 0},
+			// The security's name has words that look like a fee, but only the action is classified
+			{Model: gorm.Model{ID: 6}, Description: "Buy - TAIWAN SEMICONDUCTOR SPONS ADR", Amount: -25000},
+		},
+		2: {
+			{Model: gorm.Model{ID: 5}, Description: "Interest payment", Amount: 10},
+		},
+	}}
+	service := &InvestmentPerformanceService{
+		AccountRepository: &MockInvestmentAccountRepository{Accounts: []models.Account{
+			investmentAccount(1, InvestmentAccountCategory),
+			investmentAccount(2, "savings"),
+		}},
+		TransactionRepository: transactionRepository,
+	}
+
+	classified, err := service.ClassifyInvestmentTransactions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if classified != 3 {
+		t.Errorf("expected 3 transactions to be classified, got %d", classified)
+	}
+	expected := map[uint]string{1: models.InvestmentActivityDividend, 2: models.InvestmentActivityWithdrawal, 6: models.InvestmentActivityTrade}
+	for id, activity := range expected {
+		if transactionRepository.Updated[id] != activity {
+			t.Errorf("expected transaction %d to be a %s, got %q", id, activity, transactionRepository.Updated[id])
+		}
+	}
+	if len(transactionRepository.Updated) != len(expected) {
+		t.Errorf("expected only %d transactions to be updated, got %v", len(expected), transactionRepository.Updated)
+	}
+}
+
+func expectReturn(t *testing.T, name string, got *float64, expected float64) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("expected a %s of %.4f, got none", name, expected)
+		return
+	}
+	if math.Abs(*got-expected) > 0.0001 {
+		t.Errorf("expected a %s of %.4f, got %.4f", name, expected, *got)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// LoanAccountCategory is the account category of loans, like mortgages, whose terms can be entered
+const LoanAccountCategory = "loan"
+
+// loanPaymentLimit caps how many payments are projected, for payments that barely cover the interest
+const loanPaymentLimit = 1200
+
+// LoanExtraPaymentScenarios are the extra monthly payments, in cents, that every loan's payoff is projected with
+var LoanExtraPaymentScenarios = []int{0, 10000, 25000, 50000}
+
+// LoanRepositoryInterface specifically for LoanService
+type LoanRepositoryInterface interface {
+	GetAllLoans() ([]models.Loan, error)
+}
+
+// LoanBalanceRepositoryInterface specifically for LoanService
+type LoanBalanceRepositoryInterface interface {
+	GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance
+}
+
+type LoanService struct {
+	LoanRepository    LoanRepositoryInterface
+	BalanceRepository LoanBalanceRepositoryInterface
+}
+
+// LoanPayment is one monthly payment of an amortization schedule
+type LoanPayment struct {
+	Number    int
+	Date      time.Time
+	Payment   int
+	Principal int
+	Interest  int
+	// Balance is what's still owed after the payment
+	Balance int
+}
+
+// LoanBalanceComparison compares a balance reported for a loan account with what the schedule says was owed
+type LoanBalanceComparison struct {
+	Date      string
+	Scheduled int
+	Actual    int
+	// Difference is positive when more is owed than scheduled, and negative when the loan is ahead of schedule
+	Difference int
+}
+
+// LoanPayoffScenario is when a loan will be paid off if an extra amount goes towards the principal every month,
+// starting from the current balance
+type LoanPayoffScenario struct {
+	ExtraMonthlyPayment int
+	// PaysOff is false if the payments don't cover the interest, so the loan is never paid off
+	PaysOff       bool
+	Payments      int
+	PayoffDate    time.Time
+	TotalInterest int
+	// MonthsSaved and InterestSaved are compared with making only the regular payment
+	MonthsSaved   int
+	InterestSaved int
+}
+
+// LoanPaymentSplit is how much of a month's scheduled loan payments went to principal and to interest
+type LoanPaymentSplit struct {
+	Principal int
+	Interest  int
+}
+
+type LoanProgress struct {
+	Loan                   models.Loan
+	MonthlyPayment         int
+	Schedule               []LoanPayment
+	ScheduledPayoffDate    time.Time
+	ScheduledTotalInterest int
+	// CurrentBalance is the latest balance reported for the account, or what the schedule says is owed today
+	// if there aren't any balances since the loan started
+	CurrentBalance         int
+	CurrentBalanceDate     string
+	CurrentBalanceReported bool
+	// ScheduledBalance is what the schedule says is owed on CurrentBalanceDate
+	ScheduledBalance int
+	// Comparisons are the reported balances since the loan started, oldest first
+	Comparisons []LoanBalanceComparison
+	Scenarios   []LoanPayoffScenario
+}
+
+// GetLoanProgress generates a loan's amortization schedule, compares it with the balances reported for its
+// account, and projects when the loan will be paid off with each of LoanExtraPaymentScenarios. A custom extra
+// monthly payment is projected too if it's more than 0.
+func (ls *LoanService) GetLoanProgress(loan models.Loan, extraMonthlyPayment int, asOf time.Time) LoanProgress {
+	balances := ls.BalanceRepository.GetBalancesForAccount(context.Background(), loan.AccountID)
+	return projectLoan(loan, balances, extraMonthlyPayment, asOf)
+}
+
+// GetPaymentSplitsByMonth adds up how much of every loan's scheduled payments went to principal and to interest
+// in each month from startDate to endDate, keyed by YYYY-MM
+func (ls *LoanService) GetPaymentSplitsByMonth(startDate time.Time, endDate time.Time) (map[string]LoanPaymentSplit, error) {
+	loans, err := ls.LoanRepository.GetAllLoans()
+	if err != nil {
+		return nil, err
+	}
+	splits := map[string]LoanPaymentSplit{}
+	start := utils.TimeToISO8601DateString(startDate)
+	end := utils.TimeToISO8601DateString(endDate)
+	for _, loan := range loans {
+		for _, payment := range loanSchedule(loan) {
+			date := utils.TimeToISO8601DateString(payment.Date)
+			if date < start || date > end {
+				continue
+			}
+			month := payment.Date.Format("2006-01")
+			split := splits[month]
+			split.Principal += payment.Principal
+			split.Interest += payment.Interest
+			splits[month] = split
+		}
+	}
+	return splits, nil
+}
+
+// projectLoan does the work of GetLoanProgress. The balances are newest first. A loan whose start date can't be
+// parsed has no schedule to compare or project from, so only its monthly payment is filled in.
+func projectLoan(loan models.Loan, balances []models.Balance, extraMonthlyPayment int, asOf time.Time) LoanProgress {
+	progress := LoanProgress{
+		Loan:           loan,
+		MonthlyPayment: monthlyLoanPayment(loan.Principal, loan.InterestRate, loan.TermMonths),
+	}
+	start, err := time.Parse("2006-01-02", loan.StartDate)
+	if err != nil {
+		fmt.Printf("Unable to parse start date of loan %v: %v\n", loan.ID, err)
+		return progress
+	}
+	progress.Schedule = loanSchedule(loan)
+	if len(progress.Schedule) > 0 {
+		progress.ScheduledPayoffDate = progress.Schedule[len(progress.Schedule)-1].Date
+	}
+	for _, payment := range progress.Schedule {
+		progress.ScheduledTotalInterest += payment.Interest
+	}
+
+	// Liabilities can be reported as negative amounts, but what's owed is the same either way
+	for _, balance := range balances {
+		if balance.EffectiveDate < loan.StartDate {
+			continue
+		}
+		actual := int(math.Abs(float64(balance.Amount)))
+		scheduled := scheduledLoanBalanceOn(loan, progress.Schedule, balance.EffectiveDate)
+		progress.Comparisons = append([]LoanBalanceComparison{{
+			Date:       balance.EffectiveDate,
+			Scheduled:  scheduled,
+			Actual:     actual,
+			Difference: actual - scheduled,
+		}}, progress.Comparisons...)
+	}
+	if len(progress.Comparisons) > 0 {
+		latest := progress.Comparisons[len(progress.Comparisons)-1]
+		progress.CurrentBalance = latest.Actual
+		progress.CurrentBalanceDate = latest.Date
+		progress.CurrentBalanceReported = true
+	} else {
+		progress.CurrentBalanceDate = utils.TimeToISO8601DateString(asOf)
+		progress.CurrentBalance = scheduledLoanBalanceOn(loan, progress.Schedule, progress.CurrentBalanceDate)
+	}
+	progress.ScheduledBalance = scheduledLoanBalanceOn(loan, progress.Schedule, progress.CurrentBalanceDate)
+	if progress.CurrentBalance == 0 {
+		return progress
+	}
+
+	// Projections carry on from the next scheduled payment after the current balance
+	nextPayment := 1
+	for utils.TimeToISO8601DateString(addMonths(start, nextPayment)) <= progress.CurrentBalanceDate {
+		nextPayment++
+	}
+	extraPayments := slices.Clone(LoanExtraPaymentScenarios)
+	if extraMonthlyPayment > 0 && !slices.Contains(extraPayments, extraMonthlyPayment) {
+		extraPayments = append(extraPayments, extraMonthlyPayment)
+		slices.Sort(extraPayments)
+	}
+	for _, extra := range extraPayments {
+		payments := amortize(progress.CurrentBalance, loan.InterestRate, progress.MonthlyPayment+extra, start, nextPayment)
+		scenario := LoanPayoffScenario{ExtraMonthlyPayment: extra, Payments: len(payments)}
+		for _, payment := range payments {
+			scenario.TotalInterest += payment.Interest
+		}
+		if len(payments) > 0 && payments[len(payments)-1].Balance == 0 {
+			scenario.PaysOff = true
+			scenario.PayoffDate = payments[len(payments)-1].Date
+		}
+		progress.Scenarios = append(progress.Scenarios, scenario)
+	}
+	// The first scenario is the regular payment, with no extra
+	regular := progress.Scenarios[0]
+	for i := range progress.Scenarios {
+		if regular.PaysOff && progress.Scenarios[i].PaysOff {
+			progress.Scenarios[i].MonthsSaved = regular.Payments - progress.Scenarios[i].Payments
+			progress.Scenarios[i].InterestSaved = regular.TotalInterest - progress.Scenarios[i].TotalInterest
+		}
+	}
+	return progress
+}
+
+// loanSchedule is the amortization schedule of a loan when only the regular payment is made. It's empty if the
+// loan's start date can't be parsed.
+func loanSchedule(loan models.Loan) []LoanPayment {
+	start, err := time.Parse("2006-01-02", loan.StartDate)
+	if err != nil {
+		return nil
+	}
+	payment := monthlyLoanPayment(loan.Principal, loan.InterestRate, loan.TermMonths)
+	schedule := amortize(loan.Principal, loan.InterestRate, payment, start, 1)
+	// The payment is rounded to the cent, so the last payment of the term picks up whatever is left over
+	// rather than leaving a few cents for an extra month
+	if loan.TermMonths > 0 && len(schedule) > loan.TermMonths {
+		last := &schedule[loan.TermMonths-1]
+		for _, leftOver := range schedule[loan.TermMonths:] {
+			last.Payment += leftOver.Payment
+			last.Principal += leftOver.Principal
+			last.Interest += leftOver.Interest
+		}
+		last.Balance = 0
+		schedule = schedule[:loan.TermMonths]
+	}
+	return schedule
+}
+
+// monthlyLoanPayment is the fixed monthly payment that pays off a loan over its term, rounded to the cent
+func monthlyLoanPayment(principal int, interestRate float64, termMonths int) int {
+	if termMonths <= 0 {
+		return principal
+	}
+	monthlyRate := interestRate / 100 / 12
+	if monthlyRate == 0 {
+		return int(math.Ceil(float64(principal) / float64(termMonths)))
+	}
+	return int(math.Round(float64(principal) * monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(termMonths)))))
+}
+
+// amortize splits monthly payments into interest and principal until the balance is paid off, starting with
+// payment number firstPayment of a loan that started on startDate. The last payment is only what's left. It
+// stops early if the payment doesn't cover the interest.
+func amortize(balance int, interestRate float64, payment int, startDate time.Time, firstPayment int) []LoanPayment {
+	monthlyRate := interestRate / 100 / 12
+	payments := []LoanPayment{}
+	for number := firstPayment; balance > 0 && len(payments) < loanPaymentLimit; number++ {
+		interest := int(math.Round(float64(balance) * monthlyRate))
+		principal := min(payment-interest, balance)
+		if principal <= 0 {
+			break
+		}
+		balance -= principal
+		payments = append(payments, LoanPayment{
+			Number:    number,
+			Date:      addMonths(startDate, number),
+			Payment:   principal + interest,
+			Principal: principal,
+			Interest:  interest,
+			Balance:   balance,
+		})
+	}
+	return payments
+}
+
+// scheduledLoanBalanceOn is what the schedule says is owed after the payments made on or before date
+func scheduledLoanBalanceOn(loan models.Loan, schedule []LoanPayment, date string) int {
+	balance := loan.Principal
+	for _, payment := range schedule {
+		if utils.TimeToISO8601DateString(payment.Date) > date {
+			break
+		}
+		balance = payment.Balance
+	}
+	return balance
+}
+
+// addMonths moves a date forward by a number of months, keeping the same day of the month where it can, e.g.
+// a month after Jan 31 is Feb 28 rather than Mar 3
+func addMonths(date time.Time, months int) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), min(date.Day(), lastDay), 0, 0, 0, 0, date.Location())
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+type MockLoanRepository struct {
+	Loans []models.Loan
+}
+
+func (m *MockLoanRepository) GetAllLoans() ([]models.Loan, error) {
+	return m.Loans, nil
+}
+
+type MockLoanBalanceRepository struct {
+	BalancesByAccount map[uint][]models.Balance
+}
+
+func (m *MockLoanBalanceRepository) GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance {
+	return m.BalancesByAccount[accountID]
+}
+
+func TestMonthlyLoanPayment(t *testing.T) {
+	tests := []struct {
+		name         string
+		principal    int
+		interestRate float64
+		termMonths   int
+		expected     int
+	}{
+		{"30 year mortgage", 20000000, 6, 360, 119910},
+		{"15 year mortgage", 30000000, 5.5, 180, 245125},
+		{"no interest", 1000000, 0, 36, 27778},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monthlyLoanPayment(tt.principal, tt.interestRate, tt.termMonths); got != tt.expected {
+				t.Errorf("expected a payment of %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoanSchedule(t *testing.T) {
+	loan := models.Loan{AccountID: 1, Principal: 20000000, InterestRate: 6, TermMonths: 360, StartDate: "2026-01-31"}
+	schedule := loanSchedule(loan)
+	if len(schedule) != 360 {
+		t.Fatalf("expected 360 payments, got %d", len(schedule))
+	}
+
+	first := schedule[0]
+	if first.Interest != 100000 || first.Principal != 19910 || first.Balance != 19980090 {
+		t.Errorf("expected the first payment to be 100000 interest and 19910 principal, got %d and %d leaving %d",
+			first.Interest, first.Principal, first.Balance)
+	}
+	if got := first.Date.Format("2006-01-02"); got != "2026-02-28" {
+		t.Errorf("expected the first payment at the end of February, got %s", got)
+	}
+
+	principal := 0
+	for _, payment := range schedule {
+		principal += payment.Principal
+		if payment.Payment != payment.Principal+payment.Interest {
+			t.Errorf("payment %d doesn't add up: %d != %d + %d", payment.Number, payment.Payment, payment.Principal, payment.Interest)
+		}
+	}
+	if principal != loan.Principal {
+		t.Errorf("expected the payments to pay back %d, got %d", loan.Principal, principal)
+	}
+	last := schedule[len(schedule)-1]
+	if last.Balance != 0 || last.Date.Format("2006-01-02") != "2056-01-31" {
+		t.Errorf("expected the loan to be paid off on 2056-01-31, got %d left on %s", last.Balance, last.Date.Format("2006-01-02"))
+	}
+}
+
+func TestGetLoanProgress(t *testing.T) {
+	loan := models.Loan{AccountID: 1, Principal: 20000000, InterestRate: 6, TermMonths: 360, StartDate: "2026-01-15"}
+	schedule := loanSchedule(loan)
+	afterSixPayments := schedule[5].Balance
+	service := &LoanService{BalanceRepository: &MockLoanBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {
+			// An extra $5,000 was paid along with the sixth payment
+			{EffectiveDate: "2026-07-20", Amount: -(afterSixPayments - 500000)},
+			{EffectiveDate: "2026-04-15", Amount: schedule[2].Balance},
+			// From before the loan started
+			{EffectiveDate: "2025-12-31", Amount: 0},
+		},
+	}}}
+
+	progress := service.GetLoanProgress(loan, 20000, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+
+	if progress.MonthlyPayment != 119910 {
+		t.Errorf("expected a monthly payment of 119910, got %d", progress.MonthlyPayment)
+	}
+	if len(progress.Comparisons) != 2 {
+		t.Fatalf("expected 2 balances to compare, got %d", len(progress.Comparisons))
+	}
+	if progress.Comparisons[0].Date != "2026-04-15" || progress.Comparisons[0].Difference != 0 {
+		t.Errorf("expected the April balance to be on schedule, got %+v", progress.Comparisons[0])
+	}
+	if progress.Comparisons[1].Difference != -500000 {
+		t.Errorf("expected the July balance to be 500000 ahead of schedule, got %d", progress.Comparisons[1].Difference)
+	}
+	if !progress.CurrentBalanceReported || progress.CurrentBalance != afterSixPayments-500000 || progress.ScheduledBalance != afterSixPayments {
+		t.Errorf("expected the current balance to be the latest reported one, got %d against %d scheduled",
+			progress.CurrentBalance, progress.ScheduledBalance)
+	}
+
+	// The regular payment, the standard extra payments and the custom one
+	if len(progress.Scenarios) != 5 {
+		t.Fatalf("expected 5 payoff scenarios, got %d", len(progress.Scenarios))
+	}
+	regular := progress.Scenarios[0]
+	if regular.ExtraMonthlyPayment != 0 || !regular.PaysOff || regular.MonthsSaved != 0 {
+		t.Errorf("expected the first scenario to be the regular payment, got %+v", regular)
+	}
+	if regular.PayoffDate.After(progress.ScheduledPayoffDate) {
+		t.Errorf("expected a loan ahead of schedule to be paid off by %s, got %s", progress.ScheduledPayoffDate, regular.PayoffDate)
+	}
+	if regular.Payments > 354 {
+		t.Errorf("expected at most 354 payments left after 6, got %d", regular.Payments)
+	}
+	if custom := progress.Scenarios[2]; custom.ExtraMonthlyPayment != 20000 {
+		t.Errorf("expected the custom extra payment to be sorted in with the others, got %d", custom.ExtraMonthlyPayment)
+	}
+	previous := regular
+	for _, scenario := range progress.Scenarios[1:] {
+		if !scenario.PaysOff || scenario.MonthsSaved <= previous.MonthsSaved || scenario.InterestSaved <= previous.InterestSaved {
+			t.Errorf("expected paying %d extra to save more than paying %d extra, got %+v", scenario.ExtraMonthlyPayment, previous.ExtraMonthlyPayment, scenario)
+		}
+		previous = scenario
+	}
+}
+
+func TestGetLoanProgress_NoBalances(t *testing.T) {
+	loan := models.Loan{AccountID: 1, Principal: 1200000, InterestRate: 0, TermMonths: 12, StartDate: "2026-01-01"}
+	service := &LoanService{BalanceRepository: &MockLoanBalanceRepository{}}
+
+	progress := service.GetLoanProgress(loan, 0, time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC))
+
+	if progress.CurrentBalanceReported || progress.CurrentBalance != 900000 {
+		t.Errorf("expected the current balance to come from the schedule, got %d", progress.CurrentBalance)
+	}
+	if len(progress.Scenarios) != len(LoanExtraPaymentScenarios) {
+		t.Fatalf("expected %d payoff scenarios, got %d", len(LoanExtraPaymentScenarios), len(progress.Scenarios))
+	}
+	if got := progress.Scenarios[0].PayoffDate.Format("2006-01-02"); got != "2027-01-01" || progress.Scenarios[0].Payments != 9 {
+		t.Errorf("expected 9 payments left ending 2027-01-01, got %d ending %s", progress.Scenarios[0].Payments, got)
+	}
+}
+
+func TestGetLoanProgress_PaymentDoesNotCoverInterest(t *testing.T) {
+	loan := models.Loan{AccountID: 1, Principal: 1000000, InterestRate: 12, TermMonths: 120, StartDate: "2026-01-01"}
+	// Far more is owed than the loan was for, so the regular payment doesn't cover the interest
+	service := &LoanService{BalanceRepository: &MockLoanBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {{EffectiveDate: "2026-06-01", Amount: 100000000}},
+	}}}
+
+	progress := service.GetLoanProgress(loan, 0, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+
+	for _, scenario := range progress.Scenarios {
+		if scenario.PaysOff {
+			t.Errorf("expected paying %d extra not to pay off the loan", scenario.ExtraMonthlyPayment)
+		}
+	}
+}
+
+func TestGetLoanProgress_InvalidStartDate(t *testing.T) {
+	loan := models.Loan{AccountID: 1, Principal: 1200000, InterestRate: 0, TermMonths: 12, StartDate: "2026-02-30"}
+	service := &LoanService{BalanceRepository: &MockLoanBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {{EffectiveDate: "2026-06-01", Amount: 600000}},
+	}}}
+
+	progress := service.GetLoanProgress(loan, 0, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+
+	if progress.MonthlyPayment != 100000 {
+		t.Errorf("expected a monthly payment of 100000, got %d", progress.MonthlyPayment)
+	}
+	if len(progress.Schedule) != 0 || len(progress.Scenarios) != 0 {
+		t.Errorf("expected no schedule or scenarios, got %d payments and %d scenarios", len(progress.Schedule), len(progress.Scenarios))
+	}
+}
+
+func TestGetPaymentSplitsByMonth(t *testing.T) {
+	service := &LoanService{LoanRepository: &MockLoanRepository{Loans: []models.Loan{
+		{AccountID: 1, Principal: 1200000, InterestRate: 0, TermMonths: 12, StartDate: "2026-01-10"},
+		{AccountID: 2, Principal: 20000000, InterestRate: 6, TermMonths: 360, StartDate: "2026-02-01"},
+		// Skipped rather than breaking the other loans' splits
+		{AccountID: 3, Principal: 500000, InterestRate: 6, TermMonths: 60, StartDate: "2026-02-30"},
+	}}}
+
+	splits, err := service.GetPaymentSplitsByMonth(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]LoanPaymentSplit{
+		"2026-02": {Principal: 100000},
+		"2026-03": {Principal: 100000 + 19910, Interest: 100000},
+	}
+	if len(splits) != len(expected) {
+		t.Fatalf("expected %d months, got %v", len(expected), splits)
+	}
+	for month, split := range expected {
+		if splits[month] != split {
+			t.Errorf("expected %+v in %s, got %+v", split, month, splits[month])
+		}
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		date     string
+		months   int
+		expected string
+	}{
+		{"2026-01-31", 1, "2026-02-28"},
+		{"2026-01-31", 2, "2026-03-31"},
+		{"2026-11-15", 3, "2027-02-15"},
+		{"2024-01-30", 1, "2024-02-29"},
+	}
+
+	for _, tt := range tests {
+		date, _ := time.Parse("2006-01-02", tt.date)
+		if got := addMonths(date, tt.months).Format("2006-01-02"); got != tt.expected {
+			t.Errorf("expected %d months after %s to be %s, got %s", tt.months, tt.date, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// MerchantPatternRepositoryInterface specifically for MerchantNormalizer
+type MerchantPatternRepositoryInterface interface {
+	GetAllMerchantPatterns() ([]models.MerchantPattern, error)
+}
+
+// MerchantTransactionRepositoryInterface specifically for MerchantNormalizer
+type MerchantTransactionRepositoryInterface interface {
+	GetAllTransactionsForNormalization() ([]models.Transaction, error)
+	UpdateMerchantName(id uint, merchantName string) error
+}
+
+type MerchantNormalizerInterface interface {
+	NormalizeMerchantName(description string) string
+}
+
+type merchantAlias struct {
+	pattern      *regexp.Regexp
+	merchantName string
+}
+
+// MerchantNormalizer turns raw statement descriptions like "SQ *BLUE BOTTLE 0423 OAKLAND CA" into a
+// clean merchant name like "Blue Bottle". User-defined patterns are checked first, then a few built-in
+// aliases for abbreviations, and finally the description is cleaned up by stripping payment processor
+// prefixes, store numbers, dates and locations. A zero value MerchantNormalizer only uses the built-in rules.
+type MerchantNormalizer struct {
+	MerchantPatternRepository MerchantPatternRepositoryInterface
+	TransactionRepository     MerchantTransactionRepositoryInterface
+
+	mu       sync.RWMutex
+	patterns []merchantAlias
+}
+
+// Abbreviations that the clean up can't expand on its own
+var builtInMerchantAliases = []merchantAlias{
+	{regexp.MustCompile(`(?i)\bAMZN\b|AMAZON\.COM|AMAZON MKTPL`), "Amazon"},
+	{regexp.MustCompile(`(?i)\bWHOLEFDS\b`), "Whole Foods"},
+	{regexp.MustCompile(`(?i)\bUBER\s*\*?\s*EATS\b`), "Uber Eats"},
+}
+
+var (
+	// Payment processors prefix the merchant name, e.g. "SQ *", "TST* " or "PAYPAL *"
+	processorPrefixRegex = regexp.MustCompile(`^(SQ|SQU|TST|SP|PAYPAL|PP|IC|DD|BT|APL|GOOGLE|WPY|PY|CKE|SMP|EB|FS|PAR|LS|TOAST|ZSK|DNH)\s*\*\s*`)
+	// Banks prefix card and ACH transactions, e.g. "POS DEBIT" or "PURCHASE AUTHORIZED ON"
+	bankPrefixRegex = regexp.MustCompile(`^(DEBIT CARD|CHECK CARD|CHECKCARD|POS|DEBIT|PURCHASE|AUTHORIZED ON|PREAUTHORIZED|RECURRING|ACH|DDA|VISA|WITHDRAWAL)\b[\s:\-]*`)
+	// Card numbers, dates and reference numbers that come before the merchant name
+	leadingReferenceRegex = regexp.MustCompile(`^[\d/\-#.*]+\s+`)
+	// Reference codes appended after an asterisk, e.g. "AMZN Mktp US*2K4"
+	referenceCodeRegex = regexp.MustCompile(`\*\S*`)
+	// Fixed width statements separate the merchant, city and state with runs of spaces
+	columnSeparatorRegex = regexp.MustCompile(`\s{2,}`)
+)
+
+var usStateCodes = map[string]bool{
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true, "CT": true, "DE": true, "DC": true,
+	"FL": true, "GA": true, "HI": true, "ID": true, "IL": true, "IN": true, "IA": true, "KS": true, "KY": true,
+	"LA": true, "ME": true, "MD": true, "MA": true, "MI": true, "MN": true, "MS": true, "MO": true, "MT": true,
+	"NE": true, "NV": true, "NH": true, "NJ": true, "NM": true, "NY": true, "NC": true, "ND": true, "OH": true,
+	"OK": true, "OR": true, "PA": true, "RI": true, "SC": true, "SD": true, "TN": true, "TX": true, "UT": true,
+	"VT": true, "VA": true, "WA": true, "WV": true, "WI": true, "WY": true,
+}
+
+// First words of multi-word city names, so "SAN FRANCISCO CA" is removed as a whole
+var cityPrefixes = map[string]bool{
+	"SAN": true, "SANTA": true, "LOS": true, "LAS": true, "NEW": true, "FORT": true, "FT": true, "ST": true,
+	"SAINT": true, "SALT": true, "EL": true, "PALO": true, "LONG": true, "NORTH": true, "SOUTH": true,
+	"EAST": true, "WEST": true, "MOUNTAIN": true, "CEDAR": true, "GRAND": true, "BATON": true, "COLORADO": true,
+}
+
+// CompileMerchantPattern compiles a user-defined pattern the same way the normalizer does, so patterns
+// can be validated before they are saved
+func CompileMerchantPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// LoadPatterns reloads the user-defined patterns from the database. It must be called after patterns change.
+func (mn *MerchantNormalizer) LoadPatterns() error {
+	merchantPatterns, err := mn.MerchantPatternRepository.GetAllMerchantPatterns()
+	if err != nil {
+		return err
+	}
+	patterns := []merchantAlias{}
+	for _, merchantPattern := range merchantPatterns {
+		compiledPattern, err := CompileMerchantPattern(merchantPattern.Pattern)
+		if err != nil {
+			// Patterns are validated when they're saved, so this shouldn't happen
+			fmt.Println("Skipping invalid merchant pattern ", merchantPattern.Pattern, ": ", err)
+			continue
+		}
+		patterns = append(patterns, merchantAlias{pattern: compiledPattern, merchantName: merchantPattern.MerchantName})
+	}
+
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.patterns = patterns
+	return nil
+}
+
+func (mn *MerchantNormalizer) NormalizeMerchantName(description string) string {
+	mn.mu.RLock()
+	patterns := mn.patterns
+	mn.mu.RUnlock()
+
+	for _, aliases := range [][]merchantAlias{patterns, builtInMerchantAliases} {
+		for _, alias := range aliases {
+			if alias.pattern.MatchString(description) {
+				return alias.merchantName
+			}
+		}
+	}
+	return cleanMerchantName(description)
+}
+
+// NormalizeAllTransactions recomputes the merchant name of every transaction, returning the number
+// of transactions whose merchant name changed
+func (mn *MerchantNormalizer) NormalizeAllTransactions() (updated int, err error) {
+	transactions, err := mn.TransactionRepository.GetAllTransactionsForNormalization()
+	if err != nil {
+		return 0, err
+	}
+	for _, transaction := range transactions {
+		merchantName := mn.NormalizeMerchantName(transaction.Description)
+		if merchantName == transaction.MerchantName {
+			continue
+		}
+		err = mn.TransactionRepository.UpdateMerchantName(transaction.ID, merchantName)
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func cleanMerchantName(description string) string {
+	name := strings.ToUpper(strings.TrimSpace(description))
+
+	// Strip prefixes until there are none left, since they are often stacked, e.g. "POS DEBIT 1234 SQ *"
+	for {
+		stripped := processorPrefixRegex.ReplaceAllString(name, "")
+		stripped = bankPrefixRegex.ReplaceAllString(stripped, "")
+		stripped = leadingReferenceRegex.ReplaceAllString(stripped, "")
+		if stripped == name {
+			break
+		}
+		name = stripped
+	}
+
+	// If the statement uses fixed width columns and everything after the first column is a
+	// location, keep just the first column
+	columns := columnSeparatorRegex.Split(name, -1)
+	if len(columns) > 1 && isLocation(strings.Fields(strings.Join(columns[1:], " "))) {
+		name = columns[0]
+	}
+
+	name = referenceCodeRegex.ReplaceAllString(name, " ")
+
+	// Drop store numbers, dates and anything else containing a digit, along with stray punctuation
+	tokens := []string{}
+	for _, token := range strings.Fields(name) {
+		if strings.IndexFunc(token, unicode.IsDigit) >= 0 || strings.IndexFunc(token, unicode.IsLetter) < 0 {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	// Drop a trailing country, state and city, as long as part of the merchant name is left
+	if len(tokens) > 1 && (tokens[len(tokens)-1] == "US" || tokens[len(tokens)-1] == "USA") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) > 1 && usStateCodes[tokens[len(tokens)-1]] {
+		tokens = tokens[:len(tokens)-1]
+		if len(tokens) > 1 {
+			tokens = tokens[:len(tokens)-1]
+		}
+		if len(tokens) > 1 && cityPrefixes[tokens[len(tokens)-1]] {
+			tokens = tokens[:len(tokens)-1]
+		}
+	}
+
+	if len(tokens) == 0 {
+		return strings.TrimSpace(description)
+	}
+	for i, token := range tokens {
+		tokens[i] = titleCase(token)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// isLocation reports whether the tokens look like a city and state, or just a state or country
+func isLocation(tokens []string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return usStateCodes[last] || last == "US" || last == "USA"
+}
+
+func titleCase(word string) string {
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
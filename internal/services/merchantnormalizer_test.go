@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockMerchantPatternRepository struct {
+	Patterns []models.MerchantPattern
+	Err      error
+}
+
+func (m *MockMerchantPatternRepository) GetAllMerchantPatterns() ([]models.MerchantPattern, error) {
+	return m.Patterns, m.Err
+}
+
+func (m *MockTransactionRepository) GetAllTransactionsForNormalization() ([]models.Transaction, error) {
+	return m.Txns, m.Err
+}
+
+func (m *MockTransactionRepository) UpdateMerchantName(id uint, merchantName string) error {
+	m.SavedTxns = append(m.SavedTxns, models.Transaction{Model: gorm.Model{ID: id}, MerchantName: merchantName})
+	return m.SaveErr
+}
+
+func TestCleanMerchantName(t *testing.T) {
+	tests := map[string]string{
+		"SQ *BLUE BOTTLE 0423 OAKLAND CA":                  "Blue Bottle",
+		"POS DEBIT 1234 AMZN Mktp US*2K4":                  "Amzn Mktp",
+		"TST* THE MILL 04/23 SAN FRANCISCO CA":             "The Mill",
+		"STARBUCKS STORE #12345   SEATTLE         WA":      "Starbucks Store",
+		"PURCHASE AUTHORIZED ON 04/21 SAFEWAY #1234 CA US": "Safeway",
+		"PAYPAL *NETFLIX.COM":                              "Netflix.com",
+		"ACME CORP PAYROLL":                                "Acme Corp Payroll",
+		"CHEVRON 0091234":                                  "Chevron",
+		"12345":                                            "12345",
+	}
+	for description, expected := range tests {
+		if actual := cleanMerchantName(description); actual != expected {
+			t.Errorf("cleanMerchantName(%q) = %q; want %q", description, actual, expected)
+		}
+	}
+}
+
+func TestNormalizeMerchantName_PatternsTakePrecedence(t *testing.T) {
+	normalizer := &MerchantNormalizer{MerchantPatternRepository: &MockMerchantPatternRepository{Patterns: []models.MerchantPattern{
+		{Pattern: `blue\s+bottle`, MerchantName: "Blue Bottle Coffee"},
+		{Pattern: `amzn mktp`, MerchantName: "Amazon Marketplace"},
+	}}}
+	if err := normalizer.LoadPatterns(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"SQ *BLUE BOTTLE 0423 OAKLAND CA":   "Blue Bottle Coffee",
+		"POS DEBIT 1234 AMZN Mktp US*2K4":   "Amazon Marketplace",
+		"AMZN Digital*1A2B3C":               "Amazon",
+		"SQ *SIGHTGLASS COFFEE 94103 SF CA": "Sightglass Coffee",
+	}
+	for description, expected := range tests {
+		if actual := normalizer.NormalizeMerchantName(description); actual != expected {
+			t.Errorf("NormalizeMerchantName(%q) = %q; want %q", description, actual, expected)
+		}
+	}
+}
+
+func TestNormalizeMerchantName_ZeroValueUsesBuiltInRules(t *testing.T) {
+	normalizer := &MerchantNormalizer{}
+	if actual := normalizer.NormalizeMerchantName("POS DEBIT 1234 AMZN Mktp US*2K4"); actual != "Amazon" {
+		t.Errorf("expected Amazon, got %q", actual)
+	}
+}
+
+func TestLoadPatterns_Error(t *testing.T) {
+	normalizer := &MerchantNormalizer{MerchantPatternRepository: &MockMerchantPatternRepository{Err: errors.New("fail")}}
+	if err := normalizer.LoadPatterns(); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestNormalizeAllTransactions_OnlyUpdatesChangedNames(t *testing.T) {
+	transactionRepo := &MockTransactionRepository{Txns: []models.Transaction{
+		{Model: gorm.Model{ID: 1}, Description: "SQ *BLUE BOTTLE 0423 OAKLAND CA"},
+		{Model: gorm.Model{ID: 2}, Description: "CHEVRON 0091234", MerchantName: "Chevron"},
+	}}
+	normalizer := &MerchantNormalizer{TransactionRepository: transactionRepo}
+
+	updated, err := normalizer.NormalizeAllTransactions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 1 || len(transactionRepo.SavedTxns) != 1 {
+		t.Fatalf("expected 1 transaction to be updated, got %d", updated)
+	}
+	if saved := transactionRepo.SavedTxns[0]; saved.ID != 1 || saved.MerchantName != "Blue Bottle" {
+		t.Errorf("expected transaction 1 to be named Blue Bottle, got transaction %d named %q", saved.ID, saved.MerchantName)
+	}
+}
+
+func TestCompileMerchantPattern_Invalid(t *testing.T) {
+	if _, err := CompileMerchantPattern("blue(bottle"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
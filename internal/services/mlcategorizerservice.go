@@ -1,60 +1,315 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/GopherML/bag"
 	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
 )
 
 type Categorizes interface {
 	CategorizeTransaction(transaction *models.Transaction) (category models.Category, err error)
 }
 
+// TrainingTransactionRepositoryInterface specifically for MLCategorizer
+type TrainingTransactionRepositoryInterface interface {
+	GetTransactionsForTraining() ([]models.Transaction, error)
+}
+
+// CategorizerCategoryRepositoryInterface specifically for MLCategorizer
+type CategorizerCategoryRepositoryInterface interface {
+	GetCategoryByID(id uint) (models.Category, error)
+	GetCategoryByName(name string) (models.Category, error)
+}
+
+type CategorizerModelRepositoryInterface interface {
+	GetCategorizerModel(backend string) (models.CategorizerModel, error)
+	Save(model models.CategorizerModel) (uint, error)
+}
+
+// ModelStatus summarizes the state of the in-memory categorization model
+type ModelStatus struct {
+	Backend       string
+	SampleCount   int
+	CategoryCount int
+	LastTrained   time.Time
+}
+
+// MLCategorizer keeps a single trained model in memory. The model is loaded once at
+// startup (from the persisted training set if there is one), updated incrementally as
+// users correct categories, and only rebuilt from scratch on request. The classifier
+// behind the model is chosen by Backend, which defaults to naive Bayes.
 type MLCategorizer struct {
-	Bag                   *bag.Bag
-	CategoryRepository    *models.CategoryRepository
-	TransactionRepository *models.TransactionRepository
+	Backend                    string
+	CategoryRepository         CategorizerCategoryRepositoryInterface
+	CategorizerModelRepository CategorizerModelRepositoryInterface
+	TransactionRepository      TrainingTransactionRepositoryInterface
+
+	mu          sync.RWMutex
+	classifier  Classifier
+	trainingSet TrainingSet
+	lastTrained time.Time
 }
 
+// BuildModel rebuilds the model from every transaction flagged for training and persists it
 func (mc *MLCategorizer) BuildModel() error {
 	// Get all transactions flagged for training
 	transactions, err := mc.TransactionRepository.GetTransactionsForTraining()
 	if err != nil {
 		return err
 	}
-	bagConfig := bag.Config{}
-	mc.Bag, err = bag.New(bagConfig)
+
+	trainingSet := TrainingSet{Samples: SamplesByLabel{}}
+	for _, transaction := range transactions {
+		label := categoryLabel(transaction.CategoryID)
+		trainingSet.Samples[label] = append(trainingSet.Samples[label], categorizationText(transaction))
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.trainingSet = trainingSet
+	if err = mc.trainClassifier(); err != nil {
+		return err
+	}
+	return mc.persistModel()
+}
+
+// LoadModel restores the persisted model, falling back to a full build if nothing
+// has been persisted yet
+func (mc *MLCategorizer) LoadModel() error {
+	persistedModel, err := mc.CategorizerModelRepository.GetCategorizerModel(mc.backend())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return mc.BuildModel()
+	}
 	if err != nil {
 		return err
 	}
 
-	for _, transaction := range transactions {
-		mc.Bag.Train(transaction.Description, transaction.Category.Name)
+	var trainingSet TrainingSet
+	err = json.Unmarshal([]byte(persistedModel.TrainingSet), &trainingSet)
+	if err != nil {
+		fmt.Println("Unable to decode persisted model, rebuilding:", err)
+		return mc.BuildModel()
+	}
+	if trainingSet.Samples == nil {
+		trainingSet.Samples = SamplesByLabel{}
 	}
 
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.trainingSet = trainingSet
+	if err = mc.trainClassifier(); err != nil {
+		return err
+	}
+	mc.lastTrained = persistedModel.UpdatedAt
 	return nil
 }
 
+// SetBackend switches the categorizer to a different backend, retraining it from the
+// in-memory training set and persisting the result under the new backend's name
+func (mc *MLCategorizer) SetBackend(backend string) error {
+	if _, err := NewClassifier(backend); err != nil {
+		return err
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Backend = backend
+	if err := mc.trainClassifier(); err != nil {
+		return err
+	}
+	return mc.persistModel()
+}
+
+// LearnTransaction incrementally updates the model after a transaction has been saved.
+// previous is the transaction as it was before the change (a zero value for new
+// transactions), so a sample it contributed under its old category can be removed.
+func (mc *MLCategorizer) LearnTransaction(previous models.Transaction, current models.Transaction) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.trainingSet.Samples == nil {
+		mc.trainingSet.Samples = SamplesByLabel{}
+	}
+
+	removed := false
+	if previous.ID != 0 && previous.UseForTraining {
+		removed = mc.removeSample(previous)
+	}
+	if current.UseForTraining {
+		label := categoryLabel(current.CategoryID)
+		mc.trainingSet.Samples[label] = append(mc.trainingSet.Samples[label], categorizationText(current))
+	}
+
+	if !removed && !current.UseForTraining {
+		return nil
+	}
+
+	// None of the classifiers can "unlearn" a sample, so if one was removed (or the backend
+	// doesn't support incremental training) we retrain from the in-memory training set.
+	// Otherwise we can simply train on the new sample.
+	incrementalClassifier, incremental := mc.classifier.(IncrementalClassifier)
+	if removed || !incremental {
+		if err := mc.trainClassifier(); err != nil {
+			return err
+		}
+	} else {
+		incrementalClassifier.Learn(categorizationText(current), categoryLabel(current.CategoryID))
+		mc.lastTrained = time.Now()
+	}
+	return mc.persistModel()
+}
+
+// ForgetTransaction removes a deleted transaction's contribution to the model
+func (mc *MLCategorizer) ForgetTransaction(txn models.Transaction) error {
+	if !txn.UseForTraining {
+		return nil
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if !mc.removeSample(txn) {
+		return nil
+	}
+	if err := mc.trainClassifier(); err != nil {
+		return err
+	}
+	return mc.persistModel()
+}
+
+func (mc *MLCategorizer) GetModelStatus() ModelStatus {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	status := ModelStatus{
+		Backend:     mc.backend(),
+		LastTrained: mc.lastTrained,
+	}
+	for _, samples := range mc.trainingSet.Samples {
+		if len(samples) > 0 {
+			status.CategoryCount++
+		}
+		status.SampleCount += len(samples)
+	}
+	return status
+}
+
 // Should this return just the category name as a string, a category object, both, or something else?
 func (mc *MLCategorizer) CategorizeTransaction(transaction *models.Transaction) (category models.Category, err error) {
-	results := mc.Bag.GetResults(transaction.Description)
-	fmt.Println("categorizing results:", results)
-	categoryName := results.GetHighestProbability()
-	// On the initial run, there will be no training data and therefore categoryName will be the empty string
-	if categoryName == "" {
-		categoryName = "Unknown"
-	}
-	// If the probability of the highest probability result is less than -8, then we're better off
-	// assigning it to "Unknown"
-	if results[categoryName] < -8 {
-		categoryName = "Unknown"
-	}
-	category, err = mc.CategoryRepository.GetCategoryByName(categoryName)
+	mc.mu.RLock()
+	label, ok := "", false
+	if mc.classifier != nil {
+		label, ok = mc.classifier.Predict(categorizationText(*transaction))
+	}
+	mc.mu.RUnlock()
+
+	// On the initial run, there will be no training data and therefore no prediction.
+	// If the classifier isn't confident in any category, we're better off assigning it to "Unknown"
+	if !ok {
+		return mc.CategoryRepository.GetCategoryByName("Unknown")
+	}
+
+	categoryID, err := parseCategoryLabel(label)
 	if err != nil {
 		return category, err
-
 	}
-	fmt.Println("Categorizing transaction: ", transaction.Description, " as ", category.Name, " with score ", results[categoryName])
+	category, err = mc.CategoryRepository.GetCategoryByID(categoryID)
+	if err != nil {
+		return category, err
+	}
+	fmt.Println("Categorizing transaction: ", transaction.Description, " as ", category.Name, " using ", mc.backend())
 	return category, nil
 }
+
+// Labels are category IDs rather than names so renaming a category doesn't invalidate the model
+func categoryLabel(categoryID uint) string {
+	return strconv.FormatUint(uint64(categoryID), 10)
+}
+
+// categorizationText is what the model is trained on and categorizes by. The normalized merchant name
+// is preferred so the same merchant's store numbers and locations don't fragment the vocabulary.
+func categorizationText(txn models.Transaction) string {
+	if txn.MerchantName != "" {
+		return txn.MerchantName
+	}
+	return txn.Description
+}
+
+func parseCategoryLabel(label string) (uint, error) {
+	categoryID, err := strconv.ParseUint(label, 10, 64)
+	return uint(categoryID), err
+}
+
+// removeSample removes a single sample matching the transaction's description and category.
+// Callers must hold the write lock.
+func (mc *MLCategorizer) removeSample(txn models.Transaction) bool {
+	label := categoryLabel(txn.CategoryID)
+	samples := mc.trainingSet.Samples[label]
+	for i, sample := range samples {
+		if sample == categorizationText(txn) {
+			mc.trainingSet.Samples[label] = append(samples[:i], samples[i+1:]...)
+			if len(mc.trainingSet.Samples[label]) == 0 {
+				delete(mc.trainingSet.Samples, label)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (mc *MLCategorizer) backend() string {
+	if mc.Backend == "" {
+		return BayesBackend
+	}
+	return mc.Backend
+}
+
+// trainClassifier creates a fresh classifier for the selected backend from the in-memory
+// training set. Callers must hold the write lock.
+func (mc *MLCategorizer) trainClassifier() error {
+	if mc.trainingSet.Samples == nil {
+		mc.trainingSet.Samples = SamplesByLabel{}
+	}
+	classifier, err := NewClassifier(mc.backend())
+	if err != nil {
+		return err
+	}
+	if err = classifier.Train(mc.trainingSet.Samples); err != nil {
+		return err
+	}
+	mc.classifier = classifier
+	mc.lastTrained = time.Now()
+	return nil
+}
+
+// snapshotSamples returns a copy of the in-memory training set that is safe to use without the lock
+func (mc *MLCategorizer) snapshotSamples() SamplesByLabel {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	samples := SamplesByLabel{}
+	for label, descriptions := range mc.trainingSet.Samples {
+		samples[label] = append([]string{}, descriptions...)
+	}
+	return samples
+}
+
+// persistModel saves the in-memory training set. Callers must hold the write lock.
+func (mc *MLCategorizer) persistModel() error {
+	encodedTrainingSet, err := json.Marshal(mc.trainingSet)
+	if err != nil {
+		return err
+	}
+	sampleCount := 0
+	for _, samples := range mc.trainingSet.Samples {
+		sampleCount += len(samples)
+	}
+	_, err = mc.CategorizerModelRepository.Save(models.CategorizerModel{
+		Backend:     mc.backend(),
+		TrainingSet: string(encodedTrainingSet),
+		SampleCount: sampleCount,
+	})
+	return err
+}
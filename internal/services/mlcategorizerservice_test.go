@@ -0,0 +1,239 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func (m *MockTransactionRepository) GetTransactionsForTraining() ([]models.Transaction, error) {
+	return m.TrainingTxns, m.Err
+}
+
+type MockCategorizerCategoryRepository struct {
+	CategoriesByID map[uint]models.Category
+}
+
+func (m *MockCategorizerCategoryRepository) GetCategoryByID(id uint) (models.Category, error) {
+	category, ok := m.CategoriesByID[id]
+	if !ok {
+		return category, gorm.ErrRecordNotFound
+	}
+	return category, nil
+}
+
+func (m *MockCategorizerCategoryRepository) GetCategoryByName(name string) (models.Category, error) {
+	for _, category := range m.CategoriesByID {
+		if category.Name == name {
+			return category, nil
+		}
+	}
+	return models.Category{}, gorm.ErrRecordNotFound
+}
+
+func newTestCategorizer(trainingTxns []models.Transaction, modelRepo *MockCategorizerModelRepository) *MLCategorizer {
+	return &MLCategorizer{
+		CategoryRepository: &MockCategorizerCategoryRepository{CategoriesByID: map[uint]models.Category{
+			1: {Model: gorm.Model{ID: 1}, Name: "Unknown"},
+			2: {Model: gorm.Model{ID: 2}, Name: "Food"},
+			3: {Model: gorm.Model{ID: 3}, Name: "Auto"},
+		}},
+		CategorizerModelRepository: modelRepo,
+		TransactionRepository:      &MockTransactionRepository{TrainingTxns: trainingTxns},
+	}
+}
+
+func TestBuildModel_PersistsTrainingSet(t *testing.T) {
+	modelRepo := &MockCategorizerModelRepository{}
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "whole foods market", CategoryID: 2, UseForTraining: true},
+		{Description: "shell gas station", CategoryID: 3, UseForTraining: true},
+	}, modelRepo)
+
+	err := categorizer.BuildModel()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(modelRepo.Saved) != 1 {
+		t.Fatalf("expected model to be persisted once, got %d", len(modelRepo.Saved))
+	}
+	if modelRepo.Saved[0].SampleCount != 2 {
+		t.Errorf("expected 2 persisted samples, got %d", modelRepo.Saved[0].SampleCount)
+	}
+
+	category, err := categorizer.CategorizeTransaction(&models.Transaction{Description: "whole foods market"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if category.Name != "Food" {
+		t.Errorf("expected Food, got %s", category.Name)
+	}
+}
+
+func TestLoadModel_UsesPersistedModel(t *testing.T) {
+	trainingSet := TrainingSet{Samples: SamplesByLabel{"3": {"shell gas station"}}}
+	encoded, _ := json.Marshal(trainingSet)
+	modelRepo := &MockCategorizerModelRepository{Model: models.CategorizerModel{Backend: BayesBackend, TrainingSet: string(encoded)}}
+	// No training transactions, so the category can only come from the persisted model
+	categorizer := newTestCategorizer(nil, modelRepo)
+
+	err := categorizer.LoadModel()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(modelRepo.Saved) != 0 {
+		t.Errorf("expected the persisted model to be reused without saving, got %d saves", len(modelRepo.Saved))
+	}
+	category, _ := categorizer.CategorizeTransaction(&models.Transaction{Description: "shell gas station"})
+	if category.Name != "Auto" {
+		t.Errorf("expected Auto, got %s", category.Name)
+	}
+}
+
+func TestLoadModel_BuildsWhenNothingPersisted(t *testing.T) {
+	modelRepo := &MockCategorizerModelRepository{Err: gorm.ErrRecordNotFound}
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "whole foods market", CategoryID: 2, UseForTraining: true},
+	}, modelRepo)
+
+	err := categorizer.LoadModel()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(modelRepo.Saved) != 1 {
+		t.Errorf("expected a freshly built model to be persisted, got %d saves", len(modelRepo.Saved))
+	}
+}
+
+func TestLoadModel_Error(t *testing.T) {
+	categorizer := newTestCategorizer(nil, &MockCategorizerModelRepository{Err: errors.New("fail")})
+	if err := categorizer.LoadModel(); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestLearnTransaction_ReplacesCorrectedSample(t *testing.T) {
+	modelRepo := &MockCategorizerModelRepository{}
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "costco wholesale", CategoryID: 3, UseForTraining: true},
+	}, modelRepo)
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := models.Transaction{Model: gorm.Model{ID: 10}, Description: "costco wholesale", CategoryID: 3, UseForTraining: true}
+	corrected := previous
+	corrected.CategoryID = 2
+
+	err := categorizer.LearnTransaction(previous, corrected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status := categorizer.GetModelStatus()
+	if status.SampleCount != 1 || status.CategoryCount != 1 {
+		t.Errorf("expected 1 sample in 1 category, got %d samples in %d categories", status.SampleCount, status.CategoryCount)
+	}
+	category, _ := categorizer.CategorizeTransaction(&models.Transaction{Description: "costco wholesale"})
+	if category.Name != "Food" {
+		t.Errorf("expected Food after correction, got %s", category.Name)
+	}
+	if len(modelRepo.Saved) != 2 {
+		t.Errorf("expected the corrected model to be persisted, got %d saves", len(modelRepo.Saved))
+	}
+}
+
+func TestLearnTransaction_NewSampleWithoutBuild(t *testing.T) {
+	categorizer := newTestCategorizer(nil, &MockCategorizerModelRepository{})
+
+	err := categorizer.LearnTransaction(models.Transaction{}, models.Transaction{Description: "shell gas station", CategoryID: 3, UseForTraining: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	category, _ := categorizer.CategorizeTransaction(&models.Transaction{Description: "shell gas station"})
+	if category.Name != "Auto" {
+		t.Errorf("expected Auto, got %s", category.Name)
+	}
+}
+
+func TestForgetTransaction(t *testing.T) {
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "shell gas station", CategoryID: 3, UseForTraining: true},
+	}, &MockCategorizerModelRepository{})
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := categorizer.ForgetTransaction(models.Transaction{Description: "shell gas station", CategoryID: 3, UseForTraining: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := categorizer.GetModelStatus(); status.SampleCount != 0 {
+		t.Errorf("expected no samples, got %d", status.SampleCount)
+	}
+	category, _ := categorizer.CategorizeTransaction(&models.Transaction{Description: "shell gas station"})
+	if category.Name != "Unknown" {
+		t.Errorf("expected Unknown, got %s", category.Name)
+	}
+}
+
+func TestSetBackend_RetrainsAndPersists(t *testing.T) {
+	modelRepo := &MockCategorizerModelRepository{}
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "whole foods market", CategoryID: 2, UseForTraining: true},
+		{Description: "shell gas station", CategoryID: 3, UseForTraining: true},
+	}, modelRepo)
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := categorizer.SetBackend(KNNBackend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := categorizer.GetModelStatus(); status.Backend != KNNBackend || status.SampleCount != 2 {
+		t.Errorf("expected 2 samples using %s, got %d using %s", KNNBackend, status.SampleCount, status.Backend)
+	}
+	if saved := modelRepo.Saved[len(modelRepo.Saved)-1]; saved.Backend != KNNBackend {
+		t.Errorf("expected the model to be persisted under %s, got %s", KNNBackend, saved.Backend)
+	}
+	category, _ := categorizer.CategorizeTransaction(&models.Transaction{Description: "shell gas station"})
+	if category.Name != "Auto" {
+		t.Errorf("expected Auto, got %s", category.Name)
+	}
+}
+
+func TestSetBackend_UnknownBackend(t *testing.T) {
+	categorizer := newTestCategorizer(nil, &MockCategorizerModelRepository{})
+	if err := categorizer.SetBackend("astrology"); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if status := categorizer.GetModelStatus(); status.Backend != BayesBackend {
+		t.Errorf("expected backend to remain %s, got %s", BayesBackend, status.Backend)
+	}
+}
+
+func TestBuildModel_TrainsOnMerchantName(t *testing.T) {
+	modelRepo := &MockCategorizerModelRepository{}
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "SQ *BLUE BOTTLE 0423 OAKLAND CA", MerchantName: "Blue Bottle", CategoryID: 2, UseForTraining: true},
+		{Description: "legacy description", CategoryID: 3, UseForTraining: true},
+	}, modelRepo)
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var trainingSet TrainingSet
+	if err := json.Unmarshal([]byte(modelRepo.Saved[0].TrainingSet), &trainingSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if samples := trainingSet.Samples["2"]; len(samples) != 1 || samples[0] != "Blue Bottle" {
+		t.Errorf("expected to train on the merchant name, got %v", samples)
+	}
+	// Transactions imported before merchant names existed fall back to the description
+	if samples := trainingSet.Samples["3"]; len(samples) != 1 || samples[0] != "legacy description" {
+		t.Errorf("expected to train on the description, got %v", samples)
+	}
+}
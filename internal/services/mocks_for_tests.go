@@ -22,8 +22,28 @@ func (m *MockBalanceRepository) Save(balance models.Balance) (uint, error) {
 }
 
 type MockCategoryRepository struct {
-	Category models.Category
-	Err      error
+	Category   models.Category
+	Categories []models.Category
+	Err        error
+}
+
+type MockCategorizerModelRepository struct {
+	Model   models.CategorizerModel
+	Err     error
+	Saved   []models.CategorizerModel
+	SaveErr error
+}
+
+func (m *MockCategorizerModelRepository) GetCategorizerModel(backend string) (models.CategorizerModel, error) {
+	return m.Model, m.Err
+}
+
+func (m *MockCategorizerModelRepository) Save(model models.CategorizerModel) (uint, error) {
+	m.Saved = append(m.Saved, model)
+	if m.SaveErr != nil {
+		return 0, m.SaveErr
+	}
+	return 1, nil
 }
 
 type MockImportSubmissionRepository struct {
@@ -40,11 +60,16 @@ func (m *MockImportSubmissionRepository) Save(sub models.ImportSubmission) (uint
 }
 
 type MockTransactionRepository struct {
-	Err        error
-	SaveErr    error
-	Sum        int
-	Totals     []models.TotalByMonth
-	TxnsByHash map[string][]models.Transaction
+	Err          error
+	SaveErr      error
+	Sum          int
+	Totals       []models.TotalByMonth
+	TrainingTxns []models.Transaction
+	Txns         []models.Transaction
+	TxnsByHash   map[string][]models.Transaction
+	SavedTxns    []models.Transaction
+	// Monthly totals for each category, used instead of Totals when set
+	TotalsByCategory map[uint][]models.TotalByMonth
 }
 
 func (m *MockTransactionRepository) GetTransactionsByHash(hash string, submissionID uint) ([]models.Transaction, error) {
@@ -55,6 +80,7 @@ func (m *MockTransactionRepository) Save(txn models.Transaction) (uint, error) {
 	if m.SaveErr != nil {
 		return 0, m.SaveErr
 	}
+	m.SavedTxns = append(m.SavedTxns, txn)
 	return 1, nil
 }
 
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// Ways of breaking down net worth
+const (
+	NetWorthByAccount         = "account"
+	NetWorthByAccountCategory = "accountCategory"
+	NetWorthByGroup           = "group"
+)
+
+// NetWorthUngrouped is the row for accounts that aren't in a net worth group
+const NetWorthUngrouped = "Ungrouped"
+
+// NetWorthBalanceRepositoryInterface specifically for NetWorthService
+type NetWorthBalanceRepositoryInterface interface {
+	GetBalancesByMonth(ctx context.Context, ledgerType string, startYearMonth time.Time, endYearMonth time.Time) []models.BalancesWithDate
+}
+
+type NetWorthService struct {
+	AccountRepository AccountRepositoryInterface
+	BalanceRepository NetWorthBalanceRepositoryInterface
+}
+
+// NetWorthRow is one account, account category or group in a net worth breakdown. Liabilities are negative, so
+// rows add up to net worth.
+type NetWorthRow struct {
+	Name      string
+	Amounts   []int  // For each month of the breakdown
+	Changes   []int  // From the month before, for each month of the breakdown
+	Estimated []bool // Whether any of the row's balances were estimated, for each month of the breakdown
+}
+
+// NetWorthBreakdown is net worth at the end of each month, broken down into rows
+type NetWorthBreakdown struct {
+	Months          []time.Time
+	Rows            []NetWorthRow
+	Assets          []int
+	Liabilities     []int // Negative
+	NetWorth        []int
+	NetWorthChanges []int
+	Estimated       []bool // Whether any balance was estimated, for each month
+}
+
+// GetNetWorthBreakdown totals the latest balance of each account at the end of each month from startDate to
+// endDate, broken down by account, account category or net worth group. Rows are ordered from the largest asset
+// to the largest liability, as of the last month.
+func (nws *NetWorthService) GetNetWorthBreakdown(startDate time.Time, endDate time.Time, breakdown string) (NetWorthBreakdown, error) {
+	accounts, err := nws.AccountRepository.GetAllAccounts()
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	// Rows are keyed by account ID when breaking down by account, since account names don't have to be unique
+	rowKeys := map[uint]string{}
+	rowNames := map[string]string{}
+	for _, account := range accounts {
+		name := netWorthRowName(account, breakdown)
+		key := name
+		if breakdown == NetWorthByAccount {
+			key = fmt.Sprint(account.ID)
+		}
+		rowKeys[account.ID] = key
+		rowNames[key] = name
+	}
+
+	// Start a month early so the first month has a change too
+	assetBalances := nws.BalanceRepository.GetBalancesByMonth(context.TODO(), models.Asset, startDate.AddDate(0, -1, 0), endDate)
+	liabilityBalances := nws.BalanceRepository.GetBalancesByMonth(context.TODO(), models.Liability, startDate.AddDate(0, -1, 0), endDate)
+	if len(assetBalances) == 0 {
+		return NetWorthBreakdown{}, nil
+	}
+
+	months := len(assetBalances)
+	amountsByRow := map[string][]int{}
+	assets := make([]int, months)
+	liabilities := make([]int, months)
+	netWorth := make([]int, months)
+	estimatedByRow := map[string][]bool{}
+	estimated := make([]bool, months)
+	addBalance := func(month int, balance models.Balance, amount int) {
+		key := rowKeys[balance.AccountID]
+		if amountsByRow[key] == nil {
+			amountsByRow[key] = make([]int, months)
+			estimatedByRow[key] = make([]bool, months)
+		}
+		amountsByRow[key][month] += amount
+		netWorth[month] += amount
+		if balance.Estimated {
+			estimatedByRow[key][month] = true
+			estimated[month] = true
+		}
+	}
+	result := NetWorthBreakdown{}
+	for i, balancesByDate := range assetBalances {
+		result.Months = append(result.Months, balancesByDate.Date)
+		for _, balance := range balancesByDate.Balances {
+			assets[i] += balance.Amount
+			addBalance(i, balance, balance.Amount)
+		}
+		if i < len(liabilityBalances) {
+			for _, balance := range liabilityBalances[i].Balances {
+				liabilities[i] -= balance.Amount
+				addBalance(i, balance, -balance.Amount)
+			}
+		}
+	}
+
+	// Drop the extra month now that it's been used for the first change
+	result.Months = result.Months[1:]
+	result.Assets = assets[1:]
+	result.Liabilities = liabilities[1:]
+	result.NetWorth, result.NetWorthChanges = amountsAndChanges(netWorth)
+	result.Estimated = estimated[1:]
+	for key, amounts := range amountsByRow {
+		row := NetWorthRow{Name: rowNames[key], Estimated: estimatedByRow[key][1:]}
+		row.Amounts, row.Changes = amountsAndChanges(amounts)
+		result.Rows = append(result.Rows, row)
+	}
+	last := len(result.Months) - 1
+	sort.Slice(result.Rows, func(i, j int) bool {
+		if last < 0 || result.Rows[i].Amounts[last] == result.Rows[j].Amounts[last] {
+			return result.Rows[i].Name < result.Rows[j].Name
+		}
+		return result.Rows[i].Amounts[last] > result.Rows[j].Amounts[last]
+	})
+	return result, nil
+}
+
+// amountsAndChanges drops the first of a series of monthly amounts, returning the rest along with how much each
+// changed from the month before
+func amountsAndChanges(amounts []int) ([]int, []int) {
+	changes := make([]int, len(amounts)-1)
+	for i := 1; i < len(amounts); i++ {
+		changes[i-1] = amounts[i] - amounts[i-1]
+	}
+	return amounts[1:], changes
+}
+
+func netWorthRowName(account models.Account, breakdown string) string {
+	switch breakdown {
+	case NetWorthByAccountCategory:
+		return account.AccountType.AccountCategory
+	case NetWorthByGroup:
+		if account.NetWorthGroup == "" {
+			return NetWorthUngrouped
+		}
+		return account.NetWorthGroup
+	default:
+		return account.Name
+	}
+}
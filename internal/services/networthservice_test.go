@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockNetWorthBalanceRepository struct {
+	BalancesByLedgerType map[string][]models.BalancesWithDate
+}
+
+func (m *MockNetWorthBalanceRepository) GetBalancesByMonth(ctx context.Context, ledgerType string, startYearMonth time.Time, endYearMonth time.Time) []models.BalancesWithDate {
+	return m.BalancesByLedgerType[ledgerType]
+}
+
+func TestGetNetWorthBreakdown(t *testing.T) {
+	month := func(m time.Month) time.Time { return time.Date(2026, m, 1, 0, 0, 0, 0, time.UTC) }
+	brokerage := models.AccountType{LedgerType: models.Asset, AccountCategory: "brokerage"}
+	accounts := []models.Account{
+		{Model: gorm.Model{ID: 1}, Name: "401k", AccountType: brokerage, NetWorthGroup: "Retirement"},
+		{Model: gorm.Model{ID: 2}, Name: "IRA", AccountType: brokerage, NetWorthGroup: "Retirement"},
+		{Model: gorm.Model{ID: 3}, Name: "Checking", AccountType: models.AccountType{LedgerType: models.Asset, AccountCategory: "checking"}},
+		{Model: gorm.Model{ID: 4}, Name: "Visa", AccountType: models.AccountType{LedgerType: models.Liability, AccountCategory: "creditCard"}},
+	}
+	balanceRepository := &MockNetWorthBalanceRepository{BalancesByLedgerType: map[string][]models.BalancesWithDate{
+		models.Asset: {
+			// The month before the breakdown starts
+			{Date: month(1), Balances: []models.Balance{{AccountID: 1, Amount: 1000}, {AccountID: 3, Amount: 500}}},
+			{Date: month(2), Balances: []models.Balance{{AccountID: 1, Amount: 1200}, {AccountID: 2, Amount: 300}, {AccountID: 3, Amount: 400}}},
+			{Date: month(3), Balances: []models.Balance{{AccountID: 1, Amount: 1100}, {AccountID: 2, Amount: 350, Estimated: true}, {AccountID: 3, Amount: 600}}},
+		},
+		models.Liability: {
+			{Date: month(1), Balances: []models.Balance{{AccountID: 4, Amount: 200}}},
+			{Date: month(2), Balances: []models.Balance{{AccountID: 4, Amount: 250}}},
+			{Date: month(3), Balances: []models.Balance{{AccountID: 4, Amount: 100}}},
+		},
+	}}
+	service := &NetWorthService{AccountRepository: &MockAccountRepository{Accounts: accounts}, BalanceRepository: balanceRepository}
+
+	type expectedRow struct {
+		name    string
+		amounts []int
+		changes []int
+	}
+	tests := []struct {
+		name      string
+		breakdown string
+		expected  []expectedRow
+	}{
+		{
+			name:      "by account",
+			breakdown: NetWorthByAccount,
+			expected: []expectedRow{
+				{"401k", []int{1200, 1100}, []int{200, -100}},
+				{"Checking", []int{400, 600}, []int{-100, 200}},
+				{"IRA", []int{300, 350}, []int{300, 50}},
+				{"Visa", []int{-250, -100}, []int{-50, 150}},
+			},
+		},
+		{
+			name:      "by account category",
+			breakdown: NetWorthByAccountCategory,
+			expected: []expectedRow{
+				{"brokerage", []int{1500, 1450}, []int{500, -50}},
+				{"checking", []int{400, 600}, []int{-100, 200}},
+				{"creditCard", []int{-250, -100}, []int{-50, 150}},
+			},
+		},
+		{
+			name:      "by group",
+			breakdown: NetWorthByGroup,
+			expected: []expectedRow{
+				{"Retirement", []int{1500, 1450}, []int{500, -50}},
+				{NetWorthUngrouped, []int{150, 500}, []int{-150, 350}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, err := service.GetNetWorthBreakdown(month(2), month(4), tt.breakdown)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(breakdown.Months) != 2 || !breakdown.Months[0].Equal(month(2)) {
+				t.Errorf("Expected February and March, got %v", breakdown.Months)
+			}
+			if breakdown.NetWorth[0] != 1650 || breakdown.NetWorth[1] != 1950 || breakdown.NetWorthChanges[0] != 350 || breakdown.NetWorthChanges[1] != 300 {
+				t.Errorf("Expected net worth of 1650 then 1950, got %v with changes %v", breakdown.NetWorth, breakdown.NetWorthChanges)
+			}
+			if breakdown.Assets[1] != 2050 || breakdown.Liabilities[1] != -100 {
+				t.Errorf("Expected 2050 of assets and -100 of liabilities in March, got %d and %d", breakdown.Assets[1], breakdown.Liabilities[1])
+			}
+			if breakdown.Estimated[0] || !breakdown.Estimated[1] {
+				t.Errorf("Expected only March to include an estimated balance, got %v", breakdown.Estimated)
+			}
+			if len(breakdown.Rows) != len(tt.expected) {
+				t.Fatalf("Expected %d rows, got %+v", len(tt.expected), breakdown.Rows)
+			}
+			for i, expected := range tt.expected {
+				row := breakdown.Rows[i]
+				if row.Name != expected.name {
+					t.Errorf("Expected row %d to be %v, got %v", i, expected.name, row.Name)
+				}
+				for m := range expected.amounts {
+					if row.Amounts[m] != expected.amounts[m] || row.Changes[m] != expected.changes[m] {
+						t.Errorf("Expected %v to have %v changing by %v, got %v changing by %v", row.Name, expected.amounts, expected.changes, row.Amounts, row.Changes)
+						break
+					}
+				}
+				// Only the IRA's March balance is estimated
+				includesIRA := row.Name == "IRA" || row.Name == "brokerage" || row.Name == "Retirement"
+				if row.Estimated[0] || row.Estimated[1] != includesIRA {
+					t.Errorf("Expected %v to be estimated in March only if it includes the IRA, got %v", row.Name, row.Estimated)
+				}
+			}
+		})
+	}
+}
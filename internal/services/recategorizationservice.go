@@ -0,0 +1,134 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// RecategorizationTransactionRepositoryInterface specifically for RecategorizationService
+type RecategorizationTransactionRepositoryInterface interface {
+	GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]models.Transaction, error)
+	GetTransactionByID(id uint) (models.Transaction, error)
+	Save(txn models.Transaction) (uint, error)
+}
+
+// RecategorizationCategoryRepositoryInterface specifically for RecategorizationService
+type RecategorizationCategoryRepositoryInterface interface {
+	GetCategoryByID(id uint) (models.Category, error)
+}
+
+// RecategorizationTransferPairRepositoryInterface specifically for RecategorizationService
+type RecategorizationTransferPairRepositoryInterface interface {
+	GetConfirmedTransferTransactionIDs() ([]uint, error)
+}
+
+// RecategorizationScope limits which transactions are re-categorized. Zero values and nil
+// dates mean "don't filter on this field"
+type RecategorizationScope struct {
+	AccountID  uint
+	CategoryID uint
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+// RecategorizationChange is a transaction whose category the categorizer would change
+type RecategorizationChange struct {
+	Transaction      models.Transaction
+	ProposedCategory models.Category
+}
+
+// RecategorizationService re-runs the categorizer over transactions that have already been
+// imported, so improvements to the model can be applied to historical data
+type RecategorizationService struct {
+	Categorizer           Categorizes
+	CategoryRepository    RecategorizationCategoryRepositoryInterface
+	TransactionRepository RecategorizationTransactionRepositoryInterface
+	// Confirmed transfers stay in the transfer category until their pair is unlinked
+	TransferPairRepository RecategorizationTransferPairRepositoryInterface
+}
+
+// confirmedTransfers returns the set of transactions that belong to a confirmed transfer pair
+func (rs *RecategorizationService) confirmedTransfers() (map[uint]bool, error) {
+	ids, err := rs.TransferPairRepository.GetConfirmedTransferTransactionIDs()
+	if err != nil {
+		return nil, err
+	}
+	confirmed := map[uint]bool{}
+	for _, id := range ids {
+		confirmed[id] = true
+	}
+	return confirmed, nil
+}
+
+// PreviewRecategorization is a dry run that returns every transaction in scope whose category
+// would change. Transactions categorized by hand (the ones used for training), split transactions and
+// confirmed transfers are never included.
+func (rs *RecategorizationService) PreviewRecategorization(scope RecategorizationScope) (changes []RecategorizationChange, err error) {
+	transactions, err := rs.TransactionRepository.GetAllTransactions(scope.AccountID, scope.CategoryID, 0, "", scope.StartDate, scope.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	confirmedTransfers, err := rs.confirmedTransfers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range transactions {
+		if transaction.UseForTraining || len(transaction.Splits) > 0 || confirmedTransfers[transaction.ID] {
+			continue
+		}
+		proposedCategory, err := rs.Categorizer.CategorizeTransaction(&transaction)
+		if err != nil {
+			return nil, err
+		}
+		if proposedCategory.ID == transaction.CategoryID {
+			continue
+		}
+		changes = append(changes, RecategorizationChange{
+			Transaction:      transaction,
+			ProposedCategory: proposedCategory,
+		})
+	}
+	return changes, nil
+}
+
+// ApplyRecategorization sets the category of each transaction ID to the mapped category ID,
+// returning the number of transactions updated. Transactions that have been categorized by hand,
+// split or confirmed as a transfer since the preview was generated are skipped.
+func (rs *RecategorizationService) ApplyRecategorization(categoryIDsByTransactionID map[uint]uint) (updated int, err error) {
+	transactionIDs := make([]uint, 0, len(categoryIDsByTransactionID))
+	for transactionID := range categoryIDsByTransactionID {
+		transactionIDs = append(transactionIDs, transactionID)
+	}
+	sort.Slice(transactionIDs, func(i, j int) bool { return transactionIDs[i] < transactionIDs[j] })
+	confirmedTransfers, err := rs.confirmedTransfers()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, transactionID := range transactionIDs {
+		transaction, err := rs.TransactionRepository.GetTransactionByID(transactionID)
+		if err != nil {
+			return updated, err
+		}
+		if transaction.ID == 0 || transaction.UseForTraining || len(transaction.Splits) > 0 || confirmedTransfers[transaction.ID] {
+			continue
+		}
+		category, err := rs.CategoryRepository.GetCategoryByID(categoryIDsByTransactionID[transactionID])
+		if err != nil {
+			return updated, err
+		}
+
+		// UseForTraining stays false, the model shouldn't be trained on its own output
+		transaction.CategoryID = category.ID
+		transaction.Category = category
+		_, err = rs.TransactionRepository.Save(transaction)
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func (m *MockTransactionRepository) GetAllTransactions(accountID uint, categoryID uint, tagID uint, description string, startDate *time.Time, endDate *time.Time) ([]models.Transaction, error) {
+	txns := []models.Transaction{}
+	for _, txn := range m.Txns {
+		if categoryID != 0 && txn.CategoryID != categoryID {
+			continue
+		}
+		txns = append(txns, txn)
+	}
+	return txns, m.Err
+}
+
+func (m *MockTransactionRepository) GetTransactionByID(id uint) (models.Transaction, error) {
+	for _, txn := range m.Txns {
+		if txn.ID == id {
+			return txn, m.Err
+		}
+	}
+	return models.Transaction{}, m.Err
+}
+
+func (m *MockTransferPairRepository) GetConfirmedTransferTransactionIDs() ([]uint, error) {
+	ids := []uint{}
+	for _, pair := range m.Pairs {
+		if pair.Status == models.TransferPairConfirmed {
+			ids = append(ids, pair.TransactionID, pair.MatchedTransactionID)
+		}
+	}
+	return ids, m.Err
+}
+
+func newTestRecategorizationService(t *testing.T, txns []models.Transaction) (*RecategorizationService, *MockTransactionRepository) {
+	categorizer := newTestCategorizer([]models.Transaction{
+		{Description: "whole foods market", CategoryID: 2, UseForTraining: true},
+		{Description: "shell gas station", CategoryID: 3, UseForTraining: true},
+	}, &MockCategorizerModelRepository{})
+	if err := categorizer.BuildModel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transactionRepo := &MockTransactionRepository{Txns: txns}
+	return &RecategorizationService{
+		Categorizer:            categorizer,
+		CategoryRepository:     categorizer.CategoryRepository,
+		TransactionRepository:  transactionRepo,
+		TransferPairRepository: &MockTransferPairRepository{},
+	}, transactionRepo
+}
+
+func TestPreviewRecategorization(t *testing.T) {
+	service, transactionRepo := newTestRecategorizationService(t, []models.Transaction{
+		{Model: gorm.Model{ID: 10}, Description: "whole foods market", CategoryID: 1},
+		// Already in the category the model would pick
+		{Model: gorm.Model{ID: 11}, Description: "shell gas station", CategoryID: 3},
+		// Categorized by hand, so it must never be proposed
+		{Model: gorm.Model{ID: 12}, Description: "shell gas station", CategoryID: 2, UseForTraining: true},
+		// Split across categories, so its own category doesn't matter
+		{Model: gorm.Model{ID: 13}, Description: "whole foods market", CategoryID: 1, Splits: []models.TransactionSplit{
+			{CategoryID: 2, Amount: 500},
+			{CategoryID: 3, Amount: 500},
+		}},
+	})
+
+	changes, err := service.PreviewRecategorization(RecategorizationScope{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if changes[0].Transaction.ID != 10 || changes[0].ProposedCategory.Name != "Food" {
+		t.Errorf("expected transaction 10 to move to Food, got transaction %d to %s", changes[0].Transaction.ID, changes[0].ProposedCategory.Name)
+	}
+	if len(transactionRepo.SavedTxns) != 0 {
+		t.Errorf("expected a dry run not to save anything, got %d saves", len(transactionRepo.SavedTxns))
+	}
+}
+
+func TestPreviewRecategorization_ScopedByCategory(t *testing.T) {
+	service, _ := newTestRecategorizationService(t, []models.Transaction{
+		{Model: gorm.Model{ID: 10}, Description: "whole foods market", CategoryID: 1},
+		{Model: gorm.Model{ID: 11}, Description: "whole foods market", CategoryID: 3},
+	})
+
+	changes, err := service.PreviewRecategorization(RecategorizationScope{CategoryID: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Transaction.ID != 11 {
+		t.Errorf("expected only transaction 11 to be proposed, got %v", changes)
+	}
+}
+
+func TestRecategorization_ConfirmedTransfers(t *testing.T) {
+	service, transactionRepo := newTestRecategorizationService(t, []models.Transaction{
+		{Model: gorm.Model{ID: 10}, Description: "whole foods market", CategoryID: 1},
+		{Model: gorm.Model{ID: 11}, Description: "whole foods market", CategoryID: 1},
+		{Model: gorm.Model{ID: 12}, Description: "whole foods market", CategoryID: 1},
+		{Model: gorm.Model{ID: 13}, Description: "whole foods market", CategoryID: 1},
+	})
+	// 10 and 11 are a confirmed transfer, 12 and 13 are only a suggestion
+	service.TransferPairRepository = &MockTransferPairRepository{Pairs: []models.TransferPair{
+		{TransactionID: 10, MatchedTransactionID: 11, Status: models.TransferPairConfirmed},
+		{TransactionID: 12, MatchedTransactionID: 13, Status: models.TransferPairSuggested},
+	}}
+
+	changes, err := service.PreviewRecategorization(RecategorizationScope{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Transaction.ID != 12 || changes[1].Transaction.ID != 13 {
+		t.Errorf("expected only transactions 12 and 13 to be proposed, got %v", changes)
+	}
+
+	updated, err := service.ApplyRecategorization(map[uint]uint{10: 2, 11: 2, 12: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 1 || len(transactionRepo.SavedTxns) != 1 || transactionRepo.SavedTxns[0].ID != 12 {
+		t.Errorf("expected only transaction 12 to be updated, got %d (%d saves)", updated, len(transactionRepo.SavedTxns))
+	}
+}
+
+func TestPreviewRecategorization_Error(t *testing.T) {
+	service, transactionRepo := newTestRecategorizationService(t, nil)
+	transactionRepo.Err = errors.New("fail")
+	if _, err := service.PreviewRecategorization(RecategorizationScope{}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestApplyRecategorization(t *testing.T) {
+	service, transactionRepo := newTestRecategorizationService(t, []models.Transaction{
+		{Model: gorm.Model{ID: 10}, Description: "whole foods market", CategoryID: 1},
+		{Model: gorm.Model{ID: 11}, Description: "shell gas station", CategoryID: 1},
+		{Model: gorm.Model{ID: 12}, Description: "shell gas station", CategoryID: 2, UseForTraining: true},
+	})
+
+	// Transaction 11 wasn't selected, and 12 was categorized by hand after the preview
+	updated, err := service.ApplyRecategorization(map[uint]uint{10: 2, 12: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 1 || len(transactionRepo.SavedTxns) != 1 {
+		t.Fatalf("expected 1 transaction to be updated, got %d (%d saves)", updated, len(transactionRepo.SavedTxns))
+	}
+	saved := transactionRepo.SavedTxns[0]
+	if saved.ID != 10 || saved.CategoryID != 2 || saved.Category.Name != "Food" {
+		t.Errorf("expected transaction 10 saved as Food, got transaction %d as category %d (%s)", saved.ID, saved.CategoryID, saved.Category.Name)
+	}
+	if saved.UseForTraining {
+		t.Error("expected re-categorized transaction not to be used for training")
+	}
+}
+
+func TestApplyRecategorization_UnknownCategory(t *testing.T) {
+	service, _ := newTestRecategorizationService(t, []models.Transaction{
+		{Model: gorm.Model{ID: 10}, Description: "whole foods market", CategoryID: 1},
+	})
+	if _, err := service.ApplyRecategorization(map[uint]uint{10: 99}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// ReconciliationPeriod compares how much an account's reported balance changed between two balances with the net
+// of its transactions over the same window, which starts after the start balance's date and runs through the end
+// balance's date
+type ReconciliationPeriod struct {
+	StartBalance     models.Balance
+	EndBalance       models.Balance
+	BalanceChange    int
+	TransactionsNet  int
+	TransactionCount int
+	// Difference is the change in balance the transactions don't explain, which usually means a statement is
+	// missing or was imported twice
+	Difference int
+}
+
+// Reconciles is true if the transactions account for the whole change in balance
+func (rp ReconciliationPeriod) Reconciles() bool {
+	return rp.Difference == 0
+}
+
+// ReconciledTransaction is a transaction along with how much it moved its account's balance
+type ReconciledTransaction struct {
+	models.Transaction
+	BalanceChange int
+}
+
+// ReconcileAccount compares every pair of consecutive reported balances of an account with the transactions
+// between them, oldest first. Derived balances are skipped, since they're worked out from the transactions and
+// always reconcile, and so are estimated balances, since they come from a valuation rule.
+func (bhs *BalanceHistoryService) ReconcileAccount(accountID uint) ([]ReconciliationPeriod, error) {
+	periods, _, _, err := bhs.reconcile(accountID)
+	return periods, err
+}
+
+// GetReconciliationPeriod returns the period between two of an account's balances along with its transactions,
+// oldest first, for drilling into a discrepancy
+func (bhs *BalanceHistoryService) GetReconciliationPeriod(accountID uint, startBalanceID uint, endBalanceID uint) (ReconciliationPeriod, []ReconciledTransaction, error) {
+	periods, transactions, changes, err := bhs.reconcile(accountID)
+	if err != nil {
+		return ReconciliationPeriod{}, nil, err
+	}
+	for _, period := range periods {
+		if period.StartBalance.ID != startBalanceID || period.EndBalance.ID != endBalanceID {
+			continue
+		}
+		reconciled := []ReconciledTransaction{}
+		for _, transaction := range transactions {
+			if period.includes(transaction) {
+				reconciled = append(reconciled, ReconciledTransaction{Transaction: transaction, BalanceChange: changes[transaction.ID]})
+			}
+		}
+		sort.SliceStable(reconciled, func(i, j int) bool { return reconciled[i].Date < reconciled[j].Date })
+		return period, reconciled, nil
+	}
+	return ReconciliationPeriod{}, nil, errors.New("those balances aren't consecutive reported balances of the account")
+}
+
+// reconcile returns the account's reconciliation periods along with its transactions and how much each of them
+// moved the balance
+func (bhs *BalanceHistoryService) reconcile(accountID uint) ([]ReconciliationPeriod, []models.Transaction, map[uint]int, error) {
+	account, err := bhs.AccountRepository.GetAccountByID(accountID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	transactions, err := bhs.TransactionRepository.GetAllTransactions(accountID, 0, 0, "", nil, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	changes, err := bhs.transactionBalanceChanges(account, transactions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var balances []models.Balance
+	for _, balance := range bhs.BalanceRepository.GetBalancesForAccount(context.TODO(), accountID) {
+		if !balance.Derived && !balance.Estimated {
+			balances = append(balances, balance)
+		}
+	}
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].EffectiveDate == balances[j].EffectiveDate {
+			return balances[i].ID < balances[j].ID
+		}
+		return balances[i].EffectiveDate < balances[j].EffectiveDate
+	})
+
+	periods := []ReconciliationPeriod{}
+	for i := 1; i < len(balances); i++ {
+		period := ReconciliationPeriod{
+			StartBalance:  balances[i-1],
+			EndBalance:    balances[i],
+			BalanceChange: balances[i].Amount - balances[i-1].Amount,
+		}
+		for _, transaction := range transactions {
+			if period.includes(transaction) {
+				period.TransactionsNet += changes[transaction.ID]
+				period.TransactionCount++
+			}
+		}
+		period.Difference = period.BalanceChange - period.TransactionsNet
+		periods = append(periods, period)
+	}
+	return periods, transactions, changes, nil
+}
+
+// includes is true if the transaction is dated after the start balance and on or before the end balance
+func (rp ReconciliationPeriod) includes(transaction models.Transaction) bool {
+	return transaction.Date > rp.StartBalance.EffectiveDate && transaction.Date <= rp.EndBalance.EffectiveDate
+}
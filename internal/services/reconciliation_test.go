@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func reconciliationTestService() *BalanceHistoryService {
+	checking := models.Account{Model: gorm.Model{ID: 1}, AccountType: models.AccountType{LedgerType: models.Asset, AccountCategory: "checking"}}
+	return &BalanceHistoryService{
+		AccountRepository: &MockAccountRepository{Account: checking},
+		BalanceRepository: &MockBalanceHistoryBalanceRepository{Balances: []models.Balance{
+			{Model: gorm.Model{ID: 3}, AccountID: 1, EffectiveDate: "2026-03-31", Amount: 110000},
+			{Model: gorm.Model{ID: 1}, AccountID: 1, EffectiveDate: "2026-01-31", Amount: 100000},
+			{Model: gorm.Model{ID: 2}, AccountID: 1, EffectiveDate: "2026-02-28", Amount: 120000},
+			{Model: gorm.Model{ID: 4}, AccountID: 1, EffectiveDate: "2026-03-15", Amount: 1, Derived: true},
+		}},
+		TransactionRepository: &MockTransactionRepository{Txns: []models.Transaction{
+			// On the date of the first balance, so already counted in it
+			balanceHistoryTestTransaction(10, "2026-01-31", 99999, models.CategoryKindExpense),
+			balanceHistoryTestTransaction(11, "2026-02-01", 50000, models.CategoryKindIncome),
+			balanceHistoryTestTransaction(12, "2026-02-10", 30000, models.CategoryKindExpense),
+			balanceHistoryTestTransaction(13, "2026-02-28", 0, models.CategoryKindExpense),
+			// March is missing a paycheck
+			balanceHistoryTestTransaction(14, "2026-03-20", 60000, models.CategoryKindExpense),
+			balanceHistoryTestTransaction(15, "2026-03-05", 10000, models.CategoryKindExpense),
+		}},
+		TransferPairRepository: &MockTransferPairRepository{},
+	}
+}
+
+func TestReconcileAccount(t *testing.T) {
+	periods, err := reconciliationTestService().ReconcileAccount(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(periods) != 2 {
+		t.Fatalf("Expected 2 periods between the 3 reported balances, got %+v", periods)
+	}
+
+	february := periods[0]
+	if february.StartBalance.ID != 1 || february.EndBalance.ID != 2 {
+		t.Errorf("Expected the first period to be between balances 1 and 2, got %d and %d", february.StartBalance.ID, february.EndBalance.ID)
+	}
+	if february.BalanceChange != 20000 || february.TransactionsNet != 20000 || february.TransactionCount != 3 || !february.Reconciles() {
+		t.Errorf("Expected February to reconcile, got %+v", february)
+	}
+
+	march := periods[1]
+	if march.BalanceChange != -10000 || march.TransactionsNet != -70000 || march.Difference != 60000 || march.Reconciles() {
+		t.Errorf("Expected March to be off by 60000, got %+v", march)
+	}
+}
+
+func TestGetReconciliationPeriod(t *testing.T) {
+	service := reconciliationTestService()
+	period, transactions, err := service.GetReconciliationPeriod(1, 2, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if period.Difference != 60000 {
+		t.Errorf("Expected a difference of 60000, got %d", period.Difference)
+	}
+	if len(transactions) != 2 || transactions[0].ID != 15 || transactions[0].BalanceChange != -10000 || transactions[1].ID != 14 {
+		t.Errorf("Expected March's transactions oldest first, got %+v", transactions)
+	}
+
+	_, _, err = service.GetReconciliationPeriod(1, 1, 3)
+	if err == nil {
+		t.Errorf("Expected an error for balances that aren't consecutive")
+	}
+}
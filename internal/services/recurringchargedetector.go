@@ -0,0 +1,229 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// RecurringTransactionRepositoryInterface specifically for RecurringChargeDetector
+type RecurringTransactionRepositoryInterface interface {
+	GetTransactionsForRecurringDetection(startDate time.Time) ([]models.Transaction, error)
+}
+
+// Cadence is how often a recurring charge happens. Occurrences count as on schedule when they are within
+// ToleranceDays of Days apart, which allows for billing dates moving around weekends and short months.
+type Cadence struct {
+	Name          string
+	Days          int
+	Months        int // Calendar months between occurrences, for cadences billed on the same day of the month
+	ToleranceDays int
+	PerYear       int
+	// Number of intervals between occurrences needed before a charge is considered recurring
+	MinIntervals int
+}
+
+// Cadences are checked in order, from the most to the least frequent
+var Cadences = []Cadence{
+	{Name: "Weekly", Days: 7, ToleranceDays: 1, PerYear: 52, MinIntervals: 3},
+	{Name: "Every 2 weeks", Days: 14, ToleranceDays: 2, PerYear: 26, MinIntervals: 2},
+	{Name: "Monthly", Days: 30, Months: 1, ToleranceDays: 5, PerYear: 12, MinIntervals: 2},
+	{Name: "Quarterly", Days: 91, Months: 3, ToleranceDays: 10, PerYear: 4, MinIntervals: 2},
+	{Name: "Annual", Days: 365, Months: 12, ToleranceDays: 15, PerYear: 1, MinIntervals: 1},
+}
+
+// recurringAmountTolerance is how far apart the amounts of a merchant's transactions can be and still be
+// treated as the same recurring charge, so price increases don't split a subscription in two
+const recurringAmountTolerance = 0.25
+
+// RecurringCharge is a series of transactions from the same merchant, for about the same amount, at a
+// regular cadence
+type RecurringCharge struct {
+	Merchant         string
+	CategoryName     string
+	Cadence          Cadence
+	Transactions     []models.Transaction // Oldest first
+	LastDate         time.Time
+	NextExpectedDate time.Time
+	AverageAmount    int
+	AnnualizedCost   int
+	// Set when a charge that was always the same amount changed on the latest occurrence
+	PriceChanged   bool
+	PreviousAmount int
+	LatestAmount   int
+	// Missed is set when the next occurrence is overdue
+	Missed bool
+	// Dates of occurrences that came within a few days of the previous one, like being charged twice in a month
+	DuplicateDates []string
+}
+
+// RecurringChargeDetector finds recurring charges like subscriptions, memberships, insurance and rent in the
+// spending of the last two years
+type RecurringChargeDetector struct {
+	TransactionRepository RecurringTransactionRepositoryInterface
+}
+
+// GetRecurringCharges returns the recurring charges as of today, most expensive per year first
+func (rcd *RecurringChargeDetector) GetRecurringCharges() ([]RecurringCharge, error) {
+	asOf := time.Now()
+	transactions, err := rcd.TransactionRepository.GetTransactionsForRecurringDetection(asOf.AddDate(-2, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	return DetectRecurringCharges(transactions, asOf), nil
+}
+
+// DetectRecurringCharges clusters transactions by merchant and amount, and keeps the clusters whose
+// occurrences follow one of the Cadences. Charges that stopped more than two cycles before asOf are treated
+// as cancelled and left out.
+func DetectRecurringCharges(transactions []models.Transaction, asOf time.Time) []RecurringCharge {
+	transactionsByMerchant := map[string][]models.Transaction{}
+	for _, transaction := range transactions {
+		if transaction.Amount <= 0 {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", transaction.Date); err != nil {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(recurringMerchantName(transaction)))
+		transactionsByMerchant[key] = append(transactionsByMerchant[key], transaction)
+	}
+
+	charges := []RecurringCharge{}
+	for _, merchantTransactions := range transactionsByMerchant {
+		for _, cluster := range clusterByAmount(merchantTransactions) {
+			charge, ok := detectCadence(cluster, asOf)
+			if ok {
+				charges = append(charges, charge)
+			}
+		}
+	}
+
+	sort.SliceStable(charges, func(i, j int) bool {
+		if charges[i].AnnualizedCost != charges[j].AnnualizedCost {
+			return charges[i].AnnualizedCost > charges[j].AnnualizedCost
+		}
+		return charges[i].Merchant < charges[j].Merchant
+	})
+	return charges
+}
+
+func recurringMerchantName(transaction models.Transaction) string {
+	if transaction.MerchantName != "" {
+		return transaction.MerchantName
+	}
+	return transaction.Description
+}
+
+// clusterByAmount splits a merchant's transactions into groups of similar amounts, so e.g. an annual
+// membership isn't mixed up with everyday purchases from the same store
+func clusterByAmount(transactions []models.Transaction) [][]models.Transaction {
+	sorted := append([]models.Transaction{}, transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Amount < sorted[j].Amount
+	})
+
+	clusters := [][]models.Transaction{}
+	clusterStart := 0
+	for i, transaction := range sorted {
+		if float64(transaction.Amount) > float64(sorted[clusterStart].Amount)*(1+recurringAmountTolerance) {
+			clusters = append(clusters, sorted[clusterStart:i])
+			clusterStart = i
+		}
+	}
+	if len(sorted) > 0 {
+		clusters = append(clusters, sorted[clusterStart:])
+	}
+	return clusters
+}
+
+func detectCadence(transactions []models.Transaction, asOf time.Time) (RecurringCharge, bool) {
+	sorted := append([]models.Transaction{}, transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date < sorted[j].Date
+	})
+	if len(sorted) < 2 {
+		return RecurringCharge{}, false
+	}
+
+	dates := make([]time.Time, len(sorted))
+	for i, transaction := range sorted {
+		dates[i], _ = time.Parse("2006-01-02", transaction.Date)
+	}
+
+	for _, cadence := range Cadences {
+		onSchedule := 0
+		regular := []models.Transaction{sorted[0]}
+		duplicateDates := []string{}
+		lastRegularDate := dates[0]
+		for i := 1; i < len(sorted); i++ {
+			days := int(dates[i].Sub(lastRegularDate).Hours() / 24)
+			if days <= cadence.ToleranceDays {
+				duplicateDates = append(duplicateDates, sorted[i].Date)
+				continue
+			}
+			if days >= cadence.Days-cadence.ToleranceDays && days <= cadence.Days+cadence.ToleranceDays {
+				onSchedule++
+			}
+			regular = append(regular, sorted[i])
+			lastRegularDate = dates[i]
+		}
+		intervals := len(regular) - 1
+		// Allow the odd skipped, late or duplicated occurrence, as long as most of them are on schedule. Lots of
+		// occurrences between the scheduled ones are regular shopping rather than a recurring charge.
+		if onSchedule < cadence.MinIntervals || float64(onSchedule) < 0.75*float64(intervals) || len(duplicateDates)*2 > intervals {
+			continue
+		}
+
+		nextExpectedDate := nextOccurrence(lastRegularDate, cadence)
+		if asOf.After(nextExpectedDate.AddDate(0, 0, cadence.Days*2)) {
+			return RecurringCharge{}, false
+		}
+
+		total := 0
+		for _, transaction := range regular {
+			total += transaction.Amount
+		}
+		latest := regular[len(regular)-1]
+		charge := RecurringCharge{
+			Merchant:         recurringMerchantName(latest),
+			CategoryName:     latest.Category.Name,
+			Cadence:          cadence,
+			Transactions:     sorted,
+			LastDate:         lastRegularDate,
+			NextExpectedDate: nextExpectedDate,
+			AverageAmount:    total / len(regular),
+			LatestAmount:     latest.Amount,
+			Missed:           asOf.After(nextExpectedDate.AddDate(0, 0, cadence.ToleranceDays)),
+			DuplicateDates:   duplicateDates,
+		}
+		charge.AnnualizedCost = charge.AverageAmount * cadence.PerYear
+		charge.PreviousAmount, charge.PriceChanged = priceChange(regular)
+		return charge, true
+	}
+	return RecurringCharge{}, false
+}
+
+// priceChange reports whether the latest occurrence costs something different from the ones before it, which
+// all cost the same. Bills that vary every time, like utilities, never count as a price change.
+func priceChange(transactions []models.Transaction) (previousAmount int, changed bool) {
+	if len(transactions) < 2 {
+		return 0, false
+	}
+	previousAmount = transactions[len(transactions)-2].Amount
+	for _, transaction := range transactions[:len(transactions)-1] {
+		if transaction.Amount != previousAmount {
+			return previousAmount, false
+		}
+	}
+	return previousAmount, transactions[len(transactions)-1].Amount != previousAmount
+}
+
+func nextOccurrence(lastDate time.Time, cadence Cadence) time.Time {
+	if cadence.Months > 0 {
+		return lastDate.AddDate(0, cadence.Months, 0)
+	}
+	return lastDate.AddDate(0, 0, cadence.Days)
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+func (m *MockTransactionRepository) GetTransactionsForRecurringDetection(startDate time.Time) ([]models.Transaction, error) {
+	return m.Txns, m.Err
+}
+
+func recurringTestTransactions(merchant string, amounts []int, dates ...string) []models.Transaction {
+	transactions := []models.Transaction{}
+	for i, date := range dates {
+		transactions = append(transactions, models.Transaction{
+			Date:         date,
+			MerchantName: merchant,
+			Amount:       amounts[i%len(amounts)],
+			Category:     models.Category{Name: "Entertainment"},
+		})
+	}
+	return transactions
+}
+
+func TestDetectRecurringCharges_Cadences(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		transactions     []models.Transaction
+		expectedCadence  string
+		expectedNextDate string
+		expectedAnnual   int
+	}{
+		{
+			name:             "monthly",
+			transactions:     recurringTestTransactions("Netflix", []int{1549}, "2026-06-15", "2026-07-15", "2026-08-14", "2026-09-15", "2026-10-15"),
+			expectedCadence:  "Monthly",
+			expectedNextDate: "2026-11-15",
+			expectedAnnual:   1549 * 12,
+		},
+		{
+			name:             "weekly",
+			transactions:     recurringTestTransactions("Dog Walker", []int{4000}, "2026-09-21", "2026-09-28", "2026-10-05", "2026-10-12"),
+			expectedCadence:  "Weekly",
+			expectedNextDate: "2026-10-19",
+			expectedAnnual:   4000 * 52,
+		},
+		{
+			name:             "annual",
+			transactions:     recurringTestTransactions("Costco Membership", []int{6500}, "2024-11-02", "2025-11-01"),
+			expectedCadence:  "Annual",
+			expectedNextDate: "2026-11-01",
+			expectedAnnual:   6500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charges := DetectRecurringCharges(tt.transactions, asOf)
+			if len(charges) != 1 {
+				t.Fatalf("Expected 1 recurring charge, got %d", len(charges))
+			}
+			charge := charges[0]
+			if charge.Cadence.Name != tt.expectedCadence {
+				t.Errorf("Expected cadence %q, got %q", tt.expectedCadence, charge.Cadence.Name)
+			}
+			if nextDate := charge.NextExpectedDate.Format("2006-01-02"); nextDate != tt.expectedNextDate {
+				t.Errorf("Expected next date %v, got %v", tt.expectedNextDate, nextDate)
+			}
+			if charge.AnnualizedCost != tt.expectedAnnual {
+				t.Errorf("Expected annualized cost %d, got %d", tt.expectedAnnual, charge.AnnualizedCost)
+			}
+			if charge.Missed || charge.PriceChanged || len(charge.DuplicateDates) > 0 {
+				t.Errorf("Expected no flags, got %+v", charge)
+			}
+		})
+	}
+}
+
+func TestDetectRecurringCharges_NotRecurring(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		transactions []models.Transaction
+	}{
+		{
+			name:         "irregular dates",
+			transactions: recurringTestTransactions("Hardware Store", []int{2500}, "2026-03-02", "2026-03-20", "2026-05-29", "2026-09-01"),
+		},
+		{
+			name:         "too few occurrences",
+			transactions: recurringTestTransactions("Netflix", []int{1549}, "2026-09-15", "2026-10-15"),
+		},
+		{
+			name:         "cancelled long ago",
+			transactions: recurringTestTransactions("Old Gym", []int{3000}, "2025-01-05", "2025-02-05", "2025-03-05", "2025-04-05"),
+		},
+		{
+			name: "frequent shopping at the same store",
+			transactions: recurringTestTransactions("Coffee Shop", []int{500}, "2026-08-01", "2026-08-04", "2026-08-09", "2026-08-20",
+				"2026-09-01", "2026-09-03", "2026-09-12", "2026-10-01", "2026-10-08"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charges := DetectRecurringCharges(tt.transactions, asOf)
+			if len(charges) != 0 {
+				t.Errorf("Expected no recurring charges, got %+v", charges)
+			}
+		})
+	}
+}
+
+func TestDetectRecurringCharges_SeparatesAmounts(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	transactions := recurringTestTransactions("Amazon", []int{1499}, "2026-07-03", "2026-08-03", "2026-09-03", "2026-10-03")
+	// One-off purchases from the same merchant don't stop the subscription from being found
+	transactions = append(transactions, recurringTestTransactions("Amazon", []int{8723, 4310, 26000}, "2026-07-19", "2026-08-27", "2026-09-30")...)
+
+	charges := DetectRecurringCharges(transactions, asOf)
+	if len(charges) != 1 {
+		t.Fatalf("Expected 1 recurring charge, got %d", len(charges))
+	}
+	if charges[0].AverageAmount != 1499 {
+		t.Errorf("Expected average amount 1499, got %d", charges[0].AverageAmount)
+	}
+}
+
+func TestDetectRecurringCharges_Flags(t *testing.T) {
+	asOf := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	t.Run("price change", func(t *testing.T) {
+		transactions := recurringTestTransactions("Netflix", []int{1549}, "2026-07-15", "2026-08-15", "2026-09-15")
+		transactions = append(transactions, recurringTestTransactions("Netflix", []int{1799}, "2026-10-15")...)
+		charges := DetectRecurringCharges(transactions, asOf)
+		if len(charges) != 1 || !charges[0].PriceChanged {
+			t.Fatalf("Expected a price change, got %+v", charges)
+		}
+		if charges[0].PreviousAmount != 1549 || charges[0].LatestAmount != 1799 {
+			t.Errorf("Expected price to change from 1549 to 1799, got %d to %d", charges[0].PreviousAmount, charges[0].LatestAmount)
+		}
+	})
+
+	t.Run("varying bill isn't a price change", func(t *testing.T) {
+		transactions := recurringTestTransactions("City Utilities", []int{8012, 9233, 7540, 8100}, "2026-07-05", "2026-08-05", "2026-09-05", "2026-10-05")
+		charges := DetectRecurringCharges(transactions, asOf)
+		if len(charges) != 1 || charges[0].PriceChanged {
+			t.Fatalf("Expected a recurring charge without a price change, got %+v", charges)
+		}
+	})
+
+	t.Run("missed", func(t *testing.T) {
+		transactions := recurringTestTransactions("Gym", []int{3000}, "2026-06-01", "2026-07-01", "2026-08-01", "2026-09-01")
+		charges := DetectRecurringCharges(transactions, asOf)
+		if len(charges) != 1 || !charges[0].Missed {
+			t.Fatalf("Expected a missed occurrence, got %+v", charges)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		transactions := recurringTestTransactions("Spotify", []int{1199}, "2026-07-10", "2026-08-10", "2026-09-10", "2026-09-11", "2026-10-10")
+		charges := DetectRecurringCharges(transactions, asOf)
+		if len(charges) != 1 {
+			t.Fatalf("Expected 1 recurring charge, got %d", len(charges))
+		}
+		if len(charges[0].DuplicateDates) != 1 || charges[0].DuplicateDates[0] != "2026-09-11" {
+			t.Errorf("Expected a duplicate on 2026-09-11, got %v", charges[0].DuplicateDates)
+		}
+		if charges[0].AverageAmount != 1199 {
+			t.Errorf("Expected duplicates not to change the average, got %d", charges[0].AverageAmount)
+		}
+	})
+}
+
+func TestGetRecurringCharges(t *testing.T) {
+	detector := &RecurringChargeDetector{TransactionRepository: &MockTransactionRepository{}}
+	charges, err := detector.GetRecurringCharges()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(charges) != 0 {
+		t.Errorf("Expected no recurring charges, got %d", len(charges))
+	}
+}
@@ -95,7 +95,8 @@ type SchwabBrokerageCSVParser struct{}
 
 // Parses CSVs with the header as the 1st row, date in 0th column,
 // action in 1st column, symbol in 2nd column,
-// description in 3rd column, amount in 7th column,
+// description in 3rd column, amount in 7th column. The action is used to
+// classify the investment activity
 func (s SchwabBrokerageCSVParser) Parse(statement string) (transactions []models.Transaction, balances []models.Balance, err error) {
 	// parse the string into a CSV
 	csvReader := csv.NewReader(strings.NewReader(statement))
@@ -116,9 +117,10 @@ func (s SchwabBrokerageCSVParser) Parse(statement string) (transactions []models
 		var amount int
 		amount = utils.DollarStringToCents(record[7])
 		txn := models.Transaction{
-			Date:        isoDate,
-			Description: record[1] + " - " + record[3],
-			Amount:      amount,
+			Date:               isoDate,
+			Description:        record[1] + " - " + record[3],
+			Amount:             amount,
+			InvestmentActivity: ClassifyInvestmentActivity(record[1], amount),
 		}
 		transactions = append(transactions, txn)
 	}
@@ -160,7 +162,8 @@ func (FidelityCreditCardCSVParser) Parse(statement string) (transactions []model
 type FidelityBrokerageCSVParser struct{}
 
 // Parses CSVs with the header as the 2nd row, date in 0th column,
-// description in 1st column, amount in 10th column, and balance in 11th column
+// description (Fidelity's action) in 1st column, amount in 10th column, and
+// balance in 11th column. The action is used to classify the investment activity.
 // Transactions are sorted by newest transaction first, so the balance is the
 // first row after the header
 func (FidelityBrokerageCSVParser) Parse(statement string) (transactions []models.Transaction, balances []models.Balance, err error) {
@@ -193,9 +196,10 @@ func (FidelityBrokerageCSVParser) Parse(statement string) (transactions []models
 		var amount int
 		amount = utils.DollarStringToCents(record[10])
 		txn := models.Transaction{
-			Date:        isoDate,
-			Description: record[1],
-			Amount:      amount,
+			Date:               isoDate,
+			Description:        record[1],
+			Amount:             amount,
+			InvestmentActivity: ClassifyInvestmentActivity(record[1], amount),
 		}
 		transactions = append(transactions, txn)
 
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// TransactionSplitRepositoryInterface specifically for TransactionSplitService
+type TransactionSplitRepositoryInterface interface {
+	GetTransactionByID(id uint) (models.Transaction, error)
+	ReplaceSplits(transactionID uint, splits []models.TransactionSplit) error
+}
+
+// TransactionSplitService splits transactions across multiple categories
+type TransactionSplitService struct {
+	TransactionRepository TransactionSplitRepositoryInterface
+}
+
+// SplitTransaction replaces the splits of a transaction, after checking that there are at least two
+// splits, every split has a category and an amount, and the splits sum to the transaction's amount
+func (tss *TransactionSplitService) SplitTransaction(transactionID uint, splits []models.TransactionSplit) error {
+	transaction, err := tss.TransactionRepository.GetTransactionByID(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.ID == 0 {
+		return fmt.Errorf("transaction %d not found", transactionID)
+	}
+	if len(splits) < 2 {
+		return errors.New("a transaction must be split into at least two parts")
+	}
+
+	total := 0
+	for i, split := range splits {
+		if split.CategoryID == 0 {
+			return fmt.Errorf("split %d needs a category", i+1)
+		}
+		if split.Amount == 0 {
+			return fmt.Errorf("split %d needs an amount", i+1)
+		}
+		total += split.Amount
+	}
+	if total != transaction.Amount {
+		return fmt.Errorf("the splits add up to $%s but the transaction is $%s",
+			utils.CentsToDollarStringHumanized(total), utils.CentsToDollarStringHumanized(transaction.Amount))
+	}
+
+	return tss.TransactionRepository.ReplaceSplits(transactionID, splits)
+}
+
+// RemoveSplits un-splits a transaction, so it's counted in its own category again
+func (tss *TransactionSplitService) RemoveSplits(transactionID uint) error {
+	return tss.TransactionRepository.ReplaceSplits(transactionID, nil)
+}
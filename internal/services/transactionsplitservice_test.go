@@ -0,0 +1,112 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+func (m *MockTransactionRepository) ReplaceSplits(transactionID uint, splits []models.TransactionSplit) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	for i, txn := range m.Txns {
+		if txn.ID == transactionID {
+			m.Txns[i].Splits = splits
+		}
+	}
+	return nil
+}
+
+func newTestTransactionSplitService() (*TransactionSplitService, *MockTransactionRepository) {
+	repository := &MockTransactionRepository{
+		Txns: []models.Transaction{
+			{Model: gorm.Model{ID: 1}, Description: "COSTCO WHSE #0001", Amount: 10000, CategoryID: 6},
+		},
+	}
+	return &TransactionSplitService{TransactionRepository: repository}, repository
+}
+
+func TestSplitTransaction(t *testing.T) {
+	service, repository := newTestTransactionSplitService()
+
+	err := service.SplitTransaction(1, []models.TransactionSplit{
+		{CategoryID: 6, Amount: 7000, Note: "Groceries"},
+		{CategoryID: 3, Amount: 3000, Note: "Paper towels"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(repository.Txns[0].Splits) != 2 {
+		t.Errorf("Expected 2 splits to be saved, got %d", len(repository.Txns[0].Splits))
+	}
+}
+
+func TestSplitTransaction_Invalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		transactionID uint
+		splits        []models.TransactionSplit
+		expectedError string
+	}{
+		{
+			name:          "splits don't sum to the transaction",
+			transactionID: 1,
+			splits:        []models.TransactionSplit{{CategoryID: 6, Amount: 7000}, {CategoryID: 3, Amount: 2000}},
+			expectedError: "the splits add up to $90.00 but the transaction is $100.00",
+		},
+		{
+			name:          "only one split",
+			transactionID: 1,
+			splits:        []models.TransactionSplit{{CategoryID: 6, Amount: 10000}},
+			expectedError: "at least two parts",
+		},
+		{
+			name:          "split without a category",
+			transactionID: 1,
+			splits:        []models.TransactionSplit{{CategoryID: 6, Amount: 7000}, {Amount: 3000}},
+			expectedError: "split 2 needs a category",
+		},
+		{
+			name:          "split without an amount",
+			transactionID: 1,
+			splits:        []models.TransactionSplit{{CategoryID: 6, Amount: 10000}, {CategoryID: 3}},
+			expectedError: "split 2 needs an amount",
+		},
+		{
+			name:          "missing transaction",
+			transactionID: 2,
+			splits:        []models.TransactionSplit{{CategoryID: 6, Amount: 7000}, {CategoryID: 3, Amount: 3000}},
+			expectedError: "transaction 2 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repository := newTestTransactionSplitService()
+
+			err := service.SplitTransaction(tt.transactionID, tt.splits)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+			}
+			if len(repository.Txns[0].Splits) != 0 {
+				t.Errorf("Expected no splits to be saved")
+			}
+		})
+	}
+}
+
+func TestRemoveSplits(t *testing.T) {
+	service, repository := newTestTransactionSplitService()
+	repository.Txns[0].Splits = []models.TransactionSplit{{CategoryID: 6, Amount: 7000}, {CategoryID: 3, Amount: 3000}}
+
+	err := service.RemoveSplits(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(repository.Txns[0].Splits) != 0 {
+		t.Errorf("Expected the splits to be removed, got %d", len(repository.Txns[0].Splits))
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+// DefaultTransferMaxDaysApart is how far apart the two sides of a transfer can be, since a payment usually
+// posts to the receiving account a day or two after it leaves the sending account
+const DefaultTransferMaxDaysApart = 4
+
+// TransferPairRepositoryInterface specifically for TransferMatcher
+type TransferPairRepositoryInterface interface {
+	GetTransactionsForTransferMatching(importSubmissionID uint, maxDaysApart int) ([]models.Transaction, error)
+	CreateTransferPair(pair models.TransferPair) (uint, error)
+	ConfirmTransferPair(id uint, transferCategoryID uint) error
+}
+
+// TransferCategoryRepositoryInterface specifically for TransferMatcher
+type TransferCategoryRepositoryInterface interface {
+	GetCategoriesByKind(kind string) ([]models.Category, error)
+}
+
+// TransferMatcher finds transactions in different accounts that are two sides of the same transfer, like paying
+// a credit card from a checking account, and links them so neither side is counted as income or spending
+type TransferMatcher struct {
+	CategoryRepository     TransferCategoryRepositoryInterface
+	TransferPairRepository TransferPairRepositoryInterface
+	MaxDaysApart           int // Defaults to DefaultTransferMaxDaysApart
+}
+
+// transferDescriptionWords are words in a transaction's description that say it's a transfer or a payment
+// rather than a purchase
+var transferDescriptionWords = []string{"transfer", "xfer", "payment", "autopay", "pymt"}
+
+// transferWording returns "to" if a transaction's description says the money was sent to another account, like
+// "Online transfer to savings", "from" if it says the money came from one, and "" if it says neither
+func transferWording(transaction models.Transaction) string {
+	description := " " + strings.ToLower(transaction.Description) + " "
+	for _, word := range transferDescriptionWords {
+		if strings.Contains(description, word+" to ") {
+			return "to"
+		}
+		if strings.Contains(description, word+" from ") {
+			return "from"
+		}
+	}
+	return ""
+}
+
+// MatchTransfers suggests every pair of unpaired transactions across all accounts that looks like a transfer,
+// returning the number of pairs suggested
+func (tm *TransferMatcher) MatchTransfers() (matched int, err error) {
+	return tm.MatchImportedTransfers(0)
+}
+
+// MatchImportedTransfers suggests pairs of unpaired transactions that look like a transfer, where at least one of
+// the transactions came from the import submission, returning the number of pairs suggested. If
+// importSubmissionID is 0, transactions from every submission are matched. The transactions keep their
+// categories until the pair is confirmed.
+func (tm *TransferMatcher) MatchImportedTransfers(importSubmissionID uint) (matched int, err error) {
+	maxDaysApart := tm.MaxDaysApart
+	if maxDaysApart == 0 {
+		maxDaysApart = DefaultTransferMaxDaysApart
+	}
+	transactions, err := tm.TransferPairRepository.GetTransactionsForTransferMatching(importSubmissionID, maxDaysApart)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, pair := range FindTransferPairs(transactions, maxDaysApart, importSubmissionID) {
+		_, err = tm.TransferPairRepository.CreateTransferPair(pair)
+		if err != nil {
+			return matched, err
+		}
+		matched++
+	}
+	return matched, nil
+}
+
+// ConfirmTransferPair confirms a suggested pair and moves both of its transactions into the first transfer
+// category
+func (tm *TransferMatcher) ConfirmTransferPair(id uint) error {
+	transferCategories, err := tm.CategoryRepository.GetCategoriesByKind(models.CategoryKindTransfer)
+	if err != nil {
+		return err
+	}
+	if len(transferCategories) == 0 {
+		return errors.New("there's no transfer category to put transfers in")
+	}
+	return tm.TransferPairRepository.ConfirmTransferPair(id, transferCategories[0].ID)
+}
+
+// FindTransferPairs pairs up transactions in different accounts with the same amount that are at most
+// maxDaysApart days apart, where at least one of them is in a transfer category or described as a transfer or
+// payment. Most statement parsers store amounts without a sign, so an equal amount alone isn't enough. The
+// transaction the money left from goes first, and pairs where that can't be told are skipped rather than
+// guessed. If importSubmissionID isn't 0, at least one of the transactions has to come from that submission.
+// Each transaction is used at most once, with the closest dates paired first.
+func FindTransferPairs(transactions []models.Transaction, maxDaysApart int, importSubmissionID uint) []models.TransferPair {
+	// first is the side the money left from
+	type candidate struct {
+		first, second models.Transaction
+		daysApart     int
+	}
+
+	// Group by amount so only transactions that could match are compared
+	transactionsByAmount := map[int][]models.Transaction{}
+	for _, transaction := range transactions {
+		if transaction.Amount == 0 {
+			continue
+		}
+		amount := transaction.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		transactionsByAmount[amount] = append(transactionsByAmount[amount], transaction)
+	}
+
+	candidates := []candidate{}
+	for _, group := range transactionsByAmount {
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				first, second := group[i], group[j]
+				if first.AccountID == second.AccountID {
+					continue
+				}
+				if importSubmissionID != 0 && !fromImportSubmission(first, importSubmissionID) && !fromImportSubmission(second, importSubmissionID) {
+					continue
+				}
+				// Two negative amounts are money leaving both accounts, which can't be a transfer between them
+				if first.Amount < 0 && second.Amount < 0 {
+					continue
+				}
+				if !describedAsTransfer(first) && !describedAsTransfer(second) {
+					continue
+				}
+				daysApart, ok := transferDaysApart(first.Date, second.Date)
+				if !ok || daysApart > maxDaysApart {
+					continue
+				}
+				firstSent, known := transferSentFrom(first, second)
+				if !known {
+					continue
+				}
+				if !firstSent {
+					first, second = second, first
+				}
+				candidates = append(candidates, candidate{first: first, second: second, daysApart: daysApart})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].daysApart != candidates[j].daysApart {
+			return candidates[i].daysApart < candidates[j].daysApart
+		}
+		if candidates[i].first.ID != candidates[j].first.ID {
+			return candidates[i].first.ID < candidates[j].first.ID
+		}
+		return candidates[i].second.ID < candidates[j].second.ID
+	})
+
+	paired := map[uint]bool{}
+	pairs := []models.TransferPair{}
+	for _, c := range candidates {
+		if paired[c.first.ID] || paired[c.second.ID] {
+			continue
+		}
+		paired[c.first.ID] = true
+		paired[c.second.ID] = true
+		pairs = append(pairs, models.TransferPair{
+			TransactionID:        c.first.ID,
+			MatchedTransactionID: c.second.ID,
+			Status:               models.TransferPairSuggested,
+		})
+	}
+	return pairs
+}
+
+func fromImportSubmission(transaction models.Transaction, importSubmissionID uint) bool {
+	return transaction.ImportSubmissionID != nil && *transaction.ImportSubmissionID == importSubmissionID
+}
+
+func describedAsTransfer(transaction models.Transaction) bool {
+	if transaction.Category.Kind == models.CategoryKindTransfer {
+		return true
+	}
+	description := strings.ToLower(transaction.Description)
+	for _, word := range transferDescriptionWords {
+		if strings.Contains(description, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// transferSentFrom returns whether the money in a transfer left from the first transaction's account, and
+// whether that can be told at all: a negative amount is money leaving, a payment to a liability like a credit
+// card comes from an asset, a description like "transfer to savings" is the sending side and one like "transfer
+// from checking" the receiving side, and otherwise the earlier transaction is the side the money left from
+func transferSentFrom(first models.Transaction, second models.Transaction) (firstSent bool, known bool) {
+	if (first.Amount < 0) != (second.Amount < 0) {
+		return first.Amount < 0, true
+	}
+	firstLedgerType := first.Account.AccountType.LedgerType
+	secondLedgerType := second.Account.AccountType.LedgerType
+	if firstLedgerType == models.Asset && secondLedgerType == models.Liability {
+		return true, true
+	}
+	if firstLedgerType == models.Liability && secondLedgerType == models.Asset {
+		return false, true
+	}
+	firstWording, secondWording := transferWording(first), transferWording(second)
+	sentByWording := firstWording == "to" || secondWording == "from"
+	receivedByWording := firstWording == "from" || secondWording == "to"
+	if sentByWording != receivedByWording {
+		return sentByWording, true
+	}
+	if first.Date != second.Date {
+		return first.Date < second.Date, true
+	}
+	return false, false
+}
+
+// transferDaysApart returns the number of days between two YYYY-MM-DD dates
+func transferDaysApart(firstDate string, secondDate string) (int, bool) {
+	first, err := time.Parse("2006-01-02", firstDate)
+	if err != nil {
+		return 0, false
+	}
+	second, err := time.Parse("2006-01-02", secondDate)
+	if err != nil {
+		return 0, false
+	}
+	days := int(second.Sub(first).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	return days, true
+}
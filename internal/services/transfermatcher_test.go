@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexdglover/sage/internal/models"
+	"gorm.io/gorm"
+)
+
+type MockTransferPairRepository struct {
+	Txns      []models.Transaction
+	Created   []models.TransferPair
+	Pairs     []models.TransferPair
+	Confirmed map[uint]uint
+	Err       error
+}
+
+func (m *MockTransferPairRepository) GetTransactionsForTransferMatching(importSubmissionID uint, maxDaysApart int) ([]models.Transaction, error) {
+	return m.Txns, m.Err
+}
+
+func (m *MockTransferPairRepository) CreateTransferPair(pair models.TransferPair) (uint, error) {
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	m.Created = append(m.Created, pair)
+	return uint(len(m.Created)), nil
+}
+
+func (m *MockTransferPairRepository) ConfirmTransferPair(id uint, transferCategoryID uint) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Confirmed == nil {
+		m.Confirmed = map[uint]uint{}
+	}
+	m.Confirmed[id] = transferCategoryID
+	return nil
+}
+
+func (m *MockCategoryRepository) GetCategoriesByKind(kind string) ([]models.Category, error) {
+	if m.Err != nil || m.Category.ID == 0 {
+		return nil, m.Err
+	}
+	return []models.Category{m.Category}, nil
+}
+
+// transferTestTransaction returns a transaction in an asset account, described like a transfer
+func transferTestTransaction(id uint, accountID uint, date string, amount int) models.Transaction {
+	return models.Transaction{
+		Model:       gorm.Model{ID: id},
+		AccountID:   accountID,
+		Account:     models.Account{AccountType: models.AccountType{LedgerType: models.Asset}},
+		Date:        date,
+		Description: "Online transfer",
+		Amount:      amount,
+		CategoryID:  6,
+	}
+}
+
+// withLedgerType puts a test transaction in an account of the ledger type
+func withLedgerType(transaction models.Transaction, ledgerType string) models.Transaction {
+	transaction.Account.AccountType.LedgerType = ledgerType
+	return transaction
+}
+
+// withDescription replaces a test transaction's description and category
+func withDescription(transaction models.Transaction, description string, categoryKind string) models.Transaction {
+	transaction.Description = description
+	transaction.Category.Kind = categoryKind
+	return transaction
+}
+
+func withImportSubmission(transaction models.Transaction, importSubmissionID uint) models.Transaction {
+	transaction.ImportSubmissionID = &importSubmissionID
+	return transaction
+}
+
+func TestFindTransferPairs(t *testing.T) {
+	tests := []struct {
+		name          string
+		transactions  []models.Transaction
+		expectedPairs [][2]uint
+	}{
+		{
+			name: "same amount in different accounts a few days apart",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-03", 50000),
+			},
+			expectedPairs: [][2]uint{{1, 2}},
+		},
+		{
+			name: "signed amounts in opposite directions",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", -50000),
+				transferTestTransaction(2, 2, "2026-10-01", 50000),
+			},
+			expectedPairs: [][2]uint{{1, 2}},
+		},
+		{
+			name: "both amounts leaving their accounts",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", -50000),
+				transferTestTransaction(2, 2, "2026-10-01", -50000),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "same account",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 1, "2026-10-01", 50000),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "too far apart",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-08", 50000),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "different amounts",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-01", 50001),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "closest dates are paired first and each transaction is used once",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-04", 50000),
+				transferTestTransaction(3, 3, "2026-10-02", 50000),
+			},
+			expectedPairs: [][2]uint{{1, 3}},
+		},
+		{
+			name: "earlier transaction goes first",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-03", 50000),
+				transferTestTransaction(2, 2, "2026-10-01", 50000),
+			},
+			expectedPairs: [][2]uint{{2, 1}},
+		},
+		{
+			name: "negative amount goes first",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-03", -50000),
+			},
+			expectedPairs: [][2]uint{{2, 1}},
+		},
+		{
+			name: "unrelated purchases on two credit cards",
+			transactions: []models.Transaction{
+				withLedgerType(withDescription(transferTestTransaction(1, 1, "2026-10-01", 5000), "Coffee shop", models.CategoryKindExpense), models.Liability),
+				withLedgerType(withDescription(transferTestTransaction(2, 2, "2026-10-02", 5000), "Bookstore", models.CategoryKindExpense), models.Liability),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "credit card paid from checking, with the checking side first",
+			transactions: []models.Transaction{
+				withLedgerType(withDescription(transferTestTransaction(1, 1, "2026-10-01", 50000), "Payment - thank you", models.CategoryKindExpense), models.Liability),
+				withDescription(transferTestTransaction(2, 2, "2026-10-02", 50000), "Chase card", models.CategoryKindExpense),
+			},
+			expectedPairs: [][2]uint{{2, 1}},
+		},
+		{
+			name: "card purchase and checking debit with the same amount",
+			transactions: []models.Transaction{
+				withLedgerType(withDescription(transferTestTransaction(1, 1, "2026-10-01", 5000), "Coffee shop", models.CategoryKindExpense), models.Liability),
+				withDescription(transferTestTransaction(2, 2, "2026-10-01", 5000), "Grocery store", models.CategoryKindExpense),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "signed amounts with nothing saying it's a transfer",
+			transactions: []models.Transaction{
+				withDescription(transferTestTransaction(1, 1, "2026-10-01", -5000), "Coffee shop", models.CategoryKindExpense),
+				withDescription(transferTestTransaction(2, 2, "2026-10-01", 5000), "Refund", models.CategoryKindIncome),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "same day with no way to tell the direction",
+			transactions: []models.Transaction{
+				transferTestTransaction(1, 1, "2026-10-01", 50000),
+				transferTestTransaction(2, 2, "2026-10-01", 50000),
+			},
+			expectedPairs: [][2]uint{},
+		},
+		{
+			name: "same day with the direction in the descriptions",
+			transactions: []models.Transaction{
+				withDescription(transferTestTransaction(1, 1, "2026-10-01", 50000), "Online transfer from checking", models.CategoryKindExpense),
+				withDescription(transferTestTransaction(2, 2, "2026-10-01", 50000), "Online transfer to savings", models.CategoryKindExpense),
+			},
+			expectedPairs: [][2]uint{{2, 1}},
+		},
+		{
+			name: "one side in a transfer category",
+			transactions: []models.Transaction{
+				withDescription(transferTestTransaction(1, 1, "2026-10-01", 50000), "Savings", models.CategoryKindTransfer),
+				withDescription(transferTestTransaction(2, 2, "2026-10-02", 50000), "Deposit", models.CategoryKindExpense),
+			},
+			expectedPairs: [][2]uint{{1, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := FindTransferPairs(tt.transactions, DefaultTransferMaxDaysApart, 0)
+			if len(pairs) != len(tt.expectedPairs) {
+				t.Fatalf("Expected %d pairs, got %d: %+v", len(tt.expectedPairs), len(pairs), pairs)
+			}
+			for i, pair := range pairs {
+				if pair.TransactionID != tt.expectedPairs[i][0] || pair.MatchedTransactionID != tt.expectedPairs[i][1] {
+					t.Errorf("Expected pair %v, got %d and %d", tt.expectedPairs[i], pair.TransactionID, pair.MatchedTransactionID)
+				}
+				if pair.Status != models.TransferPairSuggested {
+					t.Errorf("Expected pair to be suggested, got %q", pair.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestFindTransferPairs_ImportSubmission(t *testing.T) {
+	transactions := []models.Transaction{
+		// Both sides were already imported, so they aren't matched again
+		transferTestTransaction(1, 1, "2026-10-01", 30000),
+		transferTestTransaction(2, 2, "2026-10-01", 30000),
+		transferTestTransaction(3, 1, "2026-10-02", 50000),
+		withImportSubmission(transferTestTransaction(4, 2, "2026-10-03", 50000), 9),
+	}
+
+	pairs := FindTransferPairs(transactions, DefaultTransferMaxDaysApart, 9)
+	if len(pairs) != 1 || pairs[0].TransactionID != 3 || pairs[0].MatchedTransactionID != 4 {
+		t.Errorf("Expected only the pair with the imported transaction, got %+v", pairs)
+	}
+}
+
+func TestMatchTransfers(t *testing.T) {
+	repository := &MockTransferPairRepository{
+		Txns: []models.Transaction{
+			transferTestTransaction(1, 1, "2026-10-01", 50000),
+			transferTestTransaction(2, 2, "2026-10-02", 50000),
+			transferTestTransaction(3, 1, "2026-10-02", 1299),
+		},
+	}
+	matcher := &TransferMatcher{
+		CategoryRepository:     &MockCategoryRepository{},
+		TransferPairRepository: repository,
+	}
+
+	matched, err := matcher.MatchTransfers()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if matched != 1 || len(repository.Created) != 1 {
+		t.Errorf("Expected 1 pair to be created, got %d", len(repository.Created))
+	}
+}
+
+func TestMatchTransfers_RepositoryError(t *testing.T) {
+	matcher := &TransferMatcher{
+		CategoryRepository:     &MockCategoryRepository{},
+		TransferPairRepository: &MockTransferPairRepository{Err: errors.New("db error")},
+	}
+	if _, err := matcher.MatchTransfers(); err == nil {
+		t.Error("Expected the repository error to be returned")
+	}
+}
+
+func TestConfirmTransferPair(t *testing.T) {
+	repository := &MockTransferPairRepository{}
+	matcher := &TransferMatcher{
+		CategoryRepository:     &MockCategoryRepository{Category: models.Category{Model: gorm.Model{ID: 2}, Kind: models.CategoryKindTransfer}},
+		TransferPairRepository: repository,
+	}
+	if err := matcher.ConfirmTransferPair(7); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if repository.Confirmed[7] != 2 {
+		t.Errorf("Expected pair 7 to be confirmed into category 2, got %v", repository.Confirmed)
+	}
+}
+
+func TestConfirmTransferPair_NoTransferCategory(t *testing.T) {
+	matcher := &TransferMatcher{
+		CategoryRepository:     &MockCategoryRepository{},
+		TransferPairRepository: &MockTransferPairRepository{},
+	}
+	if err := matcher.ConfirmTransferPair(7); err == nil {
+		t.Error("Expected an error when there's no transfer category")
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+	"github.com/alexdglover/sage/internal/utils"
+)
+
+// ValuationRuleRepositoryInterface specifically for ValuationService
+type ValuationRuleRepositoryInterface interface {
+	GetAllValuationRules() ([]models.ValuationRule, error)
+}
+
+// ValuationBalanceRepositoryInterface specifically for ValuationService
+type ValuationBalanceRepositoryInterface interface {
+	GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance
+	ReplaceEstimatedBalances(accountID uint, balances []models.Balance) error
+}
+
+// ValuationService estimates the balances of accounts that are only valued by hand, like houses and cars, from
+// their valuation rules
+type ValuationService struct {
+	ValuationRuleRepository ValuationRuleRepositoryInterface
+	BalanceRepository       ValuationBalanceRepositoryInterface
+}
+
+// ApplyValuationRule replaces the account's estimated balances with new ones from its valuation rule, on the
+// rule's start date and at the end of every month after that until today. Months that already have a reported or
+// derived balance are skipped, and the estimates after one carry on from it rather than from the rule's start
+// value, so a new appraisal moves every later estimate. It returns the number of balances estimated.
+func (vs *ValuationService) ApplyValuationRule(rule models.ValuationRule, today time.Time) (int, error) {
+	actualMonths := map[string]bool{}
+	var actualBalances []models.Balance
+	for _, balance := range vs.BalanceRepository.GetBalancesForAccount(context.TODO(), rule.AccountID) {
+		if !balance.Estimated && len(balance.EffectiveDate) >= 7 {
+			actualMonths[balance.EffectiveDate[:7]] = true
+			actualBalances = append(actualBalances, balance)
+		}
+	}
+	sort.Slice(actualBalances, func(i, j int) bool {
+		return actualBalances[i].EffectiveDate < actualBalances[j].EffectiveDate
+	})
+
+	dates, err := valuationDates(rule, today)
+	if err != nil {
+		return 0, err
+	}
+	var estimated []models.Balance
+	for _, date := range dates {
+		if actualMonths[date[:7]] {
+			continue
+		}
+		amount, err := estimateValue(rebaseValuationRule(rule, actualBalances, date), date)
+		if err != nil {
+			return 0, err
+		}
+		estimated = append(estimated, models.Balance{
+			EffectiveDate: date,
+			Amount:        amount,
+			AccountID:     rule.AccountID,
+			Estimated:     true,
+		})
+	}
+
+	err = vs.BalanceRepository.ReplaceEstimatedBalances(rule.AccountID, estimated)
+	if err != nil {
+		return 0, err
+	}
+	return len(estimated), nil
+}
+
+// rebaseValuationRule starts the rule from the latest of the sorted balances on or before the date, if there's
+// one since the rule started, so the estimate follows on from what the account was actually worth
+func rebaseValuationRule(rule models.ValuationRule, sortedBalances []models.Balance, date string) models.ValuationRule {
+	for i := len(sortedBalances) - 1; i >= 0; i-- {
+		balance := sortedBalances[i]
+		if balance.EffectiveDate > date {
+			continue
+		}
+		if balance.EffectiveDate >= rule.StartDate {
+			rule.StartDate = balance.EffectiveDate
+			rule.StartValue = balance.Amount
+		}
+		break
+	}
+	return rule
+}
+
+// ApplyValuationRuleForAccount re-estimates the account's balances if it has a valuation rule, e.g. after one of
+// its balances changed
+func (vs *ValuationService) ApplyValuationRuleForAccount(accountID uint, today time.Time) error {
+	rules, err := vs.ValuationRuleRepository.GetAllValuationRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.AccountID == accountID {
+			_, err = vs.ApplyValuationRule(rule, today)
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyAllValuationRules re-estimates the balances of every account with a valuation rule, so they carry on
+// through the current month. A rule that can't be applied is logged and skipped, so it doesn't hold up the others.
+func (vs *ValuationService) ApplyAllValuationRules(today time.Time) error {
+	rules, err := vs.ValuationRuleRepository.GetAllValuationRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		_, err = vs.ApplyValuationRule(rule, today)
+		if err != nil {
+			fmt.Printf("Unable to apply the valuation rule for account %v: %v\n", rule.AccountID, err)
+		}
+	}
+	return nil
+}
+
+// valuationDates are the start date and the end of every month after it, oldest first. Rules that end, like
+// straight-line depreciation and interpolating between appraisals, stop at their end date. Nothing is estimated
+// after today. It returns an error if the start or end date isn't a real YYYY-MM-DD date.
+func valuationDates(rule models.ValuationRule, today time.Time) ([]string, error) {
+	start, err := time.Parse("2006-01-02", rule.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	last := utils.TimeToISO8601DateString(today)
+	ends := rule.Method == models.ValuationMethodStraightLine || rule.Method == models.ValuationMethodInterpolation
+	if ends {
+		if _, err := time.Parse("2006-01-02", rule.EndDate); err != nil {
+			return nil, err
+		}
+		if rule.EndDate < last {
+			last = rule.EndDate
+		}
+	}
+	if rule.StartDate > last {
+		return nil, nil
+	}
+
+	dates := []string{rule.StartDate}
+	monthStart := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for {
+		monthEnd := utils.TimeToISO8601DateString(monthStart.AddDate(0, 1, -1))
+		if monthEnd > last {
+			break
+		}
+		if monthEnd > rule.StartDate {
+			dates = append(dates, monthEnd)
+		}
+		monthStart = monthStart.AddDate(0, 1, 0)
+	}
+	// Finish on the end date itself, so the value reaches the second appraisal or the salvage value
+	if ends && last == rule.EndDate && dates[len(dates)-1] != last {
+		dates = append(dates, last)
+	}
+	return dates, nil
+}
+
+// estimateValue is what the rule says the account is worth on a date, to the cent. It returns an error if one of
+// the dates it needs isn't a real YYYY-MM-DD date.
+func estimateValue(rule models.ValuationRule, date string) (int, error) {
+	start, err := time.Parse("2006-01-02", rule.StartDate)
+	if err != nil {
+		return 0, err
+	}
+	on, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, err
+	}
+	days := on.Sub(start).Hours() / 24
+	years := days / 365.25
+	switch rule.Method {
+	case models.ValuationMethodAppreciation:
+		return int(math.Round(float64(rule.StartValue) * math.Pow(1+rule.AnnualRate/100, years))), nil
+	case models.ValuationMethodDecliningBalance:
+		value := int(math.Round(float64(rule.StartValue) * math.Pow(1-rule.AnnualRate/100, years)))
+		// A value that's already below the salvage value, like an appraisal, doesn't go back up to it
+		return max(value, min(rule.EndValue, rule.StartValue)), nil
+	case models.ValuationMethodStraightLine, models.ValuationMethodInterpolation:
+		end, err := time.Parse("2006-01-02", rule.EndDate)
+		if err != nil {
+			return 0, err
+		}
+		totalDays := end.Sub(start).Hours() / 24
+		if totalDays <= 0 || date >= rule.EndDate {
+			return rule.EndValue, nil
+		}
+		return rule.StartValue + int(math.Round(float64(rule.EndValue-rule.StartValue)*days/totalDays)), nil
+	default:
+		return rule.StartValue, nil
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexdglover/sage/internal/models"
+)
+
+type MockValuationRuleRepository struct {
+	Rules []models.ValuationRule
+}
+
+func (m *MockValuationRuleRepository) GetAllValuationRules() ([]models.ValuationRule, error) {
+	return m.Rules, nil
+}
+
+type MockValuationBalanceRepository struct {
+	BalancesByAccount map[uint][]models.Balance
+	Estimated         map[uint][]models.Balance
+}
+
+func (m *MockValuationBalanceRepository) GetBalancesForAccount(ctx context.Context, accountID uint) []models.Balance {
+	return m.BalancesByAccount[accountID]
+}
+
+func (m *MockValuationBalanceRepository) ReplaceEstimatedBalances(accountID uint, balances []models.Balance) error {
+	if m.Estimated == nil {
+		m.Estimated = map[uint][]models.Balance{}
+	}
+	m.Estimated[accountID] = balances
+	return nil
+}
+
+func TestEstimateValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     models.ValuationRule
+		date     string
+		expected int
+	}{
+		{
+			name:     "appreciation after a year",
+			rule:     models.ValuationRule{Method: models.ValuationMethodAppreciation, StartDate: "2025-01-01", StartValue: 40000000, AnnualRate: 4},
+			date:     "2026-01-01",
+			expected: 41598883,
+		},
+		{
+			name:     "appreciation on the start date",
+			rule:     models.ValuationRule{Method: models.ValuationMethodAppreciation, StartDate: "2025-01-01", StartValue: 40000000, AnnualRate: 4},
+			date:     "2025-01-01",
+			expected: 40000000,
+		},
+		{
+			name:     "straight-line partway through the useful life",
+			rule:     models.ValuationRule{Method: models.ValuationMethodStraightLine, StartDate: "2024-01-01", StartValue: 3000000, EndDate: "2026-01-01", EndValue: 1000000},
+			date:     "2025-01-01",
+			expected: 1998632,
+		},
+		{
+			name:     "straight-line after the useful life",
+			rule:     models.ValuationRule{Method: models.ValuationMethodStraightLine, StartDate: "2024-01-01", StartValue: 3000000, EndDate: "2026-01-01", EndValue: 1000000},
+			date:     "2027-01-01",
+			expected: 1000000,
+		},
+		{
+			name:     "declining balance after two years",
+			rule:     models.ValuationRule{Method: models.ValuationMethodDecliningBalance, StartDate: "2024-01-01", StartValue: 3000000, AnnualRate: 20},
+			date:     "2026-01-01",
+			expected: 1919414,
+		},
+		{
+			name:     "declining balance stops at the salvage value",
+			rule:     models.ValuationRule{Method: models.ValuationMethodDecliningBalance, StartDate: "2016-01-01", StartValue: 3000000, AnnualRate: 20, EndValue: 500000},
+			date:     "2026-01-01",
+			expected: 500000,
+		},
+		{
+			name:     "between two appraisals",
+			rule:     models.ValuationRule{Method: models.ValuationMethodInterpolation, StartDate: "2026-01-01", StartValue: 50000000, EndDate: "2026-01-11", EndValue: 51000000},
+			date:     "2026-01-05",
+			expected: 50400000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := estimateValue(tt.rule, tt.date)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyValuationRule(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	balanceRepository := &MockValuationBalanceRepository{BalancesByAccount: map[uint][]models.Balance{
+		1: {
+			// Estimated before, so it doesn't count
+			{EffectiveDate: "2026-08-31", Amount: 1, Estimated: true},
+			// Reported partway through June, so June isn't estimated
+			{EffectiveDate: "2026-06-15", Amount: 41000000},
+		},
+	}}
+	service := &ValuationService{BalanceRepository: balanceRepository}
+	rule := models.ValuationRule{AccountID: 1, Method: models.ValuationMethodAppreciation, StartDate: "2026-04-10", StartValue: 40000000, AnnualRate: 5}
+
+	estimated, err := service.ApplyValuationRule(rule, today)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedDates := []string{"2026-04-10", "2026-04-30", "2026-05-31", "2026-07-31", "2026-08-31", "2026-09-30"}
+	balances := balanceRepository.Estimated[1]
+	if estimated != len(expectedDates) || len(balances) != len(expectedDates) {
+		t.Fatalf("expected %d estimated balances, got %d: %v", len(expectedDates), estimated, balances)
+	}
+	for i, balance := range balances {
+		if balance.EffectiveDate != expectedDates[i] {
+			t.Errorf("expected balance %d to be on %s, got %s", i, expectedDates[i], balance.EffectiveDate)
+		}
+		if !balance.Estimated || balance.AccountID != 1 {
+			t.Errorf("expected an estimated balance for account 1, got %+v", balance)
+		}
+		if i > 0 && balance.Amount <= balances[i-1].Amount {
+			t.Errorf("expected an appreciating value, got %d after %d", balance.Amount, balances[i-1].Amount)
+		}
+	}
+	// Estimates after the reported balance carry on from it, rather than from the rule's start value
+	rebased := models.ValuationRule{Method: models.ValuationMethodAppreciation, StartDate: "2026-06-15", StartValue: 41000000, AnnualRate: 5}
+	if expected, _ := estimateValue(rebased, "2026-07-31"); balances[3].Amount != expected {
+		t.Errorf("expected July to be estimated from June's balance as %d, got %d", expected, balances[3].Amount)
+	}
+	if expected, _ := estimateValue(rule, "2026-05-31"); balances[2].Amount != expected {
+		t.Errorf("expected May to be estimated from the rule's start value, got %d", balances[2].Amount)
+	}
+}
+
+func TestApplyValuationRule_InvalidDates(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rule models.ValuationRule
+	}{
+		{"start date", models.ValuationRule{AccountID: 1, Method: models.ValuationMethodAppreciation, StartDate: "2026-02-30", StartValue: 100000, AnnualRate: 3}},
+		{"end date", models.ValuationRule{AccountID: 1, Method: models.ValuationMethodStraightLine, StartDate: "2026-01-01", StartValue: 100000, EndDate: "2026-06-31"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ValuationService{BalanceRepository: &MockValuationBalanceRepository{}}
+			if _, err := service.ApplyValuationRule(tt.rule, today); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestApplyAllValuationRules_SkipsInvalidRules(t *testing.T) {
+	balanceRepository := &MockValuationBalanceRepository{}
+	service := &ValuationService{
+		ValuationRuleRepository: &MockValuationRuleRepository{Rules: []models.ValuationRule{
+			{AccountID: 1, Method: models.ValuationMethodAppreciation, StartDate: "2026-02-30", StartValue: 100000, AnnualRate: 3},
+			{AccountID: 2, Method: models.ValuationMethodAppreciation, StartDate: "2026-09-01", StartValue: 100000, AnnualRate: 3},
+		}},
+		BalanceRepository: balanceRepository,
+	}
+
+	err := service.ApplyAllValuationRules(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(balanceRepository.Estimated[2]) != 2 {
+		t.Errorf("expected 2 estimated balances for the valid rule, got %v", balanceRepository.Estimated[2])
+	}
+}
+
+func TestRebaseValuationRule(t *testing.T) {
+	rule := models.ValuationRule{Method: models.ValuationMethodStraightLine, StartDate: "2026-01-01", StartValue: 3000000, EndDate: "2028-01-01", EndValue: 1000000}
+	balances := []models.Balance{
+		// Before the rule started, so the rule's start value is used instead
+		{EffectiveDate: "2025-06-30", Amount: 3500000},
+		{EffectiveDate: "2026-06-30", Amount: 2000000},
+		{EffectiveDate: "2027-06-30", Amount: 1500000},
+	}
+
+	tests := []struct {
+		date          string
+		expectedStart string
+		expectedValue int
+	}{
+		{"2026-03-31", "2026-01-01", 3000000},
+		{"2026-06-30", "2026-06-30", 2000000},
+		{"2026-12-31", "2026-06-30", 2000000},
+		{"2027-07-31", "2027-06-30", 1500000},
+	}
+	for _, tt := range tests {
+		rebased := rebaseValuationRule(rule, balances, tt.date)
+		if rebased.StartDate != tt.expectedStart || rebased.StartValue != tt.expectedValue {
+			t.Errorf("%s: expected to start from %d on %s, got %d on %s", tt.date, tt.expectedValue, tt.expectedStart, rebased.StartValue, rebased.StartDate)
+		}
+		if rebased.EndDate != rule.EndDate || rebased.EndValue != rule.EndValue {
+			t.Errorf("%s: expected the rule to keep its end, got %+v", tt.date, rebased)
+		}
+	}
+}
+
+func TestValuationDates(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		rule     models.ValuationRule
+		expected []string
+	}{
+		{
+			name:     "ends on the second appraisal",
+			rule:     models.ValuationRule{Method: models.ValuationMethodInterpolation, StartDate: "2026-01-31", EndDate: "2026-03-15"},
+			expected: []string{"2026-01-31", "2026-02-28", "2026-03-15"},
+		},
+		{
+			name:     "appreciation carries on until today",
+			rule:     models.ValuationRule{Method: models.ValuationMethodAppreciation, StartDate: "2026-08-01", EndDate: "2026-08-15"},
+			expected: []string{"2026-08-01", "2026-08-31", "2026-09-30"},
+		},
+		{
+			name:     "starts in the future",
+			rule:     models.ValuationRule{Method: models.ValuationMethodAppreciation, StartDate: "2027-01-01"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := valuationDates(tt.rule, today)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestApplyValuationRuleForAccount(t *testing.T) {
+	balanceRepository := &MockValuationBalanceRepository{}
+	service := &ValuationService{
+		ValuationRuleRepository: &MockValuationRuleRepository{Rules: []models.ValuationRule{
+			{AccountID: 2, Method: models.ValuationMethodAppreciation, StartDate: "2026-09-01", StartValue: 100000, AnnualRate: 3},
+		}},
+		BalanceRepository: balanceRepository,
+	}
+
+	err := service.ApplyValuationRuleForAccount(1, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(balanceRepository.Estimated) != 0 {
+		t.Errorf("expected nothing to be estimated for an account without a rule, got %v", balanceRepository.Estimated)
+	}
+
+	err = service.ApplyValuationRuleForAccount(2, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(balanceRepository.Estimated[2]) != 2 {
+		t.Errorf("expected 2 estimated balances, got %v", balanceRepository.Estimated[2])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os/exec"
 	"runtime"
+	"time"
 
 	"github.com/alexdglover/sage/internal/dependencyregistry"
 	"github.com/alexdglover/sage/internal/utils/logger"
@@ -20,6 +21,44 @@ func main() {
 	bootstrapper := dependencyRegistry.GetBootstrapper()
 	bootstrapper.BootstrapDatabase(ctx)
 
+	// Recompute merchant names so transactions imported before merchant names existed (or before the
+	// normalization rules changed) are consistent. If any changed, the categorizer has to be retrained
+	// since it learns from merchant names.
+	merchantNormalizer, err := dependencyRegistry.GetMerchantNormalizer()
+	if err != nil {
+		logger.Error("Error while getting merchantNormalizer")
+		panic(err)
+	}
+	updatedMerchantNames, err := merchantNormalizer.NormalizeAllTransactions()
+	if err != nil {
+		logger.Error("Error while normalizing merchant names")
+		panic(err)
+	}
+	if updatedMerchantNames > 0 {
+		categorizer, err := dependencyRegistry.GetMLCategorizer()
+		if err != nil {
+			logger.Error("Error while getting categorizer")
+			panic(err)
+		}
+		err = categorizer.BuildModel()
+		if err != nil {
+			logger.Error("Error while rebuilding categorization model")
+			panic(err)
+		}
+	}
+
+	// Re-estimate the balances of accounts with valuation rules, so the estimates carry on through this month
+	valuationService, err := dependencyRegistry.GetValuationService()
+	if err != nil {
+		logger.Error("Error while getting valuationService")
+		panic(err)
+	}
+	err = valuationService.ApplyAllValuationRules(time.Now())
+	if err != nil {
+		logger.Error("Error while applying valuation rules")
+		panic(err)
+	}
+
 	// open local browser to localhost:8080 if the config is set to true
 	settingsRepository, err := dependencyRegistry.GetSettingsRepository()
 	if err != nil {
@@ -35,6 +74,14 @@ func main() {
 		openbrowser("http://localhost:8080")
 	}
 
+	// Check alerts now and every hour after, so budgets are watched even when nothing's being imported
+	alertService, err := dependencyRegistry.GetAlertService()
+	if err != nil {
+		logger.Error("Error while getting alertService")
+		panic(err)
+	}
+	alertService.StartSchedule(ctx, time.Hour)
+
 	// start the API server
 	apiServer, err := dependencyRegistry.GetApiServer()
 	if err != nil {